@@ -0,0 +1,39 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+const (
+	AuditLoginLockout = "login_lockout"
+)
+
+type AuditLogEntry struct {
+	ID      int64
+	Event   string
+	UserID  string
+	Login   string
+	IP      string
+	Details string
+	Created string
+}
+
+type AuditLogRepo struct {
+	DB *sql.DB
+}
+
+func NewAuditLogRepo(db *sql.DB) *AuditLogRepo {
+	return &AuditLogRepo{
+		DB: db,
+	}
+}
+
+func (repo *AuditLogRepo) Add(entry *AuditLogEntry) error {
+	fmt.Println("Audit log repo: add", entry.Event)
+	_, err := repo.DB.Exec(`INSERT INTO audit_log 
+	(event, user_id, login, ip, details, created) 
+	VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.Event, entry.UserID, entry.Login, entry.IP, entry.Details, entry.Created)
+	return err
+}
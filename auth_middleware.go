@@ -8,21 +8,57 @@ import (
 )
 
 type AuthMiddleware struct {
-	Sm SessionManagerI
+	Sm       SessionManagerI
+	RoleRepo RoleRepoI
 }
 
-func NewAuthMiddleware(sm SessionManagerI) AuthMiddleware {
+func NewAuthMiddleware(sm SessionManagerI, roleRepo RoleRepoI) AuthMiddleware {
 	fmt.Println("Create authmiddleware")
 	return AuthMiddleware{
-		Sm: sm,
+		Sm:       sm,
+		RoleRepo: roleRepo,
 	}
 }
 
 func isAuthURL(r *http.Request) bool {
-	authURLS := map[string]string{
-		"/upvote":   "GET",
-		"/downvote": "GET",
-		"/unvote":   "GET",
+	authURLS := []struct {
+		path   string
+		method string
+	}{
+		{"/api/verify", "POST"},
+		{"/api/2fa/", "POST"},
+		{"/api/roles", "POST"},
+		{"/api/bans", "POST"},
+		{"/api/categories", "POST"},
+		{"/api/notifications", "GET"},
+		{"/api/notifications", "POST"},
+		{"/api/messages", "GET"},
+		{"/api/messages", "POST"},
+		{"/api/feed/following", "GET"},
+		{"/api/user/me/", "GET"},
+		{"/api/user/me/", "POST"},
+	}
+	// the user and the category names are free text, so these match the
+	// whole route with * for one segment and not a part of the path
+	authRoutes := []struct {
+		route  string
+		method string
+	}{
+		{"/api/post/*/upvote", "GET"},
+		{"/api/post/*/downvote", "GET"},
+		{"/api/post/*/unvote", "GET"},
+		{"/api/post/*/*/upvote", "GET"},
+		{"/api/post/*/*/downvote", "GET"},
+		{"/api/post/*/*/unvote", "GET"},
+		{"/api/user/*/upvoted", "GET"},
+		{"/api/user/*/downvoted", "GET"},
+		{"/api/user/*/block", "POST"},
+		{"/api/user/*/unblock", "POST"},
+		{"/api/user/*/follow", "POST"},
+		{"/api/user/*/unfollow", "POST"},
+		{"/api/categories/*/modlog", "GET"},
+		{"/api/categories/*/reports", "GET"},
+		{"/api/categories/*/automod", "GET"},
 	}
 	authMethods := map[string]struct{}{
 		"POST":   struct{}{},
@@ -31,14 +67,33 @@ func isAuthURL(r *http.Request) bool {
 	if _, ok := authMethods[r.Method]; ok && strings.Contains(r.URL.Path, "/post") {
 		return true
 	}
-	for path, method := range authURLS {
-		if strings.Contains(r.URL.Path, path) && r.Method == method {
+	for _, authURL := range authURLS {
+		if strings.HasPrefix(r.URL.Path, authURL.path) && r.Method == authURL.method {
+			return true
+		}
+	}
+	for _, authRoute := range authRoutes {
+		if matchRoute(authRoute.route, r.URL.Path) && r.Method == authRoute.method {
 			return true
 		}
 	}
 	return false
 }
 
+func matchRoute(route string, path string) bool {
+	routeParts := strings.Split(route, "/")
+	pathParts := strings.Split(path, "/")
+	if len(routeParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range routeParts {
+		if part != "*" && part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (amw *AuthMiddleware) AuthMiddlewareSessionJWT(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -46,7 +101,7 @@ func (amw *AuthMiddleware) AuthMiddlewareSessionJWT(next http.Handler) http.Hand
 
 		if !isAuthURL(r) {
 			fmt.Println("shoudn't auth", r.URL.Path)
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, amw.withOptionalSession(r))
 			return
 		}
 
@@ -55,6 +110,14 @@ func (amw *AuthMiddleware) AuthMiddlewareSessionJWT(next http.Handler) http.Hand
 		if err != nil {
 			fmt.Println("error: no auth", err)
 			jsonError(w, http.StatusUnauthorized, "No auth")
+			return
+		}
+
+		sess.Roles, err = amw.RoleRepo.GetByUserId(sess.UserID)
+		if err != nil {
+			fmt.Println("error: can't load roles", err)
+			jsonError(w, http.StatusInternalServerError, "can't load roles")
+			return
 		}
 
 		ctx := context.WithValue(r.Context(), sessionKey, sess)
@@ -62,6 +125,25 @@ func (amw *AuthMiddleware) AuthMiddlewareSessionJWT(next http.Handler) http.Hand
 	})
 }
 
+// withOptionalSession attaches the session to public requests which carry
+// a valid token, so listings can be personalized, anything else stays anonymous
+func (amw *AuthMiddleware) withOptionalSession(r *http.Request) *http.Request {
+	if r.Header.Get("Authorization") == "" {
+		return r
+	}
+	sess, err := amw.Sm.Check(r)
+	if err != nil {
+		return r
+	}
+	sess.Roles, err = amw.RoleRepo.GetByUserId(sess.UserID)
+	if err != nil {
+		fmt.Println("error: can't load roles", err)
+		return r
+	}
+	ctx := context.WithValue(r.Context(), sessionKey, sess)
+	return r.WithContext(ctx)
+}
+
 // func (amw *AuthMiddleware) AuthMiddlewareSession(next http.Handler) http.Handler {
 // 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsAuthURL(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		isAuth bool
+	}{
+		{"GET", "/api/post/p1/upvote", true},
+		{"GET", "/api/post/p1/c1/unvote", true},
+		{"GET", "/api/user/mer/upvoted", true},
+		{"GET", "/api/user/mer/downvoted", true},
+		{"GET", "/api/categories/news/modlog", true},
+		{"POST", "/api/user/mer/follow", true},
+		{"POST", "/api/categories/news/settings", true},
+		{"GET", "/api/user/me/saved", true},
+		{"GET", "/api/notifications", true},
+		//names that only look like the protected routes
+		{"GET", "/api/user/upvote", false},
+		{"GET", "/api/user/upvoted/profile", false},
+		{"GET", "/api/user/reports/posts", false},
+		{"GET", "/api/posts/modlog", false},
+		{"GET", "/api/posts/automod", false},
+		{"GET", "/api/categories", false},
+	}
+	for _, item := range cases {
+		if isAuth := isAuthURL(httptest.NewRequest(item.method, item.path, nil)); isAuth != item.isAuth {
+			t.Errorf("%s %s: expected %v, got %v", item.method, item.path, item.isAuth, isAuth)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AutomodUserID is the system account the automoderator acts as, it is
+// created by schema.sql and has no password to log in with.
+const AutomodUserID = "00000000-0000-0000-0000-00000000a170"
+
+const (
+	AutomodActionRemove = "remove"
+	AutomodActionHold   = "hold"
+	AutomodActionFlair  = "flair"
+	AutomodActionReply  = "reply"
+
+	AutomodTargetAny = "any"
+
+	AutomodConfigMaxLen   = 20000
+	AutomodMaxRules       = 50
+	AutomodRuleNameMaxLen = 64
+	AutomodFlairMaxLen    = 64
+	AutomodReplyMaxLen    = 2000
+)
+
+var (
+	automodModLogActions = map[string]string{
+		AutomodActionRemove: ModLogAutomodRemove,
+		AutomodActionHold:   ModLogAutomodHold,
+		AutomodActionFlair:  ModLogAutomodFlair,
+		AutomodActionReply:  ModLogAutomodReply,
+	}
+	automodTargets = map[string]struct{}{
+		"":                  {},
+		AutomodTargetAny:    {},
+		ModLogTargetPost:    {},
+		ModLogTargetComment: {},
+	}
+	automodPostTypes = map[string]struct{}{
+		"":            {},
+		PostTypeText:  {},
+		PostTypeLink:  {},
+		PostTypeImage: {},
+	}
+	automodURLRe = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+)
+
+// AutomodConfig is saved per category. Every matching rule is applied,
+// a rule matches when all of its conditions hold. With DryRun the hits
+// are only written to the mod log.
+type AutomodConfig struct {
+	DryRun bool           `json:"dry_run"`
+	Rules  []*AutomodRule `json:"rules"`
+}
+
+type AutomodRule struct {
+	Name       string            `json:"name"`
+	Target     string            `json:"target,omitempty"`
+	Conditions AutomodConditions `json:"conditions"`
+	Action     string            `json:"action"`
+	Flair      string            `json:"flair,omitempty"`
+	Reply      string            `json:"reply,omitempty"`
+	DryRun     bool              `json:"dry_run,omitempty"`
+
+	titleRe *regexp.Regexp
+	bodyRe  *regexp.Regexp
+}
+
+// AutomodConditions left empty are not checked. Domains match the
+// links in the title, the body and the url, subdomains included.
+type AutomodConditions struct {
+	TitleRegex           string   `json:"title_regex,omitempty"`
+	BodyRegex            string   `json:"body_regex,omitempty"`
+	Domains              []string `json:"domains,omitempty"`
+	AccountAgeBelowHours int      `json:"account_age_below_hours,omitempty"`
+	KarmaBelow           int64    `json:"karma_below,omitempty"`
+	PostType             string   `json:"post_type,omitempty"`
+}
+
+// AutomodSubmission is a post or a comment about to be stored
+type AutomodSubmission struct {
+	TargetType string
+	CategoryID uint32
+	AuthorID   string
+	PostType   string
+	Title      string
+	Body       string
+	URL        string
+}
+
+type AutomodHit struct {
+	Rule   *AutomodRule
+	DryRun bool
+}
+
+// ParseAutomodConfig rejects unknown fields so a typo in a condition
+// doesn't silently turn the rule into a catch-all
+func ParseAutomodConfig(raw string) (*AutomodConfig, error) {
+	if len(raw) > AutomodConfigMaxLen {
+		return nil, fmt.Errorf("config is too long")
+	}
+	config := &AutomodConfig{}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(config)
+	if nil != err {
+		return nil, fmt.Errorf("can't unpack config: %s", err.Error())
+	}
+	if len(config.Rules) > AutomodMaxRules {
+		return nil, fmt.Errorf("too many rules, at most %d are allowed", AutomodMaxRules)
+	}
+	if config.Rules == nil {
+		config.Rules = []*AutomodRule{}
+	}
+	for i, rule := range config.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("rule %d is empty", i+1)
+		}
+		err = rule.compile()
+		if nil != err {
+			return nil, fmt.Errorf("rule %d: %s", i+1, err.Error())
+		}
+	}
+	return config, nil
+}
+
+func (rule *AutomodRule) compile() error {
+	if rule.Name == "" || len(rule.Name) > AutomodRuleNameMaxLen {
+		return fmt.Errorf("name must be 1-%d characters", AutomodRuleNameMaxLen)
+	}
+	if _, ok := automodTargets[rule.Target]; !ok {
+		return fmt.Errorf("unknown target %q", rule.Target)
+	}
+	if _, ok := automodModLogActions[rule.Action]; !ok {
+		return fmt.Errorf("unknown action %q", rule.Action)
+	}
+	cond := &rule.Conditions
+	if _, ok := automodPostTypes[cond.PostType]; !ok {
+		return fmt.Errorf("unknown post type %q", cond.PostType)
+	}
+	postsOnly := cond.TitleRegex != "" || cond.PostType != "" || rule.Action == AutomodActionFlair
+	if postsOnly && rule.Target == ModLogTargetComment {
+		return fmt.Errorf("title_regex, post_type and flair apply to posts only")
+	}
+	if rule.Action == AutomodActionFlair && (rule.Flair == "" || len(rule.Flair) > AutomodFlairMaxLen) {
+		return fmt.Errorf("flair must be 1-%d characters", AutomodFlairMaxLen)
+	}
+	if rule.Action == AutomodActionReply && (rule.Reply == "" || len(rule.Reply) > AutomodReplyMaxLen) {
+		return fmt.Errorf("reply must be 1-%d characters", AutomodReplyMaxLen)
+	}
+	if cond.AccountAgeBelowHours < 0 {
+		return fmt.Errorf("account_age_below_hours can't be negative")
+	}
+	if cond.TitleRegex == "" && cond.BodyRegex == "" && len(cond.Domains) == 0 &&
+		cond.AccountAgeBelowHours == 0 && cond.KarmaBelow == 0 && cond.PostType == "" {
+		return fmt.Errorf("rule has no conditions")
+	}
+
+	var err error
+	if cond.TitleRegex != "" {
+		rule.titleRe, err = regexp.Compile(cond.TitleRegex)
+		if nil != err {
+			return fmt.Errorf("bad title_regex: %s", err.Error())
+		}
+	}
+	if cond.BodyRegex != "" {
+		rule.bodyRe, err = regexp.Compile(cond.BodyRegex)
+		if nil != err {
+			return fmt.Errorf("bad body_regex: %s", err.Error())
+		}
+	}
+	for i, domain := range cond.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			return fmt.Errorf("empty domain")
+		}
+		cond.Domains[i] = domain
+	}
+	return nil
+}
+
+type Automod struct {
+	AutomodRepo AutomodRepoI
+	UserRepo    UserRepoI
+	TimeGetter  TimeGetterI
+}
+
+func NewAutomod(db *sql.DB) *Automod {
+	return &Automod{
+		AutomodRepo: NewAutomodRepo(db),
+		UserRepo:    NewUserRepo(db),
+		TimeGetter:  &TimeGetter{},
+	}
+}
+
+// Check returns the rules the submission matches. A config broken in
+// the db is logged and skipped, submissions are not blocked by it.
+func (a *Automod) Check(sub *AutomodSubmission) ([]*AutomodHit, error) {
+	settings, err := a.AutomodRepo.GetByCategoryId(sub.CategoryID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if nil != err {
+		return nil, err
+	}
+	config, err := ParseAutomodConfig(settings.Config)
+	if nil != err {
+		fmt.Println("can't parse automod config of category", sub.CategoryID, err)
+		return nil, nil
+	}
+
+	author := &automodAuthor{automod: a, id: sub.AuthorID}
+	hits := []*AutomodHit{}
+	for _, rule := range config.Rules {
+		matched, err := rule.matches(sub, author)
+		if nil != err {
+			return nil, err
+		}
+		if matched {
+			hits = append(hits, &AutomodHit{
+				Rule:   rule,
+				DryRun: config.DryRun || rule.DryRun,
+			})
+		}
+	}
+	return hits, nil
+}
+
+// matches checks the cheap conditions first, the author is loaded
+// only for the rules that need it
+func (rule *AutomodRule) matches(sub *AutomodSubmission, author *automodAuthor) (bool, error) {
+	if rule.Target != "" && rule.Target != AutomodTargetAny && rule.Target != sub.TargetType {
+		return false, nil
+	}
+	cond := &rule.Conditions
+	isPost := sub.TargetType == ModLogTargetPost
+	if !isPost && (rule.Action == AutomodActionFlair || rule.titleRe != nil || cond.PostType != "") {
+		return false, nil
+	}
+	if cond.PostType != "" && cond.PostType != sub.PostType {
+		return false, nil
+	}
+	if rule.titleRe != nil && !rule.titleRe.MatchString(sub.Title) {
+		return false, nil
+	}
+	if rule.bodyRe != nil && !rule.bodyRe.MatchString(sub.Body) {
+		return false, nil
+	}
+	if len(cond.Domains) > 0 && !linksMatchDomains(sub.Title+" "+sub.Body+" "+sub.URL, cond.Domains) {
+		return false, nil
+	}
+
+	if cond.AccountAgeBelowHours > 0 {
+		age, known, err := author.age()
+		if nil != err {
+			return false, err
+		}
+		if !known || age >= time.Duration(cond.AccountAgeBelowHours)*time.Hour {
+			return false, nil
+		}
+	}
+	if cond.KarmaBelow != 0 {
+		karma, err := author.karma()
+		if nil != err {
+			return false, err
+		}
+		if karma >= cond.KarmaBelow {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func linksMatchDomains(text string, domains []string) bool {
+	for _, link := range automodURLRe.FindAllString(text, -1) {
+		u, err := url.Parse(link)
+		if nil != err {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		for _, domain := range domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// automodAuthor caches the author lookups between the rules of one check
+type automodAuthor struct {
+	automod  *Automod
+	id       string
+	user     *User
+	karmaSum *int64
+}
+
+func (author *automodAuthor) age() (time.Duration, bool, error) {
+	if author.user == nil {
+		user, err := author.automod.UserRepo.GetById(author.id)
+		if nil != err {
+			return 0, false, err
+		}
+		author.user = user
+	}
+	age, known := accountAge(author.user, author.automod.TimeGetter.Now())
+	return age, known, nil
+}
+
+// accountAge is unknown for the accounts without a parsable creation time
+func accountAge(user *User, now time.Time) (time.Duration, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		created, err := time.Parse(layout, user.Created)
+		if nil == err {
+			return now.Sub(created), true
+		}
+	}
+	return 0, false
+}
+
+func (author *automodAuthor) karma() (int64, error) {
+	if author.karmaSum == nil {
+		karma, err := author.automod.UserRepo.GetKarma(author.id)
+		if nil != err {
+			return 0, err
+		}
+		author.karmaSum = &karma
+	}
+	return *author.karmaSum, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// automodApply changes the post or the comment before it is stored,
+// dry run hits are only logged. The first matching flair wins.
+func automodApply(hits []*AutomodHit, removed *bool, flair *string) {
+	for _, hit := range hits {
+		if hit.DryRun {
+			continue
+		}
+		switch hit.Rule.Action {
+		case AutomodActionRemove, AutomodActionHold:
+			*removed = true
+		case AutomodActionFlair:
+			if flair != nil && *flair == "" {
+				*flair = hit.Rule.Flair
+			}
+		}
+	}
+}
+
+// automodFollowUp runs once the content is stored: held items go to
+// the report queue, replies are posted as the automoderator and every
+// hit is written to the mod log. Failures here don't fail the request.
+func (h *PostsHandler) automodFollowUp(hits []*AutomodHit, target *Report) {
+	automodSess := &Session{UserID: AutomodUserID}
+	for _, hit := range hits {
+		rule := hit.Rule
+		reason := fmt.Sprintf("rule %q", rule.Name)
+		if hit.DryRun {
+			h.addModLog(automodSess, target.CategoryID, automodModLogActions[rule.Action], target.TargetType, target.TargetID, reason+" (dry run)")
+			continue
+		}
+
+		var err error
+		switch rule.Action {
+		case AutomodActionHold:
+			report := *target
+			report.ReporterID = AutomodUserID
+			report.Reason = "held by automoderator: " + reason
+			report.Created = h.TimeGetter.GetCreated()
+			_, err = h.ReportRepo.Add(&report)
+		case AutomodActionReply:
+			_, err = h.CommentRepo.Add(&Comment{
+				ID:      h.UUIDGetter.GetUUID(),
+				Body:    rule.Reply,
+				PostId:  target.PostID,
+				UserId:  AutomodUserID,
+				Created: h.TimeGetter.GetCreated(),
+			})
+		}
+		if nil != err {
+			fmt.Println("can't apply automod rule", rule.Name, err)
+			continue
+		}
+		h.addModLog(automodSess, target.CategoryID, automodModLogActions[rule.Action], target.TargetType, target.TargetID, reason)
+	}
+}
+
+// GetAutomod shows the automoderator config of the category to its moderators
+func (h *CategoriesHandler) GetAutomod(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	category, ok := h.automodCategory(w, r, sess)
+	if !ok {
+		return
+	}
+
+	settings, err := h.AutomodRepo.GetByCategoryId(category.ID)
+	if err == sql.ErrNoRows {
+		jsonResponse(w, &AutomodConfig{Rules: []*AutomodRule{}})
+		return
+	} else if err != nil {
+		fmt.Println("can't get automod config", err)
+		jsonError(w, http.StatusInternalServerError, "can't get automod config")
+		return
+	}
+	config, err := ParseAutomodConfig(settings.Config)
+	if err != nil {
+		fmt.Println("can't parse automod config", err)
+		jsonError(w, http.StatusInternalServerError, "can't parse automod config")
+		return
+	}
+	jsonResponse(w, config)
+}
+
+// SaveAutomod replaces the whole config, it is validated before saving
+// so a broken rule never reaches submissions
+func (h *CategoriesHandler) SaveAutomod(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	category, ok := h.automodCategory(w, r, sess)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(AutomodConfigMaxLen)+1))
+	r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	raw := strings.TrimSpace(string(body))
+	_, err = ParseAutomodConfig(raw)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.AutomodRepo.Save(&AutomodSettings{
+		CategoryID: category.ID,
+		Config:     raw,
+		UpdatedBy:  sess.UserID,
+		Updated:    h.TimeGetter.GetCreated(),
+	})
+	if nil != err {
+		fmt.Println("can't save automod config", err)
+		jsonError(w, http.StatusInternalServerError, "can't save automod config")
+		return
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *CategoriesHandler) automodCategory(w http.ResponseWriter, r *http.Request, sess *Session) (*Category, bool) {
+	category, err := h.DictionaryRepo.GetCategoryByName(mux.Vars(r)["CATEGORY_NAME"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "category not found")
+		return nil, false
+	} else if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return nil, false
+	}
+	if !Can(sess, ActionManageAutomod, &Resource{CategoryID: category.ID}) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return nil, false
+	}
+	return category, true
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type AutomodRepo struct {
+	DB *sql.DB
+}
+
+func NewAutomodRepo(db *sql.DB) *AutomodRepo {
+	return &AutomodRepo{
+		DB: db,
+	}
+}
+
+// GetByCategoryId returns sql.ErrNoRows for categories without a config
+func (repo *AutomodRepo) GetByCategoryId(categoryID uint32) (*AutomodSettings, error) {
+	fmt.Println("Automod repo: get by category id")
+	settings := &AutomodSettings{}
+	err := repo.DB.
+		QueryRow(`SELECT category_id, config, updated_by, updated FROM automod_config WHERE category_id = ?`, categoryID).
+		Scan(&settings.CategoryID, &settings.Config, &settings.UpdatedBy, &settings.Updated)
+	if nil != err {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (repo *AutomodRepo) Save(settings *AutomodSettings) error {
+	fmt.Println("Automod repo: save")
+	_, err := repo.DB.Exec(`INSERT INTO automod_config 
+	(category_id, config, updated_by, updated) 
+	VALUES (?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE config = VALUES(config), updated_by = VALUES(updated_by), updated = VALUES(updated)`,
+		settings.CategoryID, settings.Config, settings.UpdatedBy, settings.Updated)
+	return err
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestParseAutomodConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"valid", `{"rules":[{"name":"spam","conditions":{"domains":["spam.example"]},"action":"remove"}]}`, ""},
+		{"empty", `{}`, ""},
+		{"unknown field", `{"rules":[{"name":"spam","conditions":{"domain":["spam.example"]},"action":"remove"}]}`, "can't unpack config"},
+		{"no conditions", `{"rules":[{"name":"spam","action":"remove"}]}`, "rule has no conditions"},
+		{"unknown action", `{"rules":[{"name":"spam","conditions":{"post_type":"link"},"action":"delete"}]}`, "unknown action"},
+		{"bad regex", `{"rules":[{"name":"spam","conditions":{"body_regex":"(buy"},"action":"remove"}]}`, "bad body_regex"},
+		{"flair without text", `{"rules":[{"name":"q","conditions":{"title_regex":"\\?$"},"action":"flair"}]}`, "flair must be"},
+		{"flair on comments", `{"rules":[{"name":"q","target":"comment","conditions":{"body_regex":"\\?$"},"action":"flair","flair":"question"}]}`, "posts only"},
+		{"reply without text", `{"rules":[{"name":"new","conditions":{"account_age_below_hours":24},"action":"reply"}]}`, "reply must be"},
+		{"no name", `{"rules":[{"conditions":{"post_type":"link"},"action":"hold"}]}`, "name must be"},
+	}
+	for _, c := range cases {
+		_, err := ParseAutomodConfig(c.config)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err)
+		} else if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: expected error %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestAutomodCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	automodRepoMock := NewMockAutomodRepoI(ctrl)
+	userRepoMock := NewMockUserRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	automod := &Automod{
+		AutomodRepo: automodRepoMock,
+		UserRepo:    userRepoMock,
+		TimeGetter:  timeGetterMock,
+	}
+	config := `{"rules":[
+		{"name":"spam links","conditions":{"domains":["spam.example"]},"action":"remove"},
+		{"name":"questions","target":"post","conditions":{"title_regex":"(?i)^how","post_type":"text"},"action":"flair","flair":"question"},
+		{"name":"new accounts","conditions":{"account_age_below_hours":24,"karma_below":10},"action":"hold","dry_run":true},
+		{"name":"welcome","target":"comment","conditions":{"body_regex":"hello"},"action":"reply","reply":"hi"}
+	]}`
+	author := &User{ID: sess.UserID, Created: "2022-11-10T10:00:00Z"}
+	now := time.Date(2022, 11, 10, 12, 0, 0, 0, time.UTC)
+	ruleNames := func(hits []*AutomodHit) string {
+		names := []string{}
+		for _, hit := range hits {
+			name := hit.Rule.Name
+			if hit.DryRun {
+				name += " (dry run)"
+			}
+			names = append(names, name)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	//post matching every rule, the author is loaded once
+	automodRepoMock.EXPECT().GetByCategoryId(uint32(1)).Return(&AutomodSettings{CategoryID: 1, Config: config}, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(author, nil)
+	userRepoMock.EXPECT().GetKarma(sess.UserID).Return(int64(3), nil)
+	timeGetterMock.EXPECT().Now().Return(now)
+	hits, err := automod.Check(&AutomodSubmission{
+		TargetType: ModLogTargetPost,
+		CategoryID: 1,
+		AuthorID:   sess.UserID,
+		PostType:   "text",
+		Title:      "How to get rich",
+		Body:       "see https://www.spam.example/offer",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if names := ruleNames(hits); names != "spam links, questions, new accounts (dry run)" {
+		t.Errorf("unexpected hits: %s", names)
+		return
+	}
+
+	//comments skip the post rules, old accounts don't need the karma
+	automodRepoMock.EXPECT().GetByCategoryId(uint32(1)).Return(&AutomodSettings{CategoryID: 1, Config: config}, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(&User{ID: sess.UserID, Created: "2020-01-01 00:00:00"}, nil)
+	timeGetterMock.EXPECT().Now().Return(now)
+	hits, err = automod.Check(&AutomodSubmission{
+		TargetType: ModLogTargetComment,
+		CategoryID: 1,
+		AuthorID:   sess.UserID,
+		Body:       "hello, how are you? http://notspam.example",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if names := ruleNames(hits); names != "welcome" {
+		t.Errorf("unexpected hits: %s", names)
+		return
+	}
+
+	//no config
+	automodRepoMock.EXPECT().GetByCategoryId(uint32(2)).Return(nil, sql.ErrNoRows)
+	hits, err = automod.Check(&AutomodSubmission{TargetType: ModLogTargetPost, CategoryID: 2})
+	if err != nil || len(hits) != 0 {
+		t.Errorf("expected no hits, got %d %v", len(hits), err)
+		return
+	}
+
+	//user repo error
+	automodRepoMock.EXPECT().GetByCategoryId(uint32(1)).Return(&AutomodSettings{CategoryID: 1, Config: config}, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(nil, fmt.Errorf("db error"))
+	_, err = automod.Check(&AutomodSubmission{TargetType: ModLogTargetComment, CategoryID: 1, AuthorID: sess.UserID})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+}
+
+func TestAddAutomod(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:      postsRepoMock,
+		DTOConverter:   dtoConverterMock,
+		DictionaryRepo: dictionaryRepoMock,
+		CommentRepo:    commentRepoMock,
+		ReportRepo:     reportRepoMock,
+		ModLogRepo:     modLogRepoMock,
+		TimeGetter:     timeGetterMock,
+		UUIDGetter:     uuidGetterMock,
+		BanRepo:        banRepoMock,
+		Automod:        automodMock,
+	}
+	postID := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
+	replyID := "0b9f0e36-2b4c-4d0c-8a4e-2f4b8c7f3c11"
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z").AnyTimes()
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil)
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return(postID)
+	automodMock.EXPECT().Check(&AutomodSubmission{
+		TargetType: ModLogTargetPost,
+		CategoryID: 1,
+		AuthorID:   sess.UserID,
+		PostType:   "text",
+		Title:      "buy now",
+		Body:       "cheap",
+	}).Return([]*AutomodHit{
+		{Rule: &AutomodRule{Name: "spam", Action: AutomodActionHold}},
+		{Rule: &AutomodRule{Name: "ads", Action: AutomodActionFlair, Flair: "ad"}},
+		{Rule: &AutomodRule{Name: "welcome", Action: AutomodActionReply, Reply: "read the rules"}},
+		{Rule: &AutomodRule{Name: "trial", Action: AutomodActionRemove}, DryRun: true},
+	}, nil)
+
+	//held and flaired before it is stored
+	postsRepoMock.EXPECT().Add(&Post{
+		ID:          postID,
+		Title:       "buy now",
+		Type:        "text",
+		Description: "cheap",
+		Score:       1,
+		UserID:      sess.UserID,
+		CategoryID:  1,
+		Created:     "2022-11-09T19:51:42Z",
+		Removed:     true,
+		Flair:       "ad",
+	}).Return(&postID, nil)
+	reportRepoMock.EXPECT().Add(&Report{
+		TargetType: ModLogTargetPost,
+		TargetID:   postID,
+		PostID:     postID,
+		CategoryID: 1,
+		AuthorID:   sess.UserID,
+		ReporterID: AutomodUserID,
+		Reason:     `held by automoderator: rule "spam"`,
+		Created:    "2022-11-09T19:51:42Z",
+	}).Return(true, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return(replyID)
+	commentRepoMock.EXPECT().Add(&Comment{
+		ID:      replyID,
+		Body:    "read the rules",
+		PostId:  postID,
+		UserId:  AutomodUserID,
+		Created: "2022-11-09T19:51:42Z",
+	}).Return(&replyID, nil)
+	for _, entry := range []*ModLogEntry{
+		{Action: ModLogAutomodHold, Reason: `rule "spam"`},
+		{Action: ModLogAutomodFlair, Reason: `rule "ads"`},
+		{Action: ModLogAutomodReply, Reason: `rule "welcome"`},
+		{Action: ModLogAutomodRemove, Reason: `rule "trial" (dry run)`},
+	} {
+		entry.CategoryID = 1
+		entry.ModeratorID = AutomodUserID
+		entry.TargetType = ModLogTargetPost
+		entry.TargetID = postID
+		entry.Created = "2022-11-09T19:51:42Z"
+		modLogRepoMock.EXPECT().Add(entry).Return(nil)
+	}
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
+
+	req := httptest.NewRequest("POST", "/api/posts", strings.NewReader(`{"category":"fashion","type":"text","title":"buy now","text":"cheap"}`))
+	w := httptest.NewRecorder()
+	service.Add(w, req.WithContext(context.WithValue(req.Context(), sessionKey, sess)))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestSaveAutomod(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	automodRepoMock := NewMockAutomodRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+		AutomodRepo:    automodRepoMock,
+		TimeGetter:     timeGetterMock,
+	}
+	config := `{"dry_run":true,"rules":[{"name":"spam","conditions":{"domains":["spam.example"]},"action":"remove"}]}`
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil).AnyTimes()
+	newRequest := func(body string, s *Session) *http.Request {
+		req := httptest.NewRequest("POST", "/api/categories/fashion/automod", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"CATEGORY_NAME": "fashion"})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, s))
+	}
+
+	//success
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
+	automodRepoMock.EXPECT().Save(&AutomodSettings{
+		CategoryID: 1,
+		Config:     config,
+		UpdatedBy:  modSess.UserID,
+		Updated:    "2022-11-09T19:51:42Z",
+	}).Return(nil)
+	w := httptest.NewRecorder()
+	service.SaveAutomod(w, newRequest(config, modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//invalid config
+	w = httptest.NewRecorder()
+	service.SaveAutomod(w, newRequest(`{"rules":[{"name":"spam","action":"remove"}]}`, modSess))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//not a moderator
+	w = httptest.NewRecorder()
+	service.SaveAutomod(w, newRequest(config, sess))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//repo error
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
+	automodRepoMock.EXPECT().Save(gomock.Any()).Return(fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.SaveAutomod(w, newRequest(config, modSess))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
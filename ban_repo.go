@@ -0,0 +1,87 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// banActive is the condition for bans which haven't expired, expires is
+// RFC3339 in UTC so it compares as a string
+const banActive = `(expires = '' OR expires > ?)`
+
+func banNow() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+type BanRepo struct {
+	DB *sql.DB
+}
+
+func NewBanRepo(db *sql.DB) *BanRepo {
+	return &BanRepo{
+		DB: db,
+	}
+}
+
+// Add replaces the reason and the expiry of an existing ban
+func (repo *BanRepo) Add(ban *Ban) error {
+	fmt.Println("Ban repo: add", ban.Kind)
+	_, err := repo.DB.Exec(`INSERT INTO ban 
+	(user_id, kind, category_id, reason, moderator_id, created, expires) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE reason = VALUES(reason), moderator_id = VALUES(moderator_id), 
+	created = VALUES(created), expires = VALUES(expires)`,
+		ban.UserID, ban.Kind, ban.CategoryID, ban.Reason, ban.ModeratorID, ban.Created, ban.Expires)
+	return err
+}
+
+func (repo *BanRepo) Delete(userID string, kind string, categoryID uint32) (bool, error) {
+	fmt.Println("Ban repo: delete", kind)
+	result, err := repo.DB.Exec(
+		"DELETE FROM ban WHERE user_id = ? AND kind = ? AND category_id = ?",
+		userID, kind, categoryID)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// IsBanned checks the site-wide ban and the ban in the category
+func (repo *BanRepo) IsBanned(userID string, categoryID uint32) (bool, error) {
+	fmt.Println("Ban repo: is banned")
+	var exists bool
+	err := repo.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM ban 
+	WHERE user_id = ? AND kind = 'ban' AND category_id IN (0, ?) AND `+banActive+`)`,
+		userID, categoryID, banNow()).
+		Scan(&exists)
+	if nil != err {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (repo *BanRepo) GetShadowbannedUserIds() (map[string]struct{}, error) {
+	fmt.Println("Ban repo: get shadowbanned user ids")
+	rows, err := repo.DB.Query(`SELECT user_id FROM ban 
+	WHERE kind = 'shadowban' AND category_id = 0 AND `+banActive,
+		banNow())
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	userIds := map[string]struct{}{}
+	for rows.Next() {
+		var userID string
+		err := rows.Scan(&userID)
+		if nil != err {
+			return nil, err
+		}
+		userIds[userID] = struct{}{}
+	}
+	return userIds, rows.Err()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBanAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewBanRepo(db)
+	ban := &Ban{
+		UserID:      "522cd619-841f-43d5-866d-f880e5f48d18",
+		Kind:        BanKindBan,
+		CategoryID:  1,
+		Reason:      "spam",
+		ModeratorID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11",
+		Created:     "2022-11-10T11:24:44Z",
+		Expires:     "2022-11-11T11:24:44Z",
+	}
+
+	// success
+	mock.ExpectExec(`INSERT INTO ban .* ON DUPLICATE KEY UPDATE`).
+		WithArgs(ban.UserID, ban.Kind, ban.CategoryID, ban.Reason, ban.ModeratorID, ban.Created, ban.Expires).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = repo.Add(ban)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestBanIsBanned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewBanRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+
+	// success
+	mock.ExpectQuery(`WHERE user_id = \? AND kind = 'ban' AND category_id IN \(0, \?\) AND \(expires = '' OR expires > \?\)`).
+		WithArgs(userID, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	isBanned, err := repo.IsBanned(userID, 1)
+	if err != nil || !isBanned {
+		t.Errorf("expected ban, got %v %s", isBanned, err)
+		return
+	}
+
+	// query error
+	mock.ExpectQuery(`FROM ban`).
+		WithArgs(userID, 1, sqlmock.AnyArg()).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = repo.IsBanned(userID, 1)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestBanGetShadowbannedUserIds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewBanRepo(db)
+
+	// success
+	mock.ExpectQuery(`SELECT user_id FROM ban WHERE kind = 'shadowban' AND category_id = 0`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("522cd619-841f-43d5-866d-f880e5f48d18"))
+	userIds, err := repo.GetShadowbannedUserIds()
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if _, ok := userIds["522cd619-841f-43d5-866d-f880e5f48d18"]; !ok || len(userIds) != 1 {
+		t.Errorf("unexpected result %#v", userIds)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+type BansHandler struct {
+	UserRepo       UserRepoI
+	DictionaryRepo DictionaryRepoI
+	BanRepo        BanRepoI
+	ReportRepo     ReportRepoI
+	ModLogRepo     ModLogRepoI
+	TimeGetter     TimeGetterI
+	Logger         *log.Logger
+}
+
+const BanReasonMaxLen = 255
+
+func NewBansHandler(db *sql.DB) *BansHandler {
+	return &BansHandler{
+		UserRepo:       NewUserRepo(db),
+		DictionaryRepo: NewDictionaryRepo(db),
+		BanRepo:        NewBanRepo(db),
+		ReportRepo:     NewReportRepo(db),
+		ModLogRepo:     NewModLogRepo(db),
+		TimeGetter:     &TimeGetter{},
+		Logger:         nil,
+	}
+}
+
+// Ban resolves the open reports against the user in the banned scope,
+// that's how a moderator bans the author from the report queue
+func (h *BansHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	sess, ban, ok := h.readBanRequest(w, r)
+	if !ok {
+		return
+	}
+	err := h.BanRepo.Add(ban)
+	if nil != err {
+		fmt.Println("can't add ban: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't add ban")
+		return
+	}
+	_, err = h.ReportRepo.ResolveByAuthor(ban.UserID, ban.CategoryID)
+	if nil != err {
+		fmt.Println("can't resolve reports: ", err.Error())
+	}
+	h.addModLog(sess, ban, ModLogBanUser)
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *BansHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	sess, ban, ok := h.readBanRequest(w, r)
+	if !ok {
+		return
+	}
+	isDeleted, err := h.BanRepo.Delete(ban.UserID, ban.Kind, ban.CategoryID)
+	if nil != err {
+		fmt.Println("can't delete ban: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't delete ban")
+		return
+	}
+	if !isDeleted {
+		jsonError(w, http.StatusNotFound, "ban not found")
+		return
+	}
+	h.addModLog(sess, ban, ModLogUnbanUser)
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// readBanRequest resolves the user and the category of the request and
+// checks the policy: moderators ban in their categories, site-wide bans
+// and shadowbans are for admins; it writes the error itself.
+func (h *BansHandler) readBanRequest(w http.ResponseWriter, r *http.Request) (*Session, *Ban, bool) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return nil, nil, false
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't read request")
+		return nil, nil, false
+	}
+	banRequest := &BanRequestDTO{}
+	err = json.Unmarshal(body, banRequest)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return nil, nil, false
+	}
+
+	ban := &Ban{
+		Kind:        banRequest.Kind,
+		Reason:      banRequest.Reason,
+		ModeratorID: sess.UserID,
+		Created:     h.TimeGetter.GetCreated(),
+	}
+	if ban.Kind == "" {
+		ban.Kind = BanKindBan
+	}
+	if ban.Kind != BanKindBan && ban.Kind != BanKindShadowban {
+		jsonError(w, http.StatusBadRequest, "unknown ban kind")
+		return nil, nil, false
+	}
+	if ban.Kind == BanKindShadowban && banRequest.Category != "" {
+		jsonError(w, http.StatusBadRequest, "shadowbans are site-wide")
+		return nil, nil, false
+	}
+	if len(ban.Reason) > BanReasonMaxLen || banRequest.DurationHours < 0 {
+		jsonError(w, http.StatusBadRequest, "bad reason or duration")
+		return nil, nil, false
+	}
+	if banRequest.DurationHours > 0 {
+		duration := time.Duration(banRequest.DurationHours) * time.Hour
+		ban.Expires = h.TimeGetter.Now().UTC().Add(duration).Format(time.RFC3339)
+	}
+
+	var resource *Resource
+	if banRequest.Category != "" {
+		category, err := h.DictionaryRepo.GetCategoryByName(banRequest.Category)
+		if err == sql.ErrNoRows {
+			jsonError(w, http.StatusNotFound, "category not found")
+			return nil, nil, false
+		} else if nil != err {
+			fmt.Println("can't get category: ", err.Error())
+			jsonError(w, http.StatusInternalServerError, "can't get category")
+			return nil, nil, false
+		}
+		ban.CategoryID = category.ID
+		resource = &Resource{CategoryID: category.ID}
+	}
+	if !Can(sess, ActionBanUser, resource) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return nil, nil, false
+	}
+
+	var user *User
+	if banRequest.UserID != "" {
+		user, err = h.UserRepo.GetById(banRequest.UserID)
+	} else {
+		user, err = h.UserRepo.GetByLogin(banRequest.UserName)
+	}
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, nil, false
+	} else if nil != err {
+		fmt.Println("can't get user: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, nil, false
+	}
+	if user.ID == sess.UserID {
+		jsonError(w, http.StatusBadRequest, "can't ban yourself")
+		return nil, nil, false
+	}
+	ban.UserID = user.ID
+	return sess, ban, true
+}
+
+func (h *BansHandler) addModLog(sess *Session, ban *Ban, action string) {
+	err := h.ModLogRepo.Add(&ModLogEntry{
+		CategoryID:  ban.CategoryID,
+		ModeratorID: sess.UserID,
+		Action:      action,
+		TargetType:  ModLogTargetUser,
+		TargetID:    ban.UserID,
+		Reason:      ban.Reason,
+		Created:     ban.Created,
+	})
+	if nil != err {
+		fmt.Println("can't add mod log entry", err)
+	}
+}
+
+// checkCategoryBan writes 403 if the user may not take part in the category
+func checkCategoryBan(w http.ResponseWriter, banRepo BanRepoI, userID string, categoryID uint32) bool {
+	isBanned, err := banRepo.IsBanned(userID, categoryID)
+	if nil != err {
+		fmt.Println("can't check ban", err)
+		jsonError(w, http.StatusInternalServerError, "can't check ban")
+		return false
+	}
+	if isBanned {
+		jsonError(w, http.StatusForbidden, "you are banned in this category")
+		return false
+	}
+	return true
+}
+
+// hideShadowbanned drops the posts and comments of shadowbanned users,
+// except for their own view and for admins
+func hideShadowbanned(banRepo BanRepoI, sess *Session, posts []*PostDTO) ([]*PostDTO, error) {
+	isHidden, err := shadowbanFilter(banRepo, sess)
+	if nil != err {
+		return nil, err
+	}
+	isHiddenAuthor := func(author *AuthorDTO) bool {
+		return author != nil && isHidden(author.ID)
+	}
+
+	visible := make([]*PostDTO, 0, len(posts))
+	for _, post := range posts {
+		if isHiddenAuthor(post.Author) {
+			continue
+		}
+		comments := make([]*CommentDTO, 0, len(post.Comments))
+		for _, comment := range post.Comments {
+			if !isHiddenAuthor(comment.Author) {
+				comments = append(comments, comment)
+			}
+		}
+		post.Comments = comments
+		visible = append(visible, post)
+	}
+	return visible, nil
+}
+
+// shadowbanFilter tells whether the content of an author is hidden from
+// the viewer
+func shadowbanFilter(banRepo BanRepoI, sess *Session) (func(authorID string) bool, error) {
+	if sess != nil && sess.IsAdmin() {
+		return func(string) bool { return false }, nil
+	}
+	shadowbanned, err := banRepo.GetShadowbannedUserIds()
+	if nil != err {
+		return nil, err
+	}
+	viewerID := ""
+	if sess != nil {
+		viewerID = sess.UserID
+	}
+	return func(authorID string) bool {
+		if authorID == viewerID {
+			return false
+		}
+		_, ok := shadowbanned[authorID]
+		return ok
+	}, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestBan(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &BansHandler{
+		UserRepo:       userRepoMock,
+		DictionaryRepo: dictionaryRepoMock,
+		BanRepo:        banRepoMock,
+		ReportRepo:     reportRepoMock,
+		ModLogRepo:     modLogRepoMock,
+		TimeGetter:     timeGetterMock,
+	}
+	author := &User{ID: sess.UserID, Login: "mer"}
+	now := time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC)
+	newRequest := func(body string, s *Session) *http.Request {
+		req := httptest.NewRequest("POST", "/api/bans", strings.NewReader(body))
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, s))
+	}
+
+	//moderator bans the author in the category for a day
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	timeGetterMock.EXPECT().Now().Return(now)
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil)
+	userRepoMock.EXPECT().GetById(author.ID).Return(author, nil)
+	banRepoMock.EXPECT().Add(&Ban{
+		UserID:      author.ID,
+		Kind:        BanKindBan,
+		CategoryID:  1,
+		Reason:      "spam",
+		ModeratorID: modSess.UserID,
+		Created:     "2022-11-10T11:24:44Z",
+		Expires:     "2022-11-11T11:24:44Z",
+	}).Return(nil)
+	reportRepoMock.EXPECT().ResolveByAuthor(author.ID, uint32(1)).Return(int64(2), nil)
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil)
+	w := httptest.NewRecorder()
+	service.Ban(w, newRequest(`{"user_id":"`+author.ID+`","category":"fashion","reason":"spam","duration_hours":24}`, modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//moderator can't ban site-wide
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	w = httptest.NewRecorder()
+	service.Ban(w, newRequest(`{"username":"mer"}`, modSess))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//shadowbans are site-wide
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	w = httptest.NewRecorder()
+	service.Ban(w, newRequest(`{"username":"mer","category":"fashion","kind":"shadowban"}`, modSess))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//admin shadowbans forever
+	admin := &Session{UserID: modSess.UserID, Roles: []*Role{{Name: RoleAdmin}}}
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	userRepoMock.EXPECT().GetByLogin("mer").Return(author, nil)
+	banRepoMock.EXPECT().Add(&Ban{
+		UserID:      author.ID,
+		Kind:        BanKindShadowban,
+		ModeratorID: modSess.UserID,
+		Created:     "2022-11-10T11:24:44Z",
+	}).Return(nil)
+	reportRepoMock.EXPECT().ResolveByAuthor(author.ID, uint32(0)).Return(int64(0), nil)
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil)
+	w = httptest.NewRecorder()
+	service.Ban(w, newRequest(`{"username":"mer","kind":"shadowban"}`, admin))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	userRepoMock.EXPECT().GetByLogin("mer").Return(author, nil)
+	banRepoMock.EXPECT().Add(gomock.Any()).Return(fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.Ban(w, newRequest(`{"username":"mer"}`, admin))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestUnban(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &BansHandler{
+		UserRepo:   userRepoMock,
+		BanRepo:    banRepoMock,
+		ModLogRepo: modLogRepoMock,
+		TimeGetter: timeGetterMock,
+	}
+	admin := &Session{UserID: modSess.UserID, Roles: []*Role{{Name: RoleAdmin}}}
+	author := &User{ID: sess.UserID, Login: "mer"}
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/api/bans/revoke", strings.NewReader(`{"username":"mer"}`))
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, admin))
+	}
+
+	//success
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	userRepoMock.EXPECT().GetByLogin("mer").Return(author, nil)
+	banRepoMock.EXPECT().Delete(author.ID, BanKindBan, uint32(0)).Return(true, nil)
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil)
+	w := httptest.NewRecorder()
+	service.Unban(w, newRequest())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//not banned
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	userRepoMock.EXPECT().GetByLogin("mer").Return(author, nil)
+	banRepoMock.EXPECT().Delete(author.ID, BanKindBan, uint32(0)).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.Unban(w, newRequest())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestCategoryBanBlocksVotes(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	service := &PostsHandler{
+		PostsRepo: postsRepoMock,
+		BanRepo:   banRepoMock,
+	}
+	postId := multipleComplexData[0].Post.ID
+
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	banRepoMock.EXPECT().IsBanned(sess.UserID, uint32(1)).Return(true, nil)
+	req := httptest.NewRequest("GET", "/api/post/"+postId+"/upvote", nil)
+	req = mux.SetURLVars(req, map[string]string{"POST_ID": postId})
+	w := httptest.NewRecorder()
+	service.UpVote(w, req.WithContext(context.WithValue(req.Context(), sessionKey, sess)))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestHideShadowbanned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	banRepoMock := NewMockBanRepoI(ctrl)
+	shadowbanned := &AuthorDTO{UserName: "troll", ID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11"}
+	regular := &AuthorDTO{UserName: "mer", ID: sess.UserID}
+	newPosts := func() []*PostDTO {
+		return []*PostDTO{
+			{ID: "1", Author: shadowbanned},
+			{ID: "2", Author: regular, Comments: []*CommentDTO{
+				{ID: "c1", Author: shadowbanned},
+				{ID: "c2", Author: regular},
+			}},
+		}
+	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().
+		Return(map[string]struct{}{shadowbanned.ID: {}}, nil).AnyTimes()
+
+	// others don't see the content
+	posts, err := hideShadowbanned(banRepoMock, sess, newPosts())
+	if err != nil || len(posts) != 1 || posts[0].ID != "2" || len(posts[0].Comments) != 1 || posts[0].Comments[0].ID != "c2" {
+		t.Errorf("shadowbanned content is visible: %#v %v", posts, err)
+		return
+	}
+
+	// anonymous
+	posts, _ = hideShadowbanned(banRepoMock, nil, newPosts())
+	if len(posts) != 1 {
+		t.Errorf("shadowbanned content is visible to anonymous: %d", len(posts))
+		return
+	}
+
+	// the author sees everything
+	posts, _ = hideShadowbanned(banRepoMock, &Session{UserID: shadowbanned.ID}, newPosts())
+	if len(posts) != 2 || len(posts[1].Comments) != 2 {
+		t.Errorf("shadowbanned author doesn't see own content: %#v", posts)
+		return
+	}
+}
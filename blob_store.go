@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrBadBlobKey   = errors.New("bad blob key")
+
+	blobKeyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9/_.-]*$`)
+)
+
+// Blob is a stored file opened for reading, the caller closes Body
+type Blob struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	Modified    time.Time
+}
+
+// checkBlobKey keeps the keys to the ones the server generates, so a
+// key from the url can't walk out of the store
+func checkBlobKey(key string) error {
+	if !blobKeyRe.MatchString(key) || path.Clean(key) != key || strings.Contains(key, "..") {
+		return ErrBadBlobKey
+	}
+	return nil
+}
+
+// NewBlobStore picks the S3 store when S3_ENDPOINT is set and falls
+// back to the local directory UPLOADS_DIR, ./uploads by default.
+func NewBlobStore() BlobStore {
+	if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3BlobStore(endpoint, os.Getenv("S3_BUCKET"), region,
+			os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"))
+	}
+	dir := os.Getenv("UPLOADS_DIR")
+	if dir == "" {
+		dir = "./uploads"
+	}
+	fmt.Println("S3_ENDPOINT is not set, store uploads in", dir)
+	return NewLocalBlobStore(dir)
+}
+
+// LocalBlobStore keeps the blobs as files under Dir, the content type
+// comes from the key extension
+type LocalBlobStore struct {
+	Dir string
+}
+
+func NewLocalBlobStore(dir string) *LocalBlobStore {
+	return &LocalBlobStore{
+		Dir: dir,
+	}
+}
+
+// Put writes a temp file first, so a reader never sees half a blob
+func (store *LocalBlobStore) Put(key string, contentType string, data []byte) error {
+	if err := checkBlobKey(key); nil != err {
+		return err
+	}
+	name := filepath.Join(store.Dir, filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(name), 0o755)
+	if nil != err {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if nil != err {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); nil == err {
+		err = closeErr
+	}
+	if nil != err {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0o644)
+	if nil != err {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (store *LocalBlobStore) Get(key string) (*Blob, error) {
+	if err := checkBlobKey(key); nil != err {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(store.Dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	} else if nil != err {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if nil != err {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrBlobNotFound
+	}
+	return &Blob{
+		Body:        file,
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        info.Size(),
+		Modified:    info.ModTime(),
+	}, nil
+}
+
+func (store *LocalBlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); nil != err {
+		return err
+	}
+	err := os.Remove(filepath.Join(store.Dir, filepath.FromSlash(key)))
+	if nil != err && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// S3BlobStore talks to an S3-compatible api with path-style urls, so it
+// works with minio and the local fakes as well. Requests are signed
+// with AWS Signature Version 4.
+type S3BlobStore struct {
+	Endpoint   string
+	Bucket     string
+	Region     string
+	AccessKey  string
+	SecretKey  string
+	Client     *http.Client
+	TimeGetter TimeGetterI
+}
+
+func NewS3BlobStore(endpoint, bucket, region, accessKey, secretKey string) *S3BlobStore {
+	return &S3BlobStore{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		Bucket:     bucket,
+		Region:     region,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		TimeGetter: &TimeGetter{},
+	}
+}
+
+func (store *S3BlobStore) Put(key string, contentType string, data []byte) error {
+	resp, err := store.do(http.MethodPut, key, contentType, data)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (store *S3BlobStore) Get(key string) (*Blob, error) {
+	resp, err := store.do(http.MethodGet, key, "", nil)
+	if nil != err {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &Blob{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+		Modified:    modified,
+	}, nil
+}
+
+// Delete of a missing key succeeds, as it does in S3
+func (store *S3BlobStore) Delete(key string) error {
+	resp, err := store.do(http.MethodDelete, key, "", nil)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func (store *S3BlobStore) do(method, key, contentType string, data []byte) (*http.Response, error) {
+	if err := checkBlobKey(key); nil != err {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, store.Endpoint+"/"+store.Bucket+"/"+key, bytes.NewReader(data))
+	if nil != err {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	store.sign(req, data, store.TimeGetter.Now().UTC())
+	return store.Client.Do(req)
+}
+
+// sign adds the SigV4 headers, the signed headers are host, the payload
+// hash, the date and the content type when there is one
+func (store *S3BlobStore) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+		names = append([]string{"content-type"}, names...)
+	}
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + store.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+store.SecretKey), day)
+	signingKey = hmacSHA256(signingKey, store.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// blobKeyURL is where ServeUpload serves the blob from, the keys are
+// already url safe
+func blobKeyURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return UploadsPath + key
+}
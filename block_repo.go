@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type BlockRepo struct {
+	DB *sql.DB
+}
+
+func NewBlockRepo(db *sql.DB) *BlockRepo {
+	return &BlockRepo{
+		DB: db,
+	}
+}
+
+// Add returns false when the user is blocked already
+func (repo *BlockRepo) Add(blockerID string, blockedID string, created string) (bool, error) {
+	fmt.Println("Block repo: add")
+	result, err := repo.DB.Exec(`INSERT IGNORE INTO user_block (blocker_id, blocked_id, created) VALUES (?, ?, ?)`,
+		blockerID, blockedID, created)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *BlockRepo) Delete(blockerID string, blockedID string) (bool, error) {
+	fmt.Println("Block repo: delete")
+	result, err := repo.DB.Exec(`DELETE FROM user_block WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// IsBlocked tells whether either of the users blocked the other one
+func (repo *BlockRepo) IsBlocked(userID string, otherID string) (bool, error) {
+	fmt.Println("Block repo: is blocked")
+	var blocked bool
+	err := repo.DB.
+		QueryRow(`SELECT EXISTS (SELECT 1 FROM user_block
+		WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))`,
+			userID, otherID, otherID, userID).
+		Scan(&blocked)
+	if nil != err {
+		return false, err
+	}
+	return blocked, nil
+}
+
+// GetBlocked returns the users the user blocked, the last blocked first
+func (repo *BlockRepo) GetBlocked(blockerID string, limit int, offset int) ([]*BlockComplexData, error) {
+	fmt.Println("Block repo: get blocked")
+	rows, err := repo.DB.Query(`
+	SELECT blocker_id, blocked_id, user_block.created, user.id, user.login
+	FROM user_block
+	JOIN user ON user.id = user_block.blocked_id
+	WHERE user_block.blocker_id = ?
+	ORDER BY user_block.created DESC, blocked_id
+	LIMIT ? OFFSET ?`,
+		blockerID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := make([]*BlockComplexData, 0, 10)
+	for rows.Next() {
+		data := &BlockComplexData{}
+		err := rows.Scan(&data.Block.BlockerID, &data.Block.BlockedID, &data.Block.Created,
+			&data.User.ID, &data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		blocks = append(blocks, data)
+	}
+	return blocks, rows.Err()
+}
+
+// GetBlockedIds returns the ids of the users the user blocked, unlike
+// IsBlocked it is one way only
+func (repo *BlockRepo) GetBlockedIds(blockerID string) (map[string]struct{}, error) {
+	fmt.Println("Block repo: get blocked ids")
+	rows, err := repo.DB.Query(`SELECT blocked_id FROM user_block WHERE blocker_id = ?`, blockerID)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := map[string]struct{}{}
+	for rows.Next() {
+		var blockedID string
+		err := rows.Scan(&blockedID)
+		if nil != err {
+			return nil, err
+		}
+		blocked[blockedID] = struct{}{}
+	}
+	return blocked, rows.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const BlockedPlaceholder = "[blocked]"
+
+// BlockUser stops the messages and the replies between the current user
+// and the user, and hides the content of the user from the current one
+func (h *UserHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	sess, blocked, ok := h.readBlockRequest(w, r)
+	if !ok {
+		return
+	}
+	_, err := h.BlockRepo.Add(sess.UserID, blocked.ID, h.TimeGetter.GetCreated())
+	if nil != err {
+		fmt.Println("can't add block: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't block user")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *UserHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	sess, blocked, ok := h.readBlockRequest(w, r)
+	if !ok {
+		return
+	}
+	isDeleted, err := h.BlockRepo.Delete(sess.UserID, blocked.ID)
+	if nil != err {
+		fmt.Println("can't delete block: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't unblock user")
+		return
+	}
+	if !isDeleted {
+		jsonError(w, http.StatusNotFound, "block not found")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// Blocked lists the users the current user blocked, the last blocked first
+func (h *UserHandler) Blocked(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := h.BlockRepo.GetBlocked(sess.UserID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get blocked users: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get blocked users")
+		return
+	}
+	jsonResponse(w, h.DTOConverter.BlocksConvertToDTO(data))
+}
+
+// readBlockRequest resolves the user of the path; it writes the error itself.
+func (h *UserHandler) readBlockRequest(w http.ResponseWriter, r *http.Request) (*Session, *User, bool) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return nil, nil, false
+	}
+	user, err := h.UserRepo.GetByLogin(mux.Vars(r)["USER_LOGIN"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, nil, false
+	} else if nil != err {
+		fmt.Println("can't get user by login: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, nil, false
+	}
+	if user.ID == sess.UserID {
+		jsonError(w, http.StatusBadRequest, "can't block yourself")
+		return nil, nil, false
+	}
+	return sess, user, true
+}
+
+// checkReplyBlock answers 403 when the author of the post, or of the parent
+// comment for the replies, and the user blocked one another
+func (h *PostsHandler) checkReplyBlock(w http.ResponseWriter, userID string, post *PostComplexData, parent *Comment) bool {
+	authorID := post.Post.UserID
+	if parent != nil {
+		authorID = parent.UserId
+	}
+	if authorID == userID {
+		return true
+	}
+	blocked, err := h.BlockRepo.IsBlocked(userID, authorID)
+	if nil != err {
+		fmt.Println("can't check block: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't check block")
+		return false
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you can't reply to this user")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestHideBlockedComments(t *testing.T) {
+	newComment := func(id string, parentID string, authorID string) *CommentDTO {
+		return &CommentDTO{ID: id, ParentID: parentID, Author: &AuthorDTO{ID: authorID}, Body: "text " + id}
+	}
+	comments := []*CommentDTO{
+		newComment("c1", "", "u2"),
+		newComment("c2", "c1", "u3"),
+		newComment("c3", "", "u2"),
+		newComment("c4", "c3", "u2"),
+		newComment("c5", "", "u3"),
+	}
+
+	// c1 keeps its place for the reply, c3 and its blocked reply are gone
+	visible := hideBlockedComments(comments, map[string]struct{}{"u2": {}})
+	ids := []string{}
+	for _, comment := range visible {
+		ids = append(ids, comment.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"c1", "c2", "c5"}) {
+		t.Errorf("bad visible comments: %v", ids)
+		return
+	}
+	if !visible[0].Blocked || visible[0].Body != BlockedPlaceholder || visible[0].Author.ID != "" {
+		t.Errorf("blocked comment is not hidden: %+v", visible[0])
+		return
+	}
+	if visible[1].Blocked || visible[1].Body != "text c2" {
+		t.Errorf("reply is hidden: %+v", visible[1])
+		return
+	}
+
+	// nobody blocked
+	if visible := hideBlockedComments(comments, map[string]struct{}{}); len(visible) != len(comments) {
+		t.Errorf("expected all the comments, got %d", len(visible))
+		return
+	}
+}
+
+func TestPostsConvertToDTOBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	converter := &DTOConverter{
+		CommentRepo: commentRepoMock,
+		VoteRepo:    voteRepoMock,
+		BlockRepo:   blockRepoMock,
+	}
+	data := []*PostComplexData{
+		{Post: Post{ID: "p1"}, User: User{ID: "u2"}},
+		{Post: Post{ID: "p2"}, User: User{ID: "u3"}},
+	}
+	comments := map[string][]*CommentComplexData{
+		"p2": {{Comment: Comment{ID: "c1", PostId: "p2", Body: "hi"}, User: User{ID: "u2"}}},
+	}
+
+	//the posts of the blocked users aren't even looked up
+	blockRepoMock.EXPECT().GetBlockedIds(sess.UserID).Return(map[string]struct{}{"u2": {}}, nil)
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{"p2"}).Return(comments, nil)
+	voteRepoMock.EXPECT().GetVotesByPostIds([]string{"p2"}).Return(map[string][]*Vote{}, nil)
+	postsDTO, err := converter.PostsConvertToDTO(data, sess)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if len(postsDTO) != 1 || postsDTO[0].ID != "p2" || len(postsDTO[0].Comments) != 0 {
+		t.Errorf("blocked content is not filtered: %+v", postsDTO)
+		return
+	}
+
+	//anonymous
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{"p1", "p2"}).Return(comments, nil)
+	voteRepoMock.EXPECT().GetVotesByPostIds([]string{"p1", "p2"}).Return(map[string][]*Vote{}, nil)
+	postsDTO, err = converter.PostsConvertToDTO(data, nil)
+	if err != nil || len(postsDTO) != 2 || len(postsDTO[1].Comments) != 1 {
+		t.Errorf("expected everything for anonymous requests, got %v", err)
+		return
+	}
+}
+
+func TestBlockedList(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	service := &UserHandler{
+		BlockRepo:    blockRepoMock,
+		DTOConverter: &DTOConverter{},
+	}
+
+	blockRepoMock.EXPECT().GetBlocked(sess.UserID, ListDefaultLimit, 5).Return([]*BlockComplexData{
+		{Block: Block{BlockerID: sess.UserID, BlockedID: "u2", Created: "2022-11-10T12:00:00Z"}, User: User{ID: "u2", Login: "bob"}},
+	}, nil)
+	req := httptest.NewRequest("GET", "/api/user/me/blocked?offset=5", nil)
+	w := httptest.NewRecorder()
+	service.Blocked(w, req.WithContext(context.WithValue(req.Context(), sessionKey, sess)))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `[{"user":{"username":"bob","id":"u2"},"created":"2022-11-10T12:00:00Z"}]`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+)
+
+type CategoriesHandler struct {
+	DictionaryRepo   DictionaryRepoI
+	RoleRepo         RoleRepoI
+	SubscriptionRepo SubscriptionRepoI
+	ModLogRepo       ModLogRepoI
+	ReportRepo       ReportRepoI
+	AutomodRepo      AutomodRepoI
+	DTOConverter     DTOConverterI
+	TimeGetter       TimeGetterI
+	Logger           *log.Logger
+}
+
+var (
+	categoryNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,20}$`)
+	// names used by routes or reserved for listings
+	reservedCategoryNames = map[string]struct{}{
+		"all":     {},
+		"popular": {},
+		"feed":    {},
+		"stream":  {},
+		"api":     {},
+		"admin":   {},
+	}
+	CategoryDescriptionMaxLen = 500
+	CategoryRulesMaxLen       = 5000
+)
+
+const mysqlErrDuplicateEntry = 1062
+
+func NewCategoriesHandler(db *sql.DB) *CategoriesHandler {
+	return &CategoriesHandler{
+		DictionaryRepo:   NewDictionaryRepo(db),
+		RoleRepo:         NewRoleRepo(db),
+		SubscriptionRepo: NewSubscriptionRepo(db),
+		ModLogRepo:       NewModLogRepo(db),
+		ReportRepo:       NewReportRepo(db),
+		AutomodRepo:      NewAutomodRepo(db),
+		DTOConverter:     &DTOConverter{},
+		TimeGetter:       &TimeGetter{},
+		Logger:           nil,
+	}
+}
+
+func ValidateCategoryName(name string) error {
+	if !categoryNameRe.MatchString(name) {
+		return fmt.Errorf("category name must be 3-21 letters, digits or underscores and start with a letter")
+	}
+	if _, ok := reservedCategoryNames[strings.ToLower(name)]; ok {
+		return fmt.Errorf("category name is reserved")
+	}
+	return nil
+}
+
+func (h *CategoriesHandler) List(w http.ResponseWriter, r *http.Request) {
+	data, err := h.DictionaryRepo.GetCategories()
+	if nil != err {
+		fmt.Println("can't get categories", err)
+		jsonError(w, http.StatusInternalServerError, "can't get categories")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, h.DTOConverter.CategoriesConvertToDTO(data))
+}
+
+func (h *CategoriesHandler) Add(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &CategoryRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+
+	err = ValidateCategoryName(requestData.Name)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(requestData.Description) > CategoryDescriptionMaxLen || len(requestData.Rules) > CategoryRulesMaxLen {
+		jsonError(w, http.StatusBadRequest, "description or rules are too long")
+		return
+	}
+
+	_, err = h.DictionaryRepo.GetCategoryByName(requestData.Name)
+	if nil == err {
+		jsonError(w, http.StatusConflict, "category already exists")
+		return
+	} else if err != sql.ErrNoRows {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+
+	category := &Category{
+		Name:            requestData.Name,
+		Description:     requestData.Description,
+		Rules:           requestData.Rules,
+		CreatorID:       sess.UserID,
+		Created:         h.TimeGetter.GetCreated(),
+		RequireVerified: requestData.RequireVerified,
+	}
+	category.ID, err = h.DictionaryRepo.AddCategory(category)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		jsonError(w, http.StatusConflict, "category already exists")
+		return
+	} else if nil != err {
+		fmt.Println("can't add category", err)
+		jsonError(w, http.StatusInternalServerError, "can't add category")
+		return
+	}
+
+	// the creator moderates the new community
+	err = h.RoleRepo.Add(&Role{
+		UserID:     sess.UserID,
+		Name:       RoleModerator,
+		CategoryID: category.ID,
+	})
+	if nil != err {
+		fmt.Println("can't make creator a moderator", err)
+	}
+
+	categoriesDTO := h.DTOConverter.CategoriesConvertToDTO([]*CategoryComplexData{
+		{Category: *category, User: User{ID: sess.UserID}},
+	})
+	w.WriteHeader(http.StatusCreated)
+	jsonResponse(w, categoriesDTO[0])
+}
+
+func (h *CategoriesHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, true)
+}
+
+func (h *CategoriesHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, false)
+}
+
+func (h *CategoriesHandler) changeSubscription(w http.ResponseWriter, r *http.Request, subscribe bool) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+
+	category, err := h.DictionaryRepo.GetCategoryByName(mux.Vars(r)["CATEGORY_NAME"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "category not found")
+		return
+	} else if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+
+	// repeated requests are no-ops, the counter changes only once
+	if subscribe {
+		_, err = h.SubscriptionRepo.Subscribe(sess.UserID, category.ID, h.TimeGetter.GetCreated())
+	} else {
+		_, err = h.SubscriptionRepo.Unsubscribe(sess.UserID, category.ID)
+	}
+	if err != nil {
+		fmt.Println("can't change subscription", err)
+		jsonError(w, http.StatusInternalServerError, "can't change subscription")
+		return
+	}
+
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// SaveSettings lets the moderators of the category and admins change
+// whether posting and commenting need a verified email
+func (h *CategoriesHandler) SaveSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &CategorySettingsRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+
+	category, err := h.DictionaryRepo.GetCategoryByName(mux.Vars(r)["CATEGORY_NAME"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "category not found")
+		return
+	} else if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+	if !Can(sess, ActionManageCategory, &Resource{CategoryID: category.ID}) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	err = h.DictionaryRepo.SetRequireVerified(category.ID, requestData.RequireVerified)
+	if err != nil {
+		fmt.Println("can't save category settings", err)
+		jsonError(w, http.StatusInternalServerError, "can't save category settings")
+		return
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// ModLog is visible to the moderators of the category and admins
+func (h *CategoriesHandler) ModLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, err := h.DictionaryRepo.GetCategoryByName(mux.Vars(r)["CATEGORY_NAME"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "category not found")
+		return
+	} else if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+	if !Can(sess, ActionViewModLog, &Resource{CategoryID: category.ID}) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	data, err := h.ModLogRepo.GetByCategoryId(category.ID, opts.Limit, opts.Offset)
+	if err != nil {
+		fmt.Println("can't get mod log", err)
+		jsonError(w, http.StatusInternalServerError, "can't get mod log")
+		return
+	}
+	jsonResponse(w, h.DTOConverter.ModLogConvertToDTO(data))
+}
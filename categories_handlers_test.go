@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestCategoriesList(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+		DTOConverter:   &DTOConverter{},
+	}
+	data := []*CategoryComplexData{
+		{
+			Category: Category{ID: 7, Name: "golang", Description: "gophers", Created: "2022-11-09T19:51:42Z", Subscribers: 3, RequireVerified: true},
+			User:     User{ID: "522cd619-841f-43d5-866d-f880e5f48d18", Login: "mer"},
+		},
+		{
+			Category: Category{ID: 1, Name: "music", Created: "2022-11-02T15:24:00Z"},
+		},
+	}
+	expected := `[{"id":7,"name":"golang","description":"gophers","rules":"","creator":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"created":"2022-11-09T19:51:42Z","subscribers":3,"require_verified":true},` +
+		`{"id":1,"name":"music","description":"","rules":"","creator":null,"created":"2022-11-02T15:24:00Z","subscribers":0,"require_verified":false}]`
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategories().Return(data, nil)
+	req := httptest.NewRequest("GET", "/api/categories", nil)
+	w := httptest.NewRecorder()
+	service.List(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %#v; have: %#v", expected, string(body))
+		return
+	}
+
+	//query error
+	dictionaryRepoMock.EXPECT().GetCategories().Return(nil, fmt.Errorf("db error"))
+	req = httptest.NewRequest("GET", "/api/categories", nil)
+	w = httptest.NewRecorder()
+	service.List(w, req)
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestCategoriesAdd(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	roleRepoMock := NewMockRoleRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+		RoleRepo:       roleRepoMock,
+		DTOConverter:   &DTOConverter{},
+		TimeGetter:     timeGetterMock,
+	}
+	reqBody := `{"name":"golang","description":"gophers","rules":"be nice","require_verified":true}`
+	category := &Category{
+		Name:            "golang",
+		Description:     "gophers",
+		Rules:           "be nice",
+		CreatorID:       sess.UserID,
+		Created:         "2022-11-09T19:51:42Z",
+		RequireVerified: true,
+	}
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(nil, sql.ErrNoRows)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
+	dictionaryRepoMock.EXPECT().AddCategory(category).Return(uint32(7), nil)
+	roleRepoMock.EXPECT().Add(&Role{UserID: sess.UserID, Name: RoleModerator, CategoryID: 7}).Return(nil)
+	req := httptest.NewRequest("POST", "/api/categories", strings.NewReader(reqBody))
+	ctx := context.WithValue(req.Context(), sessionKey, sess)
+	w := httptest.NewRecorder()
+	service.Add(w, req.WithContext(ctx))
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated || !strings.Contains(string(body), `"id":7,"name":"golang"`) {
+		t.Errorf("expected created category; got: %d %s", resp.StatusCode, body)
+		return
+	}
+
+	//existing name
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	req = httptest.NewRequest("POST", "/api/categories", strings.NewReader(reqBody))
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Add(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//concurrent insert hits the unique key
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(nil, sql.ErrNoRows)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
+	dictionaryRepoMock.EXPECT().AddCategory(category).Return(uint32(0), &mysql.MySQLError{Number: 1062})
+	req = httptest.NewRequest("POST", "/api/categories", strings.NewReader(reqBody))
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Add(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//naming rules
+	for _, name := range []string{"go", "1golang", "go lang", "feed", strings.Repeat("a", 22)} {
+		req = httptest.NewRequest("POST", "/api/categories", strings.NewReader(`{"name":"`+name+`"}`))
+		ctx = context.WithValue(req.Context(), sessionKey, sess)
+		w = httptest.NewRecorder()
+		service.Add(w, req.WithContext(ctx))
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 status code for %q; got: %d", name, w.Result().StatusCode)
+			return
+		}
+	}
+}
+
+func TestCategoriesSubscribe(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	subscriptionRepoMock := NewMockSubscriptionRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo:   dictionaryRepoMock,
+		SubscriptionRepo: subscriptionRepoMock,
+		TimeGetter:       timeGetterMock,
+	}
+	vars := map[string]string{"CATEGORY_NAME": "golang"}
+
+	//subscribe
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
+	subscriptionRepoMock.EXPECT().Subscribe(sess.UserID, uint32(7), "2022-11-09T19:51:42Z").Return(true, nil)
+	req := httptest.NewRequest("POST", "/api/categories/golang/subscribe", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx := context.WithValue(req.Context(), sessionKey, sess)
+	w := httptest.NewRecorder()
+	service.Subscribe(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//unsubscribe twice is not an error
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	subscriptionRepoMock.EXPECT().Unsubscribe(sess.UserID, uint32(7)).Return(false, nil)
+	req = httptest.NewRequest("POST", "/api/categories/golang/unsubscribe", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Unsubscribe(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//unknown category
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(nil, sql.ErrNoRows)
+	req = httptest.NewRequest("POST", "/api/categories/golang/subscribe", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Subscribe(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	subscriptionRepoMock.EXPECT().Unsubscribe(sess.UserID, uint32(7)).Return(false, fmt.Errorf("db error"))
+	req = httptest.NewRequest("POST", "/api/categories/golang/unsubscribe", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Unsubscribe(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestCategoriesSaveSettings(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+	}
+	moderator := &Session{
+		UserID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11",
+		Roles:  []*Role{{Name: RoleModerator, CategoryID: 7}},
+	}
+	vars := map[string]string{"CATEGORY_NAME": "golang"}
+	reqBody := `{"require_verified":true}`
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	dictionaryRepoMock.EXPECT().SetRequireVerified(uint32(7), true).Return(nil)
+	req := httptest.NewRequest("POST", "/api/categories/golang/settings", strings.NewReader(reqBody))
+	req = mux.SetURLVars(req, vars)
+	ctx := context.WithValue(req.Context(), sessionKey, moderator)
+	w := httptest.NewRecorder()
+	service.SaveSettings(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//not a moderator
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	req = httptest.NewRequest("POST", "/api/categories/golang/settings", strings.NewReader(reqBody))
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.SaveSettings(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//unknown category
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(nil, sql.ErrNoRows)
+	req = httptest.NewRequest("POST", "/api/categories/golang/settings", strings.NewReader(reqBody))
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, moderator)
+	w = httptest.NewRecorder()
+	service.SaveSettings(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestCategoriesModLog(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+		ModLogRepo:     modLogRepoMock,
+		DTOConverter:   &DTOConverter{},
+	}
+	moderator := &Session{
+		UserID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11",
+		Roles:  []*Role{{Name: RoleModerator, CategoryID: 7}},
+	}
+	vars := map[string]string{"CATEGORY_NAME": "golang"}
+	data := []*ModLogComplexData{
+		{
+			ModLogEntry: ModLogEntry{ID: 3, CategoryID: 7, ModeratorID: moderator.UserID, Action: ModLogLockPost,
+				TargetType: "post", TargetID: "dc1e2f25-76a5-4aac-9212-96e2121c16f1", Created: "2022-11-10T11:24:44Z"},
+			User: User{Login: "mod"},
+		},
+	}
+	expected := `[{"id":3,"moderator":{"username":"mod","id":"7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11"},"action":"lock_post",` +
+		`"target_type":"post","target_id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","reason":"","created":"2022-11-10T11:24:44Z"}]`
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	modLogRepoMock.EXPECT().GetByCategoryId(uint32(7), 10, 0).Return(data, nil)
+	req := httptest.NewRequest("GET", "/api/categories/golang/modlog?limit=10", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx := context.WithValue(req.Context(), sessionKey, moderator)
+	w := httptest.NewRecorder()
+	service.ModLog(w, req.WithContext(ctx))
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %#v; have: %#v", expected, string(body))
+		return
+	}
+
+	//moderator of another category
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 8, Name: "golang"}, nil)
+	req = httptest.NewRequest("GET", "/api/categories/golang/modlog", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, moderator)
+	w = httptest.NewRecorder()
+	service.ModLog(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	dictionaryRepoMock.EXPECT().GetCategoryByName("golang").Return(&Category{ID: 7, Name: "golang"}, nil)
+	modLogRepoMock.EXPECT().GetByCategoryId(uint32(7), ListDefaultLimit, 0).Return(nil, fmt.Errorf("db error"))
+	req = httptest.NewRequest("GET", "/api/categories/golang/modlog", nil)
+	req = mux.SetURLVars(req, vars)
+	ctx = context.WithValue(req.Context(), sessionKey, moderator)
+	w = httptest.NewRecorder()
+	service.ModLog(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+}
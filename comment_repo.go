@@ -19,9 +19,9 @@ func NewCommentRepo(db *sql.DB) *CommentRepo {
 func (repo *CommentRepo) Add(comment *Comment) (*string, error) {
 	fmt.Println("Comment repo: add comment")
 	result, err := repo.DB.Exec(`INSERT INTO comment
-	(id, post_id, user_id, body, created) 
-	VALUES (?, ?, ?, ?, ?)`,
-		comment.ID, comment.PostId, comment.UserId, comment.Body, comment.Created)
+	(id, post_id, user_id, body, created, removed, parent_id) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		comment.ID, comment.PostId, comment.UserId, comment.Body, comment.Created, comment.Removed, comment.ParentID)
 	if err != nil {
 		return nil, err
 	}
@@ -35,9 +35,23 @@ func (repo *CommentRepo) Add(comment *Comment) (*string, error) {
 	return &comment.ID, nil
 }
 
-func (repo *CommentRepo) Delete(id string) (bool, error) {
+func (repo *CommentRepo) GetById(id string) (*Comment, error) {
+	fmt.Println("Comment repo: get by id")
+	comment := &Comment{}
+	err := repo.DB.
+		QueryRow(`SELECT id, post_id, user_id, body, created, removed, parent_id FROM comment WHERE id = ? AND deleted_at = ''`, id).
+		Scan(&comment.ID, &comment.PostId, &comment.UserId, &comment.Body, &comment.Created, &comment.Removed, &comment.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Delete only marks the comment, it keeps its place in the thread as
+// [deleted] until PurgeDeleted removes it
+func (repo *CommentRepo) Delete(id string, deletedAt string, deletedBy string) (bool, error) {
 	fmt.Println("Comment repo: delete comment")
-	result, err := repo.DB.Exec(`DELETE FROM comment WHERE id = ?`, id)
+	result, err := repo.DB.Exec(`UPDATE comment SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at = ''`, deletedAt, deletedBy, id)
 	if err != nil {
 		return false, err
 	}
@@ -51,6 +65,166 @@ func (repo *CommentRepo) Delete(id string) (bool, error) {
 	return true, nil
 }
 
+// Restore brings back a comment on the post its author deleted after
+// since, a comment a moderator deleted stays deleted
+func (repo *CommentRepo) Restore(id string, postID string, userID string, since string) (bool, error) {
+	fmt.Println("Comment repo: restore comment")
+	result, err := repo.DB.Exec(`UPDATE comment SET deleted_at = '', deleted_by = '' 
+	WHERE id = ? AND post_id = ? AND user_id = ? AND deleted_by = user_id AND deleted_at <> '' AND deleted_at > ?`,
+		id, postID, userID, since)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// PurgeDeleted removes the comments deleted before the given time and
+// the reports, the votes, the saves and the notifications of them. A comment that still has replies
+// stays as a tombstone without the body so the thread keeps its shape,
+// a later run takes it once the replies are gone.
+func (repo *CommentRepo) PurgeDeleted(before string) (int64, error) {
+	fmt.Println("Comment repo: purge deleted")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	const purged = `comment.deleted_at <> '' AND comment.deleted_at < ?`
+	_, err = tx.Exec(`DELETE report FROM report 
+	JOIN comment ON report.target_type = 'comment' AND report.target_id = comment.id 
+	WHERE `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	_, err = tx.Exec(`DELETE comment_vote FROM comment_vote 
+	JOIN comment ON comment.id = comment_vote.comment_id 
+	WHERE `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	_, err = tx.Exec(`DELETE saved_item FROM saved_item 
+	JOIN comment ON saved_item.type = 'comment' AND saved_item.item_id = comment.id 
+	WHERE `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	_, err = tx.Exec(`DELETE notification FROM notification 
+	JOIN comment ON notification.comment_id = comment.id 
+	WHERE `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	result, err := tx.Exec(`DELETE comment FROM comment 
+	LEFT JOIN comment AS reply ON reply.parent_id = comment.id 
+	WHERE reply.id IS NULL AND `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE comment SET body = '' WHERE body <> '' AND `+purged, before)
+	if nil != err {
+		return 0, err
+	}
+	return affected, tx.Commit()
+}
+
+func (repo *CommentRepo) SetRemoved(id string, removed bool) (bool, error) {
+	fmt.Println("Comment repo: set removed")
+	result, err := repo.DB.Exec(`UPDATE comment SET removed = ? WHERE id = ?`, removed, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetByIds returns the visible comments of the ids in no particular order
+func (repo *CommentRepo) GetByIds(ids []string) ([]*CommentComplexData, error) {
+	fmt.Println("Comment repo: get by ids")
+	if len(ids) == 0 {
+		return []*CommentComplexData{}, nil
+	}
+	placeHolders := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		placeHolders = append(placeHolders, "?")
+		args = append(args, id)
+	}
+	rows, err := repo.DB.Query(`SELECT
+	comment.id AS comment_id, post_id, body,
+	comment.created AS comment_created, comment.deleted_at, comment.parent_id,
+	comment.ups, comment.downs,
+	user.id AS user_id, user.login
+	FROM comment
+	LEFT JOIN user ON user.id = comment.user_id
+	WHERE comment.id IN (`+strings.Join(placeHolders, ",")+`) AND comment.removed = 0 AND comment.deleted_at = ''`,
+		args...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := make([]*CommentComplexData, 0, len(ids))
+	for rows.Next() {
+		data := &CommentComplexData{}
+		err := rows.Scan(&data.Comment.ID, &data.Comment.PostId,
+			&data.Comment.Body, &data.Comment.Created, &data.Comment.DeletedAt, &data.Comment.ParentID,
+			&data.Comment.Ups, &data.Comment.Downs,
+			&data.User.ID, &data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		comments = append(comments, data)
+	}
+	return comments, rows.Err()
+}
+
+// GetByUserId returns the visible comments of the user under the visible
+// posts, newest first
+func (repo *CommentRepo) GetByUserId(userID string, limit int, offset int) ([]*CommentComplexData, error) {
+	fmt.Println("Comment repo: get by user id")
+	rows, err := repo.DB.Query(`SELECT
+	comment.id AS comment_id, comment.post_id, comment.body,
+	comment.created AS comment_created, comment.deleted_at, comment.parent_id,
+	comment.ups, comment.downs,
+	user.id AS user_id, user.login
+	FROM comment
+	JOIN post ON post.id = comment.post_id
+	LEFT JOIN user ON user.id = comment.user_id
+	WHERE comment.user_id = ? AND comment.removed = 0 AND comment.deleted_at = ''
+	AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY comment.created DESC, comment.id
+	LIMIT ? OFFSET ?`,
+		userID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := make([]*CommentComplexData, 0, 10)
+	for rows.Next() {
+		data := &CommentComplexData{}
+		err := rows.Scan(&data.Comment.ID, &data.Comment.PostId,
+			&data.Comment.Body, &data.Comment.Created, &data.Comment.DeletedAt, &data.Comment.ParentID,
+			&data.Comment.Ups, &data.Comment.Downs,
+			&data.User.ID, &data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		comments = append(comments, data)
+	}
+	return comments, rows.Err()
+}
+
 func (repo *CommentRepo) GetCommentsByPostIds(postIds []string) (map[string][]*CommentComplexData, error) {
 
 	lenPostId := len(postIds)
@@ -63,11 +237,13 @@ func (repo *CommentRepo) GetCommentsByPostIds(postIds []string) (map[string][]*C
 	query :=
 		`SELECT 
 	comment.id AS comment_id, post_id, body, 
-	comment.created AS comment_created,
+	comment.created AS comment_created, comment.deleted_at, comment.parent_id,
+	comment.ups, comment.downs,
 	user.id AS user_id, user.login
 	FROM comment 
 	LEFT JOIN user ON user.id = comment.user_id
-	WHERE post_id IN (` + strings.Join(placeHolders, ",") + `)`
+	WHERE post_id IN (` + strings.Join(placeHolders, ",") + `) AND comment.removed = 0
+	ORDER BY comment.created, comment.id`
 	fmt.Println("get comments postIDs", postIds)
 	fmt.Println("get comments sql query: ", query)
 	rows, err := repo.DB.Query(query, args...)
@@ -80,7 +256,9 @@ func (repo *CommentRepo) GetCommentsByPostIds(postIds []string) (map[string][]*C
 	for rows.Next() {
 		data := &CommentComplexData{}
 		err := rows.Scan(&data.Comment.ID, &data.Comment.PostId,
-			&data.Comment.Body, &data.Comment.Created, &data.User.ID, &data.User.Login)
+			&data.Comment.Body, &data.Comment.Created, &data.Comment.DeletedAt, &data.Comment.ParentID,
+			&data.Comment.Ups, &data.Comment.Downs,
+			&data.User.ID, &data.User.Login)
 		if nil != err {
 			fmt.Println("get comments scan:", err)
 			return nil, err
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+)
+
+const (
+	CommentSortBest          = "best"
+	CommentSortTop           = "top"
+	CommentSortNew           = "new"
+	CommentSortOld           = "old"
+	CommentSortControversial = "controversial"
+
+	// wilsonZ is the 80% confidence, a comment needs a few votes before
+	// it can beat the older ones
+	wilsonZ = 1.281551565545
+)
+
+// commentSorts compare the siblings, the ties keep the order of the
+// comments by created
+var commentSorts = map[string]func(a *CommentDTO, b *CommentDTO) bool{
+	CommentSortBest: func(a *CommentDTO, b *CommentDTO) bool {
+		return wilsonLowerBound(a.ups, a.downs) > wilsonLowerBound(b.ups, b.downs)
+	},
+	CommentSortTop: func(a *CommentDTO, b *CommentDTO) bool {
+		return a.Score > b.Score
+	},
+	CommentSortNew: func(a *CommentDTO, b *CommentDTO) bool {
+		return a.Created > b.Created
+	},
+	CommentSortOld: func(a *CommentDTO, b *CommentDTO) bool {
+		return a.Created < b.Created
+	},
+	CommentSortControversial: func(a *CommentDTO, b *CommentDTO) bool {
+		return controversy(a.ups, a.downs) > controversy(b.ups, b.downs)
+	},
+}
+
+func CommentSortFromRequest(r *http.Request) (string, error) {
+	commentSort := r.URL.Query().Get("comment_sort")
+	if commentSort == "" {
+		return CommentSortBest, nil
+	}
+	if _, ok := commentSorts[commentSort]; !ok {
+		return "", fmt.Errorf("unknown comment sort: %s", commentSort)
+	}
+	return commentSort, nil
+}
+
+// sortComments sorts the replies of every comment apart and returns the
+// thread depth first, so each comment comes before its replies. The replies
+// to the comments missing from the list go with the top level ones.
+func sortComments(comments []*CommentDTO, commentSort string) []*CommentDTO {
+	less, ok := commentSorts[commentSort]
+	if !ok {
+		return comments
+	}
+	ids := make(map[string]struct{}, len(comments))
+	for _, comment := range comments {
+		ids[comment.ID] = struct{}{}
+	}
+	replies := map[string][]*CommentDTO{}
+	for _, comment := range comments {
+		parentID := comment.ParentID
+		if _, ok := ids[parentID]; !ok {
+			parentID = ""
+		}
+		replies[parentID] = append(replies[parentID], comment)
+	}
+
+	sorted := make([]*CommentDTO, 0, len(comments))
+	var walk func(parentID string)
+	walk = func(parentID string) {
+		level := replies[parentID]
+		sort.SliceStable(level, func(i, j int) bool {
+			return less(level[i], level[j])
+		})
+		for _, comment := range level {
+			sorted = append(sorted, comment)
+			walk(comment.ID)
+		}
+	}
+	walk("")
+	return sorted
+}
+
+// wilsonLowerBound is the lower bound of the Wilson score interval of the
+// share of the upvotes
+func wilsonLowerBound(ups int64, downs int64) float64 {
+	n := float64(ups + downs)
+	if n <= 0 {
+		return 0
+	}
+	p := float64(ups) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// controversy is high for many votes split evenly
+func controversy(ups int64, downs int64) float64 {
+	if ups <= 0 || downs <= 0 {
+		return 0
+	}
+	balance := float64(downs) / float64(ups)
+	if ups < downs {
+		balance = float64(ups) / float64(downs)
+	}
+	return math.Pow(float64(ups+downs), balance)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestSortComments(t *testing.T) {
+	newComment := func(id string, parentID string, created string, ups int64, downs int64) *CommentDTO {
+		return &CommentDTO{ID: id, ParentID: parentID, Created: created, Score: ups - downs, ups: ups, downs: downs}
+	}
+	comments := func() []*CommentDTO {
+		return []*CommentDTO{
+			newComment("c1", "", "2022-11-10T10:00:00Z", 1, 0),
+			newComment("c2", "", "2022-11-10T11:00:00Z", 60, 40),
+			newComment("c3", "c1", "2022-11-10T12:00:00Z", 0, 0),
+			newComment("c4", "c1", "2022-11-10T13:00:00Z", 5, 0),
+			newComment("c5", "", "2022-11-10T14:00:00Z", 10, 0),
+			newComment("c6", "gone", "2022-11-10T15:00:00Z", 0, 3),
+		}
+	}
+	ids := func(comments []*CommentDTO) []string {
+		ids := []string{}
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+		}
+		return ids
+	}
+	cases := []struct {
+		sort     string
+		expected []string
+	}{
+		// a single upvote doesn't beat ten, the replies follow their parent
+		{CommentSortBest, []string{"c5", "c2", "c1", "c4", "c3", "c6"}},
+		{CommentSortTop, []string{"c2", "c5", "c1", "c4", "c3", "c6"}},
+		{CommentSortNew, []string{"c6", "c5", "c2", "c1", "c4", "c3"}},
+		{CommentSortOld, []string{"c1", "c3", "c4", "c2", "c5", "c6"}},
+		{CommentSortControversial, []string{"c2", "c1", "c3", "c4", "c5", "c6"}},
+	}
+	for _, item := range cases {
+		sorted := ids(sortComments(comments(), item.sort))
+		if !reflect.DeepEqual(sorted, item.expected) {
+			t.Errorf("%s: want %v, have %v", item.sort, item.expected, sorted)
+		}
+	}
+
+	if wilsonLowerBound(0, 0) != 0 || wilsonLowerBound(100, 0) <= wilsonLowerBound(10, 0) {
+		t.Errorf("bad wilson lower bound")
+	}
+	if controversy(10, 0) != 0 || controversy(50, 50) <= controversy(90, 10) {
+		t.Errorf("bad controversy")
+	}
+}
+
+func TestVoteCommentHandler(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:    postsRepoMock,
+		CommentRepo:  commentRepoMock,
+		VoteRepo:     voteRepoMock,
+		BanRepo:      banRepoMock,
+		DTOConverter: dtoConverterMock,
+	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	data := multipleComplexData[0]
+	postID := data.Post.ID
+	newRequest := func(url string, commentID string) *http.Request {
+		req := httptest.NewRequest("GET", url, nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+		return mux.SetURLVars(req, map[string]string{"POST_ID": postID, "COMMENT_ID": commentID})
+	}
+
+	//success, the comments in the asked order
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil).Times(2)
+	commentRepoMock.EXPECT().GetById("c1").Return(&Comment{ID: "c1", PostId: postID}, nil)
+	voteRepoMock.EXPECT().VoteComment("c1", sess.UserID, VoteUp).Return(true, nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(data, sess).Return(&PostDTO{ID: postID, Comments: []*CommentDTO{
+		{ID: "c1", Created: "2022-11-10T10:00:00Z"},
+		{ID: "c2", Created: "2022-11-10T11:00:00Z"},
+	}}, nil)
+	w := httptest.NewRecorder()
+	service.UpVoteComment(w, newRequest("/api/post/"+postID+"/c1/upvote?comment_sort=new", "c1"))
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+	postDTO := &PostDTO{}
+	if err := json.Unmarshal(body, postDTO); err != nil {
+		t.Errorf("bad response: %s", body)
+		return
+	}
+	if len(postDTO.Comments) != 2 || postDTO.Comments[0].ID != "c2" {
+		t.Errorf("bad comments order: %s", body)
+		return
+	}
+
+	//comment of another post
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	commentRepoMock.EXPECT().GetById("c9").Return(&Comment{ID: "c9", PostId: "other"}, nil)
+	w = httptest.NewRecorder()
+	service.DownVoteComment(w, newRequest("/api/post/"+postID+"/c9/downvote", "c9"))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unknown sort
+	w = httptest.NewRecorder()
+	service.UnVoteComment(w, newRequest("/api/post/"+postID+"/c1/unvote?comment_sort=random", "c1"))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *PostsHandler) UpVoteComment(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, VoteUp)
+}
+
+func (h *PostsHandler) DownVoteComment(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, VoteDown)
+}
+
+func (h *PostsHandler) UnVoteComment(w http.ResponseWriter, r *http.Request) {
+	h.voteComment(w, r, VoteNone)
+}
+
+// voteComment answers with the post like the post votes, its comments in
+// the ?comment_sort order
+func (h *PostsHandler) voteComment(w http.ResponseWriter, r *http.Request, vote int32) {
+	params := mux.Vars(r)
+	postId := params["POST_ID"]
+	commentSort, err := CommentSortFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.canVote(w, r, postId) {
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
+	comment, err := h.CommentRepo.GetById(params["COMMENT_ID"])
+	if err == sql.ErrNoRows || (nil == err && (comment.PostId != postId || comment.Removed)) {
+		jsonError(w, http.StatusNotFound, "comment not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get comment")
+		return
+	}
+
+	_, err = h.VoteRepo.VoteComment(comment.ID, sess.UserID, vote)
+	if nil != err {
+		fmt.Println("can't vote comment", err)
+		jsonError(w, http.StatusInternalServerError, "can't vote comment")
+		return
+	}
+
+	data, err := h.PostsRepo.GetById(postId)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post")
+		return
+	}
+	postDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
+	if err != nil {
+		fmt.Println("can't convert post to dto", err)
+		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
+		return
+	}
+	postDTO.Comments = sortComments(postDTO.Comments, commentSort)
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, postDTO)
+}
@@ -18,10 +18,68 @@ func NewDictionaryRepo(db *sql.DB) *DictionaryRepo {
 func (repo *DictionaryRepo) GetCategoryByName(name string) (*Category, error) {
 	fmt.Println("Get category by name")
 	category := &Category{}
-	row := repo.DB.QueryRow(`SELECT category.* FROM category WHERE name = ?`, name)
-	err := row.Scan(&category.ID, &category.Name)
+	row := repo.DB.QueryRow(`SELECT id, name, description, rules, creator_id, created, subscribers, require_verified 
+	FROM category WHERE name = ?`, name)
+	err := row.Scan(&category.ID, &category.Name, &category.Description, &category.Rules,
+		&category.CreatorID, &category.Created, &category.Subscribers, &category.RequireVerified)
 	if err != nil {
 		return nil, err
 	}
 	return category, nil
 }
+
+func (repo *DictionaryRepo) GetCategories() ([]*CategoryComplexData, error) {
+	fmt.Println("Get categories")
+	rows, err := repo.DB.Query(`
+	SELECT
+	category.id, category.name, description, rules, creator_id, 
+	category.created AS category_created, subscribers, require_verified,
+	IFNULL(user.id, ''), IFNULL(user.login, '')
+	FROM category
+	LEFT JOIN user ON user.id = category.creator_id
+	ORDER BY subscribers DESC, category.name`)
+	if err != nil {
+		fmt.Println("get categories: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*CategoryComplexData, 0, 10)
+	for rows.Next() {
+		data := &CategoryComplexData{}
+		err := rows.Scan(&data.Category.ID, &data.Category.Name,
+			&data.Category.Description, &data.Category.Rules,
+			&data.Category.CreatorID, &data.Category.Created,
+			&data.Category.Subscribers, &data.Category.RequireVerified,
+			&data.User.ID, &data.User.Login)
+		if err != nil {
+			fmt.Println("scan: ", err)
+			return nil, err
+		}
+		categories = append(categories, data)
+	}
+	return categories, nil
+}
+
+func (repo *DictionaryRepo) AddCategory(category *Category) (uint32, error) {
+	fmt.Println("Add category")
+	result, err := repo.DB.Exec(`INSERT INTO category 
+	(name, description, rules, creator_id, created, subscribers, require_verified) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		category.Name, category.Description, category.Rules, category.CreatorID,
+		category.Created, category.Subscribers, category.RequireVerified)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint32(id), nil
+}
+
+func (repo *DictionaryRepo) SetRequireVerified(categoryID uint32, requireVerified bool) error {
+	fmt.Println("Set category require verified")
+	_, err := repo.DB.Exec("UPDATE category SET require_verified = ? WHERE id = ?", requireVerified, categoryID)
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDictionaryGetCategories(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expect := []*CategoryComplexData{
+		{
+			Category: Category{ID: 7, Name: "golang", Description: "gophers", Rules: "be nice",
+				CreatorID: "522cd619-841f-43d5-866d-f880e5f48d18", Created: "2022-11-09T19:51:42Z", Subscribers: 3},
+			User: User{ID: "522cd619-841f-43d5-866d-f880e5f48d18", Login: "mer"},
+		},
+	}
+	rows := sqlmock.NewRows([]string{
+		"id", "name", "description", "rules", "creator_id", "category_created",
+		"subscribers", "require_verified", "user_id", "login",
+	})
+	for _, c := range expect {
+		rows.AddRow(c.Category.ID, c.Category.Name, c.Category.Description, c.Category.Rules,
+			c.Category.CreatorID, c.Category.Created, c.Category.Subscribers, c.Category.RequireVerified,
+			c.User.ID, c.User.Login)
+	}
+	mock.ExpectQuery(`FROM category LEFT JOIN user ON user.id = category.creator_id`).WillReturnRows(rows)
+
+	repo := NewDictionaryRepo(db)
+	categories, err := repo.GetCategories()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(categories, expect) {
+		t.Errorf("results not match. want %#v; have: %#v", expect, categories)
+		return
+	}
+
+	//query error
+	mock.ExpectQuery(`FROM category`).WillReturnError(fmt.Errorf("db error"))
+	_, err = repo.GetCategories()
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDictionaryAddCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	category := &Category{
+		Name:      "golang",
+		CreatorID: "522cd619-841f-43d5-866d-f880e5f48d18",
+		Created:   "2022-11-09T19:51:42Z",
+	}
+	mock.ExpectExec(`INSERT INTO category`).
+		WithArgs(category.Name, category.Description, category.Rules, category.CreatorID,
+			category.Created, category.Subscribers, category.RequireVerified).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+
+	repo := NewDictionaryRepo(db)
+	id, err := repo.AddCategory(category)
+	if err != nil || id != 7 {
+		t.Errorf("unexpected result: %d %v", id, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -11,29 +11,218 @@ type AuthorDTO struct {
 
 type VoteDTO struct {
 	User string `json:"user"`
-	Vote uint32 `json:"vote"`
+	Vote int32  `json:"vote"`
 }
 
 type CommentDTO struct {
 	Author  *AuthorDTO `json:"author"`
 	Body    string     `json:"body"`
+	HTML    string     `json:"html,omitempty"`
 	Created string     `json:"created,datetime"`
 	ID      string     `json:"id"`
+	Score   int64      `json:"score"`
+	Deleted bool       `json:"deleted,omitempty"`
+	Blocked bool       `json:"blocked,omitempty"`
+	// ParentID is empty for the top level comments
+	ParentID string `json:"parent_id,omitempty"`
+	// Saved is set for the signed in requests only
+	Saved *bool `json:"saved,omitempty"`
+	// ups and downs are kept for the comment sorts
+	ups   int64
+	downs int64
 }
 
 type PostDTO struct {
-	ID               string        `json:"id"`
-	Author           *AuthorDTO    `json:"author"`
-	Category         string        `json:"category"`
-	Comments         []*CommentDTO `json:"comments"`
-	Created          string        `json:"created,datetime"`
-	Score            uint32        `json:"score"`
-	Text             string        `json:"text"`
-	Title            string        `json:"title"`
-	Type             string        `json:"type"`
-	UpVotePercentage uint          `json:"upvotepercentage"`
-	Votes            []*VoteDTO    `json:"votes"`
-	Views            uint32        `json:"views"`
+	ID               string          `json:"id"`
+	Author           *AuthorDTO      `json:"author"`
+	Category         string          `json:"category"`
+	Comments         []*CommentDTO   `json:"comments"`
+	Created          string          `json:"created,datetime"`
+	Score            uint32          `json:"score"`
+	Text             string          `json:"text"`
+	HTML             string          `json:"html,omitempty"`
+	Title            string          `json:"title"`
+	Type             string          `json:"type"`
+	URL              string          `json:"url,omitempty"`
+	Image            string          `json:"image,omitempty"`
+	Thumbnail        string          `json:"thumbnail,omitempty"`
+	UpVotePercentage uint            `json:"upvotepercentage"`
+	Votes            []*VoteDTO      `json:"votes"`
+	Views            uint32          `json:"views"`
+	Removed          bool            `json:"removed,omitempty"`
+	Locked           bool            `json:"locked,omitempty"`
+	Pinned           bool            `json:"pinned,omitempty"`
+	Flair            string          `json:"flair,omitempty"`
+	Preview          *LinkPreviewDTO `json:"preview,omitempty"`
+	Saved            *bool           `json:"saved,omitempty"`
+}
+
+// SavedItemDTO has the Post or the Comment of the Type
+type SavedItemDTO struct {
+	Type    string      `json:"type"`
+	PostID  string      `json:"post_id"`
+	SavedAt string      `json:"saved_at"`
+	Post    *PostDTO    `json:"post,omitempty"`
+	Comment *CommentDTO `json:"comment,omitempty"`
+}
+
+type LinkPreviewDTO struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+type SearchResultDTO struct {
+	Type     string     `json:"type"`
+	ID       string     `json:"id"`
+	PostID   string     `json:"post_id"`
+	PostType string     `json:"post_type"`
+	Title    string     `json:"title"`
+	Snippet  string     `json:"snippet"`
+	Author   *AuthorDTO `json:"author"`
+	Category string     `json:"category"`
+	Created  string     `json:"created"`
+}
+
+type CommentEventDTO struct {
+	PostID  string      `json:"post_id"`
+	Comment *CommentDTO `json:"comment"`
+}
+
+type CommentDeletedEventDTO struct {
+	PostID string `json:"post_id"`
+	ID     string `json:"id"`
+}
+
+type VoteEventDTO struct {
+	PostID           string `json:"post_id"`
+	Score            uint32 `json:"score"`
+	UpVotePercentage uint   `json:"upvotepercentage"`
+}
+
+type CategoryDTO struct {
+	ID              uint32     `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Rules           string     `json:"rules"`
+	Creator         *AuthorDTO `json:"creator"`
+	Created         string     `json:"created"`
+	Subscribers     uint32     `json:"subscribers"`
+	RequireVerified bool       `json:"require_verified"`
+}
+
+type ModLogEntryDTO struct {
+	ID         uint64     `json:"id"`
+	Moderator  *AuthorDTO `json:"moderator"`
+	Action     string     `json:"action"`
+	TargetType string     `json:"target_type"`
+	TargetID   string     `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Created    string     `json:"created"`
+}
+
+type NotificationDTO struct {
+	ID        uint64     `json:"id"`
+	Type      string     `json:"type"`
+	Actor     *AuthorDTO `json:"actor,omitempty"`
+	PostID    string     `json:"post_id,omitempty"`
+	CommentID string     `json:"comment_id,omitempty"`
+	Body      string     `json:"body"`
+	Read      bool       `json:"read"`
+	Created   string     `json:"created"`
+}
+
+// NotificationsDTO Unread counts the unread messages too
+type NotificationsDTO struct {
+	Unread         int64              `json:"unread"`
+	UnreadMessages int64              `json:"unread_messages"`
+	Notifications  []*NotificationDTO `json:"notifications"`
+}
+
+type NotificationsReadDTO struct {
+	IDs []uint64 `json:"ids"`
+	All bool     `json:"all"`
+}
+
+type NotificationPreferencesDTO struct {
+	Muted []string `json:"muted"`
+}
+
+type ProfileDTO struct {
+	ID           string `json:"id"`
+	UserName     string `json:"username"`
+	Created      string `json:"created"`
+	Bio          string `json:"bio"`
+	Karma        int64  `json:"karma"`
+	PostKarma    int64  `json:"post_karma"`
+	CommentKarma int64  `json:"comment_karma"`
+	Followers    int64  `json:"followers"`
+	Following    int64  `json:"following"`
+}
+
+type ProfileRequestDTO struct {
+	Bio string `json:"bio"`
+}
+
+type ProfileCommentDTO struct {
+	PostID  string      `json:"post_id"`
+	Comment *CommentDTO `json:"comment"`
+}
+
+type MessageDTO struct {
+	ID          uint64 `json:"id"`
+	SenderID    string `json:"sender_id"`
+	RecipientID string `json:"recipient_id"`
+	Body        string `json:"body"`
+	Read        bool   `json:"read"`
+	Created     string `json:"created"`
+}
+
+type MessageRequestDTO struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+type ConversationDTO struct {
+	User        *AuthorDTO  `json:"user"`
+	LastMessage *MessageDTO `json:"last_message"`
+	Unread      int64       `json:"unread"`
+}
+
+type ConversationsDTO struct {
+	Unread        int64              `json:"unread"`
+	Conversations []*ConversationDTO `json:"conversations"`
+}
+
+type ThreadDTO struct {
+	User     *AuthorDTO    `json:"user"`
+	Messages []*MessageDTO `json:"messages"`
+}
+
+type BlockDTO struct {
+	User    *AuthorDTO `json:"user"`
+	Created string     `json:"created"`
+}
+
+type FollowDTO struct {
+	User    *AuthorDTO `json:"user"`
+	Created string     `json:"created"`
+}
+
+// FollowsDTO Count is the total of the list, not of the page
+type FollowsDTO struct {
+	Count int64        `json:"count"`
+	Users []*FollowDTO `json:"users"`
+}
+
+type ReportQueueItemDTO struct {
+	TargetType    string   `json:"target_type"`
+	TargetID      string   `json:"target_id"`
+	PostID        string   `json:"post_id"`
+	AuthorID      string   `json:"author_id"`
+	Reports       uint32   `json:"reports"`
+	Reasons       []string `json:"reasons"`
+	FirstReported string   `json:"first_reported"`
 }
 
 type ErrorDTO struct {
@@ -47,10 +236,49 @@ type PostRequestDTO struct {
 	Type     string `json:"type"`
 	Title    string `json:"title"`
 	Text     string `json:"text"`
+	URL      string `json:"url"`
+	// Repost confirms a link already posted in the category recently
+	Repost bool `json:"repost"`
+}
+
+type RepostErrorDTO struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+	PostID string `json:"post_id"`
+	Link   string `json:"link"`
+}
+
+type CategoryRequestDTO struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Rules           string `json:"rules"`
+	RequireVerified bool   `json:"require_verified"`
+}
+
+type CategorySettingsRequestDTO struct {
+	RequireVerified bool `json:"require_verified"`
+}
+
+type BanRequestDTO struct {
+	UserName      string `json:"username"`
+	UserID        string `json:"user_id"`
+	Category      string `json:"category"`
+	Kind          string `json:"kind"`
+	Reason        string `json:"reason"`
+	DurationHours int    `json:"duration_hours"`
+}
+
+type ReportRequestDTO struct {
+	Reason string `json:"reason"`
+}
+
+type ModerationRequestDTO struct {
+	Reason string `json:"reason"`
 }
 
 type CommentRequestDTO struct {
-	Comment string `json:"comment"`
+	Comment  string `json:"comment"`
+	ParentID string `json:"parent_id"`
 }
 
 type LoginDTO struct {
@@ -58,12 +286,61 @@ type LoginDTO struct {
 	Password string `json:"password"`
 }
 
+type RegisterDTO struct {
+	UserName string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorLoginDTO struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type TwoFactorEnrollDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RoleRequestDTO struct {
+	UserName string `json:"username"`
+	Role     string `json:"role"`
+	Category string `json:"category"`
+}
+
 type DTOConverter struct {
 	CommentRepo CommentRepoI
 	VoteRepo    VoteRepoI
+	SavedRepo   SavedRepoI
+	BlockRepo   BlockRepoI
+	Markdown    MarkdownRenderer
+}
+
+// renderMarkdown is empty without a renderer
+func (converter *DTOConverter) renderMarkdown(source string) string {
+	if converter.Markdown == nil {
+		return ""
+	}
+	return converter.Markdown.Render(source)
+}
+
+// linkPreviewToDTO is nil until the preview job has fetched something
+func linkPreviewToDTO(preview *LinkPreview) *LinkPreviewDTO {
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return nil
+	}
+	return &LinkPreviewDTO{
+		Title:       preview.Title,
+		Description: preview.Description,
+		Image:       preview.Image,
+	}
 }
 
-func (converter *DTOConverter) PostConvertToDTO(data *PostComplexData) (*PostDTO, error) {
+func (converter *DTOConverter) PostConvertToDTO(data *PostComplexData, sess *Session) (*PostDTO, error) {
 	postDTO := &PostDTO{
 		ID: data.Post.ID,
 		Author: &AuthorDTO{
@@ -75,11 +352,20 @@ func (converter *DTOConverter) PostConvertToDTO(data *PostComplexData) (*PostDTO
 		Created:          data.Post.Created,
 		Score:            data.Post.Score,
 		Text:             data.Post.Description,
+		HTML:             converter.renderMarkdown(data.Post.Description),
 		Title:            data.Post.Title,
 		Type:             data.Post.Type,
+		URL:              data.Post.URL,
+		Image:            blobKeyURL(data.Post.Image),
+		Thumbnail:        blobKeyURL(thumbnailKey(data.Post.Image)),
 		UpVotePercentage: 0,
 		Votes:            []*VoteDTO{},
 		Views:            0,
+		Removed:          data.Post.Removed,
+		Locked:           data.Post.Locked,
+		Pinned:           data.Post.Pinned,
+		Flair:            data.Post.Flair,
+		Preview:          linkPreviewToDTO(&data.LinkPreview),
 	}
 
 	postIds := make([]string, 0, 1)
@@ -89,7 +375,11 @@ func (converter *DTOConverter) PostConvertToDTO(data *PostComplexData) (*PostDTO
 		return nil, err
 	}
 
-	postDTO.Comments = converter.CommentsConvertToDTO(comments[data.Post.ID])
+	blocked, err := converter.blockedIds(sess)
+	if nil != err {
+		return nil, err
+	}
+	postDTO.Comments = hideBlockedComments(converter.CommentsConvertToDTO(comments[data.Post.ID]), blocked)
 
 	votes, err := converter.VoteRepo.GetVotesByPostIds(postIds)
 	if nil != err {
@@ -97,9 +387,92 @@ func (converter *DTOConverter) PostConvertToDTO(data *PostComplexData) (*PostDTO
 	}
 	postDTO.Votes = converter.VotesConvertToDTO(votes[data.Post.ID])
 
+	err = converter.markSaved([]*PostDTO{postDTO}, sess)
+	if nil != err {
+		return nil, err
+	}
 	return postDTO, nil
 }
 
+// blockedIds is empty for the anonymous requests
+func (converter *DTOConverter) blockedIds(sess *Session) (map[string]struct{}, error) {
+	if sess == nil || converter.BlockRepo == nil {
+		return map[string]struct{}{}, nil
+	}
+	return converter.BlockRepo.GetBlockedIds(sess.UserID)
+}
+
+// hideBlockedComments drops the comments of the blocked users, the ones
+// with replies left keep their place in the thread as [blocked]
+func hideBlockedComments(comments []*CommentDTO, blocked map[string]struct{}) []*CommentDTO {
+	if len(blocked) == 0 {
+		return comments
+	}
+	replies := map[string][]*CommentDTO{}
+	for _, comment := range comments {
+		replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+	}
+	kept := map[string]bool{}
+	var keep func(comment *CommentDTO) bool
+	keep = func(comment *CommentDTO) bool {
+		if isKept, ok := kept[comment.ID]; ok {
+			return isKept
+		}
+		_, isBlocked := blocked[comment.Author.ID]
+		isKept := !isBlocked
+		for _, reply := range replies[comment.ID] {
+			if keep(reply) {
+				isKept = true
+			}
+		}
+		kept[comment.ID] = isKept
+		return isKept
+	}
+
+	visible := make([]*CommentDTO, 0, len(comments))
+	for _, comment := range comments {
+		if !keep(comment) {
+			continue
+		}
+		if _, isBlocked := blocked[comment.Author.ID]; isBlocked {
+			comment.Author = &AuthorDTO{UserName: BlockedPlaceholder}
+			comment.Body = BlockedPlaceholder
+			comment.HTML = ""
+			comment.Blocked = true
+		}
+		visible = append(visible, comment)
+	}
+	return visible
+}
+
+// markSaved sets Saved of the posts and of their comments with one query,
+// the anonymous requests are left without it
+func (converter *DTOConverter) markSaved(posts []*PostDTO, sess *Session) error {
+	if sess == nil || converter.SavedRepo == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+		for _, comment := range post.Comments {
+			ids = append(ids, comment.ID)
+		}
+	}
+	saved, err := converter.SavedRepo.GetSavedIds(sess.UserID, ids)
+	if nil != err {
+		return err
+	}
+	for _, post := range posts {
+		isSaved := saved[post.ID]
+		post.Saved = &isSaved
+		for _, comment := range post.Comments {
+			isSaved := saved[comment.ID]
+			comment.Saved = &isSaved
+		}
+	}
+	return nil
+}
+
 func (converter *DTOConverter) CommentsConvertToDTO(data []*CommentComplexData) []*CommentDTO {
 	commentsDTO := []*CommentDTO{}
 	for _, comment := range data {
@@ -108,9 +481,21 @@ func (converter *DTOConverter) CommentsConvertToDTO(data []*CommentComplexData)
 				UserName: comment.User.Login,
 				ID:       comment.User.ID,
 			},
-			Body:    comment.Comment.Body,
-			Created: comment.Comment.Created,
-			ID:      comment.Comment.ID,
+			Body:     comment.Comment.Body,
+			HTML:     converter.renderMarkdown(comment.Comment.Body),
+			Created:  comment.Comment.Created,
+			ID:       comment.Comment.ID,
+			Score:    comment.Comment.Ups - comment.Comment.Downs,
+			ParentID: comment.Comment.ParentID,
+			ups:      comment.Comment.Ups,
+			downs:    comment.Comment.Downs,
+		}
+		// deleted comments keep their place in the thread
+		if comment.Comment.DeletedAt != "" {
+			commentDTO.Author = &AuthorDTO{UserName: DeletedPlaceholder}
+			commentDTO.Body = DeletedPlaceholder
+			commentDTO.HTML = ""
+			commentDTO.Deleted = true
 		}
 		commentsDTO = append(commentsDTO, commentDTO)
 	}
@@ -129,10 +514,158 @@ func (converter *DTOConverter) VotesConvertToDTO(data []*Vote) []*VoteDTO {
 	return votesDTO
 }
 
-func (converter *DTOConverter) PostsConvertToDTO(data []*PostComplexData) ([]*PostDTO, error) {
+func (converter *DTOConverter) CategoriesConvertToDTO(data []*CategoryComplexData) []*CategoryDTO {
+	categoriesDTO := []*CategoryDTO{}
+	for _, category := range data {
+		categoryDTO := &CategoryDTO{
+			ID:              category.Category.ID,
+			Name:            category.Category.Name,
+			Description:     category.Category.Description,
+			Rules:           category.Category.Rules,
+			Created:         category.Category.Created,
+			Subscribers:     category.Category.Subscribers,
+			RequireVerified: category.Category.RequireVerified,
+		}
+		if category.User.ID != "" {
+			categoryDTO.Creator = &AuthorDTO{
+				UserName: category.User.Login,
+				ID:       category.User.ID,
+			}
+		}
+		categoriesDTO = append(categoriesDTO, categoryDTO)
+	}
+	return categoriesDTO
+}
+
+func (converter *DTOConverter) ModLogConvertToDTO(data []*ModLogComplexData) []*ModLogEntryDTO {
+	entriesDTO := []*ModLogEntryDTO{}
+	for _, entry := range data {
+		entriesDTO = append(entriesDTO, &ModLogEntryDTO{
+			ID: entry.ModLogEntry.ID,
+			Moderator: &AuthorDTO{
+				UserName: entry.User.Login,
+				ID:       entry.ModLogEntry.ModeratorID,
+			},
+			Action:     entry.ModLogEntry.Action,
+			TargetType: entry.ModLogEntry.TargetType,
+			TargetID:   entry.ModLogEntry.TargetID,
+			Reason:     entry.ModLogEntry.Reason,
+			Created:    entry.ModLogEntry.Created,
+		})
+	}
+	return entriesDTO
+}
+
+func (converter *DTOConverter) NotificationsConvertToDTO(data []*NotificationComplexData) []*NotificationDTO {
+	notificationsDTO := []*NotificationDTO{}
+	for _, item := range data {
+		notificationDTO := &NotificationDTO{
+			ID:        item.Notification.ID,
+			Type:      item.Notification.Type,
+			PostID:    item.Notification.PostID,
+			CommentID: item.Notification.CommentID,
+			Body:      item.Notification.Body,
+			Read:      item.Notification.Read,
+			Created:   item.Notification.Created,
+		}
+		if item.Notification.ActorID != "" {
+			notificationDTO.Actor = &AuthorDTO{
+				UserName: item.User.Login,
+				ID:       item.Notification.ActorID,
+			}
+		}
+		notificationsDTO = append(notificationsDTO, notificationDTO)
+	}
+	return notificationsDTO
+}
+
+func (converter *DTOConverter) MessagesConvertToDTO(data []*Message) []*MessageDTO {
+	messagesDTO := []*MessageDTO{}
+	for _, message := range data {
+		messagesDTO = append(messagesDTO, &MessageDTO{
+			ID:          message.ID,
+			SenderID:    message.SenderID,
+			RecipientID: message.RecipientID,
+			Body:        message.Body,
+			Read:        message.Read,
+			Created:     message.Created,
+		})
+	}
+	return messagesDTO
+}
+
+func (converter *DTOConverter) ConversationsConvertToDTO(data []*Conversation) []*ConversationDTO {
+	conversationsDTO := []*ConversationDTO{}
+	for _, conversation := range data {
+		conversationsDTO = append(conversationsDTO, &ConversationDTO{
+			User: &AuthorDTO{
+				UserName: conversation.User.Login,
+				ID:       conversation.User.ID,
+			},
+			LastMessage: converter.MessagesConvertToDTO([]*Message{&conversation.Message})[0],
+			Unread:      conversation.Unread,
+		})
+	}
+	return conversationsDTO
+}
+
+func (converter *DTOConverter) BlocksConvertToDTO(data []*BlockComplexData) []*BlockDTO {
+	blocksDTO := []*BlockDTO{}
+	for _, block := range data {
+		blocksDTO = append(blocksDTO, &BlockDTO{
+			User: &AuthorDTO{
+				UserName: block.User.Login,
+				ID:       block.User.ID,
+			},
+			Created: block.Block.Created,
+		})
+	}
+	return blocksDTO
+}
+
+func (converter *DTOConverter) FollowsConvertToDTO(data []*FollowComplexData) []*FollowDTO {
+	followsDTO := []*FollowDTO{}
+	for _, follow := range data {
+		followsDTO = append(followsDTO, &FollowDTO{
+			User: &AuthorDTO{
+				UserName: follow.User.Login,
+				ID:       follow.User.ID,
+			},
+			Created: follow.Follow.Created,
+		})
+	}
+	return followsDTO
+}
+
+func (converter *DTOConverter) ReportQueueConvertToDTO(data []*ReportQueueItem) []*ReportQueueItemDTO {
+	itemsDTO := []*ReportQueueItemDTO{}
+	for _, item := range data {
+		itemsDTO = append(itemsDTO, &ReportQueueItemDTO{
+			TargetType:    item.TargetType,
+			TargetID:      item.TargetID,
+			PostID:        item.PostID,
+			AuthorID:      item.AuthorID,
+			Reports:       item.Reports,
+			Reasons:       item.Reasons,
+			FirstReported: item.FirstReported,
+		})
+	}
+	return itemsDTO
+}
+
+// PostsConvertToDTO leaves out the posts of the users the viewer blocked
+func (converter *DTOConverter) PostsConvertToDTO(data []*PostComplexData, sess *Session) ([]*PostDTO, error) {
+	blocked, err := converter.blockedIds(sess)
+	if nil != err {
+		fmt.Println("get blocked: ", err)
+		return nil, err
+	}
 	postsDTO := []*PostDTO{}
 	postIds := make([]string, 0, 10)
 	for _, post := range data {
+		if _, ok := blocked[post.User.ID]; ok {
+			continue
+		}
 		postIds = append(postIds, post.Post.ID)
 		postDTO := &PostDTO{
 			ID: post.Post.ID,
@@ -143,13 +676,22 @@ func (converter *DTOConverter) PostsConvertToDTO(data []*PostComplexData) ([]*Po
 			Category:         post.Category.Name,
 			Comments:         []*CommentDTO{},
 			Created:          post.Post.Created,
-			Score:            post.Score,
-			Text:             post.Description,
-			Title:            post.Title,
-			Type:             post.Type,
+			Score:            post.Post.Score,
+			Text:             post.Post.Description,
+			HTML:             converter.renderMarkdown(post.Post.Description),
+			Title:            post.Post.Title,
+			Type:             post.Post.Type,
+			URL:              post.Post.URL,
+			Image:            blobKeyURL(post.Post.Image),
+			Thumbnail:        blobKeyURL(thumbnailKey(post.Post.Image)),
 			UpVotePercentage: 0,
 			Votes:            []*VoteDTO{},
 			Views:            0,
+			Removed:          post.Post.Removed,
+			Locked:           post.Post.Locked,
+			Pinned:           post.Post.Pinned,
+			Flair:            post.Post.Flair,
+			Preview:          linkPreviewToDTO(&post.LinkPreview),
 		}
 		postsDTO = append(postsDTO, postDTO)
 	}
@@ -165,9 +707,14 @@ func (converter *DTOConverter) PostsConvertToDTO(data []*PostComplexData) ([]*Po
 			return nil, err
 		}
 		for _, post := range postsDTO {
-			post.Comments = converter.CommentsConvertToDTO(comments[post.ID])
+			post.Comments = hideBlockedComments(converter.CommentsConvertToDTO(comments[post.ID]), blocked)
 			post.Votes = converter.VotesConvertToDTO(votes[post.ID])
 		}
+		err = converter.markSaved(postsDTO, sess)
+		if nil != err {
+			fmt.Println("get saved: ", err)
+			return nil, err
+		}
 	}
 
 	return postsDTO, nil
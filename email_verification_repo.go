@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type EmailVerificationRepo struct {
+	DB *sql.DB
+}
+
+func NewEmailVerificationRepo(db *sql.DB) *EmailVerificationRepo {
+	return &EmailVerificationRepo{
+		DB: db,
+	}
+}
+
+func (repo *EmailVerificationRepo) Create(verification *EmailVerification) error {
+	fmt.Println("Email verification repo: create token")
+	_, err := repo.DB.Exec(
+		"INSERT INTO email_verification (token, user_id, email, expires) VALUES(?, ?, ?, ?)",
+		verification.Token,
+		verification.UserID,
+		verification.Email,
+		verification.Expires,
+	)
+	return err
+}
+
+func (repo *EmailVerificationRepo) GetByToken(token string) (*EmailVerification, error) {
+	fmt.Println("Email verification repo: get by token")
+	verification := &EmailVerification{}
+	err := repo.DB.
+		QueryRow("SELECT token, user_id, email, expires FROM email_verification WHERE token = ?", token).
+		Scan(&verification.Token, &verification.UserID, &verification.Email, &verification.Expires)
+	if nil != err {
+		return nil, err
+	}
+	return verification, nil
+}
+
+// GetByUserId returns the latest token of the user
+func (repo *EmailVerificationRepo) GetByUserId(userID string) (*EmailVerification, error) {
+	fmt.Println("Email verification repo: get by user id")
+	verification := &EmailVerification{}
+	err := repo.DB.
+		QueryRow("SELECT token, user_id, email, expires FROM email_verification WHERE user_id = ? ORDER BY expires DESC LIMIT 1", userID).
+		Scan(&verification.Token, &verification.UserID, &verification.Email, &verification.Expires)
+	if nil != err {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func (repo *EmailVerificationRepo) DeleteByUserId(userID string) error {
+	fmt.Println("Email verification repo: delete by user id")
+	_, err := repo.DB.Exec("DELETE FROM email_verification WHERE user_id = ?", userID)
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventComment        = "comment"
+	EventCommentDeleted = "comment_deleted"
+	EventVote           = "vote"
+	EventPost           = "post"
+	// EventReset tells the client it missed events and has to refetch
+	EventReset = "reset"
+
+	EventHistorySize  = 1024
+	EventClientBuffer = 64
+)
+
+// Event ids are "<epoch>-<seq>", the epoch changes on every start, so
+// a Last-Event-ID from before a restart asks for a reset and not for
+// the events of someone else's sequence
+type Event struct {
+	ID     string
+	Type   string
+	PostID string
+	Data   []byte
+	seq    uint64
+}
+
+// EventClient is an open stream, PostID is empty for the stream of all
+// posts. Events is closed when the client is dropped.
+type EventClient struct {
+	PostID string
+	Events chan *Event
+	closed bool
+}
+
+// EventHub fans out the events to the open streams and keeps the last
+// EventHistorySize of them for the clients that reconnect. A client
+// which doesn't keep up is dropped instead of blocking the publisher,
+// it reconnects with the Last-Event-ID and gets the rest from the
+// history.
+type EventHub struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64
+	history []*Event
+	clients map[*EventClient]struct{}
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]*Event, 0, EventHistorySize),
+		clients: map[*EventClient]struct{}{},
+	}
+}
+
+func (client *EventClient) wants(event *Event) bool {
+	return client.PostID == "" || client.PostID == event.PostID
+}
+
+// Publish fills in the event id
+func (hub *EventHub) Publish(event *Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.seq++
+	event.seq = hub.seq
+	event.ID = hub.epoch + "-" + strconv.FormatUint(hub.seq, 10)
+	if len(hub.history) == EventHistorySize {
+		copy(hub.history, hub.history[1:])
+		hub.history = hub.history[:EventHistorySize-1]
+	}
+	hub.history = append(hub.history, event)
+
+	for client := range hub.clients {
+		if !client.wants(event) {
+			continue
+		}
+		select {
+		case client.Events <- event:
+		default:
+			fmt.Println("drop slow event client")
+			hub.drop(client)
+		}
+	}
+}
+
+// Subscribe registers the client and returns the events it missed since
+// lastEventID, both under the lock so nothing falls in between. The
+// bool is false when the missed events aren't in the history anymore.
+func (hub *EventHub) Subscribe(postID string, lastEventID string) (*EventClient, []*Event, bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	client := &EventClient{
+		PostID: postID,
+		Events: make(chan *Event, EventClientBuffer),
+	}
+	hub.clients[client] = struct{}{}
+	if lastEventID == "" {
+		return client, nil, true
+	}
+
+	seq, ok := hub.parseEventID(lastEventID)
+	if !ok || seq > hub.seq {
+		return client, nil, false
+	}
+	if len(hub.history) > 0 && seq+1 < hub.history[0].seq {
+		return client, nil, false
+	}
+	missed := []*Event{}
+	for _, event := range hub.history {
+		if event.seq > seq && client.wants(event) {
+			missed = append(missed, event)
+		}
+	}
+	return client, missed, true
+}
+
+func (hub *EventHub) Unsubscribe(client *EventClient) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.drop(client)
+}
+
+func (hub *EventHub) drop(client *EventClient) {
+	delete(hub.clients, client)
+	if !client.closed {
+		client.closed = true
+		close(client.Events)
+	}
+}
+
+func (hub *EventHub) parseEventID(id string) (uint64, bool) {
+	epoch, rawSeq, found := strings.Cut(id, "-")
+	if !found || epoch != hub.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+	if nil != err {
+		return 0, false
+	}
+	return seq, true
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func eventTypes(events []*Event) []string {
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type+":"+event.PostID)
+	}
+	return types
+}
+
+func TestEventHub(t *testing.T) {
+	hub := NewEventHub()
+	all, _, _ := hub.Subscribe("", "")
+	one, _, _ := hub.Subscribe("p1", "")
+
+	hub.Publish(&Event{Type: EventComment, PostID: "p1", Data: []byte(`{}`)})
+	hub.Publish(&Event{Type: EventVote, PostID: "p2", Data: []byte(`{}`)})
+	if len(all.Events) != 2 || len(one.Events) != 1 {
+		t.Errorf("bad fan out: %d %d", len(all.Events), len(one.Events))
+		return
+	}
+	first := <-one.Events
+	if first.Type != EventComment || !strings.HasSuffix(first.ID, "-1") {
+		t.Errorf("bad event: %+v", first)
+		return
+	}
+
+	//resume gets only the missed events of the post
+	hub.Publish(&Event{Type: EventCommentDeleted, PostID: "p1", Data: []byte(`{}`)})
+	_, missed, isComplete := hub.Subscribe("p1", first.ID)
+	if !isComplete || !reflect.DeepEqual(eventTypes(missed), []string{"comment_deleted:p1"}) {
+		t.Errorf("bad resume: %v %v", isComplete, eventTypes(missed))
+		return
+	}
+	_, missed, isComplete = hub.Subscribe("", first.ID)
+	if !isComplete || !reflect.DeepEqual(eventTypes(missed), []string{"vote:p2", "comment_deleted:p1"}) {
+		t.Errorf("bad resume: %v %v", isComplete, eventTypes(missed))
+		return
+	}
+
+	//ids of another start, unknown ids and lost history ask for a reset
+	for _, id := range []string{"otherepoch-1", "garbage", hub.epoch + "-100"} {
+		_, _, isComplete = hub.Subscribe("", id)
+		if isComplete {
+			t.Errorf("expected reset for %s", id)
+		}
+	}
+	for i := 0; i < EventHistorySize; i++ {
+		hub.Publish(&Event{Type: EventVote, PostID: "p3", Data: []byte(`{}`)})
+	}
+	_, _, isComplete = hub.Subscribe("p1", first.ID)
+	if isComplete {
+		t.Errorf("expected reset after the history is gone")
+		return
+	}
+
+	//slow client is dropped, the unsubscribe after it is fine
+	for range all.Events {
+	}
+	hub.Unsubscribe(all)
+	hub.Unsubscribe(one)
+	if _, ok := hub.clients[all]; ok {
+		t.Errorf("slow client is still subscribed")
+	}
+}
+
+func TestStreamPost(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	eventHubMock := NewMockEventHubI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:       postsRepoMock,
+		Events:          eventHubMock,
+		StreamHeartbeat: time.Millisecond,
+	}
+	postID := multipleComplexData[0].Post.ID
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/api/post/"+postID+"/stream", nil)
+		req.Header.Set("Last-Event-ID", "e-1")
+		return mux.SetURLVars(req, map[string]string{"POST_ID": postID})
+	}
+
+	//missed events, then the live ones until the client is dropped
+	client := &EventClient{PostID: postID, Events: make(chan *Event, 1)}
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	eventHubMock.EXPECT().Subscribe(postID, "e-1").Return(client, []*Event{
+		{ID: "e-2", Type: EventVote, PostID: postID, Data: []byte(`{"score":2}`)},
+	}, true)
+	eventHubMock.EXPECT().Unsubscribe(client)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		client.Events <- &Event{ID: "e-3", Type: EventCommentDeleted, PostID: postID, Data: []byte(`{"id":"c1"}`)}
+		close(client.Events)
+	}()
+	w := httptest.NewRecorder()
+	service.StreamPost(w, newRequest())
+	body := w.Body.String()
+	if w.Result().Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("bad content type: %s", w.Result().Header.Get("Content-Type"))
+		return
+	}
+	expected := "retry: 3000\n\nid: e-2\nevent: vote\ndata: {\"score\":2}\n\n"
+	if !strings.HasPrefix(body, expected) ||
+		!strings.HasSuffix(body, "id: e-3\nevent: comment_deleted\ndata: {\"id\":\"c1\"}\n\n") ||
+		!strings.Contains(body, ": ping\n\n") {
+		t.Errorf("bad stream: %q", body)
+		return
+	}
+
+	//lost history and the client goes away
+	client = &EventClient{PostID: postID, Events: make(chan *Event, 1)}
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	eventHubMock.EXPECT().Subscribe(postID, "e-1").Return(client, nil, false)
+	eventHubMock.EXPECT().Unsubscribe(client)
+	req := newRequest()
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	w = httptest.NewRecorder()
+	service.StreamPost(w, req.WithContext(ctx))
+	if !strings.Contains(w.Body.String(), "event: reset\ndata: {}\n\n") {
+		t.Errorf("expected reset: %q", w.Body.String())
+		return
+	}
+
+	//removed post
+	removed := *multipleComplexData[0]
+	removed.Post.Removed = true
+	postsRepoMock.EXPECT().GetById(postID).Return(&removed, nil)
+	w = httptest.NewRecorder()
+	service.StreamPost(w, newRequest())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestPublishEvents(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userRepoMock := NewMockUserRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	hub := NewEventHub()
+	service := &PostsHandler{
+		UserRepo:     userRepoMock,
+		BanRepo:      banRepoMock,
+		DTOConverter: &DTOConverter{Markdown: NewMarkdownCache()},
+		Events:       hub,
+	}
+	data := multipleComplexData[0]
+	postID := data.Post.ID
+	client, _, _ := hub.Subscribe(postID, "")
+	comment := &Comment{ID: "c1", PostId: postID, UserId: "u2", Body: "**hi**", Created: "2022-11-10T11:24:44Z", Ups: 1}
+
+	//new comment, rendered and scored like on the post page
+	userRepoMock.EXPECT().GetById("u2").Return(&User{ID: "u2", Login: "bob"}, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	service.commentAdded(comment, data)
+	event := <-client.Events
+	expected := `{"post_id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","comment":{"author":{"username":"bob","id":"u2"},"body":"**hi**","html":"\u003cp\u003e\u003cstrong\u003ehi\u003c/strong\u003e\u003c/p\u003e\n","created":"2022-11-10T11:24:44Z","id":"c1","score":1}}`
+	if event.Type != EventComment || string(event.Data) != expected {
+		t.Errorf("bad event: %s %s", event.Type, event.Data)
+		return
+	}
+
+	//shadowbanned and held comments stay out
+	userRepoMock.EXPECT().GetById("u2").Return(&User{ID: "u2", Login: "bob"}, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{"u2": {}}, nil)
+	service.commentAdded(comment, data)
+	held := *comment
+	held.Removed = true
+	service.commentAdded(&held, data)
+
+	//vote
+	service.publishVote(&PostDTO{ID: postID, Score: 3, UpVotePercentage: 75})
+	event = <-client.Events
+	expected = `{"post_id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","score":3,"upvotepercentage":75}`
+	if event.Type != EventVote || string(event.Data) != expected {
+		t.Errorf("bad event: %s %s", event.Type, event.Data)
+		return
+	}
+	if len(client.Events) != 0 {
+		t.Errorf("unexpected events: %d", len(client.Events))
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Follow adds the posts of the user to the followed feed of the current
+// user, the user is notified of a new follower unless they muted it
+func (h *UserHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	sess, followed, ok := h.readFollowRequest(w, r)
+	if !ok {
+		return
+	}
+	blocked, err := h.BlockRepo.IsBlocked(sess.UserID, followed.ID)
+	if nil != err {
+		fmt.Println("can't check block: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't follow user")
+		return
+	}
+	if blocked {
+		jsonError(w, http.StatusForbidden, "you can't follow this user")
+		return
+	}
+	follow := &Follow{
+		FollowerID: sess.UserID,
+		FollowedID: followed.ID,
+		Created:    h.TimeGetter.GetCreated(),
+	}
+	isAdded, err := h.FollowRepo.Add(follow)
+	if nil != err {
+		fmt.Println("can't add follow: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't follow user")
+		return
+	}
+	if isAdded {
+		h.notifyFollow(follow)
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *UserHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	sess, followed, ok := h.readFollowRequest(w, r)
+	if !ok {
+		return
+	}
+	isDeleted, err := h.FollowRepo.Delete(sess.UserID, followed.ID)
+	if nil != err {
+		fmt.Println("can't delete follow: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't unfollow user")
+		return
+	}
+	if !isDeleted {
+		jsonError(w, http.StatusNotFound, "follow not found")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// notifyFollow doesn't fail the follow, and the shadowbanned followers
+// stay unnoticed
+func (h *UserHandler) notifyFollow(follow *Follow) {
+	shadowbanned, err := h.BanRepo.GetShadowbannedUserIds()
+	if nil != err {
+		fmt.Println("can't get shadowbans: ", err.Error())
+		return
+	}
+	if _, ok := shadowbanned[follow.FollowerID]; ok {
+		return
+	}
+	_, err = h.NotificationRepo.Add(&Notification{
+		UserID:  follow.FollowedID,
+		Type:    NotificationFollow,
+		ActorID: follow.FollowerID,
+		Created: follow.Created,
+	})
+	if nil != err {
+		fmt.Println("can't add notification", NotificationFollow, err)
+	}
+}
+
+func (h *UserHandler) Followers(w http.ResponseWriter, r *http.Request) {
+	h.follows(w, r, true)
+}
+
+func (h *UserHandler) Following(w http.ResponseWriter, r *http.Request) {
+	h.follows(w, r, false)
+}
+
+func (h *UserHandler) follows(w http.ResponseWriter, r *http.Request, followers bool) {
+	w.Header().Add("Content-Type", "application/json")
+	user, opts, ok := h.readProfileTab(w, r, false)
+	if !ok {
+		return
+	}
+	followersCount, followingCount, err := h.FollowRepo.Count(user.ID)
+	if nil != err {
+		fmt.Println("can't count follows", err)
+		jsonError(w, http.StatusInternalServerError, "can't get follows")
+		return
+	}
+	followsDTO := &FollowsDTO{Count: followingCount}
+	var data []*FollowComplexData
+	if followers {
+		followsDTO.Count = followersCount
+		data, err = h.FollowRepo.GetFollowers(user.ID, opts.Limit, opts.Offset)
+	} else {
+		data, err = h.FollowRepo.GetFollowing(user.ID, opts.Limit, opts.Offset)
+	}
+	if nil != err {
+		fmt.Println("can't get follows", err)
+		jsonError(w, http.StatusInternalServerError, "can't get follows")
+		return
+	}
+	followsDTO.Users = h.DTOConverter.FollowsConvertToDTO(data)
+	jsonResponse(w, followsDTO)
+}
+
+// readFollowRequest resolves the user of the path; it writes the error itself.
+func (h *UserHandler) readFollowRequest(w http.ResponseWriter, r *http.Request) (*Session, *User, bool) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return nil, nil, false
+	}
+	user, err := h.UserRepo.GetByLogin(mux.Vars(r)["USER_LOGIN"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, nil, false
+	} else if nil != err {
+		fmt.Println("can't get user by login: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, nil, false
+	}
+	if user.ID == sess.UserID {
+		jsonError(w, http.StatusBadRequest, "can't follow yourself")
+		return nil, nil, false
+	}
+	return sess, user, true
+}
+
+// FollowedFeed lists the posts of the followed users with the sort and
+// the pages of the other listings
+func (h *PostsHandler) FollowedFeed(w http.ResponseWriter, r *http.Request) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.PostsRepo.GetFollowedFeed(sess.UserID, opts)
+	if nil != err {
+		fmt.Println("can't get followed feed", err)
+		jsonError(w, http.StatusInternalServerError, "DB err")
+		return
+	}
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
+	if err != nil {
+		fmt.Println("can't convert posts to dto", err)
+		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
+		return
+	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if err != nil {
+		fmt.Println("can't hide shadowbanned posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, postsDTO)
+}
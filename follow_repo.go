@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type FollowRepo struct {
+	DB *sql.DB
+}
+
+func NewFollowRepo(db *sql.DB) *FollowRepo {
+	return &FollowRepo{
+		DB: db,
+	}
+}
+
+// Add returns false when the user is followed already
+func (repo *FollowRepo) Add(follow *Follow) (bool, error) {
+	fmt.Println("Follow repo: add")
+	result, err := repo.DB.Exec(`INSERT IGNORE INTO user_follow (follower_id, followed_id, created) VALUES (?, ?, ?)`,
+		follow.FollowerID, follow.FollowedID, follow.Created)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *FollowRepo) Delete(followerID string, followedID string) (bool, error) {
+	fmt.Println("Follow repo: delete")
+	result, err := repo.DB.Exec(`DELETE FROM user_follow WHERE follower_id = ? AND followed_id = ?`, followerID, followedID)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetFollowers returns the followers of the user, the last ones first
+func (repo *FollowRepo) GetFollowers(userID string, limit int, offset int) ([]*FollowComplexData, error) {
+	fmt.Println("Follow repo: get followers")
+	return repo.query(`
+	SELECT follower_id, followed_id, user_follow.created, user.id, user.login
+	FROM user_follow
+	JOIN user ON user.id = user_follow.follower_id
+	WHERE user_follow.followed_id = ?
+	ORDER BY user_follow.created DESC, follower_id
+	LIMIT ? OFFSET ?`,
+		userID, limit, offset)
+}
+
+// GetFollowing returns the users the user follows, the last followed first
+func (repo *FollowRepo) GetFollowing(userID string, limit int, offset int) ([]*FollowComplexData, error) {
+	fmt.Println("Follow repo: get following")
+	return repo.query(`
+	SELECT follower_id, followed_id, user_follow.created, user.id, user.login
+	FROM user_follow
+	JOIN user ON user.id = user_follow.followed_id
+	WHERE user_follow.follower_id = ?
+	ORDER BY user_follow.created DESC, followed_id
+	LIMIT ? OFFSET ?`,
+		userID, limit, offset)
+}
+
+func (repo *FollowRepo) query(query string, args ...interface{}) ([]*FollowComplexData, error) {
+	rows, err := repo.DB.Query(query, args...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := make([]*FollowComplexData, 0, 10)
+	for rows.Next() {
+		data := &FollowComplexData{}
+		err := rows.Scan(&data.Follow.FollowerID, &data.Follow.FollowedID, &data.Follow.Created,
+			&data.User.ID, &data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		follows = append(follows, data)
+	}
+	return follows, rows.Err()
+}
+
+// Count returns the number of the followers of the user and of the users
+// they follow
+func (repo *FollowRepo) Count(userID string) (int64, int64, error) {
+	fmt.Println("Follow repo: count")
+	var followers, following int64
+	err := repo.DB.
+		QueryRow(`SELECT
+		(SELECT COUNT(*) FROM user_follow WHERE followed_id = ?),
+		(SELECT COUNT(*) FROM user_follow WHERE follower_id = ?)`,
+			userID, userID).
+		Scan(&followers, &following)
+	if nil != err {
+		return 0, 0, err
+	}
+	return followers, following, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestFollowRepo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewFollowRepo(db)
+
+	// followers with the follower user
+	mock.ExpectQuery(`JOIN user ON user.id = user_follow.follower_id\s+WHERE user_follow.followed_id = \?\s+ORDER BY user_follow.created DESC, follower_id\s+LIMIT \? OFFSET \?`).
+		WithArgs("u1", 25, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id", "followed_id", "created", "id", "login"}).
+			AddRow("u2", "u1", "2022-11-10T12:00:00Z", "u2", "bob"))
+	data, err := repo.GetFollowers("u1", 25, 0)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	expected := []*FollowComplexData{{
+		Follow: Follow{FollowerID: "u2", FollowedID: "u1", Created: "2022-11-10T12:00:00Z"},
+		User:   User{ID: "u2", Login: "bob"},
+	}}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, data)
+		return
+	}
+
+	// both counts at once
+	mock.ExpectQuery(`SELECT\s+\(SELECT COUNT\(\*\) FROM user_follow WHERE followed_id = \?\),\s+\(SELECT COUNT\(\*\) FROM user_follow WHERE follower_id = \?\)`).
+		WithArgs("u1", "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"followers", "following"}).AddRow(3, 1))
+	followers, following, err := repo.Count("u1")
+	if err != nil || followers != 3 || following != 1 {
+		t.Errorf("expected 3 and 1, got %d %d %v", followers, following, err)
+		return
+	}
+
+	// already followed
+	mock.ExpectExec(`INSERT IGNORE INTO user_follow`).
+		WithArgs("u2", "u1", "2022-11-10T12:00:00Z").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isAdded, err := repo.Add(&Follow{FollowerID: "u2", FollowedID: "u1", Created: "2022-11-10T12:00:00Z"})
+	if err != nil || isAdded {
+		t.Errorf("expected not added, got %v %v", isAdded, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestFollowUser(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	followRepoMock := NewMockFollowRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	notificationRepoMock := NewMockNotificationRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &UserHandler{
+		UserRepo:         userRepoMock,
+		BlockRepo:        blockRepoMock,
+		FollowRepo:       followRepoMock,
+		BanRepo:          banRepoMock,
+		NotificationRepo: notificationRepoMock,
+		TimeGetter:       timeGetterMock,
+		DTOConverter:     &DTOConverter{},
+	}
+	newRequest := func(method string, url string, login string) *http.Request {
+		req := httptest.NewRequest(method, url, nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+		return mux.SetURLVars(req, map[string]string{"USER_LOGIN": login})
+	}
+	bob := &User{ID: "u2", Login: "bob"}
+	follow := &Follow{FollowerID: sess.UserID, FollowedID: "u2", Created: "2022-11-10T12:00:00Z"}
+
+	//a new follower is notified
+	userRepoMock.EXPECT().GetByLogin("bob").Return(bob, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(false, nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T12:00:00Z")
+	followRepoMock.EXPECT().Add(follow).Return(true, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	notificationRepoMock.EXPECT().Add(&Notification{
+		UserID:  "u2",
+		Type:    NotificationFollow,
+		ActorID: sess.UserID,
+		Created: "2022-11-10T12:00:00Z",
+	}).Return(true, nil)
+	w := httptest.NewRecorder()
+	service.Follow(w, newRequest("POST", "/api/user/bob/follow", "bob"))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//following again doesn't notify
+	userRepoMock.EXPECT().GetByLogin("bob").Return(bob, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(false, nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T12:00:00Z")
+	followRepoMock.EXPECT().Add(follow).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.Follow(w, newRequest("POST", "/api/user/bob/follow", "bob"))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//blocked
+	userRepoMock.EXPECT().GetByLogin("bob").Return(bob, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(true, nil)
+	w = httptest.NewRecorder()
+	service.Follow(w, newRequest("POST", "/api/user/bob/follow", "bob"))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//yourself
+	userRepoMock.EXPECT().GetByLogin("mer").Return(&User{ID: sess.UserID, Login: "mer"}, nil)
+	w = httptest.NewRecorder()
+	service.Follow(w, newRequest("POST", "/api/user/mer/follow", "mer"))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unfollow without a follow
+	userRepoMock.EXPECT().GetByLogin("bob").Return(bob, nil)
+	followRepoMock.EXPECT().Delete(sess.UserID, "u2").Return(false, nil)
+	w = httptest.NewRecorder()
+	service.Unfollow(w, newRequest("POST", "/api/user/bob/unfollow", "bob"))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//followers with the total count
+	userRepoMock.EXPECT().GetByLogin("bob").Return(bob, nil)
+	followRepoMock.EXPECT().Count("u2").Return(int64(3), int64(1), nil)
+	followRepoMock.EXPECT().GetFollowers("u2", 2, 0).Return([]*FollowComplexData{
+		{Follow: Follow{FollowerID: sess.UserID, FollowedID: "u2", Created: "2022-11-10T12:00:00Z"}, User: User{ID: sess.UserID, Login: "mer"}},
+	}, nil)
+	w = httptest.NewRecorder()
+	service.Followers(w, newRequest("GET", "/api/user/bob/followers?limit=2", "bob"))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `{"count":3,"users":[{"user":{"username":"mer","id":"` + sess.UserID + `"},"created":"2022-11-10T12:00:00Z"}]}`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+}
+
+func TestFollowedFeed(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:    postsRepoMock,
+		BanRepo:      banRepoMock,
+		DTOConverter: dtoConverterMock,
+	}
+	newRequest := func(url string) *http.Request {
+		req := httptest.NewRequest("GET", url, nil)
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+
+	//the sort and the page of the listings
+	postsRepoMock.EXPECT().GetFollowedFeed(sess.UserID, &ListOptions{Sort: SortNew, Limit: 10, Offset: 20}).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, sess).Return(postsDTO, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	w := httptest.NewRecorder()
+	service.FollowedFeed(w, newRequest("/api/feed/following?sort=new&limit=10&offset=20"))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//bad sort
+	w = httptest.NewRecorder()
+	service.FollowedFeed(w, newRequest("/api/feed/following?sort=random"))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
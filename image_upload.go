@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	UploadsPath = "/uploads/"
+
+	MaxUploadBytes = 10 << 20
+	// MaxImagePixels is checked before decoding, for gifs it counts
+	// every frame
+	MaxImagePixels = 25 * 1000 * 1000
+	ThumbnailSize  = 320
+
+	imageQuality     = 90
+	thumbnailQuality = 80
+	// uploadFormOverhead is allowed on top of the file for the other
+	// form fields and the multipart framing
+	uploadFormOverhead = 64 << 10
+)
+
+var (
+	ErrUnsupportedImage = errors.New("only jpeg, png and gif images are allowed")
+	ErrImageTooLarge    = errors.New("image is too large")
+
+	imageExtensions = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+	}
+)
+
+// ProcessedImage is the re-encoded upload, the re-encoding drops EXIF
+// and any other metadata. The thumbnail is always a jpeg.
+type ProcessedImage struct {
+	ContentType string
+	Data        []byte
+	Thumbnail   []byte
+	Width       int
+	Height      int
+}
+
+// ProcessImage trusts the sniffed content type only, not the file name
+// or the type the client has sent. Jpegs are rotated by their EXIF
+// orientation before it is dropped.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	if len(data) > MaxUploadBytes {
+		return nil, ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return nil, ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if nil != err {
+		return nil, fmt.Errorf("can't read image: %s", err.Error())
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if pixels > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	var img image.Image
+	var frames int
+	var animation *gif.GIF
+	out := &bytes.Buffer{}
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if nil != err {
+			return nil, fmt.Errorf("can't decode image: %s", err.Error())
+		}
+		img = orientImage(img, jpegOrientation(data))
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: imageQuality})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if nil != err {
+			return nil, fmt.Errorf("can't decode image: %s", err.Error())
+		}
+		err = png.Encode(out, img)
+	case "image/gif":
+		frames, err = gifFrameCount(data)
+		if nil != err {
+			return nil, fmt.Errorf("can't read image: %s", err.Error())
+		}
+		if pixels*int64(frames) > MaxImagePixels {
+			return nil, ErrImageTooLarge
+		}
+		animation, err = gif.DecodeAll(bytes.NewReader(data))
+		if nil != err {
+			return nil, fmt.Errorf("can't decode image: %s", err.Error())
+		}
+		first := animation.Image[0]
+		canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+		draw.Draw(canvas, first.Bounds(), first, first.Bounds().Min, draw.Over)
+		img = canvas
+		err = gif.EncodeAll(out, animation)
+	}
+	if nil != err {
+		return nil, fmt.Errorf("can't encode image: %s", err.Error())
+	}
+
+	thumb := &bytes.Buffer{}
+	err = jpeg.Encode(thumb, thumbnail(img, ThumbnailSize), &jpeg.Options{Quality: thumbnailQuality})
+	if nil != err {
+		return nil, fmt.Errorf("can't encode thumbnail: %s", err.Error())
+	}
+	bounds := img.Bounds()
+	return &ProcessedImage{
+		ContentType: contentType,
+		Data:        out.Bytes(),
+		Thumbnail:   thumb.Bytes(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// imageKey and thumbnailKey name the blobs of an image post
+func imageKey(id string, contentType string) string {
+	return "images/" + id + imageExtensions[contentType]
+}
+
+func thumbnailKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "_thumb.jpg"
+}
+
+// imagePostID is the post of an image or a thumbnail key
+func imagePostID(key string) string {
+	name := strings.TrimPrefix(key, "images/")
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return strings.TrimSuffix(name, "_thumb")
+}
+
+// gifFrameCount walks the gif blocks without decoding them, so the
+// frames are counted before DecodeAll allocates them
+func gifFrameCount(data []byte) (int, error) {
+	errBad := fmt.Errorf("bad gif")
+	if len(data) < 13 {
+		return 0, errBad
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return true
+			}
+			pos += size
+		}
+		return false
+	}
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21:
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, errBad
+			}
+		case 0x2C:
+			if pos+10 > len(data) {
+				return 0, errBad
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size
+			pos++
+			if !skipSubBlocks() {
+				return 0, errBad
+			}
+			frames++
+		case 0x3B:
+			return frames, nil
+		default:
+			return 0, errBad
+		}
+	}
+	return frames, nil
+}
+
+// jpegOrientation reads the EXIF orientation tag, 1 when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// the scan has started, EXIF comes before it
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+	return 1
+}
+
+// orientImage applies the EXIF orientation, 5-8 swap the sides
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// thumbnail fits the image into size x size by averaging the source
+// pixels, transparent parts end up white
+func thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// the colors are premultiplied, so white shows through by 1-alpha
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// postRequestFromForm reads an image post sent as multipart/form-data,
+// the form has the same fields as the json payload and the file in
+// "image". The file is nil when the form has none.
+func postRequestFromForm(w http.ResponseWriter, r *http.Request) (*PostRequestDTO, []byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadBytes+uploadFormOverhead)
+	err := r.ParseMultipartForm(MaxUploadBytes)
+	if nil != err {
+		return nil, nil, err
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	repost, _ := strconv.ParseBool(r.FormValue("repost"))
+	requestData := &PostRequestDTO{
+		Category: r.FormValue("category"),
+		Type:     r.FormValue("type"),
+		Title:    r.FormValue("title"),
+		Text:     r.FormValue("text"),
+		URL:      r.FormValue("url"),
+		Repost:   repost,
+	}
+	file, _, err := r.FormFile("image")
+	if err == http.ErrMissingFile {
+		return requestData, nil, nil
+	} else if nil != err {
+		return nil, nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxUploadBytes+1))
+	if nil != err {
+		return nil, nil, err
+	}
+	if len(data) > MaxUploadBytes {
+		return nil, nil, ErrImageTooLarge
+	}
+	return requestData, data, nil
+}
+
+func isMultipartRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// storeImage puts the image and its thumbnail, a half stored upload is
+// cleaned up
+func (h *PostsHandler) storeImage(key string, img *ProcessedImage) error {
+	err := h.BlobStore.Put(key, img.ContentType, img.Data)
+	if nil != err {
+		return err
+	}
+	err = h.BlobStore.Put(thumbnailKey(key), "image/jpeg", img.Thumbnail)
+	if nil != err {
+		deleteImageBlobs(h.BlobStore, key)
+		return err
+	}
+	return nil
+}
+
+// deleteImageBlobs removes the image and its thumbnail
+func deleteImageBlobs(store BlobStore, key string) {
+	for _, blobKey := range []string{key, thumbnailKey(key)} {
+		err := store.Delete(blobKey)
+		if nil != err {
+			fmt.Println("can't delete upload", blobKey, err)
+		}
+	}
+}
+
+// ServeUpload serves the images of the visible posts. The keys are never
+// reused, but the posts can be deleted, so the files are cached for a day
+// only. The images of the deleted posts are kept for a restore until the
+// purge job removes them.
+func (h *PostsHandler) ServeUpload(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, UploadsPath)
+	data, err := h.PostsRepo.GetById(imagePostID(key))
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	} else if nil != err {
+		fmt.Println("can't get post of upload", key, err)
+		http.Error(w, "can't get upload", http.StatusInternalServerError)
+		return
+	}
+	// the images of removed posts stay visible to the moderators only
+	cacheControl := "public, max-age=86400"
+	if data.Post.Removed {
+		sess, _ := SessionFromContext(r.Context())
+		if !Can(sess, ActionRemovePost, PostResource(&data.Post)) {
+			http.NotFound(w, r)
+			return
+		}
+		cacheControl = "private, no-store"
+	}
+	etag := `"` + key + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	blob, err := h.BlobStore.Get(key)
+	if err == ErrBlobNotFound || err == ErrBadBlobKey {
+		http.NotFound(w, r)
+		return
+	} else if nil != err {
+		fmt.Println("can't get upload", key, err)
+		http.Error(w, "can't get upload", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Body.Close()
+	if _, ok := imageExtensions[blob.ContentType]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", blob.ContentType)
+	if blob.Size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+	}
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", etag)
+	if !blob.Modified.IsZero() {
+		header.Set("Last-Modified", blob.Modified.UTC().Format(http.TimeFormat))
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "default-src 'none'")
+	_, err = io.Copy(w, blob.Body)
+	if nil != err {
+		fmt.Println("can't write upload", key, err)
+	}
+}
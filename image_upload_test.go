@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func testPNG(t *testing.T, img image.Image) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("can't encode png: %s", err)
+	}
+	return buf.Bytes()
+}
+
+// testJPEGWithExif puts an APP1 segment with the orientation tag right
+// after SOI, as cameras do
+func testJPEGWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("can't encode jpeg: %s", err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	//png keeps its size, thumbnail fits the box
+	processed, err := ProcessImage(testPNG(t, testImage(640, 320)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if processed.ContentType != "image/png" || processed.Width != 640 || processed.Height != 320 {
+		t.Errorf("wrong image: %s %dx%d", processed.ContentType, processed.Width, processed.Height)
+		return
+	}
+	thumb, format, err := image.DecodeConfig(bytes.NewReader(processed.Thumbnail))
+	if err != nil || format != "jpeg" || thumb.Width != ThumbnailSize || thumb.Height != ThumbnailSize/2 {
+		t.Errorf("wrong thumbnail: %s %dx%d %v", format, thumb.Width, thumb.Height, err)
+		return
+	}
+
+	//jpeg is rotated by the orientation and the EXIF is dropped
+	data := testJPEGWithExif(t, testImage(40, 20), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("expected orientation 6, got %d", jpegOrientation(data))
+	}
+	processed, err = ProcessImage(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if processed.Width != 20 || processed.Height != 40 {
+		t.Errorf("expected the rotated 20x40 image, got %dx%d", processed.Width, processed.Height)
+		return
+	}
+	if bytes.Contains(processed.Data, []byte("Exif")) || jpegOrientation(processed.Data) != 1 {
+		t.Errorf("EXIF is not stripped")
+		return
+	}
+
+	//animated gif keeps its frames
+	palette := color.Palette{color.White, color.Black}
+	animation := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 10, 10), palette))
+		animation.Delay = append(animation.Delay, 10)
+	}
+	buf := &bytes.Buffer{}
+	if err = gif.EncodeAll(buf, animation); err != nil {
+		t.Fatalf("can't encode gif: %s", err)
+	}
+	if frames, err := gifFrameCount(buf.Bytes()); err != nil || frames != 3 {
+		t.Errorf("expected 3 frames, got %d %v", frames, err)
+		return
+	}
+	processed, err = ProcessImage(buf.Bytes())
+	if err != nil || processed.ContentType != "image/gif" {
+		t.Errorf("unexpected result: %v", err)
+		return
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(processed.Data))
+	if err != nil || len(decoded.Image) != 3 {
+		t.Errorf("frames lost: %v", err)
+		return
+	}
+
+	//content type is sniffed
+	if _, err = ProcessImage([]byte("<html><script>alert(1)</script></html>")); err != ErrUnsupportedImage {
+		t.Errorf("expected ErrUnsupportedImage, got %v", err)
+		return
+	}
+
+	//dimensions are checked before decoding, the IHDR is patched with a valid crc
+	huge := testPNG(t, testImage(1, 1))
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 10000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err = ProcessImage(huge); err != ErrImageTooLarge {
+		t.Errorf("expected ErrImageTooLarge, got %v", err)
+		return
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	//90 degrees clockwise puts the left pixel on top
+	rotated := orientImage(img, 6)
+	if rotated.Bounds().Dx() != 1 || rotated.Bounds().Dy() != 2 {
+		t.Fatalf("wrong bounds %v", rotated.Bounds())
+	}
+	if r, _, _, _ := rotated.At(0, 0).RGBA(); r != 0xffff {
+		t.Errorf("expected red on top")
+	}
+	//mirror
+	if _, _, b, _ := orientImage(img, 2).At(0, 0).RGBA(); b != 0xffff {
+		t.Errorf("expected blue on the left")
+	}
+	if orientImage(img, 1) != img {
+		t.Errorf("orientation 1 must not copy")
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir())
+
+	if err := store.Put("images/a.png", "image/png", []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	blob, err := store.Get("images/a.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, _ := io.ReadAll(blob.Body)
+	blob.Body.Close()
+	if string(data) != "data" || blob.ContentType != "image/png" || blob.Size != 4 {
+		t.Errorf("wrong blob %q %s %d", data, blob.ContentType, blob.Size)
+		return
+	}
+
+	if err = store.Delete("images/a.png"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err = store.Get("images/a.png"); err != ErrBlobNotFound {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+		return
+	}
+
+	for _, key := range []string{"../a.png", "images/../../a.png", "/etc/passwd", "images//a.png", "Images/a.png", ""} {
+		if err = store.Put(key, "image/png", []byte("data")); err != ErrBadBlobKey {
+			t.Errorf("%q: expected ErrBadBlobKey, got %v", key, err)
+		}
+	}
+}
+
+// fakeS3 keeps the objects in memory and checks the parts of the
+// signature a real server would reject first
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/20221110/us-east-1/s3/aws4_request, ") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+		r.Header.Get("X-Amz-Date") != "20221110T112444Z" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch r.Method {
+	case http.MethodPut:
+		s3.objects[key] = body
+		s3.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := s3.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s3.types[key])
+		w.Header().Set("Last-Modified", "Thu, 10 Nov 2022 11:24:44 GMT")
+		w.Write(data)
+	case http.MethodDelete:
+		delete(s3.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC)).AnyTimes()
+	store := NewS3BlobStore(server.URL+"/", "bucket", "us-east-1", "access", "secret")
+	store.TimeGetter = timeGetterMock
+
+	if err := store.Put("images/a.png", "image/png", []byte("data")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	blob, err := store.Get("images/a.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, _ := io.ReadAll(blob.Body)
+	blob.Body.Close()
+	if string(data) != "data" || blob.ContentType != "image/png" || blob.Modified.IsZero() {
+		t.Errorf("wrong blob %q %s %v", data, blob.ContentType, blob.Modified)
+		return
+	}
+	if err = store.Delete("images/a.png"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err = store.Get("images/a.png"); err != ErrBlobNotFound {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+		return
+	}
+
+	//server errors are returned
+	store.AccessKey = "wrong"
+	if err = store.Put("images/a.png", "image/png", []byte("data")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected 403 error, got %v", err)
+	}
+}
+
+func TestAddImagePost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
+	blobStoreMock := NewMockBlobStore(ctrl)
+	service := &PostsHandler{
+		PostsRepo:      postsRepoMock,
+		DTOConverter:   dtoConverterMock,
+		DictionaryRepo: dictionaryRepoMock,
+		TimeGetter:     timeGetterMock,
+		UUIDGetter:     uuidGetterMock,
+		BanRepo:        banRepoMock,
+		Automod:        automodMock,
+		BlobStore:      blobStoreMock,
+	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	automodMock.EXPECT().Check(gomock.Any()).Return(nil, nil).AnyTimes()
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil).AnyTimes()
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z").AnyTimes()
+	postID := "0b9f0e36-2b4c-4d0c-8a4e-2f4b8c7f3c11"
+	newRequest := func(postType string, file []byte) *http.Request {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		form.WriteField("category", "fashion")
+		form.WriteField("type", postType)
+		form.WriteField("title", "look")
+		if file != nil {
+			part, _ := form.CreateFormFile("image", "look.jpg")
+			part.Write(file)
+		}
+		form.Close()
+		req := httptest.NewRequest("POST", "/api/posts", body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+	pngData := testPNG(t, testImage(8, 8))
+
+	//success, the file name doesn't decide the type
+	uuidGetterMock.EXPECT().GetUUID().Return(postID)
+	blobStoreMock.EXPECT().Put("images/"+postID+".png", "image/png", gomock.Any()).Return(nil)
+	blobStoreMock.EXPECT().Put("images/"+postID+"_thumb.jpg", "image/jpeg", gomock.Any()).Return(nil)
+	postsRepoMock.EXPECT().Add(&Post{
+		ID:         postID,
+		Title:      "look",
+		Type:       PostTypeImage,
+		Image:      "images/" + postID + ".png",
+		Score:      1,
+		UserID:     sess.UserID,
+		CategoryID: 1,
+		Created:    "2022-11-09T19:51:42Z",
+	}).Return(&postID, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
+	w := httptest.NewRecorder()
+	service.Add(w, newRequest(PostTypeImage, pngData))
+	if w.Result().StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(w.Result().Body)
+		t.Errorf("expected 200 statuscode; got %d %s", w.Result().StatusCode, body)
+		return
+	}
+
+	//failed insert removes the stored files
+	uuidGetterMock.EXPECT().GetUUID().Return(postID)
+	blobStoreMock.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	postsRepoMock.EXPECT().Add(gomock.Any()).Return(nil, fmt.Errorf("add error"))
+	blobStoreMock.EXPECT().Delete("images/" + postID + ".png").Return(nil)
+	blobStoreMock.EXPECT().Delete("images/" + postID + "_thumb.jpg").Return(nil)
+	w = httptest.NewRecorder()
+	service.Add(w, newRequest(PostTypeImage, pngData))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//not an image
+	w = httptest.NewRecorder()
+	service.Add(w, newRequest(PostTypeImage, []byte("just some text")))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//image post without a file and a text post with one
+	for _, req := range []*http.Request{newRequest(PostTypeImage, nil), newRequest(PostTypeText, pngData)} {
+		w = httptest.NewRecorder()
+		service.Add(w, req)
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+			return
+		}
+	}
+
+	//over the size limit
+	w = httptest.NewRecorder()
+	service.Add(w, newRequest(PostTypeImage, make([]byte, MaxUploadBytes+1)))
+	if w.Result().StatusCode != http.StatusRequestEntityTooLarge && w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 413 or 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestServeUpload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	store := NewLocalBlobStore(t.TempDir())
+	store.Put("images/a.png", "image/png", []byte("png data"))
+	store.Put("images/a_thumb.jpg", "image/jpeg", []byte("jpeg data"))
+	store.Put("images/a.html", "text/html", []byte("<script></script>"))
+	store.Put("images/d.png", "image/png", []byte("png data"))
+	store.Put("images/r.png", "image/png", []byte("png data"))
+	service := &PostsHandler{BlobStore: store, PostsRepo: postsRepoMock}
+	postsRepoMock.EXPECT().GetById("a").Return(multipleComplexData[0], nil).AnyTimes()
+	removed := &PostComplexData{Post: Post{ID: "r", CategoryID: 1, Removed: true}}
+	postsRepoMock.EXPECT().GetById("r").Return(removed, nil).AnyTimes()
+	postsRepoMock.EXPECT().GetById(gomock.Not("a")).Return(nil, sql.ErrNoRows).AnyTimes()
+
+	//success with caching headers
+	w := httptest.NewRecorder()
+	service.ServeUpload(w, httptest.NewRequest("GET", "/uploads/images/a.png", nil))
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "png data" ||
+		resp.Header.Get("Content-Type") != "image/png" ||
+		resp.Header.Get("Cache-Control") != "public, max-age=86400" ||
+		resp.Header.Get("ETag") != `"images/a.png"` ||
+		resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("wrong response %d %q %v", resp.StatusCode, body, resp.Header)
+		return
+	}
+
+	//revalidation
+	req := httptest.NewRequest("GET", "/uploads/images/a.png", nil)
+	req.Header.Set("If-None-Match", `"images/a.png"`)
+	w = httptest.NewRecorder()
+	service.ServeUpload(w, req)
+	if w.Result().StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//thumbnail of the post
+	w = httptest.NewRecorder()
+	service.ServeUpload(w, httptest.NewRequest("GET", "/uploads/images/a_thumb.jpg", nil))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//missing, outside the store, not an image and of a deleted post
+	for _, path := range []string{"/uploads/images/b.png", "/uploads/../schema.sql", "/uploads/images/a.html", "/uploads/images/d.png"} {
+		w = httptest.NewRecorder()
+		service.ServeUpload(w, httptest.NewRequest("GET", path, nil))
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404 statuscode; got %d", path, w.Result().StatusCode)
+		}
+	}
+
+	//of a removed post for others than the moderators
+	req = httptest.NewRequest("GET", "/uploads/images/r.png", nil)
+	w = httptest.NewRecorder()
+	service.ServeUpload(w, req.WithContext(context.WithValue(req.Context(), sessionKey, sess)))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//of a removed post for a moderator, not cached
+	w = httptest.NewRecorder()
+	service.ServeUpload(w, req.WithContext(context.WithValue(req.Context(), sessionKey, modSess)))
+	if w.Result().StatusCode != http.StatusOK || w.Result().Header.Get("Cache-Control") != "private, no-store" {
+		t.Errorf("expected 200 statuscode; got %d %v", w.Result().StatusCode, w.Result().Header)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LinkPreviewTimeout      = 10 * time.Second
+	LinkPreviewMaxBytes     = 1 << 20
+	LinkPreviewMaxRedirects = 5
+	LinkPreviewBatch        = 20
+
+	LinkPreviewTitleMaxLen       = 255
+	LinkPreviewDescriptionMaxLen = 1000
+	LinkPreviewErrorMaxLen       = 255
+)
+
+var LinkPreviewInterval = time.Minute
+
+var ErrPrivateAddress = errors.New("address is not public")
+
+var (
+	metaTagRe  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	htmlAttrRe = regexp.MustCompile(`(?is)([a-z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTagRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+	// nonPublicNets are not covered by the net.IP helpers
+	nonPublicNets = []*net.IPNet{
+		mustParseCIDR("0.0.0.0/8"),
+		mustParseCIDR("100.64.0.0/10"),
+		mustParseCIDR("192.0.0.0/24"),
+		mustParseCIDR("198.18.0.0/15"),
+		mustParseCIDR("240.0.0.0/4"),
+		mustParseCIDR("64:ff9b::/96"),
+	}
+)
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if nil != err {
+		panic(err)
+	}
+	return ipNet
+}
+
+// isPublicIP rejects loopback, private, link-local, multicast and the
+// other special purpose ranges a fetch could reach internal services by
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range nonPublicNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// LinkFetcher downloads link pages for the previews. The address is
+// checked when the connection is dialed, after DNS resolution, so
+// neither a redirect nor a hostname pointing inside the network gets
+// around it.
+type LinkFetcher struct {
+	Client   *http.Client
+	MaxBytes int64
+}
+
+func NewLinkFetcher() *LinkFetcher {
+	return newLinkFetcher(isPublicIP)
+}
+
+// newLinkFetcher lets the tests allow the loopback httptest servers
+func newLinkFetcher(allowIP func(ip net.IP) bool) *LinkFetcher {
+	dialer := &net.Dialer{
+		Timeout: LinkPreviewTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if nil != err {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    LinkPreviewTimeout,
+		ResponseHeaderTimeout:  LinkPreviewTimeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		DisableKeepAlives:      true,
+	}
+	return &LinkFetcher{
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   LinkPreviewTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= LinkPreviewMaxRedirects {
+					return fmt.Errorf("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to %s is not allowed", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		MaxBytes: LinkPreviewMaxBytes,
+	}
+}
+
+// Fetch reads at most MaxBytes of an html page, the meta tags are in
+// the head so a cut page still has them
+func (f *LinkFetcher) Fetch(pageURL string) (*LinkPreview, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "redditclone-preview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.Client.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if nil != err || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, fmt.Errorf("not an html page: %q", resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes))
+	if nil != err {
+		return nil, err
+	}
+	preview := ParseLinkPreview(string(body), resp.Request.URL)
+	preview.URL = pageURL
+	return preview, nil
+}
+
+// ParseLinkPreview takes the OpenGraph tags first, then the Twitter card
+// ones and then the plain title and description. Relative images are
+// resolved against the page url.
+func ParseLinkPreview(page string, base *url.URL) *LinkPreview {
+	meta := map[string]string{}
+	for _, tag := range metaTagRe.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, match := range htmlAttrRe.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, ok := meta[key]; key == "" || ok {
+			continue
+		}
+		meta[key] = cleanPreviewText(attrs["content"])
+	}
+
+	preview := &LinkPreview{
+		Title:       firstNonEmpty(meta["og:title"], meta["twitter:title"]),
+		Description: firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"]),
+		Image: firstNonEmpty(meta["og:image:secure_url"], meta["og:image"], meta["og:image:url"],
+			meta["twitter:image"], meta["twitter:image:src"]),
+	}
+	if preview.Title == "" {
+		if match := titleTagRe.FindStringSubmatch(page); match != nil {
+			preview.Title = cleanPreviewText(match[1])
+		}
+	}
+	preview.Title = truncateRunes(preview.Title, LinkPreviewTitleMaxLen)
+	preview.Description = truncateRunes(preview.Description, LinkPreviewDescriptionMaxLen)
+	preview.Image = resolvePreviewImage(base, preview.Image)
+	return preview
+}
+
+func cleanPreviewText(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncateRunes(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max])
+}
+
+// resolvePreviewImage drops the images that are not http(s) or too long
+// to store
+func resolvePreviewImage(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(image)
+	if nil != err {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	resolved := ref.String()
+	if len(resolved) > PostURLMaxLen {
+		return ""
+	}
+	return resolved
+}
+
+// LinkPreviewJob fetches the previews of the new link posts
+type LinkPreviewJob struct {
+	LinkPreviewRepo LinkPreviewRepoI
+	Fetcher         LinkFetcherI
+	TimeGetter      TimeGetterI
+	Batch           int
+	Interval        time.Duration
+}
+
+func NewLinkPreviewJob(db *sql.DB) *LinkPreviewJob {
+	return &LinkPreviewJob{
+		LinkPreviewRepo: NewLinkPreviewRepo(db),
+		Fetcher:         NewLinkFetcher(),
+		TimeGetter:      &TimeGetter{},
+		Batch:           LinkPreviewBatch,
+		Interval:        LinkPreviewInterval,
+	}
+}
+
+// Run stores a failed preview when the fetch fails, so a dead link is
+// not fetched again on every run
+func (job *LinkPreviewJob) Run() error {
+	pending, err := job.LinkPreviewRepo.GetPending(job.Batch)
+	if nil != err {
+		return fmt.Errorf("can't get pending previews: %s", err.Error())
+	}
+	for _, post := range pending {
+		preview, err := job.Fetcher.Fetch(post.URL)
+		if nil != err {
+			fmt.Println("can't fetch preview of", post.URL, err)
+			preview = &LinkPreview{
+				Status: LinkPreviewFailed,
+				Error:  truncateRunes(err.Error(), LinkPreviewErrorMaxLen),
+			}
+		} else {
+			preview.Status = LinkPreviewOK
+		}
+		preview.PostID = post.PostID
+		preview.URL = post.URL
+		preview.Fetched = job.TimeGetter.GetCreated()
+		err = job.LinkPreviewRepo.Save(preview)
+		if nil != err {
+			return fmt.Errorf("can't save preview: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// Start runs the job right away and then every Interval until done is closed
+func (job *LinkPreviewJob) Start(done <-chan struct{}) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		err := job.Run()
+		if nil != err {
+			fmt.Println("link preview job:", err)
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type LinkPreviewRepo struct {
+	DB *sql.DB
+}
+
+func NewLinkPreviewRepo(db *sql.DB) *LinkPreviewRepo {
+	return &LinkPreviewRepo{
+		DB: db,
+	}
+}
+
+// GetPending returns the newest link posts without a preview row, the
+// failed fetches have one too so they are not retried
+func (repo *LinkPreviewRepo) GetPending(limit int) ([]*LinkPreview, error) {
+	fmt.Println("Link preview repo: get pending")
+	rows, err := repo.DB.Query(`SELECT post.id, post.url FROM post 
+	LEFT JOIN link_preview ON link_preview.post_id = post.id
+	WHERE post.type = 'link' AND post.url <> '' AND link_preview.post_id IS NULL 
+	AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY post.created DESC
+	LIMIT ?`, limit)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	previews := []*LinkPreview{}
+	for rows.Next() {
+		preview := &LinkPreview{}
+		err = rows.Scan(&preview.PostID, &preview.URL)
+		if nil != err {
+			return nil, err
+		}
+		previews = append(previews, preview)
+	}
+	return previews, rows.Err()
+}
+
+func (repo *LinkPreviewRepo) Save(preview *LinkPreview) error {
+	fmt.Println("Link preview repo: save")
+	_, err := repo.DB.Exec(`INSERT INTO link_preview 
+	(post_id, url, title, description, image, status, error, fetched) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE url = VALUES(url), title = VALUES(title), description = VALUES(description),
+	image = VALUES(image), status = VALUES(status), error = VALUES(error), fetched = VALUES(fetched)`,
+		preview.PostID, preview.URL, preview.Title, preview.Description, preview.Image,
+		preview.Status, preview.Error, preview.Fetched)
+	return err
+}
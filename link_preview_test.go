@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+)
+
+const previewPage = `<!DOCTYPE html>
+<html><head>
+<title>Plain title</title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="Fashion &amp; style" />
+<meta content='Spring
+	collection' property='og:description'>
+<meta name="twitter:title" content="Twitter title">
+<meta property="og:image" content="/img/cover.png">
+</head><body></body></html>`
+
+func allowAllIPs(ip net.IP) bool {
+	return true
+}
+
+func TestParseLinkPreview(t *testing.T) {
+	base, _ := url.Parse("https://example.com/post/1")
+
+	//og tags win, relative image is resolved
+	preview := ParseLinkPreview(previewPage, base)
+	expect := &LinkPreview{
+		Title:       "Fashion & style",
+		Description: "Spring collection",
+		Image:       "https://example.com/img/cover.png",
+	}
+	if !reflect.DeepEqual(preview, expect) {
+		t.Errorf("results not match, want %v, have %v", expect, preview)
+		return
+	}
+
+	//twitter card and title fallbacks
+	preview = ParseLinkPreview(`<title> Only
+	title </title><meta name="twitter:description" content="card"><meta name="twitter:image" content="javascript:alert(1)">`, base)
+	expect = &LinkPreview{
+		Title:       "Only title",
+		Description: "card",
+	}
+	if !reflect.DeepEqual(preview, expect) {
+		t.Errorf("results not match, want %v, have %v", expect, preview)
+		return
+	}
+
+	//long title is cut
+	preview = ParseLinkPreview(`<meta property="og:title" content="`+strings.Repeat("я", 300)+`">`, base)
+	if len([]rune(preview.Title)) != LinkPreviewTitleMaxLen {
+		t.Errorf("expected title of %d runes, got %d", LinkPreviewTitleMaxLen, len([]rune(preview.Title)))
+		return
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+	for ip, expect := range cases {
+		if isPublicIP(net.ParseIP(ip)) != expect {
+			t.Errorf("%s: expected public %v", ip, expect)
+		}
+	}
+}
+
+func TestLinkFetcherFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(previewPage))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<meta property="og:title" content="big">` + strings.Repeat(" ", 4096) +
+			`<meta property="og:description" content="too far">`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := newLinkFetcher(allowAllIPs)
+
+	//success after a redirect, the image is resolved against the final page
+	preview, err := fetcher.Fetch(server.URL + "/redirect")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if preview.Title != "Fashion & style" || preview.Image != server.URL+"/img/cover.png" ||
+		preview.URL != server.URL+"/redirect" {
+		t.Errorf("wrong preview: %+v", preview)
+		return
+	}
+
+	//body over the limit is cut
+	fetcher.MaxBytes = 1024
+	preview, err = fetcher.Fetch(server.URL + "/big")
+	if err != nil || preview.Title != "big" || preview.Description != "" {
+		t.Errorf("expected the cut page, got %+v %v", preview, err)
+		return
+	}
+
+	for _, path := range []string{"/json", "/missing", "/loop"} {
+		_, err = fetcher.Fetch(server.URL + path)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", path)
+			return
+		}
+	}
+
+	//the default fetcher doesn't reach loopback
+	_, err = NewLinkFetcher().Fetch(server.URL + "/page")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected ErrPrivateAddress, got %v", err)
+		return
+	}
+}
+
+func TestLinkPreviewJobRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoMock := NewMockLinkPreviewRepoI(ctrl)
+	fetcherMock := NewMockLinkFetcherI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	job := &LinkPreviewJob{
+		LinkPreviewRepo: repoMock,
+		Fetcher:         fetcherMock,
+		TimeGetter:      timeGetterMock,
+		Batch:           10,
+	}
+	fetched := "2022-11-10T11:24:44+03:00"
+	timeGetterMock.EXPECT().GetCreated().Return(fetched).AnyTimes()
+
+	//success and a failed fetch are both saved
+	repoMock.EXPECT().GetPending(10).Return([]*LinkPreview{
+		{PostID: "1", URL: "https://example.com/"},
+		{PostID: "2", URL: "https://dead.example.com/"},
+	}, nil)
+	fetcherMock.EXPECT().Fetch("https://example.com/").Return(&LinkPreview{Title: "Example"}, nil)
+	fetcherMock.EXPECT().Fetch("https://dead.example.com/").Return(nil, fmt.Errorf("unexpected status 404"))
+	repoMock.EXPECT().Save(&LinkPreview{
+		PostID:  "1",
+		URL:     "https://example.com/",
+		Title:   "Example",
+		Status:  LinkPreviewOK,
+		Fetched: fetched,
+	}).Return(nil)
+	repoMock.EXPECT().Save(&LinkPreview{
+		PostID:  "2",
+		URL:     "https://dead.example.com/",
+		Status:  LinkPreviewFailed,
+		Error:   "unexpected status 404",
+		Fetched: fetched,
+	}).Return(nil)
+	if err := job.Run(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+
+	//repo error
+	repoMock.EXPECT().GetPending(10).Return(nil, fmt.Errorf("db error"))
+	if err := job.Run(); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+}
+
+func TestLinkPreviewRepo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewLinkPreviewRepo(db)
+
+	//get pending
+	mock.ExpectQuery(`LEFT JOIN link_preview ON link_preview.post_id = post.id
+	WHERE post.type = 'link' AND post.url <> '' AND link_preview.post_id IS NULL`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url"}).AddRow("1", "https://example.com/"))
+	pending, err := repo.GetPending(5)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(pending, []*LinkPreview{{PostID: "1", URL: "https://example.com/"}}) {
+		t.Errorf("wrong pending: %+v", pending)
+		return
+	}
+
+	//save
+	preview := &LinkPreview{PostID: "1", URL: "https://example.com/", Title: "Example",
+		Status: LinkPreviewOK, Fetched: "2022-11-10T11:24:44+03:00"}
+	mock.ExpectExec(`INSERT INTO link_preview`).
+		WithArgs("1", "https://example.com/", "Example", "", "", LinkPreviewOK, "", "2022-11-10T11:24:44+03:00").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err = repo.Save(preview); err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostsConvertToDTOPreview(t *testing.T) {
+	data := &PostComplexData{
+		Post:        Post{ID: "1", Type: PostTypeLink, URL: "https://example.com/"},
+		LinkPreview: LinkPreview{Title: "Example", Image: "https://example.com/a.png"},
+	}
+	expect := &LinkPreviewDTO{Title: "Example", Image: "https://example.com/a.png"}
+	if have := linkPreviewToDTO(&data.LinkPreview); !reflect.DeepEqual(have, expect) {
+		t.Errorf("results not match, want %v, have %v", expect, have)
+		return
+	}
+	if have := linkPreviewToDTO(&LinkPreview{}); have != nil {
+		t.Errorf("expected no preview, got %v", have)
+	}
+}
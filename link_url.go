@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	PostTypeText  = "text"
+	PostTypeLink  = "link"
+	PostTypeImage = "image"
+	PostURLMaxLen = 2048
+)
+
+// RepostWindow is how far back a link counts as already posted
+var RepostWindow = 30 * 24 * time.Hour
+
+var (
+	// trackingParams are dropped from the query, keys with these
+	// prefixes too
+	trackingParams = map[string]struct{}{
+		"fbclid":  {},
+		"gclid":   {},
+		"dclid":   {},
+		"msclkid": {},
+		"yclid":   {},
+		"igshid":  {},
+		"mc_cid":  {},
+		"mc_eid":  {},
+		"_ga":     {},
+		"ref_src": {},
+	}
+	trackingParamPrefixes = []string{"utm_"}
+	defaultPorts          = map[string]string{
+		"http":  "80",
+		"https": "443",
+	}
+)
+
+// CanonicalizeURL makes the links to the same page compare equal: the
+// scheme and the host are lowercased, www., default ports, fragments and
+// tracking params are dropped and the query is sorted. Scheme-relative
+// and scheme-less links become https.
+func CanonicalizeURL(raw string) (string, error) {
+	raw, err := absoluteURL(raw)
+	if nil != err {
+		return "", err
+	}
+
+	u, err := url.Parse(raw)
+	if nil != err {
+		return "", fmt.Errorf("bad url")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return "", fmt.Errorf("url must be http or https")
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("url must not contain credentials")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	host = strings.TrimPrefix(host, "www.")
+	if host == "" || strings.ContainsAny(host, " _") {
+		return "", fmt.Errorf("url has no valid host")
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literal
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
+
+// absoluteURL trims the link and makes scheme-relative and scheme-less
+// links https
+func absoluteURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	if len(raw) > PostURLMaxLen {
+		return "", fmt.Errorf("url is too long")
+	}
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	} else if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	return raw, nil
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := trackingParams[key]; ok {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostURLFromRequest returns the url of a link post as submitted and
+// its canonical form for the repost check, text and image posts have none
+func PostURLFromRequest(requestData *PostRequestDTO) (string, string, error) {
+	switch requestData.Type {
+	case PostTypeText, PostTypeImage:
+		if strings.TrimSpace(requestData.URL) != "" {
+			return "", "", fmt.Errorf("%s posts can't have a url", requestData.Type)
+		}
+		return "", "", nil
+	case PostTypeLink:
+		canonicalURL, err := CanonicalizeURL(requestData.URL)
+		if nil != err {
+			return "", "", err
+		}
+		postURL, err := absoluteURL(requestData.URL)
+		return postURL, canonicalURL, err
+	}
+	return "", "", fmt.Errorf("post type must be text, link or image")
+}
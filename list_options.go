@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	SortNew = "new"
+	SortTop = "top"
+	SortHot = "hot"
+
+	ListDefaultLimit = 25
+	ListMaxLimit     = 100
+)
+
+// hot ranks by log10 of the score plus the age in units of 12.5 hours,
+// so a post needs ten times the score to beat a post 12.5 hours newer
+const hotOrderBy = `LOG10(GREATEST(post.score, 1)) + 
+	UNIX_TIMESTAMP(STR_TO_DATE(LEFT(post.created, 19), '%Y-%m-%dT%H:%i:%s')) / 45000 DESC, post.id`
+
+var listOrderBy = map[string]string{
+	SortNew: "post.created DESC, post.id",
+	SortTop: "post.score DESC, post.created DESC, post.id",
+	SortHot: hotOrderBy,
+}
+
+type ListOptions struct {
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+func NewListOptions() *ListOptions {
+	return &ListOptions{
+		Sort:  SortNew,
+		Limit: ListDefaultLimit,
+	}
+}
+
+// OrderBy returns only the known ORDER BY clauses, never the raw sort param
+func (opts *ListOptions) OrderBy() string {
+	if orderBy, ok := listOrderBy[opts.Sort]; ok {
+		return orderBy
+	}
+	return listOrderBy[SortNew]
+}
+
+func ListOptionsFromRequest(r *http.Request) (*ListOptions, error) {
+	opts := NewListOptions()
+	query := r.URL.Query()
+	if sort := query.Get("sort"); sort != "" {
+		if _, ok := listOrderBy[sort]; !ok {
+			return nil, fmt.Errorf("unknown sort: %s", sort)
+		}
+		opts.Sort = sort
+	}
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if nil != err || value < 1 {
+			return nil, fmt.Errorf("bad limit: %s", limit)
+		}
+		if value > ListMaxLimit {
+			value = ListMaxLimit
+		}
+		opts.Limit = value
+	}
+	if offset := query.Get("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if nil != err || value < 0 {
+			return nil, fmt.Errorf("bad offset: %s", offset)
+		}
+		opts.Offset = value
+	}
+	return opts, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type LoginAttempts struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// MemoryLoginAttemptStore is the default store, it is enough for a
+// single instance of the app.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*LoginAttempts
+}
+
+// prune stale entries once the map grows beyond this size
+const memoryLoginAttemptsLimit = 10000
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: map[string]*LoginAttempts{},
+	}
+}
+
+func (store *MemoryLoginAttemptStore) Get(key string) (*LoginAttempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	attempts, ok := store.attempts[key]
+	if !ok {
+		return &LoginAttempts{Key: key}, nil
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+func (store *MemoryLoginAttemptStore) AddFailure(key string, now time.Time, window time.Duration) (*LoginAttempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.attempts) > memoryLoginAttemptsLimit {
+		for k, a := range store.attempts {
+			if now.Sub(a.LastFailure) > window && now.After(a.LockedUntil) {
+				delete(store.attempts, k)
+			}
+		}
+	}
+	attempts, ok := store.attempts[key]
+	if !ok || now.Sub(attempts.LastFailure) > window {
+		attempts = &LoginAttempts{Key: key}
+		store.attempts[key] = attempts
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	copied := *attempts
+	return &copied, nil
+}
+
+func (store *MemoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if attempts, ok := store.attempts[key]; ok {
+		attempts.LockedUntil = until
+	}
+	return nil
+}
+
+func (store *MemoryLoginAttemptStore) Reset(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.attempts, key)
+	return nil
+}
+
+// SQLLoginAttemptStore shares the counters between several instances.
+type SQLLoginAttemptStore struct {
+	DB *sql.DB
+}
+
+func NewSQLLoginAttemptStore(db *sql.DB) *SQLLoginAttemptStore {
+	return &SQLLoginAttemptStore{
+		DB: db,
+	}
+}
+
+func (store *SQLLoginAttemptStore) Get(key string) (*LoginAttempts, error) {
+	var lastFailure, lockedUntil int64
+	attempts := &LoginAttempts{Key: key}
+	err := store.DB.
+		QueryRow("SELECT failures, last_failure, locked_until FROM login_attempt WHERE `key` = ?", key).
+		Scan(&attempts.Failures, &lastFailure, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return attempts, nil
+	} else if nil != err {
+		return nil, err
+	}
+	attempts.LastFailure = time.Unix(lastFailure, 0)
+	attempts.LockedUntil = time.Unix(lockedUntil, 0)
+	return attempts, nil
+}
+
+func (store *SQLLoginAttemptStore) AddFailure(key string, now time.Time, window time.Duration) (*LoginAttempts, error) {
+	fmt.Println("Login attempt store: add failure")
+	_, err := store.DB.Exec(
+		"INSERT INTO login_attempt (`key`, failures, last_failure, locked_until) VALUES (?, 1, ?, 0) "+
+			"ON DUPLICATE KEY UPDATE failures = IF(last_failure < ?, 1, failures + 1), last_failure = VALUES(last_failure)",
+		key, now.Unix(), now.Add(-window).Unix())
+	if nil != err {
+		return nil, err
+	}
+	return store.Get(key)
+}
+
+func (store *SQLLoginAttemptStore) Lock(key string, until time.Time) error {
+	_, err := store.DB.Exec("UPDATE login_attempt SET locked_until = ? WHERE `key` = ?", until.Unix(), key)
+	return err
+}
+
+func (store *SQLLoginAttemptStore) Reset(key string) error {
+	_, err := store.DB.Exec("DELETE FROM login_attempt WHERE `key` = ?", key)
+	return err
+}
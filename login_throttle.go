@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+type LoginAttemptStoreI interface {
+	Get(key string) (*LoginAttempts, error)
+	AddFailure(key string, now time.Time, window time.Duration) (*LoginAttempts, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+// ThrottlePolicy allows FreeAttempts failures, after that every next
+// failure locks the key for BaseDelay, doubled each time up to MaxDelay.
+// Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < over && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+type LoginThrottle struct {
+	Store         LoginAttemptStoreI
+	AuditLog      AuditLogRepoI
+	AccountPolicy ThrottlePolicy
+	IPPolicy      ThrottlePolicy
+}
+
+var (
+	DefaultAccountPolicy = ThrottlePolicy{
+		FreeAttempts: 5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     30 * time.Minute,
+		Window:       time.Hour,
+	}
+	DefaultIPPolicy = ThrottlePolicy{
+		FreeAttempts: 20,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+)
+
+// NewLoginThrottle keeps counters in memory unless LOGIN_ATTEMPT_STORE=sql
+// is set, which is required when several instances serve logins.
+func NewLoginThrottle(db *sql.DB) *LoginThrottle {
+	var store LoginAttemptStoreI = NewMemoryLoginAttemptStore()
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "sql" {
+		store = NewSQLLoginAttemptStore(db)
+	}
+	return &LoginThrottle{
+		Store:         store,
+		AuditLog:      NewAuditLogRepo(db),
+		AccountPolicy: DefaultAccountPolicy,
+		IPPolicy:      DefaultIPPolicy,
+	}
+}
+
+func accountKey(login string) string {
+	return "account:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the caller has to wait before the next attempt.
+func (t *LoginThrottle) Check(login string, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{accountKey(login), ipKey(ip)} {
+		attempts, err := t.Store.Get(key)
+		if nil != err {
+			return 0, err
+		}
+		if left := attempts.LockedUntil.Sub(now); left > wait {
+			wait = left
+		}
+	}
+	return wait, nil
+}
+
+func (t *LoginThrottle) Failed(login string, ip string, now time.Time) error {
+	err := t.fail(accountKey(login), t.AccountPolicy, login, ip, now)
+	if nil != err {
+		return err
+	}
+	return t.fail(ipKey(ip), t.IPPolicy, login, ip, now)
+}
+
+func (t *LoginThrottle) Succeeded(login string) error {
+	return t.Store.Reset(accountKey(login))
+}
+
+func (t *LoginThrottle) fail(key string, policy ThrottlePolicy, login string, ip string, now time.Time) error {
+	attempts, err := t.Store.AddFailure(key, now, policy.Window)
+	if nil != err {
+		return err
+	}
+	delay := policy.delay(attempts.Failures)
+	if delay == 0 {
+		return nil
+	}
+	err = t.Store.Lock(key, now.Add(delay))
+	if nil != err {
+		return err
+	}
+	// every lock is audited, the escalated and the extended ones too
+	err = t.AuditLog.Add(&AuditLogEntry{
+		Event:   AuditLoginLockout,
+		Login:   login,
+		IP:      ip,
+		Details: fmt.Sprintf("%s locked for %s after %d failed attempts", key, delay, attempts.Failures),
+		Created: now.Format(time.RFC3339),
+	})
+	if nil != err {
+		fmt.Println("can't write audit log: ", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	auditLogMock := NewMockAuditLogRepoI(ctrl)
+	throttle := &LoginThrottle{
+		Store:    NewMemoryLoginAttemptStore(),
+		AuditLog: auditLogMock,
+		AccountPolicy: ThrottlePolicy{
+			FreeAttempts: 2,
+			BaseDelay:    time.Second,
+			MaxDelay:     4 * time.Second,
+			Window:       time.Hour,
+		},
+		IPPolicy: DefaultIPPolicy,
+	}
+	now := time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)
+	login, ip := "mer", "192.0.2.1"
+
+	// free attempts
+	for i := 0; i < 2; i++ {
+		if err := throttle.Failed(login, ip, now); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if wait, _ := throttle.Check(login, ip, now); wait != 0 {
+		t.Errorf("expected no wait after free attempts; got: %s", wait)
+		return
+	}
+
+	// lockout doubles every failure and every lock is written to the audit log
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, delay := range expected {
+		details := fmt.Sprintf("account:mer locked for %s after %d failed attempts", delay, i+3)
+		auditLogMock.EXPECT().Add(gomock.Any()).DoAndReturn(func(entry *AuditLogEntry) error {
+			if entry.Event != AuditLoginLockout || entry.Login != login || entry.IP != ip || entry.Details != details {
+				t.Errorf("unexpected audit log entry: %#v", entry)
+			}
+			return nil
+		})
+		throttle.Failed(login, ip, now)
+		if wait, _ := throttle.Check(login, ip, now); wait != delay {
+			t.Errorf("wait is not matched; want: %s; have: %s", delay, wait)
+			return
+		}
+	}
+
+	// another ip is locked by the account key too
+	if wait, _ := throttle.Check(login, "192.0.2.2", now); wait == 0 {
+		t.Errorf("expected account lockout for another ip")
+		return
+	}
+
+	// success resets the account
+	throttle.Succeeded(login)
+	if wait, _ := throttle.Check(login, "192.0.2.2", now); wait != 0 {
+		t.Errorf("expected no wait after success; got: %s", wait)
+		return
+	}
+
+	// old failures are forgotten
+	throttle.Failed(login, ip, now)
+	throttle.Failed(login, ip, now)
+	throttle.Failed(login, ip, now.Add(2*time.Hour))
+	if wait, _ := throttle.Check(login, "192.0.2.2", now.Add(2*time.Hour)); wait != 0 {
+		t.Errorf("expected no wait after window; got: %s", wait)
+		return
+	}
+}
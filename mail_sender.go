@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// SMTPMailSender delivers mail through a plain SMTP relay.
+type SMTPMailSender struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func (s *SMTPMailSender) Send(mail *Mail) error {
+	msg := strings.Join([]string{
+		"From: " + s.From,
+		"To: " + mail.To,
+		"Subject: " + mail.Subject,
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		mail.Body,
+	}, "\r\n")
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{mail.To}, []byte(msg))
+}
+
+// LocalMailSender keeps mail in memory instead of sending it;
+// used for local runs and tests.
+type LocalMailSender struct {
+	mu   sync.Mutex
+	Sent []*Mail
+}
+
+func (s *LocalMailSender) Send(mail *Mail) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Printf("local mail to %s: %s\n", mail.To, mail.Subject)
+	s.Sent = append(s.Sent, mail)
+	return nil
+}
+
+func (s *LocalMailSender) Last() *Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Sent) == 0 {
+		return nil
+	}
+	return s.Sent[len(s.Sent)-1]
+}
+
+// NewMailSender picks the SMTP sender when SMTP_HOST is set and
+// falls back to the local one otherwise.
+func NewMailSender() MailSenderI {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		fmt.Println("SMTP_HOST is not set, use local mail sender")
+		return &LocalMailSender{}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "25"
+	}
+	sender := &SMTPMailSender{
+		Addr: host + ":" + port,
+		From: os.Getenv("SMTP_FROM"),
+	}
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		sender.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return sender
+}
@@ -72,20 +72,91 @@ func main() {
 		return
 	}
 
+	err = RunMigrations(db, Migrations)
+	if nil != err {
+		fmt.Println("can't run migrations: ", err.Error())
+		return
+	}
+
+	_, err = PasswordHashConfigFromEnv()
+	if nil != err {
+		fmt.Println("bad password hash config: ", err.Error())
+		return
+	}
+	_, err = CheckStoredPasswordHashes(NewUserRepo(db), NewUserUtils())
+	if nil != err {
+		fmt.Println("can't check stored password hashes: ", err.Error())
+	}
+
+	err = GrantAdminFromEnv(NewUserRepo(db), NewRoleRepo(db))
+	if nil != err {
+		fmt.Println("can't grant admin: ", err.Error())
+	}
+
 	sm := NewSessionDBManagerJWT(db)
 
 	postsHandler := NewPostsHandler(db)
 	userHandler := NewUserHandler(db, sm)
+	categoriesHandler := NewCategoriesHandler(db)
+	bansHandler := NewBansHandler(db)
+
+	go NewPurgeJob(db).Start(nil)
+	go NewLinkPreviewJob(db).Start(nil)
 
 	router := mux.NewRouter()
 
 	router.HandleFunc("/api/register", userHandler.Register).Methods("POST")
 	router.HandleFunc("/api/login", userHandler.Login).Methods("POST")
+	router.HandleFunc("/api/login/2fa", userHandler.LoginTwoFactor).Methods("POST")
+	router.HandleFunc("/api/verify", userHandler.ResendVerification).Methods("POST")
+	router.HandleFunc("/api/2fa/enroll", userHandler.EnrollTwoFactor).Methods("POST")
+	router.HandleFunc("/api/2fa/confirm", userHandler.ConfirmTwoFactor).Methods("POST")
+	router.HandleFunc("/api/2fa/disable", userHandler.DisableTwoFactor).Methods("POST")
+	router.HandleFunc("/api/verify/{TOKEN}", userHandler.VerifyEmail).Methods("GET")
 	router.HandleFunc("/api/user/{USER_LOGIN}", userHandler.GetPosts).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/profile", userHandler.Profile).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/posts", userHandler.ProfilePosts).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/comments", userHandler.ProfileComments).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/upvoted", userHandler.ProfileUpvoted).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/downvoted", userHandler.ProfileDownvoted).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/followers", userHandler.Followers).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/following", userHandler.Following).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/follow", userHandler.Follow).Methods("POST")
+	router.HandleFunc("/api/user/{USER_LOGIN}/unfollow", userHandler.Unfollow).Methods("POST")
+	router.HandleFunc("/api/user/me/profile", userHandler.SaveProfile).Methods("POST")
+	router.HandleFunc("/api/notifications", userHandler.Notifications).Methods("GET")
+	router.HandleFunc("/api/notifications/read", userHandler.ReadNotifications).Methods("POST")
+	router.HandleFunc("/api/notifications/preferences", userHandler.NotificationPreferences).Methods("GET")
+	router.HandleFunc("/api/notifications/preferences", userHandler.SaveNotificationPreferences).Methods("POST")
+	router.HandleFunc("/api/messages", userHandler.Conversations).Methods("GET")
+	router.HandleFunc("/api/messages", userHandler.SendMessage).Methods("POST")
+	router.HandleFunc("/api/messages/{USER_LOGIN}", userHandler.Thread).Methods("GET")
+	router.HandleFunc("/api/user/{USER_LOGIN}/block", userHandler.BlockUser).Methods("POST")
+	router.HandleFunc("/api/user/{USER_LOGIN}/unblock", userHandler.UnblockUser).Methods("POST")
+	router.HandleFunc("/api/user/me/blocked", userHandler.Blocked).Methods("GET")
+	router.HandleFunc("/api/roles", userHandler.GrantRole).Methods("POST")
+	router.HandleFunc("/api/roles/revoke", userHandler.RevokeRole).Methods("POST")
+	router.HandleFunc("/api/bans", bansHandler.Ban).Methods("POST")
+	router.HandleFunc("/api/bans/revoke", bansHandler.Unban).Methods("POST")
+
+	router.HandleFunc("/api/categories", categoriesHandler.List).Methods("GET")
+	router.HandleFunc("/api/categories", categoriesHandler.Add).Methods("POST")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/subscribe", categoriesHandler.Subscribe).Methods("POST")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/unsubscribe", categoriesHandler.Unsubscribe).Methods("POST")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/settings", categoriesHandler.SaveSettings).Methods("POST")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/modlog", categoriesHandler.ModLog).Methods("GET")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/reports", categoriesHandler.ReportQueue).Methods("GET")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/automod", categoriesHandler.GetAutomod).Methods("GET")
+	router.HandleFunc("/api/categories/{CATEGORY_NAME}/automod", categoriesHandler.SaveAutomod).Methods("POST")
 
 	router.HandleFunc("/api/posts/", postsHandler.List).Methods("GET")
+	router.HandleFunc("/api/feed", postsHandler.Feed).Methods("GET")
+	router.HandleFunc("/api/feed/following", postsHandler.FollowedFeed).Methods("GET")
+	router.HandleFunc("/api/search", postsHandler.Search).Methods("GET")
+	router.HandleFunc("/api/posts/stream", postsHandler.StreamPosts).Methods("GET")
 	router.HandleFunc("/api/posts/{CATEGORY_NAME}", postsHandler.GetByCategoryName).Methods("GET")
 	router.HandleFunc("/api/post/{POST_ID}", postsHandler.GetById).Methods("GET")
+	router.HandleFunc("/api/post/{POST_ID}/stream", postsHandler.StreamPost).Methods("GET")
 	router.HandleFunc("/api/post/{POST_ID}/upvote", postsHandler.UpVote).Methods("GET")
 	router.HandleFunc("/api/post/{POST_ID}/downvote", postsHandler.DownVote).Methods("GET")
 	router.HandleFunc("/api/post/{POST_ID}/unvote", postsHandler.UnVote).Methods("GET")
@@ -94,6 +165,31 @@ func main() {
 
 	router.HandleFunc("/api/post/{POST_ID}", postsHandler.AddComment).Methods("POST")
 	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}", postsHandler.DeleteComment).Methods("DELETE")
+	router.HandleFunc("/api/post/{POST_ID}/restore", postsHandler.RestorePost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/restore", postsHandler.RestoreComment).Methods("POST")
+
+	router.HandleFunc("/api/post/{POST_ID}/remove", postsHandler.RemovePost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/approve", postsHandler.ApprovePost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/lock", postsHandler.LockPost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/unlock", postsHandler.UnlockPost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/pin", postsHandler.PinPost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/unpin", postsHandler.UnpinPost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/remove", postsHandler.RemoveComment).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/approve", postsHandler.ApproveComment).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/dismiss", postsHandler.DismissPostReports).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/dismiss", postsHandler.DismissCommentReports).Methods("POST")
+
+	router.HandleFunc("/api/post/{POST_ID}/report", postsHandler.ReportPost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/report", postsHandler.ReportComment).Methods("POST")
+
+	router.HandleFunc("/api/user/me/saved", postsHandler.Saved).Methods("GET")
+	router.HandleFunc("/api/post/{POST_ID}/save", postsHandler.SavePost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/unsave", postsHandler.UnsavePost).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/upvote", postsHandler.UpVoteComment).Methods("GET")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/downvote", postsHandler.DownVoteComment).Methods("GET")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/unvote", postsHandler.UnVoteComment).Methods("GET")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/save", postsHandler.SaveComment).Methods("POST")
+	router.HandleFunc("/api/post/{POST_ID}/{COMMENT_ID}/unsave", postsHandler.UnsaveComment).Methods("POST")
 
 	router.Handle("/", Index(templates))
 
@@ -104,8 +200,9 @@ func main() {
 		http.FileServer(http.Dir("./static")),
 	)
 	router.PathPrefix("/static/").Handler(staticHandler)
+	router.PathPrefix(UploadsPath).HandlerFunc(postsHandler.ServeUpload).Methods("GET", "HEAD")
 
-	amw := NewAuthMiddleware(sm)
+	amw := NewAuthMiddleware(sm, NewRoleRepo(db))
 	router.Use(amw.AuthMiddlewareSessionJWT)
 
 	logger, err := zap.NewProduction()
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"crypto/sha256"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// markdownMaxDepth limits the nested quotes and emphasis, the deeper
+	// ones stay text
+	markdownMaxDepth = 8
+	// the cache is dropped as a whole once it grows beyond this size
+	markdownCacheLimit = 10000
+)
+
+var (
+	markdownFenceRe       = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	markdownQuoteRe       = regexp.MustCompile(`^ {0,3}> ?`)
+	markdownBulletItemRe  = regexp.MustCompile(`^ {0,3}[-*+](?: +|$)`)
+	markdownOrderedItemRe = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)](?: +|$)`)
+
+	// the references take the logins of the mentions and the names of the
+	// categories, a longer name is no reference at all
+	markdownUserRefRe     = regexp.MustCompile(`^u/([A-Za-z0-9_-]{1,32})`)
+	markdownCategoryRefRe = regexp.MustCompile(`^r/([A-Za-z][A-Za-z0-9_]{2,20})`)
+)
+
+// MarkdownCache keeps the renders by the hash of the source, so every
+// revision of a text is rendered once and an edited text never gets the
+// render of the previous one
+type MarkdownCache struct {
+	mu      sync.Mutex
+	renders map[[sha256.Size]byte]string
+}
+
+func NewMarkdownCache() *MarkdownCache {
+	return &MarkdownCache{
+		renders: map[[sha256.Size]byte]string{},
+	}
+}
+
+func (cache *MarkdownCache) Render(source string) string {
+	if source == "" {
+		return ""
+	}
+	key := sha256.Sum256([]byte(source))
+	cache.mu.Lock()
+	rendered, ok := cache.renders[key]
+	cache.mu.Unlock()
+	if ok {
+		return rendered
+	}
+
+	rendered = RenderMarkdown(source)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.renders) >= markdownCacheLimit {
+		cache.renders = map[[sha256.Size]byte]string{}
+	}
+	cache.renders[key] = rendered
+	return rendered
+}
+
+// RenderMarkdown renders the subset of CommonMark the posts and the
+// comments take: paragraphs, quotes, lists, fenced code, code spans,
+// emphasis, links and the u/login and r/category references.
+//
+// Raw HTML is escaped like any other text, so the output has only the tags
+// written here, and the only attribute values are the link targets
+// safeLinkURL lets through.
+func RenderMarkdown(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	out := &strings.Builder{}
+	renderMarkdownBlocks(out, strings.Split(source, "\n"), 0)
+	return out.String()
+}
+
+func renderMarkdownBlocks(out *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case markdownFenceRe.MatchString(line):
+			i = renderMarkdownFence(out, lines, i)
+		case depth < markdownMaxDepth && markdownQuoteRe.MatchString(line):
+			quoted := []string{}
+			for ; i < len(lines) && markdownQuoteRe.MatchString(lines[i]); i++ {
+				quoted = append(quoted, markdownQuoteRe.ReplaceAllString(lines[i], ""))
+			}
+			out.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(out, quoted, depth+1)
+			out.WriteString("</blockquote>\n")
+		case markdownBulletItemRe.MatchString(line) || markdownOrderedItemRe.MatchString(line):
+			i = renderMarkdownList(out, lines, i, depth)
+		default:
+			paragraph := []string{strings.TrimSpace(line)}
+			for i++; i < len(lines) && !isMarkdownBlockStart(lines[i], depth); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			out.WriteString("<p>")
+			renderMarkdownSpans(out, strings.Join(paragraph, "\n"), true)
+			out.WriteString("</p>\n")
+		}
+	}
+}
+
+func isMarkdownBlockStart(line string, depth int) bool {
+	return strings.TrimSpace(line) == "" ||
+		markdownFenceRe.MatchString(line) ||
+		(depth < markdownMaxDepth && markdownQuoteRe.MatchString(line)) ||
+		markdownBulletItemRe.MatchString(line) ||
+		markdownOrderedItemRe.MatchString(line)
+}
+
+// renderMarkdownFence returns the line after the closing fence, a fence
+// which isn't closed runs to the end of the text
+func renderMarkdownFence(out *strings.Builder, lines []string, start int) int {
+	fence := markdownFenceRe.FindStringSubmatch(lines[start])[1]
+	out.WriteString("<pre><code>")
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		out.WriteString(html.EscapeString(lines[i]))
+		out.WriteString("\n")
+	}
+	out.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderMarkdownList takes the items of one kind of list whatever their
+// markers, the lines which don't start a block continue the item before them
+func renderMarkdownList(out *strings.Builder, lines []string, start int, depth int) int {
+	itemRe := markdownBulletItemRe
+	closeTag := "</ul>\n"
+	if match := markdownOrderedItemRe.FindStringSubmatch(lines[start]); match != nil {
+		itemRe = markdownOrderedItemRe
+		closeTag = "</ol>\n"
+		number, _ := strconv.Atoi(match[1])
+		if number == 1 {
+			out.WriteString("<ol>\n")
+		} else {
+			out.WriteString(`<ol start="` + strconv.Itoa(number) + `">` + "\n")
+		}
+	} else {
+		out.WriteString("<ul>\n")
+	}
+
+	items := [][]string{}
+	i := start
+	for ; i < len(lines); i++ {
+		if loc := itemRe.FindStringIndex(lines[i]); loc != nil {
+			items = append(items, []string{strings.TrimSpace(lines[i][loc[1]:])})
+			continue
+		}
+		if isMarkdownBlockStart(lines[i], depth) {
+			break
+		}
+		items[len(items)-1] = append(items[len(items)-1], strings.TrimSpace(lines[i]))
+	}
+	for _, item := range items {
+		out.WriteString("<li>")
+		renderMarkdownSpans(out, strings.Join(item, "\n"), true)
+		out.WriteString("</li>\n")
+	}
+	out.WriteString(closeTag)
+	return i
+}
+
+// renderMarkdownSpans escapes the text and renders the spans in it. The
+// links don't nest, their labels have the other spans only.
+func renderMarkdownSpans(out *strings.Builder, text string, links bool) {
+	spans := &markdownSpans{
+		text:    text,
+		closers: map[string]int{},
+	}
+	spans.render(out, 0, len(text), links, 0)
+}
+
+// markdownSpans finds the closers of the spans in one text. The spans are
+// parsed left to right, nested ones included, so every kind of closer is
+// looked up from a later position than the last time, and the text is
+// scanned once per kind whatever the number of the unclosed openers.
+type markdownSpans struct {
+	text string
+	// closers keeps the first closer of each kind found so far, len(text)
+	// when there is none
+	closers map[string]int
+	// codeRuns maps every run of backticks to the next run of the same
+	// length, -1 when there is none
+	codeRuns map[int]int
+}
+
+// render renders text[from:to], depth is the number of the emphasis around
+// it and the deeper emphasis stays text
+func (spans *markdownSpans) render(out *strings.Builder, from int, to int, links bool, depth int) {
+	text := spans.text
+	for i := from; i < to; {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < to && isMarkdownPunct(text[i+1]):
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			end, code, ok := spans.codeSpan(i, to)
+			if ok {
+				out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			} else {
+				out.WriteString(text[i:end])
+			}
+			i = end
+			continue
+		case (c == '*' || c == '_') && depth < markdownMaxDepth:
+			if end, tag, open, closing, ok := spans.emphasis(i, from, to); ok {
+				out.WriteString("<" + tag + ">")
+				spans.render(out, open, closing, links, depth+1)
+				out.WriteString("</" + tag + ">")
+				i = end
+				continue
+			}
+		case c == '[' && links:
+			if end, labelEnd, target, ok := spans.link(i, to); ok {
+				href, isSafe := safeLinkURL(target)
+				if isSafe {
+					out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">`)
+				}
+				spans.render(out, i+1, labelEnd, false, depth)
+				if isSafe {
+					out.WriteString("</a>")
+				}
+				i = end
+				continue
+			}
+		case (c == 'u' || c == 'r') && links:
+			if ref, href, ok := markdownRef(text[from:to], i-from); ok {
+				out.WriteString(`<a href="` + href + `">` + ref + "</a>")
+				i += len(ref)
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:to])
+		out.WriteString(html.EscapeString(text[i : i+size]))
+		i += size
+	}
+}
+
+// closer returns the first position from which isCloser takes, len(text)
+// when there is none
+func (spans *markdownSpans) closer(kind string, from int, isCloser func(i int) bool) int {
+	if found, ok := spans.closers[kind]; ok && found >= from {
+		return found
+	}
+	found := from
+	for found < len(spans.text) && !isCloser(found) {
+		found++
+	}
+	spans.closers[kind] = found
+	return found
+}
+
+// codeSpan closes the run of backticks at start with the next run of the
+// same length, an unclosed run stays text
+func (spans *markdownSpans) codeSpan(start int, to int) (int, string, bool) {
+	text := spans.text
+	if spans.codeRuns == nil {
+		spans.codeRuns = markdownCodeRuns(text)
+	}
+	closer, ok := spans.codeRuns[start]
+	if !ok {
+		// the rest of a run after an escaped backtick
+		return start + 1, "", false
+	}
+	open := start
+	for open < len(text) && text[open] == '`' {
+		open++
+	}
+	fence := open - start
+	if closer < 0 || closer+fence > to {
+		return open, "", false
+	}
+	code := text[open:closer]
+	if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	return closer + fence, code, true
+}
+
+// markdownCodeRuns maps the start of every run of backticks to the start of
+// the next run of the same length
+func markdownCodeRuns(text string) map[int]int {
+	runs := map[int]int{}
+	next := map[int]int{}
+	for end := len(text); end > 0; {
+		if text[end-1] != '`' {
+			end--
+			continue
+		}
+		start := end - 1
+		for start > 0 && text[start-1] == '`' {
+			start--
+		}
+		runs[start] = -1
+		if closer, ok := next[end-start]; ok {
+			runs[start] = closer
+		}
+		next[end-start] = start
+		end = start
+	}
+	return runs
+}
+
+// emphasis finds the closing ** or __ for strong and * or _ for em. The
+// opening delimiter comes before a non space and the closing one after a
+// non space, and _ doesn't work inside a word. It returns the end of the
+// span and the bounds of its text.
+func (spans *markdownSpans) emphasis(start int, from int, to int) (int, string, int, int, bool) {
+	text := spans.text
+	c := text[start]
+	delim, tag := text[start:start+1], "em"
+	if start+1 < to && text[start+1] == c {
+		delim, tag = text[start:start+2], "strong"
+	}
+	open := start + len(delim)
+	if open >= to || isMarkdownSpace(text[open]) {
+		return 0, "", 0, 0, false
+	}
+	if c == '_' && start > from && isMarkdownAlnum(text[start-1]) {
+		return 0, "", 0, 0, false
+	}
+	closing := spans.closer(delim, open+1, func(i int) bool {
+		return isMarkdownCloser(text, i, delim)
+	})
+	if closing+len(delim) > to {
+		return 0, "", 0, 0, false
+	}
+	return closing + len(delim), tag, open, closing, true
+}
+
+// isMarkdownCloser tells if the delimiter at i closes an emphasis. The end
+// of an odd run closes an em, the rest of the run is a strong delimiter
+// inside it.
+func isMarkdownCloser(text string, i int, delim string) bool {
+	if !strings.HasPrefix(text[i:], delim) || i == 0 || isMarkdownSpace(text[i-1]) {
+		return false
+	}
+	c := delim[0]
+	end := i + len(delim)
+	if len(delim) == 1 {
+		if end < len(text) && text[end] == c {
+			return false
+		}
+		run := 1
+		for j := i - 1; j >= 0 && text[j] == c; j-- {
+			run++
+		}
+		if run%2 == 0 {
+			return false
+		}
+	}
+	return c != '_' || end >= len(text) || !isMarkdownAlnum(text[end])
+}
+
+// link parses [label](target), the target has no spaces. It returns the
+// end of the link and the end of its label.
+func (spans *markdownSpans) link(start int, to int) (int, int, string, bool) {
+	text := spans.text
+	labelEnd := spans.closer("]", start+1, func(i int) bool {
+		return text[i] == ']'
+	})
+	if labelEnd+1 >= to || text[labelEnd+1] != '(' {
+		return 0, 0, "", false
+	}
+	targetEnd := spans.closer(")", labelEnd+2, func(i int) bool {
+		return text[i] == ')'
+	})
+	if targetEnd >= to {
+		return 0, 0, "", false
+	}
+	target := strings.TrimSpace(text[labelEnd+2 : targetEnd])
+	if target == "" || strings.ContainsAny(target, " \t\n") {
+		return 0, 0, "", false
+	}
+	return targetEnd + 1, labelEnd, target, true
+}
+
+// markdownRef matches u/login and r/category which don't continue a word
+// or a path, like the mentions do
+func markdownRef(text string, start int) (string, string, bool) {
+	if start > 0 && (isMarkdownWord(text[start-1]) || strings.IndexByte("/@.-", text[start-1]) >= 0) {
+		return "", "", false
+	}
+	refRe, path := markdownUserRefRe, "/u/"
+	if text[start] == 'r' {
+		refRe, path = markdownCategoryRefRe, "/a/"
+	}
+	match := refRe.FindStringSubmatch(text[start:])
+	if match == nil {
+		return "", "", false
+	}
+	end := start + len(match[0])
+	if end < len(text) && (isMarkdownWord(text[end]) || text[end] == '-') {
+		return "", "", false
+	}
+	return match[0], path + match[1], true
+}
+
+// safeLinkURL lets through the http, https and mailto links and the paths
+// of this site, the rest, javascript: included, stays text
+func safeLinkURL(target string) (string, bool) {
+	if strings.ContainsAny(target, `\`) {
+		return "", false
+	}
+	parsed, err := url.Parse(target)
+	if nil != err {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return target, parsed.Host != ""
+	case "mailto":
+		return target, parsed.Opaque != ""
+	case "":
+		isPath := strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+		return target, isPath
+	}
+	return "", false
+}
+
+func isMarkdownPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isMarkdownSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isMarkdownWord(c byte) bool {
+	return c == '_' || isMarkdownAlnum(c)
+}
+
+func isMarkdownAlnum(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
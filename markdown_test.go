@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{"hello\nworld", "<p>hello\nworld</p>\n"},
+		{"one\n\ntwo", "<p>one</p>\n<p>two</p>\n"},
+		{"**bold** and *em* and __b__ _e_", "<p><strong>bold</strong> and <em>em</em> and <strong>b</strong> <em>e</em></p>\n"},
+		{"*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"*a **b***", "<p><em>a <strong>b</strong></em></p>\n"},
+		{"snake_case_name and 2 * 3 * 4", "<p>snake_case_name and 2 * 3 * 4</p>\n"},
+		{"use `a <b> *c*` and ``x ` y``", "<p>use <code>a &lt;b&gt; *c*</code> and <code>x ` y</code></p>\n"},
+		{"\\*not em\\* and `open", "<p>*not em* and `open</p>\n"},
+		{"```go\n<script>\n\n*x*\n```\nafter", "<pre><code>&lt;script&gt;\n\n*x*\n</code></pre>\n<p>after</p>\n"},
+		{"> quoted\n> > nested\n\ntext", "<blockquote>\n<p>quoted</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n<p>text</p>\n"},
+		{"- one\n- two\n  more\n* three", "<ul>\n<li>one</li>\n<li>two\nmore</li>\n<li>three</li>\n</ul>\n"},
+		{"3. three\n4) four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n"},
+		{"1. *one*\n- two", "<ol>\n<li><em>one</em></li>\n</ol>\n<ul>\n<li>two</li>\n</ul>\n"},
+		{"[site](https://example.com/a?b=1&c=2)", "<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow noopener\">site</a></p>\n"},
+		{"[home](/a/golang) [mail](mailto:me@example.com)", "<p><a href=\"/a/golang\" rel=\"nofollow noopener\">home</a> <a href=\"mailto:me@example.com\" rel=\"nofollow noopener\">mail</a></p>\n"},
+		{"see u/rvasily in r/golang", "<p>see <a href=\"/u/rvasily\">u/rvasily</a> in <a href=\"/a/golang\">r/golang</a></p>\n"},
+		{"menu/rvasily a@u/b r/go r/a_very_long_category_name", "<p>menu/rvasily a@u/b r/go r/a_very_long_category_name</p>\n"},
+		{"[u/rvasily](/u/rvasily)", "<p><a href=\"/u/rvasily\" rel=\"nofollow noopener\">u/rvasily</a></p>\n"},
+		{"", ""},
+	}
+	for _, item := range cases {
+		if rendered := RenderMarkdown(item.source); rendered != item.expected {
+			t.Errorf("%q: want %q, have %q", item.source, item.expected, rendered)
+		}
+	}
+}
+
+func TestRenderMarkdownUnsafe(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{`<script>alert(1)</script>`, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{`<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n"},
+		{"[x](javascript:alert(1))", "<p>x)</p>\n"},
+		{"[x](JavaScript:alert`1`)", "<p>x</p>\n"},
+		{"[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"[x](//evil.com) [y](/\\evil.com) [z](https:evil)", "<p>x y z</p>\n"},
+		{`[x](https://a.com/"onmouseover="alert(1))`, "<p><a href=\"https://a.com/&#34;onmouseover=&#34;alert(1\" rel=\"nofollow noopener\">x</a>)</p>\n"},
+		{"[*<b>*](https://a.com)", "<p><a href=\"https://a.com\" rel=\"nofollow noopener\"><em>&lt;b&gt;</em></a></p>\n"},
+	}
+	for _, item := range cases {
+		if rendered := RenderMarkdown(item.source); rendered != item.expected {
+			t.Errorf("%q: want %q, have %q", item.source, item.expected, rendered)
+		}
+	}
+}
+
+func TestRenderMarkdownLinear(t *testing.T) {
+	// the unclosed openers used to scan the rest of the text each
+	sources := []string{
+		strings.Repeat("*a ", 50000),
+		strings.Repeat("_a ", 50000),
+		strings.Repeat("**a ", 40000),
+		strings.Repeat("[a ", 50000),
+		strings.Repeat("[a](", 40000),
+		strings.Repeat("`a ``b ", 25000),
+		strings.Repeat("*a **b ", 25000) + strings.Repeat("c* ", 25000),
+	}
+	for _, source := range sources {
+		started := time.Now()
+		RenderMarkdown(source)
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("%q...: rendered in %s", source[:8], elapsed)
+		}
+	}
+}
+
+func TestMarkdownCache(t *testing.T) {
+	cache := NewMarkdownCache()
+	if cache.Render("") != "" {
+		t.Errorf("empty source rendered")
+	}
+	if cache.Render("*one*") != "<p><em>one</em></p>\n" || len(cache.renders) != 1 {
+		t.Errorf("bad render")
+	}
+	// the edited text is another revision with its own render
+	if cache.Render("*two*") != "<p><em>two</em></p>\n" || cache.Render("*one*") != "<p><em>one</em></p>\n" || len(cache.renders) != 2 {
+		t.Errorf("bad render of a revision")
+	}
+
+	converter := &DTOConverter{Markdown: cache}
+	comments := converter.CommentsConvertToDTO([]*CommentComplexData{
+		{Comment: Comment{ID: "c1", Body: "**hi**"}},
+		{Comment: Comment{ID: "c2", Body: "**bye**", DeletedAt: "2022-11-10T10:00:00Z"}},
+	})
+	if comments[0].HTML != "<p><strong>hi</strong></p>\n" || comments[1].HTML != "" {
+		t.Errorf("bad comment html: %q, %q", comments[0].HTML, comments[1].HTML)
+	}
+}
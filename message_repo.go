@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type MessageRepo struct {
+	DB *sql.DB
+}
+
+func NewMessageRepo(db *sql.DB) *MessageRepo {
+	return &MessageRepo{
+		DB: db,
+	}
+}
+
+func (repo *MessageRepo) Add(message *Message) (uint64, error) {
+	fmt.Println("Message repo: add")
+	result, err := repo.DB.Exec(`INSERT INTO message
+	(sender_id, recipient_id, body, created)
+	VALUES (?, ?, ?, ?)`,
+		message.SenderID, message.RecipientID, message.Body, message.Created)
+	if nil != err {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if nil != err {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// GetConversations returns a row per user the user exchanged messages
+// with, the latest conversation first
+func (repo *MessageRepo) GetConversations(userID string, limit int, offset int) ([]*Conversation, error) {
+	fmt.Println("Message repo: get conversations")
+	rows, err := repo.DB.Query(`
+	SELECT
+	message.id, message.sender_id, message.recipient_id, message.body, message.is_read,
+	message.created AS message_created,
+	user.id AS user_id, user.login, conversation.unread
+	FROM (
+		SELECT IF(sender_id = ?, recipient_id, sender_id) AS user_id, MAX(id) AS last_id,
+		SUM(recipient_id = ? AND is_read = 0) AS unread
+		FROM message
+		WHERE sender_id = ? OR recipient_id = ?
+		GROUP BY 1
+	) AS conversation
+	JOIN message ON message.id = conversation.last_id
+	JOIN user ON user.id = conversation.user_id
+	ORDER BY conversation.last_id DESC
+	LIMIT ? OFFSET ?`,
+		userID, userID, userID, userID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := make([]*Conversation, 0, 10)
+	for rows.Next() {
+		data := &Conversation{}
+		err := rows.Scan(&data.Message.ID, &data.Message.SenderID, &data.Message.RecipientID,
+			&data.Message.Body, &data.Message.Read, &data.Message.Created,
+			&data.User.ID, &data.User.Login, &data.Unread)
+		if nil != err {
+			return nil, err
+		}
+		conversations = append(conversations, data)
+	}
+	return conversations, rows.Err()
+}
+
+// GetThread returns the messages between the two users, newest first
+func (repo *MessageRepo) GetThread(userID string, otherID string, limit int, offset int) ([]*Message, error) {
+	fmt.Println("Message repo: get thread")
+	rows, err := repo.DB.Query(`
+	SELECT id, sender_id, recipient_id, body, is_read, created
+	FROM message
+	WHERE (sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?)
+	ORDER BY id DESC
+	LIMIT ? OFFSET ?`,
+		userID, otherID, otherID, userID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*Message, 0, 10)
+	for rows.Next() {
+		message := &Message{}
+		err := rows.Scan(&message.ID, &message.SenderID, &message.RecipientID,
+			&message.Body, &message.Read, &message.Created)
+		if nil != err {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// MarkThreadRead marks the messages the other user sent to the user
+func (repo *MessageRepo) MarkThreadRead(userID string, otherID string) (int64, error) {
+	fmt.Println("Message repo: mark thread read")
+	result, err := repo.DB.Exec(`UPDATE message SET is_read = 1
+	WHERE recipient_id = ? AND sender_id = ? AND is_read = 0`, userID, otherID)
+	if nil != err {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (repo *MessageRepo) CountUnread(userID string) (int64, error) {
+	fmt.Println("Message repo: count unread")
+	var unread int64
+	err := repo.DB.
+		QueryRow(`SELECT COUNT(*) FROM message WHERE recipient_id = ? AND is_read = 0`, userID).
+		Scan(&unread)
+	if nil != err {
+		return 0, err
+	}
+	return unread, nil
+}
+
+func (repo *MessageRepo) CountSentSince(senderID string, since string) (int64, error) {
+	fmt.Println("Message repo: count sent since")
+	var sent int64
+	err := repo.DB.
+		QueryRow(`SELECT COUNT(*) FROM message WHERE sender_id = ? AND created >= ?`, senderID, since).
+		Scan(&sent)
+	if nil != err {
+		return 0, err
+	}
+	return sent, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMessageGetConversations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewMessageRepo(db)
+	expected := []*Conversation{
+		{
+			Message: Message{ID: 7, SenderID: "u2", RecipientID: "u1", Body: "hi", Created: "2022-11-10T12:00:00Z"},
+			User:    User{ID: "u2", Login: "bob"},
+			Unread:  3,
+		},
+	}
+	rows := sqlmock.NewRows([]string{"id", "sender_id", "recipient_id", "body", "is_read", "message_created", "user_id", "login", "unread"}).
+		AddRow(7, "u2", "u1", "hi", false, "2022-11-10T12:00:00Z", "u2", "bob", 3)
+
+	// success
+	mock.ExpectQuery(`GROUP BY 1\s+\) AS conversation .* ORDER BY conversation.last_id DESC\s+LIMIT \? OFFSET \?`).
+		WithArgs("u1", "u1", "u1", "u1", 25, 0).
+		WillReturnRows(rows)
+	data, err := repo.GetConversations("u1", 25, 0)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, data)
+		return
+	}
+
+	// db error
+	mock.ExpectQuery(`FROM message`).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.GetConversations("u1", 25, 0)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestMessageMarkThreadRead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewMessageRepo(db)
+
+	// only the received ones
+	mock.ExpectExec(`UPDATE message SET is_read = 1\s+WHERE recipient_id = \? AND sender_id = \? AND is_read = 0`).
+		WithArgs("u1", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	marked, err := repo.MarkThreadRead("u1", "u2")
+	if err != nil || marked != 2 {
+		t.Errorf("expected 2 marked, got %d %v", marked, err)
+		return
+	}
+
+	// sent in the last hour
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM message WHERE sender_id = \? AND created >= \?`).
+		WithArgs("u1", "2022-11-10T11:00:00Z").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	sent, err := repo.CountSentSince("u1", "2022-11-10T11:00:00Z")
+	if err != nil || sent != 4 {
+		t.Errorf("expected 4 sent, got %d %v", sent, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestBlockIsBlocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewBlockRepo(db)
+
+	// either direction
+	mock.ExpectQuery(`WHERE \(blocker_id = \? AND blocked_id = \?\) OR \(blocker_id = \? AND blocked_id = \?\)`).
+		WithArgs("u1", "u2", "u2", "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	blocked, err := repo.IsBlocked("u1", "u2")
+	if err != nil || !blocked {
+		t.Errorf("expected blocked, got %v %v", blocked, err)
+		return
+	}
+
+	// already blocked
+	mock.ExpectExec(`INSERT IGNORE INTO user_block`).
+		WithArgs("u1", "u2", "2022-11-10T12:00:00Z").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isAdded, err := repo.Add("u1", "u2", "2022-11-10T12:00:00Z")
+	if err != nil || isAdded {
+		t.Errorf("expected not added, got %v %v", isAdded, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const MessageMaxLen = 10000
+
+// the accounts younger than NewAccountAge send NewAccountMessagesPerHour
+// messages at most
+var (
+	NewAccountAge             = 7 * 24 * time.Hour
+	NewAccountMessagesPerHour = 5
+)
+
+// SendMessage sends a direct message to the user with the "to" login
+func (h *UserHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &MessageRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	if strings.TrimSpace(requestData.Body) == "" || len(requestData.Body) > MessageMaxLen {
+		jsonError(w, http.StatusBadRequest, "bad message body")
+		return
+	}
+
+	recipient, ok := h.messageRecipient(w, sess, requestData.To)
+	if !ok {
+		return
+	}
+	limited, err := h.messageRateLimited(sess.UserID)
+	if nil != err {
+		fmt.Println("can't check message rate limit", err)
+		jsonError(w, http.StatusInternalServerError, "can't send message")
+		return
+	}
+	if limited {
+		jsonError(w, http.StatusTooManyRequests, "too many messages, try again later")
+		return
+	}
+
+	message := &Message{
+		SenderID:    sess.UserID,
+		RecipientID: recipient.ID,
+		Body:        requestData.Body,
+		Created:     h.TimeGetter.GetCreated(),
+	}
+	message.ID, err = h.MessageRepo.Add(message)
+	if nil != err {
+		fmt.Println("can't add message", err)
+		jsonError(w, http.StatusInternalServerError, "can't send message")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	jsonResponse(w, h.DTOConverter.MessagesConvertToDTO([]*Message{message})[0])
+}
+
+// messageRecipient resolves the recipient and checks that the users may
+// message each other; it writes the error itself.
+func (h *UserHandler) messageRecipient(w http.ResponseWriter, sess *Session, login string) (*User, bool) {
+	recipient, err := h.UserRepo.GetByLogin(login)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, false
+	} else if nil != err {
+		fmt.Println("can't get user by login", err)
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, false
+	}
+	if recipient.ID == sess.UserID {
+		jsonError(w, http.StatusBadRequest, "can't message yourself")
+		return nil, false
+	}
+	isBlocked, err := h.BlockRepo.IsBlocked(sess.UserID, recipient.ID)
+	if nil != err {
+		fmt.Println("can't check blocks", err)
+		jsonError(w, http.StatusInternalServerError, "can't check blocks")
+		return nil, false
+	}
+	if isBlocked {
+		jsonError(w, http.StatusForbidden, "user is blocked")
+		return nil, false
+	}
+	return recipient, true
+}
+
+// messageRateLimited counts the messages of the last hour for the new
+// accounts, the accounts of unknown age are not limited
+func (h *UserHandler) messageRateLimited(userID string) (bool, error) {
+	sender, err := h.UserRepo.GetById(userID)
+	if nil != err {
+		return false, err
+	}
+	now := h.TimeGetter.Now()
+	age, known := accountAge(sender, now)
+	if !known || age >= NewAccountAge {
+		return false, nil
+	}
+	since := now.Add(-time.Hour).Format(time.RFC3339)
+	sent, err := h.MessageRepo.CountSentSince(userID, since)
+	if nil != err {
+		return false, err
+	}
+	return sent >= int64(NewAccountMessagesPerHour), nil
+}
+
+// Conversations lists the users the current user exchanged messages with,
+// the latest conversation first
+func (h *UserHandler) Conversations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.MessageRepo.GetConversations(sess.UserID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get conversations", err)
+		jsonError(w, http.StatusInternalServerError, "can't get conversations")
+		return
+	}
+	unread, err := h.MessageRepo.CountUnread(sess.UserID)
+	if nil != err {
+		fmt.Println("can't count unread messages", err)
+		jsonError(w, http.StatusInternalServerError, "can't count unread messages")
+		return
+	}
+	jsonResponse(w, &ConversationsDTO{
+		Unread:        unread,
+		Conversations: h.DTOConverter.ConversationsConvertToDTO(data),
+	})
+}
+
+// Thread returns the messages with the user, newest first, and marks the
+// received ones read
+func (h *UserHandler) Thread(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	other, err := h.UserRepo.GetByLogin(mux.Vars(r)["USER_LOGIN"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return
+	} else if nil != err {
+		fmt.Println("can't get user by login", err)
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return
+	}
+
+	data, err := h.MessageRepo.GetThread(sess.UserID, other.ID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get thread", err)
+		jsonError(w, http.StatusInternalServerError, "can't get messages")
+		return
+	}
+	_, err = h.MessageRepo.MarkThreadRead(sess.UserID, other.ID)
+	if nil != err {
+		fmt.Println("can't mark thread read", err)
+	}
+	jsonResponse(w, &ThreadDTO{
+		User: &AuthorDTO{
+			UserName: other.Login,
+			ID:       other.ID,
+		},
+		Messages: h.DTOConverter.MessagesConvertToDTO(data),
+	})
+}
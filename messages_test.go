@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestSendMessage(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	messageRepoMock := NewMockMessageRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &UserHandler{
+		UserRepo:     userRepoMock,
+		MessageRepo:  messageRepoMock,
+		BlockRepo:    blockRepoMock,
+		TimeGetter:   timeGetterMock,
+		DTOConverter: &DTOConverter{},
+	}
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/messages", strings.NewReader(body))
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+	now := time.Date(2022, 11, 10, 12, 0, 0, 0, time.UTC)
+	recipient := &User{ID: "u2", Login: "bob"}
+	oldSender := &User{ID: sess.UserID, Login: "mer", Created: "2022-01-01T00:00:00Z"}
+	newSender := &User{ID: sess.UserID, Login: "mer", Created: "2022-11-09T00:00:00Z"}
+
+	//success, an old account isn't limited
+	userRepoMock.EXPECT().GetByLogin("bob").Return(recipient, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(false, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(oldSender, nil)
+	timeGetterMock.EXPECT().Now().Return(now)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T12:00:00Z")
+	messageRepoMock.EXPECT().Add(&Message{SenderID: sess.UserID, RecipientID: "u2", Body: "hi", Created: "2022-11-10T12:00:00Z"}).
+		Return(uint64(7), nil)
+	w := httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"bob","body":"hi"}`))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `{"id":7,"sender_id":"` + sess.UserID + `","recipient_id":"u2","body":"hi","read":false,"created":"2022-11-10T12:00:00Z"}`
+	if w.Result().StatusCode != http.StatusCreated || string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %d %s", expected, w.Result().StatusCode, body)
+		return
+	}
+
+	//new account over the hourly limit
+	userRepoMock.EXPECT().GetByLogin("bob").Return(recipient, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(false, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(newSender, nil)
+	timeGetterMock.EXPECT().Now().Return(now)
+	messageRepoMock.EXPECT().CountSentSince(sess.UserID, "2022-11-10T11:00:00Z").Return(int64(NewAccountMessagesPerHour), nil)
+	w = httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"bob","body":"hi"}`))
+	if w.Result().StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//blocked
+	userRepoMock.EXPECT().GetByLogin("bob").Return(recipient, nil)
+	blockRepoMock.EXPECT().IsBlocked(sess.UserID, "u2").Return(true, nil)
+	w = httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"bob","body":"hi"}`))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unknown recipient
+	userRepoMock.EXPECT().GetByLogin("nobody").Return(nil, sql.ErrNoRows)
+	w = httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"nobody","body":"hi"}`))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//to yourself
+	userRepoMock.EXPECT().GetByLogin("mer").Return(oldSender, nil)
+	w = httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"mer","body":"hi"}`))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//empty body
+	w = httptest.NewRecorder()
+	service.SendMessage(w, newRequest(`{"to":"bob","body":"  "}`))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestMessageThreads(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	messageRepoMock := NewMockMessageRepoI(ctrl)
+	service := &UserHandler{
+		UserRepo:     userRepoMock,
+		MessageRepo:  messageRepoMock,
+		DTOConverter: &DTOConverter{},
+	}
+	newRequest := func(url string) *http.Request {
+		req := httptest.NewRequest("GET", url, nil)
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+	message := Message{ID: 7, SenderID: "u2", RecipientID: sess.UserID, Body: "hi", Created: "2022-11-10T12:00:00Z"}
+	messageJSON := `{"id":7,"sender_id":"u2","recipient_id":"` + sess.UserID + `","body":"hi","read":false,"created":"2022-11-10T12:00:00Z"}`
+
+	//conversations with the unread state
+	messageRepoMock.EXPECT().GetConversations(sess.UserID, ListDefaultLimit, 0).Return([]*Conversation{
+		{Message: message, User: User{ID: "u2", Login: "bob"}, Unread: 1},
+	}, nil)
+	messageRepoMock.EXPECT().CountUnread(sess.UserID).Return(int64(1), nil)
+	w := httptest.NewRecorder()
+	service.Conversations(w, newRequest("/api/messages"))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `{"unread":1,"conversations":[{"user":{"username":"bob","id":"u2"},"last_message":` + messageJSON + `,"unread":1}]}`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//the thread is marked read
+	userRepoMock.EXPECT().GetByLogin("bob").Return(&User{ID: "u2", Login: "bob"}, nil)
+	messageRepoMock.EXPECT().GetThread(sess.UserID, "u2", ListDefaultLimit, 0).Return([]*Message{&message}, nil)
+	messageRepoMock.EXPECT().MarkThreadRead(sess.UserID, "u2").Return(int64(1), nil)
+	w = httptest.NewRecorder()
+	service.Thread(w, mux.SetURLVars(newRequest("/api/messages/bob"), map[string]string{"USER_LOGIN": "bob"}))
+	body, _ = io.ReadAll(w.Result().Body)
+	expected = `{"user":{"username":"bob","id":"u2"},"messages":[` + messageJSON + `]}`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//db error
+	messageRepoMock.EXPECT().GetConversations(sess.UserID, ListDefaultLimit, 0).Return(nil, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.Conversations(w, newRequest("/api/messages"))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestBlockUser(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &UserHandler{
+		UserRepo:   userRepoMock,
+		BlockRepo:  blockRepoMock,
+		TimeGetter: timeGetterMock,
+	}
+	newRequest := func(login string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/user/"+login+"/block", nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+		return mux.SetURLVars(req, map[string]string{"USER_LOGIN": login})
+	}
+
+	//block
+	userRepoMock.EXPECT().GetByLogin("bob").Return(&User{ID: "u2", Login: "bob"}, nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T12:00:00Z")
+	blockRepoMock.EXPECT().Add(sess.UserID, "u2", "2022-11-10T12:00:00Z").Return(true, nil)
+	w := httptest.NewRecorder()
+	service.BlockUser(w, newRequest("bob"))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unblock without a block
+	userRepoMock.EXPECT().GetByLogin("bob").Return(&User{ID: "u2", Login: "bob"}, nil)
+	blockRepoMock.EXPECT().Delete(sess.UserID, "u2").Return(false, nil)
+	w = httptest.NewRecorder()
+	service.UnblockUser(w, newRequest("bob"))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//yourself
+	userRepoMock.EXPECT().GetByLogin("mer").Return(&User{ID: sess.UserID, Login: "mer"}, nil)
+	w = httptest.NewRecorder()
+	service.BlockUser(w, newRequest("mer"))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration brings a database created by an older schema.sql up to date,
+// schema.sql marks the migrations it already covers as applied
+type Migration struct {
+	Name string
+	Up   func(db *sql.DB) error
+}
+
+var Migrations = []*Migration{
+	{Name: "user_karma", Up: migrateUserKarma},
+}
+
+type migrationColumn struct {
+	name       string
+	definition string
+}
+
+// RunMigrations applies the migrations missing in schema_migration in
+// order, it stops at the first failed one
+func RunMigrations(db *sql.DB, migrations []*Migration) error {
+	fmt.Println("Run migrations")
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migration (
+	name varchar(64) NOT NULL,
+	applied varchar(255) NOT NULL,
+	PRIMARY KEY (name)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if nil != err {
+		return err
+	}
+	for _, migration := range migrations {
+		var exists bool
+		err = db.QueryRow(`SELECT EXISTS(SELECT 1 FROM schema_migration WHERE name = ?)`, migration.Name).Scan(&exists)
+		if nil != err {
+			return err
+		}
+		if exists {
+			continue
+		}
+		fmt.Println("Apply migration", migration.Name)
+		err = migration.Up(db)
+		if nil != err {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		_, err = db.Exec(`INSERT INTO schema_migration (name, applied) VALUES (?, ?)`,
+			migration.Name, time.Now().UTC().Format(time.RFC3339))
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumns skips the columns the table already has, ALTER TABLE commits
+// on its own so a migration can be run again after a failure
+func addColumns(db *sql.DB, table string, columns []migrationColumn) error {
+	for _, column := range columns {
+		var exists bool
+		err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?)`,
+			table, column.name).Scan(&exists)
+		if nil != err {
+			return err
+		}
+		if exists {
+			continue
+		}
+		_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column.name + ` ` + column.definition)
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUserKarma adds the profile columns and counts the post votes
+// cast before post_karma was kept up to date; the own votes don't count
+func migrateUserKarma(db *sql.DB) error {
+	err := addColumns(db, "user", []migrationColumn{
+		{"bio", "varchar(255) NOT NULL DEFAULT ''"},
+		{"post_karma", "bigint(20) NOT NULL DEFAULT 0"},
+		{"comment_karma", "bigint(20) NOT NULL DEFAULT 0"},
+	})
+	if nil != err {
+		return err
+	}
+	_, err = db.Exec(`UPDATE user SET post_karma = (
+	SELECT COALESCE(SUM(vote.vote), 0)
+	FROM vote
+	JOIN post ON post.id = vote.post_id
+	WHERE post.user_id = user.id AND vote.user_id <> user.id
+	)`)
+	return err
+}
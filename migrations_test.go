@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRunMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	applied := []string{}
+	migrations := []*Migration{
+		{Name: "first", Up: func(db *sql.DB) error { applied = append(applied, "first"); return nil }},
+		{Name: "second", Up: func(db *sql.DB) error { applied = append(applied, "second"); return nil }},
+		{Name: "broken", Up: func(db *sql.DB) error { return fmt.Errorf("db error") }},
+	}
+
+	//the applied ones are skipped, a failed one is not recorded
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migration`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM schema_migration WHERE name = \?\)`).
+		WithArgs("first").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM schema_migration WHERE name = \?\)`).
+		WithArgs("second").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`INSERT INTO schema_migration \(name, applied\) VALUES \(\?, \?\)`).
+		WithArgs("second", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM schema_migration WHERE name = \?\)`).
+		WithArgs("broken").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	err = RunMigrations(db, migrations)
+	if err == nil || len(applied) != 1 || applied[0] != "second" {
+		t.Errorf("expected only second applied and an error, got %v %v", applied, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateUserKarma(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	//only the missing columns are added before the backfill
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM information_schema.columns`).
+		WithArgs("user", "bio").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM information_schema.columns`).
+		WithArgs("user", "post_karma").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`ALTER TABLE user ADD COLUMN post_karma bigint\(20\) NOT NULL DEFAULT 0`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM information_schema.columns`).
+		WithArgs("user", "comment_karma").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`ALTER TABLE user ADD COLUMN comment_karma`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE user SET post_karma = \( SELECT COALESCE\(SUM\(vote.vote\), 0\) FROM vote JOIN post ON post.id = vote.post_id WHERE post.user_id = user.id AND vote.user_id <> user.id \)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	err = migrateUserKarma(db)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ModLogRepo is append-only, entries are never updated or deleted.
+type ModLogRepo struct {
+	DB *sql.DB
+}
+
+func NewModLogRepo(db *sql.DB) *ModLogRepo {
+	return &ModLogRepo{
+		DB: db,
+	}
+}
+
+func (repo *ModLogRepo) Add(entry *ModLogEntry) error {
+	fmt.Println("Mod log repo: add", entry.Action)
+	_, err := repo.DB.Exec(`INSERT INTO mod_log 
+	(category_id, moderator_id, action, target_type, target_id, reason, created) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.CategoryID, entry.ModeratorID, entry.Action, entry.TargetType, entry.TargetID, entry.Reason, entry.Created)
+	return err
+}
+
+func (repo *ModLogRepo) GetByCategoryId(categoryID uint32, limit int, offset int) ([]*ModLogComplexData, error) {
+	fmt.Println("Mod log repo: get by category id")
+	rows, err := repo.DB.Query(`
+	SELECT
+	mod_log.id, category_id, moderator_id, action, target_type, target_id, reason, 
+	mod_log.created AS mod_log_created,
+	IFNULL(user.login, '')
+	FROM mod_log
+	LEFT JOIN user ON user.id = mod_log.moderator_id
+	WHERE category_id = ?
+	ORDER BY mod_log.id DESC
+	LIMIT ? OFFSET ?`,
+		categoryID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*ModLogComplexData, 0, 10)
+	for rows.Next() {
+		data := &ModLogComplexData{}
+		err := rows.Scan(&data.ModLogEntry.ID, &data.ModLogEntry.CategoryID,
+			&data.ModLogEntry.ModeratorID, &data.ModLogEntry.Action,
+			&data.ModLogEntry.TargetType, &data.ModLogEntry.TargetID,
+			&data.ModLogEntry.Reason, &data.ModLogEntry.Created,
+			&data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		entries = append(entries, data)
+	}
+	return entries, rows.Err()
+}
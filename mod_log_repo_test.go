@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestModLogAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewModLogRepo(db)
+	entry := &ModLogEntry{
+		CategoryID:  7,
+		ModeratorID: "522cd619-841f-43d5-866d-f880e5f48d18",
+		Action:      ModLogRemovePost,
+		TargetType:  "post",
+		TargetID:    "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+		Reason:      "spam",
+		Created:     "2022-11-10T11:24:44Z",
+	}
+
+	// success
+	mock.ExpectExec(`INSERT INTO mod_log`).
+		WithArgs(entry.CategoryID, entry.ModeratorID, entry.Action, entry.TargetType, entry.TargetID, entry.Reason, entry.Created).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	err = repo.Add(entry)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestModLogGetByCategoryId(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewModLogRepo(db)
+	expected := []*ModLogComplexData{
+		{
+			ModLogEntry: ModLogEntry{ID: 3, CategoryID: 7, ModeratorID: "522cd619-841f-43d5-866d-f880e5f48d18",
+				Action: ModLogLockPost, TargetType: "post", TargetID: "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+				Created: "2022-11-10T11:24:44Z"},
+			User: User{Login: "mer"},
+		},
+	}
+	rows := sqlmock.NewRows([]string{
+		"id", "category_id", "moderator_id", "action", "target_type", "target_id", "reason", "mod_log_created", "login",
+	})
+	for _, e := range expected {
+		rows.AddRow(e.ModLogEntry.ID, e.ModLogEntry.CategoryID, e.ModLogEntry.ModeratorID, e.ModLogEntry.Action,
+			e.ModLogEntry.TargetType, e.ModLogEntry.TargetID, e.ModLogEntry.Reason, e.ModLogEntry.Created, e.User.Login)
+	}
+
+	// success
+	mock.ExpectQuery(`FROM mod_log LEFT JOIN user ON user.id = mod_log.moderator_id WHERE category_id = \? ORDER BY mod_log.id DESC LIMIT \? OFFSET \?`).
+		WithArgs(7, 25, 0).
+		WillReturnRows(rows)
+	entries, err := repo.GetByCategoryId(7, 25, 0)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("results are not matched; want: %#v, have: %#v", expected, entries)
+		return
+	}
+
+	// query error
+	mock.ExpectQuery(`FROM mod_log`).
+		WithArgs(7, 25, 0).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = repo.GetByCategoryId(7, 25, 0)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
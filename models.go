@@ -1,49 +1,310 @@
 package main
 
 type Post struct {
-	ID          string
-	Title       string
-	Type        string
+	ID    string
+	Title string
+	Type  string
+	URL   string
+	// CanonicalURL is only for the repost check, URL is shown
+	CanonicalURL string
+	// Image is the blob key of an image post
+	Image       string
 	Description string
 	Score       uint32
 	UserID      string
 	CategoryID  uint
 	Created     string
+	Removed     bool
+	Locked      bool
+	Pinned      bool
+	Flair       string
 }
 
 type User struct {
 	ID       string
 	Login    string
 	Password string
+	Email    string
+	Verified bool
 	Created  string
 }
 
+// UserProfile karma is kept by the votes, it isn't summed on read
+type UserProfile struct {
+	User
+	Bio          string
+	PostKarma    int64
+	CommentKarma int64
+}
+
 type Comment struct {
 	ID      string
 	Body    string
 	PostId  string
 	UserId  string
 	Created string
+	Removed bool
+	// ParentID is empty for the top level comments
+	ParentID string
+	// DeletedAt is empty for comments which aren't deleted
+	DeletedAt string
+	Ups       int64
+	Downs     int64
 }
 
+const (
+	VoteUp   int32 = 1
+	VoteNone int32 = 0
+	VoteDown int32 = -1
+)
+
 type Vote struct {
 	PostID string
 	UserID string
-	Vote   uint32
+	Vote   int32
 }
 
 type Category struct {
-	ID   uint32
-	Name string
+	ID              uint32
+	Name            string
+	Description     string
+	Rules           string
+	CreatorID       string
+	Created         string
+	Subscribers     uint32
+	RequireVerified bool
+}
+
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// Role with CategoryID 0 is site-wide.
+type Role struct {
+	UserID     string
+	Name       string
+	CategoryID uint32
+}
+
+type UserTOTP struct {
+	UserID   string
+	Secret   string
+	Enabled  bool
+	LastStep int64
+	Created  string
+}
+
+type LoginChallenge struct {
+	Token    string
+	UserID   string
+	Attempts int
+	Expires  string
+}
+
+type EmailVerification struct {
+	Token   string
+	UserID  string
+	Email   string
+	Expires string
+}
+
+const (
+	ModLogRemovePost     = "remove_post"
+	ModLogApprovePost    = "approve_post"
+	ModLogLockPost       = "lock_post"
+	ModLogUnlockPost     = "unlock_post"
+	ModLogPinPost        = "pin_post"
+	ModLogUnpinPost      = "unpin_post"
+	ModLogRemoveComment  = "remove_comment"
+	ModLogApproveComment = "approve_comment"
+	ModLogDismissReports = "dismiss_reports"
+	ModLogBanUser        = "ban_user"
+	ModLogUnbanUser      = "unban_user"
+	ModLogAutomodRemove  = "automod_remove"
+	ModLogAutomodHold    = "automod_hold"
+	ModLogAutomodFlair   = "automod_flair"
+	ModLogAutomodReply   = "automod_reply"
+)
+
+// ModLogEntry is never updated or deleted once written.
+type ModLogEntry struct {
+	ID          uint64
+	CategoryID  uint32
+	ModeratorID string
+	Action      string
+	TargetType  string
+	TargetID    string
+	Reason      string
+	Created     string
+}
+
+type Report struct {
+	ID         uint64
+	TargetType string
+	TargetID   string
+	PostID     string
+	CategoryID uint32
+	AuthorID   string
+	ReporterID string
+	Reason     string
+	Created    string
+}
+
+// ReportQueueItem groups the open reports of one post or comment.
+type ReportQueueItem struct {
+	TargetType    string
+	TargetID      string
+	PostID        string
+	AuthorID      string
+	Reports       uint32
+	Reasons       []string
+	FirstReported string
+}
+
+const (
+	BanKindBan       = "ban"
+	BanKindShadowban = "shadowban"
+)
+
+// Ban with CategoryID 0 is site-wide, empty Expires never expires.
+type Ban struct {
+	UserID      string
+	Kind        string
+	CategoryID  uint32
+	Reason      string
+	ModeratorID string
+	Created     string
+	Expires     string
 }
 
 type PostComplexData struct {
 	Post
 	User
 	Category
+	LinkPreview
+}
+
+type CategoryComplexData struct {
+	Category
+	User
+}
+
+const (
+	NotificationPostReply    = "post_reply"
+	NotificationCommentReply = "comment_reply"
+	NotificationMention      = "mention"
+	NotificationModAction    = "mod_action"
+	NotificationFollow       = "follow"
+)
+
+// Notification ActorID is empty for the mod actions, the moderators stay
+// anonymous to the author
+type Notification struct {
+	ID        uint64
+	UserID    string
+	Type      string
+	ActorID   string
+	PostID    string
+	CommentID string
+	Body      string
+	Read      bool
+	Created   string
+}
+
+type NotificationComplexData struct {
+	Notification
+	User
+}
+
+const (
+	SavedTypePost    = "post"
+	SavedTypeComment = "comment"
+)
+
+// SavedItem PostID is the post of the saved comment or the saved post itself
+type SavedItem struct {
+	UserID  string
+	Type    string
+	ItemID  string
+	PostID  string
+	Created string
+}
+
+type Message struct {
+	ID          uint64
+	SenderID    string
+	RecipientID string
+	Body        string
+	Read        bool
+	Created     string
+}
+
+// Conversation is the last message exchanged with User and the count of
+// the unread ones from them
+type Conversation struct {
+	Message
+	User
+	Unread int64
+}
+
+type Block struct {
+	BlockerID string
+	BlockedID string
+	Created   string
+}
+
+type BlockComplexData struct {
+	Block
+	User
+}
+
+type Follow struct {
+	FollowerID string
+	FollowedID string
+	Created    string
+}
+
+// FollowComplexData User is the other side of the follow, the follower
+// in the followers list and the followed user in the following one
+type FollowComplexData struct {
+	Follow
+	User
+}
+
+type ModLogComplexData struct {
+	ModLogEntry
+	User
 }
 
 type CommentComplexData struct {
 	Comment
 	User
 }
+
+// AutomodSettings keeps the raw JSON config of a category, it is parsed
+// by ParseAutomodConfig on every check.
+type AutomodSettings struct {
+	CategoryID uint32
+	Config     string
+	UpdatedBy  string
+	Updated    string
+}
+
+const (
+	LinkPreviewOK     = "ok"
+	LinkPreviewFailed = "failed"
+)
+
+// LinkPreview is fetched in the background for link posts, the failed
+// ones are kept too so they are not fetched again.
+type LinkPreview struct {
+	PostID      string
+	URL         string
+	Title       string
+	Description string
+	Image       string
+	Status      string
+	Error       string
+	Fetched     string
+}
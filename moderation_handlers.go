@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	ModLogTargetPost    = "post"
+	ModLogTargetComment = "comment"
+	ModLogTargetUser    = "user"
+	ModLogReasonMaxLen  = 255
+)
+
+func (h *PostsHandler) RemovePost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionRemovePost, ModLogRemovePost, func(post *Post) (bool, error) {
+		isChanged, err := h.PostsRepo.SetRemoved(post.ID, true)
+		if isChanged {
+			h.unindexSearch(SearchTargetPost, post.ID)
+		}
+		return isChanged, err
+	})
+}
+
+func (h *PostsHandler) ApprovePost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionRemovePost, ModLogApprovePost, func(post *Post) (bool, error) {
+		isChanged, err := h.PostsRepo.SetRemoved(post.ID, false)
+		if isChanged {
+			h.reindexPost(post.ID)
+		}
+		return isChanged, err
+	})
+}
+
+func (h *PostsHandler) LockPost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionLockPost, ModLogLockPost, func(post *Post) (bool, error) {
+		return h.PostsRepo.SetLocked(post.ID, true)
+	})
+}
+
+func (h *PostsHandler) UnlockPost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionLockPost, ModLogUnlockPost, func(post *Post) (bool, error) {
+		return h.PostsRepo.SetLocked(post.ID, false)
+	})
+}
+
+func (h *PostsHandler) PinPost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionPinPost, ModLogPinPost, func(post *Post) (bool, error) {
+		if post.Pinned {
+			return false, nil
+		}
+		return h.PostsRepo.Pin(post.ID, post.CategoryID)
+	})
+}
+
+func (h *PostsHandler) UnpinPost(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionPinPost, ModLogUnpinPost, func(post *Post) (bool, error) {
+		return h.PostsRepo.Unpin(post.ID)
+	})
+}
+
+func (h *PostsHandler) DismissPostReports(w http.ResponseWriter, r *http.Request) {
+	h.moderatePost(w, r, ActionRemovePost, ModLogDismissReports, func(post *Post) (bool, error) {
+		return false, nil
+	})
+}
+
+func (h *PostsHandler) RemoveComment(w http.ResponseWriter, r *http.Request) {
+	h.moderateComment(w, r, ModLogRemoveComment, func(comment *Comment) (bool, error) {
+		isChanged, err := h.CommentRepo.SetRemoved(comment.ID, true)
+		if isChanged {
+			h.unindexSearch(SearchTargetComment, comment.ID)
+		}
+		return isChanged, err
+	})
+}
+
+func (h *PostsHandler) ApproveComment(w http.ResponseWriter, r *http.Request) {
+	h.moderateComment(w, r, ModLogApproveComment, func(comment *Comment) (bool, error) {
+		isChanged, err := h.CommentRepo.SetRemoved(comment.ID, false)
+		if isChanged {
+			h.reindexComment(comment.ID)
+		}
+		return isChanged, err
+	})
+}
+
+func (h *PostsHandler) DismissCommentReports(w http.ResponseWriter, r *http.Request) {
+	h.moderateComment(w, r, ModLogDismissReports, func(comment *Comment) (bool, error) {
+		return false, nil
+	})
+}
+
+// reportResolvingActions close the open reports of the item
+var reportResolvingActions = map[string]struct{}{
+	ModLogRemovePost:     {},
+	ModLogApprovePost:    {},
+	ModLogRemoveComment:  {},
+	ModLogApproveComment: {},
+	ModLogDismissReports: {},
+}
+
+// moderatePost checks the policy for the category of the post, applies the
+// change and logs it, repeated actions which change nothing are not logged
+func (h *PostsHandler) moderatePost(w http.ResponseWriter, r *http.Request, action Action, logAction string, apply func(post *Post) (bool, error)) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	data, err := h.PostsRepo.GetById(mux.Vars(r)["POST_ID"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+	if !Can(sess, action, PostResource(&data.Post)) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+	reason, err := readModerationReason(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	isChanged, err := apply(&data.Post)
+	if err == ErrPinLimit {
+		jsonError(w, http.StatusConflict, fmt.Sprintf("only %d posts can be pinned", MaxPinnedPosts))
+		return
+	} else if nil != err {
+		fmt.Println("can't moderate post", err)
+		jsonError(w, http.StatusInternalServerError, "can't moderate post")
+		return
+	}
+	isResolved, err := h.resolveReports(logAction, ModLogTargetPost, data.Post.ID)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't resolve reports")
+		return
+	}
+	if isChanged || isResolved {
+		h.addModLog(sess, uint32(data.Post.CategoryID), logAction, ModLogTargetPost, data.Post.ID, reason)
+	}
+	if isChanged {
+		h.notifyModAction(sess, logAction, data.Post.UserID, data.Post.ID, "", reason)
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *PostsHandler) moderateComment(w http.ResponseWriter, r *http.Request, logAction string, apply func(comment *Comment) (bool, error)) {
+	w.Header().Add("Content-Type", "application/json")
+	params := mux.Vars(r)
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	comment, err := h.CommentRepo.GetById(params["COMMENT_ID"])
+	if err == sql.ErrNoRows || (nil == err && comment.PostId != params["POST_ID"]) {
+		jsonError(w, http.StatusNotFound, "comment not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get comment")
+		return
+	}
+	data, err := h.PostsRepo.GetById(comment.PostId)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post")
+		return
+	}
+	resource := &Resource{
+		CategoryID: uint32(data.Post.CategoryID),
+		OwnerID:    comment.UserId,
+	}
+	if !Can(sess, ActionRemoveComment, resource) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+	reason, err := readModerationReason(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	isChanged, err := apply(comment)
+	if nil != err {
+		fmt.Println("can't moderate comment", err)
+		jsonError(w, http.StatusInternalServerError, "can't moderate comment")
+		return
+	}
+	isResolved, err := h.resolveReports(logAction, ModLogTargetComment, comment.ID)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't resolve reports")
+		return
+	}
+	if isChanged || isResolved {
+		h.addModLog(sess, resource.CategoryID, logAction, ModLogTargetComment, comment.ID, reason)
+	}
+	if isChanged {
+		h.notifyModAction(sess, logAction, comment.UserId, comment.PostId, comment.ID, reason)
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *PostsHandler) resolveReports(logAction string, targetType string, targetID string) (bool, error) {
+	if _, ok := reportResolvingActions[logAction]; !ok {
+		return false, nil
+	}
+	resolved, err := h.ReportRepo.Resolve(targetType, targetID)
+	if nil != err {
+		fmt.Println("can't resolve reports", err)
+		return false, err
+	}
+	return resolved > 0, nil
+}
+
+// readModerationReason accepts an empty body, the reason is optional
+func readModerationReason(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if nil != err {
+		return "", fmt.Errorf("read request err")
+	}
+	if len(body) == 0 {
+		return "", nil
+	}
+	requestData := &ModerationRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		return "", fmt.Errorf("can't unpack payload")
+	}
+	if len(requestData.Reason) > ModLogReasonMaxLen {
+		return "", fmt.Errorf("reason is too long")
+	}
+	return requestData.Reason, nil
+}
+
+func (h *PostsHandler) addModLog(sess *Session, categoryID uint32, action string, targetType string, targetID string, reason string) {
+	err := h.ModLogRepo.Add(&ModLogEntry{
+		CategoryID:  categoryID,
+		ModeratorID: sess.UserID,
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Reason:      reason,
+		Created:     h.TimeGetter.GetCreated(),
+	})
+	if nil != err {
+		fmt.Println("can't add mod log entry", err)
+	}
+}
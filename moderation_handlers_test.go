@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+var modSess = &Session{
+	ID:     "456",
+	UserID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11",
+	Roles: []*Role{
+		{UserID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11", Name: RoleModerator, CategoryID: 1},
+	},
+}
+
+func TestModeratePost(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:  postsRepoMock,
+		ModLogRepo: modLogRepoMock,
+		ReportRepo: reportRepoMock,
+		TimeGetter: timeGetterMock,
+	}
+	postID := multipleComplexData[0].Post.ID
+	urlVars := map[string]string{"POST_ID": postID}
+	newRequest := func(body string, s *Session) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+postID+"/remove", strings.NewReader(body))
+		req = mux.SetURLVars(req, urlVars)
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, s))
+	}
+
+	//remove with reason is logged
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().SetRemoved(postID, true).Return(true, nil)
+	reportRepoMock.EXPECT().Resolve(ModLogTargetPost, postID).Return(int64(2), nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	modLogRepoMock.EXPECT().Add(&ModLogEntry{
+		CategoryID:  1,
+		ModeratorID: modSess.UserID,
+		Action:      ModLogRemovePost,
+		TargetType:  ModLogTargetPost,
+		TargetID:    postID,
+		Reason:      "spam",
+		Created:     "2022-11-10T11:24:44Z",
+	}).Return(nil)
+	w := httptest.NewRecorder()
+	service.RemovePost(w, newRequest(`{"reason":"spam"}`, modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//repeated lock changes nothing and isn't logged
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().SetLocked(postID, true).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.LockPost(w, newRequest("", modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//dismiss resolves the reports and is logged
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	reportRepoMock.EXPECT().Resolve(ModLogTargetPost, postID).Return(int64(1), nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil)
+	w = httptest.NewRecorder()
+	service.DismissPostReports(w, newRequest("", modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//dismiss without open reports isn't logged
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	reportRepoMock.EXPECT().Resolve(ModLogTargetPost, postID).Return(int64(0), nil)
+	w = httptest.NewRecorder()
+	service.DismissPostReports(w, newRequest("", modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//author isn't a moderator
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	w = httptest.NewRecorder()
+	service.RemovePost(w, newRequest("", sess))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//pin limit
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().Pin(postID, uint(1)).Return(false, ErrPinLimit)
+	w = httptest.NewRecorder()
+	service.PinPost(w, newRequest("", modSess))
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//too long reason
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	w = httptest.NewRecorder()
+	service.UnpinPost(w, newRequest(`{"reason":"`+strings.Repeat("a", ModLogReasonMaxLen+1)+`"}`, modSess))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().SetRemoved(postID, false).Return(false, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.ApprovePost(w, newRequest("", modSess))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestModerateComment(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:   postsRepoMock,
+		CommentRepo: commentRepoMock,
+		ModLogRepo:  modLogRepoMock,
+		ReportRepo:  reportRepoMock,
+		TimeGetter:  timeGetterMock,
+	}
+	comment := &Comment{
+		ID:     "dbed62a8-79c5-43bd-9594-92cddeb261ac",
+		PostId: multipleComplexData[0].Post.ID,
+		UserId: sess.UserID,
+	}
+	urlVars := map[string]string{"POST_ID": comment.PostId, "COMMENT_ID": comment.ID}
+	newRequest := func(s *Session) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+comment.PostId+"/"+comment.ID+"/remove", nil)
+		req = mux.SetURLVars(req, urlVars)
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, s))
+	}
+
+	//remove
+	commentRepoMock.EXPECT().GetById(comment.ID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(comment.PostId).Return(multipleComplexData[0], nil)
+	commentRepoMock.EXPECT().SetRemoved(comment.ID, true).Return(true, nil)
+	reportRepoMock.EXPECT().Resolve(ModLogTargetComment, comment.ID).Return(int64(0), nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil)
+	w := httptest.NewRecorder()
+	service.RemoveComment(w, newRequest(modSess))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//author can't approve own comment
+	commentRepoMock.EXPECT().GetById(comment.ID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(comment.PostId).Return(multipleComplexData[0], nil)
+	w = httptest.NewRecorder()
+	service.ApproveComment(w, newRequest(sess))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//comment of another post
+	commentRepoMock.EXPECT().GetById(comment.ID).Return(&Comment{ID: comment.ID, PostId: "other"}, nil)
+	w = httptest.NewRecorder()
+	service.RemoveComment(w, newRequest(modSess))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type NotificationRepo struct {
+	DB *sql.DB
+}
+
+func NewNotificationRepo(db *sql.DB) *NotificationRepo {
+	return &NotificationRepo{
+		DB: db,
+	}
+}
+
+// Add skips the notification when the user muted its type, the bool
+// tells whether it was added
+func (repo *NotificationRepo) Add(notification *Notification) (bool, error) {
+	fmt.Println("Notification repo: add", notification.Type)
+	result, err := repo.DB.Exec(`INSERT INTO notification
+	(user_id, type, actor_id, post_id, comment_id, body, created)
+	SELECT ?, ?, ?, ?, ?, ?, ? FROM DUAL
+	WHERE NOT EXISTS (SELECT 1 FROM notification_mute WHERE user_id = ? AND type = ?)`,
+		notification.UserID, notification.Type, notification.ActorID, notification.PostID,
+		notification.CommentID, notification.Body, notification.Created,
+		notification.UserID, notification.Type)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *NotificationRepo) GetByUserId(userID string, unreadOnly bool, limit int, offset int) ([]*NotificationComplexData, error) {
+	fmt.Println("Notification repo: get by user id")
+	where := `notification.user_id = ?`
+	if unreadOnly {
+		where += ` AND notification.is_read = 0`
+	}
+	rows, err := repo.DB.Query(`
+	SELECT
+	notification.id, notification.user_id, type, actor_id, post_id, comment_id, body, is_read,
+	notification.created AS notification_created,
+	IFNULL(user.login, '')
+	FROM notification
+	LEFT JOIN user ON user.id = notification.actor_id
+	WHERE `+where+`
+	ORDER BY notification.id DESC
+	LIMIT ? OFFSET ?`,
+		userID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*NotificationComplexData, 0, 10)
+	for rows.Next() {
+		data := &NotificationComplexData{}
+		err := rows.Scan(&data.Notification.ID, &data.Notification.UserID,
+			&data.Notification.Type, &data.Notification.ActorID,
+			&data.Notification.PostID, &data.Notification.CommentID,
+			&data.Notification.Body, &data.Notification.Read,
+			&data.Notification.Created, &data.User.Login)
+		if nil != err {
+			return nil, err
+		}
+		notifications = append(notifications, data)
+	}
+	return notifications, rows.Err()
+}
+
+func (repo *NotificationRepo) CountUnread(userID string) (int64, error) {
+	fmt.Println("Notification repo: count unread")
+	var unread int64
+	err := repo.DB.
+		QueryRow(`SELECT COUNT(*) FROM notification WHERE user_id = ? AND is_read = 0`, userID).
+		Scan(&unread)
+	if nil != err {
+		return 0, err
+	}
+	return unread, nil
+}
+
+// MarkRead marks every notification of the user when ids is empty
+func (repo *NotificationRepo) MarkRead(userID string, ids []uint64) (int64, error) {
+	fmt.Println("Notification repo: mark read")
+	query := `UPDATE notification SET is_read = 1 WHERE user_id = ? AND is_read = 0`
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		placeHolders := make([]string, 0, len(ids))
+		for _, id := range ids {
+			placeHolders = append(placeHolders, "?")
+			args = append(args, id)
+		}
+		query += ` AND id IN (` + strings.Join(placeHolders, ",") + `)`
+	}
+	result, err := repo.DB.Exec(query, args...)
+	if nil != err {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (repo *NotificationRepo) GetMuted(userID string) ([]string, error) {
+	fmt.Println("Notification repo: get muted")
+	rows, err := repo.DB.Query(`SELECT type FROM notification_mute WHERE user_id = ? ORDER BY type`, userID)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	muted := []string{}
+	for rows.Next() {
+		var notificationType string
+		err = rows.Scan(&notificationType)
+		if nil != err {
+			return nil, err
+		}
+		muted = append(muted, notificationType)
+	}
+	return muted, rows.Err()
+}
+
+// SetMuted replaces the muted types of the user
+func (repo *NotificationRepo) SetMuted(userID string, types []string) error {
+	fmt.Println("Notification repo: set muted")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`DELETE FROM notification_mute WHERE user_id = ?`, userID)
+	if nil != err {
+		return err
+	}
+	for _, notificationType := range types {
+		_, err = tx.Exec(`INSERT INTO notification_mute (user_id, type) VALUES (?, ?)`, userID, notificationType)
+		if nil != err {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNotificationAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewNotificationRepo(db)
+	notification := &Notification{
+		UserID:    "522cd619-841f-43d5-866d-f880e5f48d18",
+		Type:      NotificationPostReply,
+		ActorID:   "u2",
+		PostID:    "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+		CommentID: "c1",
+		Body:      "nice post",
+		Created:   "2022-11-10T11:24:44Z",
+	}
+
+	// success
+	mock.ExpectExec(`INSERT INTO notification .* WHERE NOT EXISTS \(SELECT 1 FROM notification_mute WHERE user_id = \? AND type = \?\)`).
+		WithArgs(notification.UserID, notification.Type, notification.ActorID, notification.PostID,
+			notification.CommentID, notification.Body, notification.Created, notification.UserID, notification.Type).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	isAdded, err := repo.Add(notification)
+	if err != nil || !isAdded {
+		t.Errorf("expected added notification, got %v %v", isAdded, err)
+		return
+	}
+
+	// muted
+	mock.ExpectExec(`INSERT INTO notification`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isAdded, err = repo.Add(notification)
+	if err != nil || isAdded {
+		t.Errorf("expected skipped notification, got %v %v", isAdded, err)
+		return
+	}
+
+	// db error
+	mock.ExpectExec(`INSERT INTO notification`).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.Add(notification)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestNotificationGetByUserId(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewNotificationRepo(db)
+	expected := []*NotificationComplexData{
+		{
+			Notification: Notification{ID: 5, UserID: "u1", Type: NotificationMention, ActorID: "u2",
+				PostID: "p1", CommentID: "c1", Body: "hi @mer", Created: "2022-11-10T11:24:44Z"},
+			User: User{Login: "bob"},
+		},
+	}
+	rows := sqlmock.NewRows([]string{"id", "user_id", "type", "actor_id", "post_id", "comment_id", "body", "is_read", "created", "login"}).
+		AddRow(5, "u1", NotificationMention, "u2", "p1", "c1", "hi @mer", false, "2022-11-10T11:24:44Z", "bob")
+
+	// success, unread only
+	mock.ExpectQuery(`FROM notification .* WHERE notification.user_id = \? AND notification.is_read = 0\s+ORDER BY notification.id DESC\s+LIMIT \? OFFSET \?`).
+		WithArgs("u1", 25, 50).
+		WillReturnRows(rows)
+	data, err := repo.GetByUserId("u1", true, 25, 50)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, data)
+		return
+	}
+
+	// db error
+	mock.ExpectQuery(`FROM notification`).
+		WithArgs("u1", 25, 0).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.GetByUserId("u1", false, 25, 0)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestNotificationMarkRead(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewNotificationRepo(db)
+
+	// the given ids
+	mock.ExpectExec(`UPDATE notification SET is_read = 1 WHERE user_id = \? AND is_read = 0 AND id IN \(\?,\?\)`).
+		WithArgs("u1", uint64(3), uint64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	marked, err := repo.MarkRead("u1", []uint64{3, 4})
+	if err != nil || marked != 2 {
+		t.Errorf("expected 2 marked, got %d %v", marked, err)
+		return
+	}
+
+	// all
+	mock.ExpectExec(`UPDATE notification SET is_read = 1 WHERE user_id = \? AND is_read = 0$`).
+		WithArgs("u1").
+		WillReturnResult(sqlmock.NewResult(0, 7))
+	marked, err = repo.MarkRead("u1", nil)
+	if err != nil || marked != 7 {
+		t.Errorf("expected 7 marked, got %d %v", marked, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestNotificationSetMuted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewNotificationRepo(db)
+
+	// success
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM notification_mute WHERE user_id = \?`).
+		WithArgs("u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO notification_mute`).
+		WithArgs("u1", NotificationMention).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = repo.SetMuted("u1", []string{NotificationMention})
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// insert error rolls back
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM notification_mute`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO notification_mute`).
+		WillReturnError(fmt.Errorf("db_error"))
+	mock.ExpectRollback()
+	err = repo.SetMuted("u1", []string{NotificationModAction})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	NotificationBodyMaxLen = 200
+	MaxMentions            = 10
+)
+
+var (
+	notificationTypes = map[string]struct{}{
+		NotificationPostReply:    {},
+		NotificationCommentReply: {},
+		NotificationMention:      {},
+		NotificationModAction:    {},
+		NotificationFollow:       {},
+	}
+
+	// mentionRe matches @login and u/login which don't continue a word,
+	// an email or a path
+	mentionRe = regexp.MustCompile(`(?:^|[^A-Za-z0-9_/@.-])(?:@|u/)([A-Za-z0-9_-]{1,32})`)
+
+	modActionNotices = map[string]string{
+		ModLogRemovePost:     "removed your post",
+		ModLogApprovePost:    "approved your post",
+		ModLogLockPost:       "locked your post",
+		ModLogUnlockPost:     "unlocked your post",
+		ModLogPinPost:        "pinned your post",
+		ModLogRemoveComment:  "removed your comment",
+		ModLogApproveComment: "approved your comment",
+	}
+)
+
+// parseMentions returns the mentioned logins in the order of the text,
+// each once and at most MaxMentions of them
+func parseMentions(text string) []string {
+	logins := []string{}
+	seen := map[string]struct{}{}
+	for _, match := range mentionRe.FindAllStringSubmatch(text, -1) {
+		login := match[1]
+		if _, ok := seen[login]; ok {
+			continue
+		}
+		seen[login] = struct{}{}
+		logins = append(logins, login)
+		if len(logins) == MaxMentions {
+			break
+		}
+	}
+	return logins
+}
+
+// notify doesn't fail the request, a lost notification is not worth an
+// error for the author of the change
+func (h *PostsHandler) notify(notification *Notification) {
+	if h.NotificationRepo == nil {
+		return
+	}
+	notification.Created = h.TimeGetter.GetCreated()
+	_, err := h.NotificationRepo.Add(notification)
+	if nil != err {
+		fmt.Println("can't add notification", notification.Type, err)
+	}
+}
+
+// notifyComment tells the author of the parent comment, or of the post for
+// the top level comments, and the mentioned users. Nobody is notified
+// twice, and nobody about their own comment.
+func (h *PostsHandler) notifyComment(comment *Comment, parent *Comment, post *PostComplexData) {
+	if h.NotificationRepo == nil || comment.Removed || h.isShadowbanned(comment.UserId) {
+		return
+	}
+	notified := map[string]struct{}{comment.UserId: {}}
+	body := truncateRunes(comment.Body, NotificationBodyMaxLen)
+	reply := &Notification{
+		UserID:    post.Post.UserID,
+		Type:      NotificationPostReply,
+		ActorID:   comment.UserId,
+		PostID:    post.Post.ID,
+		CommentID: comment.ID,
+		Body:      body,
+	}
+	if parent != nil {
+		reply.UserID = parent.UserId
+		reply.Type = NotificationCommentReply
+	}
+	if _, ok := notified[reply.UserID]; !ok {
+		notified[reply.UserID] = struct{}{}
+		h.notify(reply)
+	}
+	h.notifyMentions(comment.Body, notified, &Notification{
+		ActorID:   comment.UserId,
+		PostID:    post.Post.ID,
+		CommentID: comment.ID,
+		Body:      body,
+	})
+}
+
+func (h *PostsHandler) notifyPost(post *Post) {
+	if h.NotificationRepo == nil || post.Removed || h.isShadowbanned(post.UserID) {
+		return
+	}
+	h.notifyMentions(post.Title+"\n"+post.Description, map[string]struct{}{post.UserID: {}}, &Notification{
+		ActorID: post.UserID,
+		PostID:  post.ID,
+		Body:    truncateRunes(post.Title, NotificationBodyMaxLen),
+	})
+}
+
+func (h *PostsHandler) notifyMentions(text string, notified map[string]struct{}, template *Notification) {
+	for _, login := range parseMentions(text) {
+		user, err := h.UserRepo.GetByLogin(login)
+		if err == sql.ErrNoRows {
+			continue
+		} else if nil != err {
+			fmt.Println("can't get mentioned user", err)
+			continue
+		}
+		if _, ok := notified[user.ID]; ok {
+			continue
+		}
+		// the users who blocked the author, or were blocked by them, are
+		// not told about the mention, like they can't get a reply
+		if h.BlockRepo != nil {
+			blocked, err := h.BlockRepo.IsBlocked(user.ID, template.ActorID)
+			if nil != err {
+				fmt.Println("can't check block of mentioned user", err)
+				continue
+			}
+			if blocked {
+				continue
+			}
+		}
+		notified[user.ID] = struct{}{}
+		mention := *template
+		mention.UserID = user.ID
+		mention.Type = NotificationMention
+		h.notify(&mention)
+	}
+}
+
+// notifyModAction tells the author what a moderator did to their post or
+// comment, the reason included
+func (h *PostsHandler) notifyModAction(sess *Session, logAction string, authorID string, postID string, commentID string, reason string) {
+	notice, ok := modActionNotices[logAction]
+	if !ok || authorID == sess.UserID {
+		return
+	}
+	body := "a moderator " + notice
+	if reason = strings.TrimSpace(reason); reason != "" {
+		body += ": " + reason
+	}
+	h.notify(&Notification{
+		UserID:    authorID,
+		Type:      NotificationModAction,
+		PostID:    postID,
+		CommentID: commentID,
+		Body:      truncateRunes(body, NotificationBodyMaxLen),
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Notifications lists the notifications of the current user, newest
+// first, with ?unread=true for the unread ones only
+func (h *UserHandler) Notifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	data, err := h.NotificationRepo.GetByUserId(sess.UserID, unreadOnly, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get notifications", err)
+		jsonError(w, http.StatusInternalServerError, "can't get notifications")
+		return
+	}
+	counts, err := h.unreadCounts(sess.UserID)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't count unread notifications")
+		return
+	}
+	counts.Notifications = h.DTOConverter.NotificationsConvertToDTO(data)
+	jsonResponse(w, counts)
+}
+
+// ReadNotifications marks the given ids, or all of them with "all", and
+// returns the unread count left
+func (h *UserHandler) ReadNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &NotificationsReadDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	if requestData.All == (len(requestData.IDs) > 0) {
+		jsonError(w, http.StatusBadRequest, "either ids or all is required")
+		return
+	}
+	if len(requestData.IDs) > ListMaxLimit {
+		jsonError(w, http.StatusBadRequest, "too many ids")
+		return
+	}
+
+	_, err = h.NotificationRepo.MarkRead(sess.UserID, requestData.IDs)
+	if nil != err {
+		fmt.Println("can't mark notifications read", err)
+		jsonError(w, http.StatusInternalServerError, "can't mark notifications read")
+		return
+	}
+	counts, err := h.unreadCounts(sess.UserID)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't count unread notifications")
+		return
+	}
+	jsonResponse(w, counts)
+}
+
+// unreadCounts feeds the unread messages into the notification count
+func (h *UserHandler) unreadCounts(userID string) (*NotificationsDTO, error) {
+	unread, err := h.NotificationRepo.CountUnread(userID)
+	if nil != err {
+		fmt.Println("can't count unread notifications", err)
+		return nil, err
+	}
+	unreadMessages, err := h.MessageRepo.CountUnread(userID)
+	if nil != err {
+		fmt.Println("can't count unread messages", err)
+		return nil, err
+	}
+	return &NotificationsDTO{
+		Unread:         unread + unreadMessages,
+		UnreadMessages: unreadMessages,
+		Notifications:  []*NotificationDTO{},
+	}, nil
+}
+
+func (h *UserHandler) NotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	muted, err := h.NotificationRepo.GetMuted(sess.UserID)
+	if nil != err {
+		fmt.Println("can't get muted notifications", err)
+		jsonError(w, http.StatusInternalServerError, "can't get notification preferences")
+		return
+	}
+	jsonResponse(w, &NotificationPreferencesDTO{Muted: muted})
+}
+
+// SaveNotificationPreferences replaces the muted types, the notifications
+// of a muted type are not created at all
+func (h *UserHandler) SaveNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &NotificationPreferencesDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	muted := []string{}
+	seen := map[string]struct{}{}
+	for _, notificationType := range requestData.Muted {
+		if _, ok := notificationTypes[notificationType]; !ok {
+			jsonError(w, http.StatusBadRequest, "unknown notification type: "+notificationType)
+			return
+		}
+		if _, ok := seen[notificationType]; !ok {
+			seen[notificationType] = struct{}{}
+			muted = append(muted, notificationType)
+		}
+	}
+
+	err = h.NotificationRepo.SetMuted(sess.UserID, muted)
+	if nil != err {
+		fmt.Println("can't save muted notifications", err)
+		jsonError(w, http.StatusInternalServerError, "can't save notification preferences")
+		return
+	}
+	jsonResponse(w, &NotificationPreferencesDTO{Muted: muted})
+}
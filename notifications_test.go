@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected []string
+	}{
+		{"hi @mer and u/bob, @mer again", []string{"mer", "bob"}},
+		{"@start of text", []string{"start"}},
+		{"mail me at me@example.com or see /u/path and r/u/nope", []string{}},
+		{"no mentions here", []string{}},
+	}
+	for _, item := range cases {
+		logins := parseMentions(item.text)
+		if !reflect.DeepEqual(logins, item.expected) {
+			t.Errorf("bad mentions of %q: %v", item.text, logins)
+		}
+	}
+
+	many := ""
+	for i := 0; i < MaxMentions+5; i++ {
+		many += fmt.Sprintf("@user%d ", i)
+	}
+	if logins := parseMentions(many); len(logins) != MaxMentions {
+		t.Errorf("expected %d mentions, got %d", MaxMentions, len(logins))
+	}
+}
+
+func TestAddCommentNotifications(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
+	userRepoMock := NewMockUserRepoI(ctrl)
+	notificationRepoMock := NewMockNotificationRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:        postsRepoMock,
+		DictionaryRepo:   dictionaryRepoMock,
+		DTOConverter:     dtoConverterMock,
+		CommentRepo:      commentRepoMock,
+		TimeGetter:       timeGetterMock,
+		UUIDGetter:       uuidGetterMock,
+		BanRepo:          banRepoMock,
+		Automod:          automodMock,
+		UserRepo:         userRepoMock,
+		NotificationRepo: notificationRepoMock,
+		BlockRepo:        blockRepoMock,
+	}
+	dictionaryRepoMock.EXPECT().GetCategoryByName(gomock.Any()).Return(&Category{ID: 1, Name: "fashion"}, nil).AnyTimes()
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	blockRepoMock.EXPECT().IsBlocked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	automodMock.EXPECT().Check(gomock.Any()).Return(nil, nil).AnyTimes()
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z").AnyTimes()
+	dtoConverterMock.EXPECT().PostConvertToDTO(gomock.Any(), gomock.Any()).Return(postsDTO[0], nil).AnyTimes()
+
+	data := multipleComplexData[0]
+	postID := data.Post.ID
+	commenter := &Session{ID: "1", UserID: "u2"}
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+postID, strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": postID})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, commenter))
+	}
+	parent := &Comment{ID: "c1", PostId: postID, UserId: "u3", Body: "first"}
+
+	//reply to a comment notifies its author and the mentioned users once
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	commentRepoMock.EXPECT().GetById("c1").Return(parent, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return("c2")
+	commentRepoMock.EXPECT().Add(gomock.Any()).DoAndReturn(func(comment *Comment) (*string, error) {
+		if comment.ParentID != "c1" {
+			t.Errorf("bad parent: %q", comment.ParentID)
+		}
+		return &comment.ID, nil
+	})
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	notificationRepoMock.EXPECT().Add(&Notification{
+		UserID:    "u3",
+		Type:      NotificationCommentReply,
+		ActorID:   "u2",
+		PostID:    postID,
+		CommentID: "c2",
+		Body:      "agree @mer, @ann and @u3",
+		Created:   "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	userRepoMock.EXPECT().GetByLogin("mer").Return(&User{ID: data.Post.UserID, Login: "mer"}, nil)
+	userRepoMock.EXPECT().GetByLogin("ann").Return(nil, sql.ErrNoRows)
+	userRepoMock.EXPECT().GetByLogin("u3").Return(&User{ID: "u3", Login: "u3"}, nil)
+	notificationRepoMock.EXPECT().Add(&Notification{
+		UserID:    data.Post.UserID,
+		Type:      NotificationMention,
+		ActorID:   "u2",
+		PostID:    postID,
+		CommentID: "c2",
+		Body:      "agree @mer, @ann and @u3",
+		Created:   "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	w := httptest.NewRecorder()
+	service.AddComment(w, newRequest(`{"comment":"agree @mer, @ann and @u3","parent_id":"c1"}`))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//top level comment notifies the post author
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return("c3")
+	commentRepoMock.EXPECT().Add(gomock.Any()).Return(nil, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	notificationRepoMock.EXPECT().Add(gomock.Any()).DoAndReturn(func(notification *Notification) (bool, error) {
+		if notification.Type != NotificationPostReply || notification.UserID != data.Post.UserID {
+			t.Errorf("bad notification: %+v", notification)
+		}
+		return false, nil
+	})
+	w = httptest.NewRecorder()
+	service.AddComment(w, newRequest(`{"comment":"nice"}`))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//shadowbanned commenter notifies nobody
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return("c4")
+	commentRepoMock.EXPECT().Add(gomock.Any()).Return(nil, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{"u2": {}}, nil)
+	w = httptest.NewRecorder()
+	service.AddComment(w, newRequest(`{"comment":"nice @mer"}`))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//parent from another post
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	commentRepoMock.EXPECT().GetById("c9").Return(&Comment{ID: "c9", PostId: "other"}, nil)
+	w = httptest.NewRecorder()
+	service.AddComment(w, newRequest(`{"comment":"nice","parent_id":"c9"}`))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestMentionBlocked(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	notificationRepoMock := NewMockNotificationRepoI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		UserRepo:         userRepoMock,
+		NotificationRepo: notificationRepoMock,
+		BlockRepo:        blockRepoMock,
+		TimeGetter:       timeGetterMock,
+	}
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z").AnyTimes()
+
+	//the blocker and the failed check are skipped, the others are told
+	userRepoMock.EXPECT().GetByLogin("mer").Return(&User{ID: "u1", Login: "mer"}, nil)
+	userRepoMock.EXPECT().GetByLogin("ann").Return(&User{ID: "u3", Login: "ann"}, nil)
+	userRepoMock.EXPECT().GetByLogin("bob").Return(&User{ID: "u4", Login: "bob"}, nil)
+	blockRepoMock.EXPECT().IsBlocked("u1", "u2").Return(true, nil)
+	blockRepoMock.EXPECT().IsBlocked("u3", "u2").Return(false, fmt.Errorf("db error"))
+	blockRepoMock.EXPECT().IsBlocked("u4", "u2").Return(false, nil)
+	notificationRepoMock.EXPECT().Add(&Notification{
+		UserID:  "u4",
+		Type:    NotificationMention,
+		ActorID: "u2",
+		PostID:  "p1",
+		Body:    "hi",
+		Created: "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	service.notifyMentions("@mer @ann @bob", map[string]struct{}{"u2": {}}, &Notification{
+		ActorID: "u2",
+		PostID:  "p1",
+		Body:    "hi",
+	})
+}
+
+func TestModActionNotification(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	modLogRepoMock := NewMockModLogRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	notificationRepoMock := NewMockNotificationRepoI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:        postsRepoMock,
+		ModLogRepo:       modLogRepoMock,
+		ReportRepo:       reportRepoMock,
+		TimeGetter:       timeGetterMock,
+		NotificationRepo: notificationRepoMock,
+	}
+	postID := multipleComplexData[0].Post.ID
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+postID+"/lock", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": postID})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, modSess))
+	}
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z").AnyTimes()
+	modLogRepoMock.EXPECT().Add(gomock.Any()).Return(nil).AnyTimes()
+
+	//lock tells the author with the reason, the moderator stays anonymous
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().SetLocked(postID, true).Return(true, nil)
+	notificationRepoMock.EXPECT().Add(&Notification{
+		UserID:  multipleComplexData[0].Post.UserID,
+		Type:    NotificationModAction,
+		PostID:  postID,
+		Body:    "a moderator locked your post: off topic",
+		Created: "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	w := httptest.NewRecorder()
+	service.LockPost(w, newRequest(`{"reason":"off topic"}`))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//nothing changed, nothing to tell
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().SetLocked(postID, true).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.LockPost(w, newRequest(""))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestNotificationsHandlers(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notificationRepoMock := NewMockNotificationRepoI(ctrl)
+	messageRepoMock := NewMockMessageRepoI(ctrl)
+	service := &UserHandler{
+		NotificationRepo: notificationRepoMock,
+		MessageRepo:      messageRepoMock,
+		DTOConverter:     &DTOConverter{},
+	}
+	newRequest := func(method string, url string, body string) *http.Request {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+
+	//list with the unread count
+	notificationRepoMock.EXPECT().GetByUserId(sess.UserID, true, 10, 0).Return([]*NotificationComplexData{
+		{
+			Notification: Notification{ID: 5, UserID: sess.UserID, Type: NotificationMention, ActorID: "u2",
+				PostID: "p1", CommentID: "c1", Body: "hi @mer", Created: "2022-11-10T11:24:44Z"},
+			User: User{Login: "bob"},
+		},
+		{
+			Notification: Notification{ID: 4, UserID: sess.UserID, Type: NotificationModAction,
+				PostID: "p1", Body: "a moderator locked your post", Read: true, Created: "2022-11-10T11:20:00Z"},
+		},
+	}, nil)
+	notificationRepoMock.EXPECT().CountUnread(sess.UserID).Return(int64(1), nil)
+	messageRepoMock.EXPECT().CountUnread(sess.UserID).Return(int64(2), nil)
+	w := httptest.NewRecorder()
+	service.Notifications(w, newRequest("GET", "/api/notifications?unread=true&limit=10", ""))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `{"unread":3,"unread_messages":2,"notifications":[` +
+		`{"id":5,"type":"mention","actor":{"username":"bob","id":"u2"},"post_id":"p1","comment_id":"c1","body":"hi @mer","read":false,"created":"2022-11-10T11:24:44Z"},` +
+		`{"id":4,"type":"mod_action","post_id":"p1","body":"a moderator locked your post","read":true,"created":"2022-11-10T11:20:00Z"}]}`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//mark read
+	notificationRepoMock.EXPECT().MarkRead(sess.UserID, []uint64{5}).Return(int64(1), nil)
+	notificationRepoMock.EXPECT().CountUnread(sess.UserID).Return(int64(0), nil)
+	messageRepoMock.EXPECT().CountUnread(sess.UserID).Return(int64(0), nil)
+	w = httptest.NewRecorder()
+	service.ReadNotifications(w, newRequest("POST", "/api/notifications/read", `{"ids":[5]}`))
+	body, _ = io.ReadAll(w.Result().Body)
+	if string(body) != `{"unread":0,"unread_messages":0,"notifications":[]}` {
+		t.Errorf("bad response: %s", body)
+		return
+	}
+
+	//ids and all together
+	w = httptest.NewRecorder()
+	service.ReadNotifications(w, newRequest("POST", "/api/notifications/read", `{"ids":[5],"all":true}`))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//preferences
+	notificationRepoMock.EXPECT().SetMuted(sess.UserID, []string{NotificationMention}).Return(nil)
+	w = httptest.NewRecorder()
+	service.SaveNotificationPreferences(w, newRequest("POST", "/api/notifications/preferences", `{"muted":["mention","mention"]}`))
+	body, _ = io.ReadAll(w.Result().Body)
+	if string(body) != `{"muted":["mention"]}` {
+		t.Errorf("bad response: %s", body)
+		return
+	}
+	w = httptest.NewRecorder()
+	service.SaveNotificationPreferences(w, newRequest("POST", "/api/notifications/preferences", `{"muted":["spam"]}`))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+	notificationRepoMock.EXPECT().GetMuted(sess.UserID).Return(nil, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.NotificationPreferences(w, newRequest("GET", "/api/notifications/preferences", ""))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
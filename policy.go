@@ -0,0 +1,74 @@
+package main
+
+type Action string
+
+const (
+	ActionDeletePost     Action = "delete_post"
+	ActionDeleteComment  Action = "delete_comment"
+	ActionRemovePost     Action = "remove_post"
+	ActionRemoveComment  Action = "remove_comment"
+	ActionPinPost        Action = "pin_post"
+	ActionLockPost       Action = "lock_post"
+	ActionViewModLog     Action = "view_mod_log"
+	ActionViewReports    Action = "view_reports"
+	ActionBanUser        Action = "ban_user"
+	ActionManageAutomod  Action = "manage_automod"
+	ActionManageRoles    Action = "manage_roles"
+	ActionManageCategory Action = "manage_category"
+)
+
+// Resource is what an action is applied to: the category it belongs
+// to and its author, if any.
+type Resource struct {
+	CategoryID uint32
+	OwnerID    string
+}
+
+var (
+	authorActions = map[Action]struct{}{
+		ActionDeletePost:    {},
+		ActionDeleteComment: {},
+	}
+	moderatorActions = map[Action]struct{}{
+		ActionDeletePost:     {},
+		ActionDeleteComment:  {},
+		ActionRemovePost:     {},
+		ActionRemoveComment:  {},
+		ActionPinPost:        {},
+		ActionLockPost:       {},
+		ActionViewModLog:     {},
+		ActionViewReports:    {},
+		ActionBanUser:        {},
+		ActionManageAutomod:  {},
+		ActionManageCategory: {},
+	}
+)
+
+// Can is the single place handlers ask whether a session may do an action:
+// admins may do everything, moderators act inside their categories and
+// authors may delete their own content.
+func Can(sess *Session, action Action, resource *Resource) bool {
+	if sess == nil {
+		return false
+	}
+	if sess.IsAdmin() {
+		return true
+	}
+	if resource == nil {
+		return false
+	}
+	if _, ok := moderatorActions[action]; ok && sess.IsModerator(resource.CategoryID) {
+		return true
+	}
+	if _, ok := authorActions[action]; ok && resource.OwnerID != "" && resource.OwnerID == sess.UserID {
+		return true
+	}
+	return false
+}
+
+func PostResource(post *Post) *Resource {
+	return &Resource{
+		CategoryID: uint32(post.CategoryID),
+		OwnerID:    post.UserID,
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	author := &Session{UserID: "author"}
+	moderator := &Session{UserID: "mod", Roles: []*Role{{Name: RoleModerator, CategoryID: 1}}}
+	admin := &Session{UserID: "admin", Roles: []*Role{{Name: RoleAdmin}}}
+	inCategory := &Resource{CategoryID: 1, OwnerID: "author"}
+	otherCategory := &Resource{CategoryID: 2, OwnerID: "author"}
+
+	cases := []struct {
+		sess     *Session
+		action   Action
+		resource *Resource
+		expected bool
+	}{
+		{author, ActionDeletePost, inCategory, true},
+		{author, ActionPinPost, inCategory, false},
+		{&Session{UserID: "other"}, ActionDeletePost, inCategory, false},
+		{moderator, ActionDeletePost, inCategory, true},
+		{moderator, ActionLockPost, inCategory, true},
+		{moderator, ActionBanUser, otherCategory, false},
+		{moderator, ActionManageRoles, inCategory, false},
+		{admin, ActionManageRoles, nil, true},
+		{admin, ActionPinPost, otherCategory, true},
+		{nil, ActionDeletePost, inCategory, false},
+	}
+	for i, c := range cases {
+		if have := Can(c.sess, c.action, c.resource); have != c.expected {
+			t.Errorf("case %d: %s is not matched; want: %v; have: %v", i, c.action, c.expected, have)
+		}
+	}
+}
@@ -13,40 +13,143 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// the markdown of the posts and the comments is rendered for every reader,
+// so its length is limited like the one of the messages
+const (
+	PostTextMaxLen = 40000
+	CommentMaxLen  = 10000
+)
+
 type PostRepoI interface {
 	GetAll() ([]*PostComplexData, error)
+	GetAllPaged(opts *ListOptions) ([]*PostComplexData, error)
+	GetFeed(userID string, since string, opts *ListOptions) ([]*PostComplexData, error)
+	GetFollowedFeed(userID string, opts *ListOptions) ([]*PostComplexData, error)
 	GetById(id string) (*PostComplexData, error)
+	GetByIds(ids []string) ([]*PostComplexData, error)
 	GetByCategoryName(categoryName string) ([]*PostComplexData, error)
 	GetByUserLogin(userLogin string) ([]*PostComplexData, error)
+	GetByUserId(userID string, opts *ListOptions) ([]*PostComplexData, error)
+	GetVotedByUserId(userID string, vote int32, opts *ListOptions) ([]*PostComplexData, error)
+	GetRecentByURL(categoryID uint, url string, since string) (*PostComplexData, error)
 	Add(post *Post) (*string, error)
-	Delete(id string) (bool, error)
-	UpVote(id string) (bool, error)
-	DownVote(id string) (bool, error)
+	Delete(id string, deletedAt string, deletedBy string) (bool, error)
+	Restore(id string, userID string, since string) (bool, error)
+	PurgeDeleted(before string) (int64, []string, error)
+	SetRemoved(id string, removed bool) (bool, error)
+	SetLocked(id string, locked bool) (bool, error)
+	Pin(id string, categoryID uint) (bool, error)
+	Unpin(id string) (bool, error)
 }
 
 type CommentRepoI interface {
 	Add(comment *Comment) (*string, error)
-	Delete(id string) (bool, error)
+	GetById(id string) (*Comment, error)
+	GetByIds(ids []string) ([]*CommentComplexData, error)
+	GetByUserId(userID string, limit int, offset int) ([]*CommentComplexData, error)
+	Delete(id string, deletedAt string, deletedBy string) (bool, error)
+	Restore(id string, postID string, userID string, since string) (bool, error)
+	PurgeDeleted(before string) (int64, error)
+	SetRemoved(id string, removed bool) (bool, error)
 	GetCommentsByPostIds(postIds []string) (map[string][]*CommentComplexData, error)
 }
 
 type VoteRepoI interface {
 	GetVotesByPostIds(postIds []string) (map[string][]*Vote, error)
+	Vote(postID string, userID string, vote int32) (bool, error)
+	VoteComment(commentID string, userID string, vote int32) (bool, error)
+}
+
+type SavedRepoI interface {
+	Add(item *SavedItem) (bool, error)
+	Delete(userID string, itemType string, itemID string) (bool, error)
+	GetByUserId(userID string, itemType string, limit int, offset int) ([]*SavedItem, error)
+	GetSavedIds(userID string, ids []string) (map[string]bool, error)
 }
 
 type DictionaryRepoI interface {
 	GetCategoryByName(name string) (*Category, error)
+	GetCategories() ([]*CategoryComplexData, error)
+	AddCategory(category *Category) (uint32, error)
+	SetRequireVerified(categoryID uint32, requireVerified bool) error
+}
+
+type SubscriptionRepoI interface {
+	Subscribe(userID string, categoryID uint32, created string) (bool, error)
+	Unsubscribe(userID string, categoryID uint32) (bool, error)
+	HasSubscriptions(userID string) (bool, error)
+}
+
+type ModLogRepoI interface {
+	Add(entry *ModLogEntry) error
+	GetByCategoryId(categoryID uint32, limit int, offset int) ([]*ModLogComplexData, error)
+}
+
+type ReportRepoI interface {
+	Add(report *Report) (bool, error)
+	GetQueue(categoryID uint32, limit int, offset int) ([]*ReportQueueItem, error)
+	Resolve(targetType string, targetID string) (int64, error)
+	ResolveByAuthor(authorID string, categoryID uint32) (int64, error)
+}
+
+type AutomodRepoI interface {
+	GetByCategoryId(categoryID uint32) (*AutomodSettings, error)
+	Save(settings *AutomodSettings) error
+}
+
+type AutomodI interface {
+	Check(sub *AutomodSubmission) ([]*AutomodHit, error)
+}
+
+type LinkPreviewRepoI interface {
+	GetPending(limit int) ([]*LinkPreview, error)
+	Save(preview *LinkPreview) error
+}
+
+type LinkFetcherI interface {
+	Fetch(pageURL string) (*LinkPreview, error)
+}
+
+// SearchIndex is told about every change of the visible posts and
+// comments, an index that follows the tables itself may ignore it
+type SearchIndex interface {
+	Index(doc *SearchDoc) error
+	Remove(target string, id string) error
+	Search(query *SearchQuery) ([]*SearchHit, error)
+}
+
+// BlobStore keeps the uploaded files, Get returns ErrBlobNotFound for
+// missing keys
+type BlobStore interface {
+	Put(key string, contentType string, data []byte) error
+	Get(key string) (*Blob, error)
+	Delete(key string) error
+}
+
+// MarkdownRenderer turns the markdown of the posts and the comments into
+// HTML which is safe to put into the page as is
+type MarkdownRenderer interface {
+	Render(source string) string
 }
 
 type DTOConverterI interface {
-	PostConvertToDTO(data *PostComplexData) (*PostDTO, error)
+	PostConvertToDTO(data *PostComplexData, sess *Session) (*PostDTO, error)
 	CommentsConvertToDTO(data []*CommentComplexData) []*CommentDTO
 	VotesConvertToDTO(data []*Vote) []*VoteDTO
-	PostsConvertToDTO(data []*PostComplexData) ([]*PostDTO, error)
+	CategoriesConvertToDTO(data []*CategoryComplexData) []*CategoryDTO
+	ModLogConvertToDTO(data []*ModLogComplexData) []*ModLogEntryDTO
+	ReportQueueConvertToDTO(data []*ReportQueueItem) []*ReportQueueItemDTO
+	NotificationsConvertToDTO(data []*NotificationComplexData) []*NotificationDTO
+	MessagesConvertToDTO(data []*Message) []*MessageDTO
+	ConversationsConvertToDTO(data []*Conversation) []*ConversationDTO
+	FollowsConvertToDTO(data []*FollowComplexData) []*FollowDTO
+	BlocksConvertToDTO(data []*BlockComplexData) []*BlockDTO
+	PostsConvertToDTO(data []*PostComplexData, sess *Session) ([]*PostDTO, error)
 }
 
 type TimeGetterI interface {
 	GetCreated() string
+	Now() time.Time
 }
 
 type TimeGetter struct{}
@@ -55,6 +158,17 @@ func (timer *TimeGetter) GetCreated() string {
 	return time.Now().Format(time.RFC3339)
 }
 
+func (timer *TimeGetter) Now() time.Time {
+	return time.Now()
+}
+
+// EventHubI fans out the changes of the posts to the open streams
+type EventHubI interface {
+	Publish(event *Event)
+	Subscribe(postID string, lastEventID string) (*EventClient, []*Event, bool)
+	Unsubscribe(client *EventClient)
+}
+
 type UUIDGetterI interface {
 	GetUUID() string
 }
@@ -66,50 +180,110 @@ func (uuidGetter *UUIDGetter) GetUUID() string {
 }
 
 type PostsHandler struct {
-	PostsRepo      PostRepoI
-	DTOConverter   DTOConverterI
-	DictionaryRepo DictionaryRepoI
-	CommentRepo    CommentRepoI
-	TimeGetter     TimeGetterI
-	UUIDGetter     UUIDGetterI
-	Logger         *log.Logger
+	PostsRepo        PostRepoI
+	DTOConverter     DTOConverterI
+	DictionaryRepo   DictionaryRepoI
+	CommentRepo      CommentRepoI
+	VoteRepo         VoteRepoI
+	UserRepo         UserRepoI
+	SubscriptionRepo SubscriptionRepoI
+	ModLogRepo       ModLogRepoI
+	ReportRepo       ReportRepoI
+	BanRepo          BanRepoI
+	Automod          AutomodI
+	BlobStore        BlobStore
+	SearchIndex      SearchIndex
+	Events           EventHubI
+	NotificationRepo NotificationRepoI
+	SavedRepo        SavedRepoI
+	BlockRepo        BlockRepoI
+	StreamHeartbeat  time.Duration
+	TimeGetter       TimeGetterI
+	UUIDGetter       UUIDGetterI
+	Logger           *log.Logger
 }
 
 var ScoreDefault uint32 = 1
 
 func NewPostsHandler(db *sql.DB) *PostsHandler {
 	commentRepo := NewCommentRepo(db)
+	voteRepo := NewVoteRepo(db)
+	savedRepo := NewSavedRepo(db)
+	blockRepo := NewBlockRepo(db)
 	return &PostsHandler{
 		PostsRepo: NewPostsRepo(db),
 		DTOConverter: &DTOConverter{
 			CommentRepo: commentRepo,
-			VoteRepo:    NewVoteRepo(db),
+			VoteRepo:    voteRepo,
+			SavedRepo:   savedRepo,
+			BlockRepo:   blockRepo,
+			Markdown:    NewMarkdownCache(),
 		},
-		DictionaryRepo: NewDictionaryRepo(db),
-		CommentRepo:    commentRepo,
-		TimeGetter:     &TimeGetter{},
-		UUIDGetter:     &UUIDGetter{},
-		Logger:         nil,
+		DictionaryRepo:   NewDictionaryRepo(db),
+		CommentRepo:      commentRepo,
+		VoteRepo:         voteRepo,
+		UserRepo:         NewUserRepo(db),
+		SubscriptionRepo: NewSubscriptionRepo(db),
+		ModLogRepo:       NewModLogRepo(db),
+		ReportRepo:       NewReportRepo(db),
+		BanRepo:          NewBanRepo(db),
+		Automod:          NewAutomod(db),
+		BlobStore:        NewBlobStore(),
+		SearchIndex:      NewSearchIndex(db),
+		Events:           NewEventHub(),
+		NotificationRepo: NewNotificationRepo(db),
+		SavedRepo:        savedRepo,
+		BlockRepo:        blockRepo,
+		TimeGetter:       &TimeGetter{},
+		UUIDGetter:       &UUIDGetter{},
+		Logger:           nil,
 	}
 }
 
+// GetById takes ?comment_sort, the best comments first by default
 func (h *PostsHandler) GetById(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["POST_ID"]
 	fmt.Printf("param: %#v", params)
-	data, err := h.PostsRepo.GetById(id)
+	commentSort, err := CommentSortFromRequest(r)
 	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := h.PostsRepo.GetById(id)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
 		fmt.Println("can't get post by id", err)
 		jsonError(w, http.StatusInternalServerError, "can't get post by id")
 		return
 	}
+	// removed posts stay visible to the moderators of the category only
+	sess, _ := SessionFromContext(r.Context())
+	if data.Post.Removed {
+		if !Can(sess, ActionRemovePost, PostResource(&data.Post)) {
+			jsonError(w, http.StatusNotFound, "post not found")
+			return
+		}
+	}
 
-	postDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	postDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert post to dto")
 		return
 	}
+	visible, err := hideShadowbanned(h.BanRepo, sess, []*PostDTO{postDTO})
+	if err != nil {
+		fmt.Println("can't hide shadowbanned post", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	} else if len(visible) == 0 {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	}
+	postDTO.Comments = sortComments(postDTO.Comments, commentSort)
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postDTO)
@@ -126,12 +300,19 @@ func (h *PostsHandler) GetByCategoryName(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data)
+	sess, _ := SessionFromContext(r.Context())
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert posts to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if err != nil {
+		fmt.Println("can't hide shadowbanned posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postsDTO)
@@ -145,12 +326,72 @@ func (h *PostsHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data)
+	sess, _ := SessionFromContext(r.Context())
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert posts to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if err != nil {
+		fmt.Println("can't hide shadowbanned posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, postsDTO)
+}
+
+// FeedHotWindow is how old the posts of the hot feed may be
+var FeedHotWindow = 7 * 24 * time.Hour
+
+// Feed lists the posts of the subscribed categories, anonymous users and
+// users without subscriptions get the global listing
+func (h *PostsHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hasSubscriptions := false
+	sess, err := SessionFromContext(r.Context())
+	if nil == err {
+		hasSubscriptions, err = h.SubscriptionRepo.HasSubscriptions(sess.UserID)
+		if nil != err {
+			fmt.Println("can't check subscriptions", err)
+			jsonError(w, http.StatusInternalServerError, "can't check subscriptions")
+			return
+		}
+	}
+
+	var data []*PostComplexData
+	if hasSubscriptions {
+		since := h.TimeGetter.Now().Add(-FeedHotWindow).Format(time.RFC3339)
+		data, err = h.PostsRepo.GetFeed(sess.UserID, since, opts)
+	} else {
+		data, err = h.PostsRepo.GetAllPaged(opts)
+	}
+	if nil != err {
+		fmt.Println("can't get feed", err)
+		jsonError(w, http.StatusInternalServerError, "DB err")
+		return
+	}
+
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
+	if err != nil {
+		fmt.Println("can't convert posts to dto", err)
+		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
+		return
+	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if err != nil {
+		fmt.Println("can't hide shadowbanned posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postsDTO)
@@ -164,17 +405,41 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	r.Body.Close()
+	requestData := &PostRequestDTO{}
+	var upload []byte
+	if isMultipartRequest(r) {
+		requestData, upload, err = postRequestFromForm(w, r)
+		if err == ErrImageTooLarge {
+			jsonError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		} else if nil != err {
+			jsonError(w, http.StatusBadRequest, "can't read form")
+			return
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if nil != err {
+			jsonError(w, http.StatusInternalServerError, "read reqeust err")
+			return
+		}
+		err = json.Unmarshal(body, requestData)
+		if nil != err {
+			jsonError(w, http.StatusBadRequest, "can't unpack payload")
+			return
+		}
+	}
+	if len(requestData.Text) > PostTextMaxLen {
+		jsonError(w, http.StatusBadRequest, "text is too long")
+		return
+	}
+	postURL, canonicalURL, err := PostURLFromRequest(requestData)
 	if nil != err {
-		jsonError(w, http.StatusInternalServerError, "read reqeust err")
+		jsonError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	requestData := &PostRequestDTO{}
-	err = json.Unmarshal(body, requestData)
-	if nil != err {
-		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+	if (requestData.Type == PostTypeImage) != (upload != nil) {
+		jsonError(w, http.StatusBadRequest, "image posts need an image file, other posts can't have one")
 		return
 	}
 
@@ -184,24 +449,101 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkCategoryBan(w, h.BanRepo, sess.UserID, category.ID) {
+		return
+	}
+
+	if !h.checkRequireVerified(w, category, sess.UserID, "post") {
+		return
+	}
+
+	if canonicalURL != "" && !requestData.Repost {
+		since := h.TimeGetter.Now().Add(-RepostWindow).Format(time.RFC3339)
+		repost, err := h.PostsRepo.GetRecentByURL(uint(category.ID), canonicalURL, since)
+		if nil == err {
+			w.WriteHeader(http.StatusConflict)
+			jsonResponse(w, &RepostErrorDTO{
+				Status: http.StatusConflict,
+				Error:  "this link was posted in the category recently",
+				PostID: repost.Post.ID,
+				Link:   "/a/" + category.Name + "/" + repost.Post.ID,
+			})
+			return
+		} else if err != sql.ErrNoRows {
+			fmt.Println("can't check reposts", err)
+			jsonError(w, http.StatusInternalServerError, "can't check reposts")
+			return
+		}
+	}
+
+	var img *ProcessedImage
+	if upload != nil {
+		img, err = ProcessImage(upload)
+		if err == ErrImageTooLarge {
+			jsonError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		} else if nil != err {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	newPost := &Post{
-		ID:          h.UUIDGetter.GetUUID(),
-		Title:       requestData.Title,
-		Type:        requestData.Type,
-		Description: requestData.Text,
-		Score:       ScoreDefault,
-		UserID:      sess.UserID,
-		CategoryID:  uint(category.ID),
-		Created:     h.TimeGetter.GetCreated(),
+		ID:           h.UUIDGetter.GetUUID(),
+		Title:        requestData.Title,
+		Type:         requestData.Type,
+		URL:          postURL,
+		CanonicalURL: canonicalURL,
+		Description:  requestData.Text,
+		Score:        ScoreDefault,
+		UserID:       sess.UserID,
+		CategoryID:   uint(category.ID),
+		Created:      h.TimeGetter.GetCreated(),
+	}
+
+	hits, err := h.Automod.Check(&AutomodSubmission{
+		TargetType: ModLogTargetPost,
+		CategoryID: category.ID,
+		AuthorID:   sess.UserID,
+		PostType:   newPost.Type,
+		Title:      newPost.Title,
+		Body:       newPost.Description,
+		URL:        newPost.URL,
+	})
+	if nil != err {
+		fmt.Println("can't run automoderator", err)
+		jsonError(w, http.StatusInternalServerError, "can't run automoderator")
+		return
+	}
+	automodApply(hits, &newPost.Removed, &newPost.Flair)
+
+	if img != nil {
+		newPost.Image = imageKey(newPost.ID, img.ContentType)
+		err = h.storeImage(newPost.Image, img)
+		if nil != err {
+			fmt.Println("can't store image", err)
+			jsonError(w, http.StatusInternalServerError, "can't store image")
+			return
+		}
 	}
 
 	lastID, err := h.PostsRepo.Add(newPost)
 
 	if nil != err {
 		fmt.Println("can't add post", err)
+		if newPost.Image != "" {
+			deleteImageBlobs(h.BlobStore, newPost.Image)
+		}
 		jsonError(w, http.StatusInternalServerError, "can't add post")
 		return
 	}
+	h.automodFollowUp(hits, &Report{
+		TargetType: ModLogTargetPost,
+		TargetID:   newPost.ID,
+		PostID:     newPost.ID,
+		CategoryID: category.ID,
+		AuthorID:   sess.UserID,
+	})
 
 	data, err := h.PostsRepo.GetById(*lastID)
 	if nil != err {
@@ -209,13 +551,21 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusInternalServerError, "can't get by id the added post")
 		return
 	}
+	if !data.Post.Removed {
+		h.indexSearch(postSearchDoc(data))
+	}
 
-	postDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	// the new post goes to the streams as is, without the saved state
+	postDTO, err := h.DTOConverter.PostConvertToDTO(data, nil)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	if !data.Post.Removed {
+		h.postAdded(postDTO)
+		h.notifyPost(&data.Post)
+	}
 
 	jsonResponse(w, postDTO)
 }
@@ -223,13 +573,32 @@ func (h *PostsHandler) Add(w http.ResponseWriter, r *http.Request) {
 func (h *PostsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["POST_ID"]
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
 
-	isDeleted, err := h.PostsRepo.Delete(id)
+	data, err := h.PostsRepo.GetById(id)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+	if !Can(sess, ActionDeletePost, PostResource(&data.Post)) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	isDeleted, err := h.PostsRepo.Delete(id, deletedNow(h.TimeGetter), sess.UserID)
 
 	if nil != err || !isDeleted {
 		jsonError(w, http.StatusInternalServerError, "can't delete post, err")
 		return
 	}
+	h.unindexSearch(SearchTargetPost, id)
 
 	fmt.Println("Delete post", id)
 
@@ -237,13 +606,37 @@ func (h *PostsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"message": "success"}`))
 }
 
+// canVote writes the error if the post is gone or the voter is banned
+// in its category
+func (h *PostsHandler) canVote(w http.ResponseWriter, r *http.Request, postId string) bool {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return false
+	}
+	data, err := h.PostsRepo.GetById(postId)
+	if err == sql.ErrNoRows || (nil == err && data.Post.Removed) {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return false
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return false
+	}
+	return checkCategoryBan(w, h.BanRepo, sess.UserID, uint32(data.Post.CategoryID))
+}
+
 func (h *PostsHandler) UpVote(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	postId := params["POST_ID"]
+	if !h.canVote(w, r, postId) {
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
 
-	isUpVoted, err := h.PostsRepo.UpVote(postId)
+	_, err := h.VoteRepo.Vote(postId, sess.UserID, VoteUp)
 
-	if nil != err || !isUpVoted {
+	if nil != err {
+		fmt.Println("can't up vote", err)
 		jsonError(w, http.StatusInternalServerError, "can't up vote")
 		return
 	}
@@ -255,12 +648,13 @@ func (h *PostsHandler) UpVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	h.publishVote(postUpdatedDTO)
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postUpdatedDTO)
@@ -269,8 +663,12 @@ func (h *PostsHandler) UpVote(w http.ResponseWriter, r *http.Request) {
 func (h *PostsHandler) DownVote(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	postId := params["POST_ID"]
+	if !h.canVote(w, r, postId) {
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
 
-	_, err := h.PostsRepo.DownVote(postId)
+	_, err := h.VoteRepo.Vote(postId, sess.UserID, VoteDown)
 
 	if nil != err {
 		fmt.Println("can't down vote", err)
@@ -285,12 +683,13 @@ func (h *PostsHandler) DownVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	h.publishVote(postUpdatedDTO)
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postUpdatedDTO)
@@ -299,12 +698,16 @@ func (h *PostsHandler) DownVote(w http.ResponseWriter, r *http.Request) {
 func (h *PostsHandler) UnVote(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	postId := params["POST_ID"]
+	if !h.canVote(w, r, postId) {
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
 
-	_, err := h.PostsRepo.DownVote(postId)
+	_, err := h.VoteRepo.Vote(postId, sess.UserID, VoteNone)
 
 	if nil != err {
-		fmt.Println("can't down vote", err)
-		jsonError(w, http.StatusInternalServerError, "can't down vote")
+		fmt.Println("can't unvote", err)
+		jsonError(w, http.StatusInternalServerError, "can't unvote")
 		return
 	}
 
@@ -315,17 +718,37 @@ func (h *PostsHandler) UnVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
 		return
 	}
+	h.publishVote(postUpdatedDTO)
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postUpdatedDTO)
 }
 
+// checkRequireVerified lets only the users with a verified email post and
+// comment in the categories which require it; it writes the error itself.
+func (h *PostsHandler) checkRequireVerified(w http.ResponseWriter, category *Category, userID string, action string) bool {
+	if !category.RequireVerified {
+		return true
+	}
+	author, err := h.UserRepo.GetById(userID)
+	if err != nil {
+		fmt.Println("can't get author", err)
+		jsonError(w, http.StatusInternalServerError, "can't get author")
+		return false
+	}
+	if !author.Verified {
+		jsonError(w, http.StatusForbidden, "verified email is required to "+action+" in this category")
+		return false
+	}
+	return true
+}
+
 func (h *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	postId := params["POST_ID"]
@@ -347,26 +770,86 @@ func (h *PostsHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusInternalServerError, "can't unpack payload")
 		return
 	}
+	if len(commentRequest.Comment) > CommentMaxLen {
+		jsonError(w, http.StatusBadRequest, "comment is too long")
+		return
+	}
+	data, err := h.PostsRepo.GetById(postId)
+	if err == sql.ErrNoRows || (nil == err && data.Post.Removed) {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		fmt.Println("can't get post", err)
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+	if data.Post.Locked {
+		jsonError(w, http.StatusForbidden, "post is locked")
+		return
+	}
+	if !checkCategoryBan(w, h.BanRepo, sess.UserID, uint32(data.Post.CategoryID)) {
+		return
+	}
+	category, err := h.DictionaryRepo.GetCategoryByName(data.Category.Name)
+	if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+	if !h.checkRequireVerified(w, category, sess.UserID, "comment") {
+		return
+	}
+	var parent *Comment
+	if commentRequest.ParentID != "" {
+		parent, err = h.CommentRepo.GetById(commentRequest.ParentID)
+		if err == sql.ErrNoRows || (nil == err && (parent.PostId != postId || parent.Removed)) {
+			jsonError(w, http.StatusNotFound, "parent comment not found")
+			return
+		} else if nil != err {
+			fmt.Println("can't get parent comment", err)
+			jsonError(w, http.StatusInternalServerError, "can't get parent comment")
+			return
+		}
+	}
+	if !h.checkReplyBlock(w, sess.UserID, data, parent) {
+		return
+	}
 	newComment := &Comment{
-		ID:      h.UUIDGetter.GetUUID(),
-		Body:    commentRequest.Comment,
-		PostId:  postId,
-		UserId:  sess.UserID,
-		Created: h.TimeGetter.GetCreated(),
+		ID:       h.UUIDGetter.GetUUID(),
+		Body:     commentRequest.Comment,
+		PostId:   postId,
+		UserId:   sess.UserID,
+		Created:  h.TimeGetter.GetCreated(),
+		ParentID: commentRequest.ParentID,
+	}
+	hits, err := h.Automod.Check(&AutomodSubmission{
+		TargetType: ModLogTargetComment,
+		CategoryID: uint32(data.Post.CategoryID),
+		AuthorID:   sess.UserID,
+		Body:       newComment.Body,
+	})
+	if nil != err {
+		fmt.Println("can't run automoderator", err)
+		jsonError(w, http.StatusInternalServerError, "can't run automoderator")
+		return
 	}
+	automodApply(hits, &newComment.Removed, nil)
 	_, err = h.CommentRepo.Add(newComment)
 	if nil != err {
 		fmt.Println("can't add comment", err)
 		jsonError(w, http.StatusInternalServerError, "can't add comment")
 		return
 	}
-	data, err := h.PostsRepo.GetById(postId)
-	if nil != err {
-		fmt.Println("can't get updated post", err)
-		jsonError(w, http.StatusInternalServerError, "can't get by id updated post")
-		return
-	}
-	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	h.commentAdded(newComment, data)
+	h.notifyComment(newComment, parent, data)
+	h.automodFollowUp(hits, &Report{
+		TargetType: ModLogTargetComment,
+		TargetID:   newComment.ID,
+		PostID:     postId,
+		CategoryID: uint32(data.Post.CategoryID),
+		AuthorID:   sess.UserID,
+	})
+	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
@@ -380,18 +863,49 @@ func (h *PostsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	postId := params["POST_ID"]
 	commentId := params["COMMENT_ID"]
-	isDeleted, err := h.CommentRepo.Delete(commentId)
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	comment, err := h.CommentRepo.GetById(commentId)
+	if err == sql.ErrNoRows || (nil == err && comment.PostId != postId) {
+		jsonError(w, http.StatusNotFound, "comment not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get comment")
+		return
+	}
+	data, err := h.PostsRepo.GetById(postId)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post")
+		return
+	}
+	resource := &Resource{
+		CategoryID: uint32(data.Post.CategoryID),
+		OwnerID:    comment.UserId,
+	}
+	if !Can(sess, ActionDeleteComment, resource) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+	isDeleted, err := h.CommentRepo.Delete(commentId, deletedNow(h.TimeGetter), sess.UserID)
 	if nil != err || !isDeleted {
 		jsonError(w, http.StatusInternalServerError, "can't delete comment, err")
 		return
 	}
+	h.unindexSearch(SearchTargetComment, commentId)
+	h.publish(EventCommentDeleted, postId, &CommentDeletedEventDTO{
+		PostID: postId,
+		ID:     commentId,
+	})
 	fmt.Println("Delete comment")
-	data, err := h.PostsRepo.GetById(postId)
+	data, err = h.PostsRepo.GetById(postId)
 	if nil != err {
 		jsonError(w, http.StatusInternalServerError, "can't get updated post")
 		return
 	}
-	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data)
+	postUpdatedDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
 	if err != nil {
 		fmt.Println("can't convert post to dto", err)
 		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
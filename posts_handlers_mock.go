@@ -6,6 +6,7 @@ package main
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -49,48 +50,48 @@ func (mr *MockPostRepoIMockRecorder) Add(post interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockPostRepoI) Delete(id string) (bool, error) {
+func (m *MockPostRepoI) Delete(id, deletedAt, deletedBy string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", id, deletedAt, deletedBy)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockPostRepoIMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockPostRepoIMockRecorder) Delete(id, deletedAt, deletedBy interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostRepoI)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostRepoI)(nil).Delete), id, deletedAt, deletedBy)
 }
 
-// DownVote mocks base method.
-func (m *MockPostRepoI) DownVote(id string) (bool, error) {
+// GetAll mocks base method.
+func (m *MockPostRepoI) GetAll() ([]*PostComplexData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DownVote", id)
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "GetAll")
+	ret0, _ := ret[0].([]*PostComplexData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DownVote indicates an expected call of DownVote.
-func (mr *MockPostRepoIMockRecorder) DownVote(id interface{}) *gomock.Call {
+// GetAll indicates an expected call of GetAll.
+func (mr *MockPostRepoIMockRecorder) GetAll() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownVote", reflect.TypeOf((*MockPostRepoI)(nil).DownVote), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockPostRepoI)(nil).GetAll))
 }
 
-// GetAll mocks base method.
-func (m *MockPostRepoI) GetAll() ([]*PostComplexData, error) {
+// GetAllPaged mocks base method.
+func (m *MockPostRepoI) GetAllPaged(opts *ListOptions) ([]*PostComplexData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAllPaged", opts)
 	ret0, _ := ret[0].([]*PostComplexData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockPostRepoIMockRecorder) GetAll() *gomock.Call {
+// GetAllPaged indicates an expected call of GetAllPaged.
+func (mr *MockPostRepoIMockRecorder) GetAllPaged(opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockPostRepoI)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllPaged", reflect.TypeOf((*MockPostRepoI)(nil).GetAllPaged), opts)
 }
 
 // GetByCategoryName mocks base method.
@@ -123,6 +124,36 @@ func (mr *MockPostRepoIMockRecorder) GetById(id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockPostRepoI)(nil).GetById), id)
 }
 
+// GetByIds mocks base method.
+func (m *MockPostRepoI) GetByIds(ids []string) ([]*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ids)
+	ret0, _ := ret[0].([]*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockPostRepoIMockRecorder) GetByIds(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockPostRepoI)(nil).GetByIds), ids)
+}
+
+// GetByUserId mocks base method.
+func (m *MockPostRepoI) GetByUserId(userID string, opts *ListOptions) ([]*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID, opts)
+	ret0, _ := ret[0].([]*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockPostRepoIMockRecorder) GetByUserId(userID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockPostRepoI)(nil).GetByUserId), userID, opts)
+}
+
 // GetByUserLogin mocks base method.
 func (m *MockPostRepoI) GetByUserLogin(userLogin string) ([]*PostComplexData, error) {
 	m.ctrl.T.Helper()
@@ -138,19 +169,155 @@ func (mr *MockPostRepoIMockRecorder) GetByUserLogin(userLogin interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserLogin", reflect.TypeOf((*MockPostRepoI)(nil).GetByUserLogin), userLogin)
 }
 
-// UpVote mocks base method.
-func (m *MockPostRepoI) UpVote(id string) (bool, error) {
+// GetFeed mocks base method.
+func (m *MockPostRepoI) GetFeed(userID, since string, opts *ListOptions) ([]*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeed", userID, since, opts)
+	ret0, _ := ret[0].([]*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeed indicates an expected call of GetFeed.
+func (mr *MockPostRepoIMockRecorder) GetFeed(userID, since, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeed", reflect.TypeOf((*MockPostRepoI)(nil).GetFeed), userID, since, opts)
+}
+
+// GetFollowedFeed mocks base method.
+func (m *MockPostRepoI) GetFollowedFeed(userID string, opts *ListOptions) ([]*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowedFeed", userID, opts)
+	ret0, _ := ret[0].([]*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowedFeed indicates an expected call of GetFollowedFeed.
+func (mr *MockPostRepoIMockRecorder) GetFollowedFeed(userID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowedFeed", reflect.TypeOf((*MockPostRepoI)(nil).GetFollowedFeed), userID, opts)
+}
+
+// GetRecentByURL mocks base method.
+func (m *MockPostRepoI) GetRecentByURL(categoryID uint, url, since string) (*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecentByURL", categoryID, url, since)
+	ret0, _ := ret[0].(*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecentByURL indicates an expected call of GetRecentByURL.
+func (mr *MockPostRepoIMockRecorder) GetRecentByURL(categoryID, url, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecentByURL", reflect.TypeOf((*MockPostRepoI)(nil).GetRecentByURL), categoryID, url, since)
+}
+
+// GetVotedByUserId mocks base method.
+func (m *MockPostRepoI) GetVotedByUserId(userID string, vote int32, opts *ListOptions) ([]*PostComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVotedByUserId", userID, vote, opts)
+	ret0, _ := ret[0].([]*PostComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVotedByUserId indicates an expected call of GetVotedByUserId.
+func (mr *MockPostRepoIMockRecorder) GetVotedByUserId(userID, vote, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVotedByUserId", reflect.TypeOf((*MockPostRepoI)(nil).GetVotedByUserId), userID, vote, opts)
+}
+
+// Pin mocks base method.
+func (m *MockPostRepoI) Pin(id string, categoryID uint) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pin", id, categoryID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pin indicates an expected call of Pin.
+func (mr *MockPostRepoIMockRecorder) Pin(id, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pin", reflect.TypeOf((*MockPostRepoI)(nil).Pin), id, categoryID)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockPostRepoI) PurgeDeleted(before string) (int64, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockPostRepoIMockRecorder) PurgeDeleted(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockPostRepoI)(nil).PurgeDeleted), before)
+}
+
+// Restore mocks base method.
+func (m *MockPostRepoI) Restore(id, userID, since string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", id, userID, since)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockPostRepoIMockRecorder) Restore(id, userID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockPostRepoI)(nil).Restore), id, userID, since)
+}
+
+// SetLocked mocks base method.
+func (m *MockPostRepoI) SetLocked(id string, locked bool) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLocked", id, locked)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLocked indicates an expected call of SetLocked.
+func (mr *MockPostRepoIMockRecorder) SetLocked(id, locked interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLocked", reflect.TypeOf((*MockPostRepoI)(nil).SetLocked), id, locked)
+}
+
+// SetRemoved mocks base method.
+func (m *MockPostRepoI) SetRemoved(id string, removed bool) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRemoved", id, removed)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRemoved indicates an expected call of SetRemoved.
+func (mr *MockPostRepoIMockRecorder) SetRemoved(id, removed interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRemoved", reflect.TypeOf((*MockPostRepoI)(nil).SetRemoved), id, removed)
+}
+
+// Unpin mocks base method.
+func (m *MockPostRepoI) Unpin(id string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpVote", id)
+	ret := m.ctrl.Call(m, "Unpin", id)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpVote indicates an expected call of UpVote.
-func (mr *MockPostRepoIMockRecorder) UpVote(id interface{}) *gomock.Call {
+// Unpin indicates an expected call of Unpin.
+func (mr *MockPostRepoIMockRecorder) Unpin(id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpVote", reflect.TypeOf((*MockPostRepoI)(nil).UpVote), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unpin", reflect.TypeOf((*MockPostRepoI)(nil).Unpin), id)
 }
 
 // MockCommentRepoI is a mock of CommentRepoI interface.
@@ -192,18 +359,63 @@ func (mr *MockCommentRepoIMockRecorder) Add(comment interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockCommentRepoI) Delete(id string) (bool, error) {
+func (m *MockCommentRepoI) Delete(id, deletedAt, deletedBy string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", id, deletedAt, deletedBy)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockCommentRepoIMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockCommentRepoIMockRecorder) Delete(id, deletedAt, deletedBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCommentRepoI)(nil).Delete), id, deletedAt, deletedBy)
+}
+
+// GetById mocks base method.
+func (m *MockCommentRepoI) GetById(id string) (*Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetById", id)
+	ret0, _ := ret[0].(*Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetById indicates an expected call of GetById.
+func (mr *MockCommentRepoIMockRecorder) GetById(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockCommentRepoI)(nil).GetById), id)
+}
+
+// GetByIds mocks base method.
+func (m *MockCommentRepoI) GetByIds(ids []string) ([]*CommentComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIds", ids)
+	ret0, _ := ret[0].([]*CommentComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIds indicates an expected call of GetByIds.
+func (mr *MockCommentRepoIMockRecorder) GetByIds(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIds", reflect.TypeOf((*MockCommentRepoI)(nil).GetByIds), ids)
+}
+
+// GetByUserId mocks base method.
+func (m *MockCommentRepoI) GetByUserId(userID string, limit, offset int) ([]*CommentComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID, limit, offset)
+	ret0, _ := ret[0].([]*CommentComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockCommentRepoIMockRecorder) GetByUserId(userID, limit, offset interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCommentRepoI)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockCommentRepoI)(nil).GetByUserId), userID, limit, offset)
 }
 
 // GetCommentsByPostIds mocks base method.
@@ -221,6 +433,51 @@ func (mr *MockCommentRepoIMockRecorder) GetCommentsByPostIds(postIds interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentsByPostIds", reflect.TypeOf((*MockCommentRepoI)(nil).GetCommentsByPostIds), postIds)
 }
 
+// PurgeDeleted mocks base method.
+func (m *MockCommentRepoI) PurgeDeleted(before string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockCommentRepoIMockRecorder) PurgeDeleted(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockCommentRepoI)(nil).PurgeDeleted), before)
+}
+
+// Restore mocks base method.
+func (m *MockCommentRepoI) Restore(id, postID, userID, since string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", id, postID, userID, since)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockCommentRepoIMockRecorder) Restore(id, postID, userID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockCommentRepoI)(nil).Restore), id, postID, userID, since)
+}
+
+// SetRemoved mocks base method.
+func (m *MockCommentRepoI) SetRemoved(id string, removed bool) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRemoved", id, removed)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRemoved indicates an expected call of SetRemoved.
+func (mr *MockCommentRepoIMockRecorder) SetRemoved(id, removed interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRemoved", reflect.TypeOf((*MockCommentRepoI)(nil).SetRemoved), id, removed)
+}
+
 // MockVoteRepoI is a mock of VoteRepoI interface.
 type MockVoteRepoI struct {
 	ctrl     *gomock.Controller
@@ -259,142 +516,963 @@ func (mr *MockVoteRepoIMockRecorder) GetVotesByPostIds(postIds interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVotesByPostIds", reflect.TypeOf((*MockVoteRepoI)(nil).GetVotesByPostIds), postIds)
 }
 
-// MockDictionaryRepoI is a mock of DictionaryRepoI interface.
-type MockDictionaryRepoI struct {
-	ctrl     *gomock.Controller
-	recorder *MockDictionaryRepoIMockRecorder
-}
-
-// MockDictionaryRepoIMockRecorder is the mock recorder for MockDictionaryRepoI.
-type MockDictionaryRepoIMockRecorder struct {
-	mock *MockDictionaryRepoI
-}
-
-// NewMockDictionaryRepoI creates a new mock instance.
-func NewMockDictionaryRepoI(ctrl *gomock.Controller) *MockDictionaryRepoI {
-	mock := &MockDictionaryRepoI{ctrl: ctrl}
-	mock.recorder = &MockDictionaryRepoIMockRecorder{mock}
-	return mock
+// Vote mocks base method.
+func (m *MockVoteRepoI) Vote(postID, userID string, vote int32) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Vote", postID, userID, vote)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockDictionaryRepoI) EXPECT() *MockDictionaryRepoIMockRecorder {
-	return m.recorder
+// Vote indicates an expected call of Vote.
+func (mr *MockVoteRepoIMockRecorder) Vote(postID, userID, vote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Vote", reflect.TypeOf((*MockVoteRepoI)(nil).Vote), postID, userID, vote)
 }
 
-// GetCategoryByName mocks base method.
-func (m *MockDictionaryRepoI) GetCategoryByName(name string) (*Category, error) {
+// VoteComment mocks base method.
+func (m *MockVoteRepoI) VoteComment(commentID, userID string, vote int32) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCategoryByName", name)
-	ret0, _ := ret[0].(*Category)
+	ret := m.ctrl.Call(m, "VoteComment", commentID, userID, vote)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetCategoryByName indicates an expected call of GetCategoryByName.
-func (mr *MockDictionaryRepoIMockRecorder) GetCategoryByName(name interface{}) *gomock.Call {
+// VoteComment indicates an expected call of VoteComment.
+func (mr *MockVoteRepoIMockRecorder) VoteComment(commentID, userID, vote interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryByName", reflect.TypeOf((*MockDictionaryRepoI)(nil).GetCategoryByName), name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoteComment", reflect.TypeOf((*MockVoteRepoI)(nil).VoteComment), commentID, userID, vote)
 }
 
-// MockDTOConverterI is a mock of DTOConverterI interface.
-type MockDTOConverterI struct {
+// MockSavedRepoI is a mock of SavedRepoI interface.
+type MockSavedRepoI struct {
 	ctrl     *gomock.Controller
-	recorder *MockDTOConverterIMockRecorder
+	recorder *MockSavedRepoIMockRecorder
 }
 
-// MockDTOConverterIMockRecorder is the mock recorder for MockDTOConverterI.
-type MockDTOConverterIMockRecorder struct {
-	mock *MockDTOConverterI
+// MockSavedRepoIMockRecorder is the mock recorder for MockSavedRepoI.
+type MockSavedRepoIMockRecorder struct {
+	mock *MockSavedRepoI
 }
 
-// NewMockDTOConverterI creates a new mock instance.
-func NewMockDTOConverterI(ctrl *gomock.Controller) *MockDTOConverterI {
-	mock := &MockDTOConverterI{ctrl: ctrl}
-	mock.recorder = &MockDTOConverterIMockRecorder{mock}
+// NewMockSavedRepoI creates a new mock instance.
+func NewMockSavedRepoI(ctrl *gomock.Controller) *MockSavedRepoI {
+	mock := &MockSavedRepoI{ctrl: ctrl}
+	mock.recorder = &MockSavedRepoIMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockDTOConverterI) EXPECT() *MockDTOConverterIMockRecorder {
+func (m *MockSavedRepoI) EXPECT() *MockSavedRepoIMockRecorder {
 	return m.recorder
 }
 
-// CommentsConvertToDTO mocks base method.
-func (m *MockDTOConverterI) CommentsConvertToDTO(data []*CommentComplexData) []*CommentDTO {
+// Add mocks base method.
+func (m *MockSavedRepoI) Add(item *SavedItem) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CommentsConvertToDTO", data)
-	ret0, _ := ret[0].([]*CommentDTO)
-	return ret0
+	ret := m.ctrl.Call(m, "Add", item)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CommentsConvertToDTO indicates an expected call of CommentsConvertToDTO.
-func (mr *MockDTOConverterIMockRecorder) CommentsConvertToDTO(data interface{}) *gomock.Call {
+// Add indicates an expected call of Add.
+func (mr *MockSavedRepoIMockRecorder) Add(item interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommentsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).CommentsConvertToDTO), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockSavedRepoI)(nil).Add), item)
 }
 
-// PostConvertToDTO mocks base method.
-func (m *MockDTOConverterI) PostConvertToDTO(data *PostComplexData) (*PostDTO, error) {
+// Delete mocks base method.
+func (m *MockSavedRepoI) Delete(userID, itemType, itemID string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PostConvertToDTO", data)
-	ret0, _ := ret[0].(*PostDTO)
+	ret := m.ctrl.Call(m, "Delete", userID, itemType, itemID)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// PostConvertToDTO indicates an expected call of PostConvertToDTO.
-func (mr *MockDTOConverterIMockRecorder) PostConvertToDTO(data interface{}) *gomock.Call {
+// Delete indicates an expected call of Delete.
+func (mr *MockSavedRepoIMockRecorder) Delete(userID, itemType, itemID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).PostConvertToDTO), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSavedRepoI)(nil).Delete), userID, itemType, itemID)
 }
 
-// PostsConvertToDTO mocks base method.
-func (m *MockDTOConverterI) PostsConvertToDTO(data []*PostComplexData) ([]*PostDTO, error) {
+// GetByUserId mocks base method.
+func (m *MockSavedRepoI) GetByUserId(userID, itemType string, limit, offset int) ([]*SavedItem, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PostsConvertToDTO", data)
-	ret0, _ := ret[0].([]*PostDTO)
+	ret := m.ctrl.Call(m, "GetByUserId", userID, itemType, limit, offset)
+	ret0, _ := ret[0].([]*SavedItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// PostsConvertToDTO indicates an expected call of PostsConvertToDTO.
-func (mr *MockDTOConverterIMockRecorder) PostsConvertToDTO(data interface{}) *gomock.Call {
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockSavedRepoIMockRecorder) GetByUserId(userID, itemType, limit, offset interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).PostsConvertToDTO), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockSavedRepoI)(nil).GetByUserId), userID, itemType, limit, offset)
 }
 
-// VotesConvertToDTO mocks base method.
-func (m *MockDTOConverterI) VotesConvertToDTO(data []*Vote) []*VoteDTO {
+// GetSavedIds mocks base method.
+func (m *MockSavedRepoI) GetSavedIds(userID string, ids []string) (map[string]bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VotesConvertToDTO", data)
-	ret0, _ := ret[0].([]*VoteDTO)
-	return ret0
+	ret := m.ctrl.Call(m, "GetSavedIds", userID, ids)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// VotesConvertToDTO indicates an expected call of VotesConvertToDTO.
-func (mr *MockDTOConverterIMockRecorder) VotesConvertToDTO(data interface{}) *gomock.Call {
+// GetSavedIds indicates an expected call of GetSavedIds.
+func (mr *MockSavedRepoIMockRecorder) GetSavedIds(userID, ids interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VotesConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).VotesConvertToDTO), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSavedIds", reflect.TypeOf((*MockSavedRepoI)(nil).GetSavedIds), userID, ids)
 }
 
-// MockTimeGetterI is a mock of TimeGetterI interface.
-type MockTimeGetterI struct {
+// MockDictionaryRepoI is a mock of DictionaryRepoI interface.
+type MockDictionaryRepoI struct {
 	ctrl     *gomock.Controller
-	recorder *MockTimeGetterIMockRecorder
+	recorder *MockDictionaryRepoIMockRecorder
 }
 
-// MockTimeGetterIMockRecorder is the mock recorder for MockTimeGetterI.
-type MockTimeGetterIMockRecorder struct {
-	mock *MockTimeGetterI
+// MockDictionaryRepoIMockRecorder is the mock recorder for MockDictionaryRepoI.
+type MockDictionaryRepoIMockRecorder struct {
+	mock *MockDictionaryRepoI
 }
 
-// NewMockTimeGetterI creates a new mock instance.
-func NewMockTimeGetterI(ctrl *gomock.Controller) *MockTimeGetterI {
-	mock := &MockTimeGetterI{ctrl: ctrl}
-	mock.recorder = &MockTimeGetterIMockRecorder{mock}
-	return mock
-}
+// NewMockDictionaryRepoI creates a new mock instance.
+func NewMockDictionaryRepoI(ctrl *gomock.Controller) *MockDictionaryRepoI {
+	mock := &MockDictionaryRepoI{ctrl: ctrl}
+	mock.recorder = &MockDictionaryRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDictionaryRepoI) EXPECT() *MockDictionaryRepoIMockRecorder {
+	return m.recorder
+}
+
+// AddCategory mocks base method.
+func (m *MockDictionaryRepoI) AddCategory(category *Category) (uint32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCategory", category)
+	ret0, _ := ret[0].(uint32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddCategory indicates an expected call of AddCategory.
+func (mr *MockDictionaryRepoIMockRecorder) AddCategory(category interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCategory", reflect.TypeOf((*MockDictionaryRepoI)(nil).AddCategory), category)
+}
+
+// GetCategories mocks base method.
+func (m *MockDictionaryRepoI) GetCategories() ([]*CategoryComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategories")
+	ret0, _ := ret[0].([]*CategoryComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategories indicates an expected call of GetCategories.
+func (mr *MockDictionaryRepoIMockRecorder) GetCategories() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategories", reflect.TypeOf((*MockDictionaryRepoI)(nil).GetCategories))
+}
+
+// GetCategoryByName mocks base method.
+func (m *MockDictionaryRepoI) GetCategoryByName(name string) (*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryByName", name)
+	ret0, _ := ret[0].(*Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryByName indicates an expected call of GetCategoryByName.
+func (mr *MockDictionaryRepoIMockRecorder) GetCategoryByName(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryByName", reflect.TypeOf((*MockDictionaryRepoI)(nil).GetCategoryByName), name)
+}
+
+// SetRequireVerified mocks base method.
+func (m *MockDictionaryRepoI) SetRequireVerified(categoryID uint32, requireVerified bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRequireVerified", categoryID, requireVerified)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRequireVerified indicates an expected call of SetRequireVerified.
+func (mr *MockDictionaryRepoIMockRecorder) SetRequireVerified(categoryID, requireVerified interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequireVerified", reflect.TypeOf((*MockDictionaryRepoI)(nil).SetRequireVerified), categoryID, requireVerified)
+}
+
+// MockSubscriptionRepoI is a mock of SubscriptionRepoI interface.
+type MockSubscriptionRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriptionRepoIMockRecorder
+}
+
+// MockSubscriptionRepoIMockRecorder is the mock recorder for MockSubscriptionRepoI.
+type MockSubscriptionRepoIMockRecorder struct {
+	mock *MockSubscriptionRepoI
+}
+
+// NewMockSubscriptionRepoI creates a new mock instance.
+func NewMockSubscriptionRepoI(ctrl *gomock.Controller) *MockSubscriptionRepoI {
+	mock := &MockSubscriptionRepoI{ctrl: ctrl}
+	mock.recorder = &MockSubscriptionRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubscriptionRepoI) EXPECT() *MockSubscriptionRepoIMockRecorder {
+	return m.recorder
+}
+
+// HasSubscriptions mocks base method.
+func (m *MockSubscriptionRepoI) HasSubscriptions(userID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasSubscriptions", userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasSubscriptions indicates an expected call of HasSubscriptions.
+func (mr *MockSubscriptionRepoIMockRecorder) HasSubscriptions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasSubscriptions", reflect.TypeOf((*MockSubscriptionRepoI)(nil).HasSubscriptions), userID)
+}
+
+// Subscribe mocks base method.
+func (m *MockSubscriptionRepoI) Subscribe(userID string, categoryID uint32, created string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", userID, categoryID, created)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockSubscriptionRepoIMockRecorder) Subscribe(userID, categoryID, created interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSubscriptionRepoI)(nil).Subscribe), userID, categoryID, created)
+}
+
+// Unsubscribe mocks base method.
+func (m *MockSubscriptionRepoI) Unsubscribe(userID string, categoryID uint32) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsubscribe", userID, categoryID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unsubscribe indicates an expected call of Unsubscribe.
+func (mr *MockSubscriptionRepoIMockRecorder) Unsubscribe(userID, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MockSubscriptionRepoI)(nil).Unsubscribe), userID, categoryID)
+}
+
+// MockModLogRepoI is a mock of ModLogRepoI interface.
+type MockModLogRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockModLogRepoIMockRecorder
+}
+
+// MockModLogRepoIMockRecorder is the mock recorder for MockModLogRepoI.
+type MockModLogRepoIMockRecorder struct {
+	mock *MockModLogRepoI
+}
+
+// NewMockModLogRepoI creates a new mock instance.
+func NewMockModLogRepoI(ctrl *gomock.Controller) *MockModLogRepoI {
+	mock := &MockModLogRepoI{ctrl: ctrl}
+	mock.recorder = &MockModLogRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModLogRepoI) EXPECT() *MockModLogRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockModLogRepoI) Add(entry *ModLogEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockModLogRepoIMockRecorder) Add(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockModLogRepoI)(nil).Add), entry)
+}
+
+// GetByCategoryId mocks base method.
+func (m *MockModLogRepoI) GetByCategoryId(categoryID uint32, limit, offset int) ([]*ModLogComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByCategoryId", categoryID, limit, offset)
+	ret0, _ := ret[0].([]*ModLogComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByCategoryId indicates an expected call of GetByCategoryId.
+func (mr *MockModLogRepoIMockRecorder) GetByCategoryId(categoryID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByCategoryId", reflect.TypeOf((*MockModLogRepoI)(nil).GetByCategoryId), categoryID, limit, offset)
+}
+
+// MockReportRepoI is a mock of ReportRepoI interface.
+type MockReportRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportRepoIMockRecorder
+}
+
+// MockReportRepoIMockRecorder is the mock recorder for MockReportRepoI.
+type MockReportRepoIMockRecorder struct {
+	mock *MockReportRepoI
+}
+
+// NewMockReportRepoI creates a new mock instance.
+func NewMockReportRepoI(ctrl *gomock.Controller) *MockReportRepoI {
+	mock := &MockReportRepoI{ctrl: ctrl}
+	mock.recorder = &MockReportRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportRepoI) EXPECT() *MockReportRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockReportRepoI) Add(report *Report) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", report)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockReportRepoIMockRecorder) Add(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockReportRepoI)(nil).Add), report)
+}
+
+// GetQueue mocks base method.
+func (m *MockReportRepoI) GetQueue(categoryID uint32, limit, offset int) ([]*ReportQueueItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQueue", categoryID, limit, offset)
+	ret0, _ := ret[0].([]*ReportQueueItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQueue indicates an expected call of GetQueue.
+func (mr *MockReportRepoIMockRecorder) GetQueue(categoryID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueue", reflect.TypeOf((*MockReportRepoI)(nil).GetQueue), categoryID, limit, offset)
+}
+
+// Resolve mocks base method.
+func (m *MockReportRepoI) Resolve(targetType, targetID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", targetType, targetID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockReportRepoIMockRecorder) Resolve(targetType, targetID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockReportRepoI)(nil).Resolve), targetType, targetID)
+}
+
+// ResolveByAuthor mocks base method.
+func (m *MockReportRepoI) ResolveByAuthor(authorID string, categoryID uint32) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveByAuthor", authorID, categoryID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveByAuthor indicates an expected call of ResolveByAuthor.
+func (mr *MockReportRepoIMockRecorder) ResolveByAuthor(authorID, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveByAuthor", reflect.TypeOf((*MockReportRepoI)(nil).ResolveByAuthor), authorID, categoryID)
+}
+
+// MockAutomodRepoI is a mock of AutomodRepoI interface.
+type MockAutomodRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockAutomodRepoIMockRecorder
+}
+
+// MockAutomodRepoIMockRecorder is the mock recorder for MockAutomodRepoI.
+type MockAutomodRepoIMockRecorder struct {
+	mock *MockAutomodRepoI
+}
+
+// NewMockAutomodRepoI creates a new mock instance.
+func NewMockAutomodRepoI(ctrl *gomock.Controller) *MockAutomodRepoI {
+	mock := &MockAutomodRepoI{ctrl: ctrl}
+	mock.recorder = &MockAutomodRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAutomodRepoI) EXPECT() *MockAutomodRepoIMockRecorder {
+	return m.recorder
+}
+
+// GetByCategoryId mocks base method.
+func (m *MockAutomodRepoI) GetByCategoryId(categoryID uint32) (*AutomodSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByCategoryId", categoryID)
+	ret0, _ := ret[0].(*AutomodSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByCategoryId indicates an expected call of GetByCategoryId.
+func (mr *MockAutomodRepoIMockRecorder) GetByCategoryId(categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByCategoryId", reflect.TypeOf((*MockAutomodRepoI)(nil).GetByCategoryId), categoryID)
+}
+
+// Save mocks base method.
+func (m *MockAutomodRepoI) Save(settings *AutomodSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockAutomodRepoIMockRecorder) Save(settings interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAutomodRepoI)(nil).Save), settings)
+}
+
+// MockAutomodI is a mock of AutomodI interface.
+type MockAutomodI struct {
+	ctrl     *gomock.Controller
+	recorder *MockAutomodIMockRecorder
+}
+
+// MockAutomodIMockRecorder is the mock recorder for MockAutomodI.
+type MockAutomodIMockRecorder struct {
+	mock *MockAutomodI
+}
+
+// NewMockAutomodI creates a new mock instance.
+func NewMockAutomodI(ctrl *gomock.Controller) *MockAutomodI {
+	mock := &MockAutomodI{ctrl: ctrl}
+	mock.recorder = &MockAutomodIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAutomodI) EXPECT() *MockAutomodIMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockAutomodI) Check(sub *AutomodSubmission) ([]*AutomodHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", sub)
+	ret0, _ := ret[0].([]*AutomodHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockAutomodIMockRecorder) Check(sub interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockAutomodI)(nil).Check), sub)
+}
+
+// MockLinkPreviewRepoI is a mock of LinkPreviewRepoI interface.
+type MockLinkPreviewRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkPreviewRepoIMockRecorder
+}
+
+// MockLinkPreviewRepoIMockRecorder is the mock recorder for MockLinkPreviewRepoI.
+type MockLinkPreviewRepoIMockRecorder struct {
+	mock *MockLinkPreviewRepoI
+}
+
+// NewMockLinkPreviewRepoI creates a new mock instance.
+func NewMockLinkPreviewRepoI(ctrl *gomock.Controller) *MockLinkPreviewRepoI {
+	mock := &MockLinkPreviewRepoI{ctrl: ctrl}
+	mock.recorder = &MockLinkPreviewRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkPreviewRepoI) EXPECT() *MockLinkPreviewRepoIMockRecorder {
+	return m.recorder
+}
+
+// GetPending mocks base method.
+func (m *MockLinkPreviewRepoI) GetPending(limit int) ([]*LinkPreview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPending", limit)
+	ret0, _ := ret[0].([]*LinkPreview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPending indicates an expected call of GetPending.
+func (mr *MockLinkPreviewRepoIMockRecorder) GetPending(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPending", reflect.TypeOf((*MockLinkPreviewRepoI)(nil).GetPending), limit)
+}
+
+// Save mocks base method.
+func (m *MockLinkPreviewRepoI) Save(preview *LinkPreview) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", preview)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockLinkPreviewRepoIMockRecorder) Save(preview interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockLinkPreviewRepoI)(nil).Save), preview)
+}
+
+// MockLinkFetcherI is a mock of LinkFetcherI interface.
+type MockLinkFetcherI struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkFetcherIMockRecorder
+}
+
+// MockLinkFetcherIMockRecorder is the mock recorder for MockLinkFetcherI.
+type MockLinkFetcherIMockRecorder struct {
+	mock *MockLinkFetcherI
+}
+
+// NewMockLinkFetcherI creates a new mock instance.
+func NewMockLinkFetcherI(ctrl *gomock.Controller) *MockLinkFetcherI {
+	mock := &MockLinkFetcherI{ctrl: ctrl}
+	mock.recorder = &MockLinkFetcherIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkFetcherI) EXPECT() *MockLinkFetcherIMockRecorder {
+	return m.recorder
+}
+
+// Fetch mocks base method.
+func (m *MockLinkFetcherI) Fetch(pageURL string) (*LinkPreview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fetch", pageURL)
+	ret0, _ := ret[0].(*LinkPreview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fetch indicates an expected call of Fetch.
+func (mr *MockLinkFetcherIMockRecorder) Fetch(pageURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockLinkFetcherI)(nil).Fetch), pageURL)
+}
+
+// MockSearchIndex is a mock of SearchIndex interface.
+type MockSearchIndex struct {
+	ctrl     *gomock.Controller
+	recorder *MockSearchIndexMockRecorder
+}
+
+// MockSearchIndexMockRecorder is the mock recorder for MockSearchIndex.
+type MockSearchIndexMockRecorder struct {
+	mock *MockSearchIndex
+}
+
+// NewMockSearchIndex creates a new mock instance.
+func NewMockSearchIndex(ctrl *gomock.Controller) *MockSearchIndex {
+	mock := &MockSearchIndex{ctrl: ctrl}
+	mock.recorder = &MockSearchIndexMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSearchIndex) EXPECT() *MockSearchIndexMockRecorder {
+	return m.recorder
+}
+
+// Index mocks base method.
+func (m *MockSearchIndex) Index(doc *SearchDoc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Index", doc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Index indicates an expected call of Index.
+func (mr *MockSearchIndexMockRecorder) Index(doc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Index", reflect.TypeOf((*MockSearchIndex)(nil).Index), doc)
+}
+
+// Remove mocks base method.
+func (m *MockSearchIndex) Remove(target, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", target, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockSearchIndexMockRecorder) Remove(target, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockSearchIndex)(nil).Remove), target, id)
+}
+
+// Search mocks base method.
+func (m *MockSearchIndex) Search(query *SearchQuery) ([]*SearchHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", query)
+	ret0, _ := ret[0].([]*SearchHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockSearchIndexMockRecorder) Search(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockSearchIndex)(nil).Search), query)
+}
+
+// MockBlobStore is a mock of BlobStore interface.
+type MockBlobStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlobStoreMockRecorder
+}
+
+// MockBlobStoreMockRecorder is the mock recorder for MockBlobStore.
+type MockBlobStoreMockRecorder struct {
+	mock *MockBlobStore
+}
+
+// NewMockBlobStore creates a new mock instance.
+func NewMockBlobStore(ctrl *gomock.Controller) *MockBlobStore {
+	mock := &MockBlobStore{ctrl: ctrl}
+	mock.recorder = &MockBlobStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlobStore) EXPECT() *MockBlobStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockBlobStore) Delete(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBlobStoreMockRecorder) Delete(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBlobStore)(nil).Delete), key)
+}
+
+// Get mocks base method.
+func (m *MockBlobStore) Get(key string) (*Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", key)
+	ret0, _ := ret[0].(*Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockBlobStoreMockRecorder) Get(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockBlobStore)(nil).Get), key)
+}
+
+// Put mocks base method.
+func (m *MockBlobStore) Put(key, contentType string, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", key, contentType, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockBlobStoreMockRecorder) Put(key, contentType, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockBlobStore)(nil).Put), key, contentType, data)
+}
+
+// MockMarkdownRenderer is a mock of MarkdownRenderer interface.
+type MockMarkdownRenderer struct {
+	ctrl     *gomock.Controller
+	recorder *MockMarkdownRendererMockRecorder
+}
+
+// MockMarkdownRendererMockRecorder is the mock recorder for MockMarkdownRenderer.
+type MockMarkdownRendererMockRecorder struct {
+	mock *MockMarkdownRenderer
+}
+
+// NewMockMarkdownRenderer creates a new mock instance.
+func NewMockMarkdownRenderer(ctrl *gomock.Controller) *MockMarkdownRenderer {
+	mock := &MockMarkdownRenderer{ctrl: ctrl}
+	mock.recorder = &MockMarkdownRendererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMarkdownRenderer) EXPECT() *MockMarkdownRendererMockRecorder {
+	return m.recorder
+}
+
+// Render mocks base method.
+func (m *MockMarkdownRenderer) Render(source string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", source)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Render indicates an expected call of Render.
+func (mr *MockMarkdownRendererMockRecorder) Render(source interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockMarkdownRenderer)(nil).Render), source)
+}
+
+// MockDTOConverterI is a mock of DTOConverterI interface.
+type MockDTOConverterI struct {
+	ctrl     *gomock.Controller
+	recorder *MockDTOConverterIMockRecorder
+}
+
+// MockDTOConverterIMockRecorder is the mock recorder for MockDTOConverterI.
+type MockDTOConverterIMockRecorder struct {
+	mock *MockDTOConverterI
+}
+
+// NewMockDTOConverterI creates a new mock instance.
+func NewMockDTOConverterI(ctrl *gomock.Controller) *MockDTOConverterI {
+	mock := &MockDTOConverterI{ctrl: ctrl}
+	mock.recorder = &MockDTOConverterIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDTOConverterI) EXPECT() *MockDTOConverterIMockRecorder {
+	return m.recorder
+}
+
+// BlocksConvertToDTO mocks base method.
+func (m *MockDTOConverterI) BlocksConvertToDTO(data []*BlockComplexData) []*BlockDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlocksConvertToDTO", data)
+	ret0, _ := ret[0].([]*BlockDTO)
+	return ret0
+}
+
+// BlocksConvertToDTO indicates an expected call of BlocksConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) BlocksConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlocksConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).BlocksConvertToDTO), data)
+}
+
+// CategoriesConvertToDTO mocks base method.
+func (m *MockDTOConverterI) CategoriesConvertToDTO(data []*CategoryComplexData) []*CategoryDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CategoriesConvertToDTO", data)
+	ret0, _ := ret[0].([]*CategoryDTO)
+	return ret0
+}
+
+// CategoriesConvertToDTO indicates an expected call of CategoriesConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) CategoriesConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CategoriesConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).CategoriesConvertToDTO), data)
+}
+
+// CommentsConvertToDTO mocks base method.
+func (m *MockDTOConverterI) CommentsConvertToDTO(data []*CommentComplexData) []*CommentDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommentsConvertToDTO", data)
+	ret0, _ := ret[0].([]*CommentDTO)
+	return ret0
+}
+
+// CommentsConvertToDTO indicates an expected call of CommentsConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) CommentsConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommentsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).CommentsConvertToDTO), data)
+}
+
+// ConversationsConvertToDTO mocks base method.
+func (m *MockDTOConverterI) ConversationsConvertToDTO(data []*Conversation) []*ConversationDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConversationsConvertToDTO", data)
+	ret0, _ := ret[0].([]*ConversationDTO)
+	return ret0
+}
+
+// ConversationsConvertToDTO indicates an expected call of ConversationsConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) ConversationsConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConversationsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).ConversationsConvertToDTO), data)
+}
+
+// FollowsConvertToDTO mocks base method.
+func (m *MockDTOConverterI) FollowsConvertToDTO(data []*FollowComplexData) []*FollowDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FollowsConvertToDTO", data)
+	ret0, _ := ret[0].([]*FollowDTO)
+	return ret0
+}
+
+// FollowsConvertToDTO indicates an expected call of FollowsConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) FollowsConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FollowsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).FollowsConvertToDTO), data)
+}
+
+// MessagesConvertToDTO mocks base method.
+func (m *MockDTOConverterI) MessagesConvertToDTO(data []*Message) []*MessageDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MessagesConvertToDTO", data)
+	ret0, _ := ret[0].([]*MessageDTO)
+	return ret0
+}
+
+// MessagesConvertToDTO indicates an expected call of MessagesConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) MessagesConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MessagesConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).MessagesConvertToDTO), data)
+}
+
+// ModLogConvertToDTO mocks base method.
+func (m *MockDTOConverterI) ModLogConvertToDTO(data []*ModLogComplexData) []*ModLogEntryDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModLogConvertToDTO", data)
+	ret0, _ := ret[0].([]*ModLogEntryDTO)
+	return ret0
+}
+
+// ModLogConvertToDTO indicates an expected call of ModLogConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) ModLogConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModLogConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).ModLogConvertToDTO), data)
+}
+
+// NotificationsConvertToDTO mocks base method.
+func (m *MockDTOConverterI) NotificationsConvertToDTO(data []*NotificationComplexData) []*NotificationDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotificationsConvertToDTO", data)
+	ret0, _ := ret[0].([]*NotificationDTO)
+	return ret0
+}
+
+// NotificationsConvertToDTO indicates an expected call of NotificationsConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) NotificationsConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotificationsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).NotificationsConvertToDTO), data)
+}
+
+// PostConvertToDTO mocks base method.
+func (m *MockDTOConverterI) PostConvertToDTO(data *PostComplexData, sess *Session) (*PostDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostConvertToDTO", data, sess)
+	ret0, _ := ret[0].(*PostDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostConvertToDTO indicates an expected call of PostConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) PostConvertToDTO(data, sess interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).PostConvertToDTO), data, sess)
+}
+
+// PostsConvertToDTO mocks base method.
+func (m *MockDTOConverterI) PostsConvertToDTO(data []*PostComplexData, sess *Session) ([]*PostDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostsConvertToDTO", data, sess)
+	ret0, _ := ret[0].([]*PostDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostsConvertToDTO indicates an expected call of PostsConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) PostsConvertToDTO(data, sess interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostsConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).PostsConvertToDTO), data, sess)
+}
+
+// ReportQueueConvertToDTO mocks base method.
+func (m *MockDTOConverterI) ReportQueueConvertToDTO(data []*ReportQueueItem) []*ReportQueueItemDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportQueueConvertToDTO", data)
+	ret0, _ := ret[0].([]*ReportQueueItemDTO)
+	return ret0
+}
+
+// ReportQueueConvertToDTO indicates an expected call of ReportQueueConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) ReportQueueConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportQueueConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).ReportQueueConvertToDTO), data)
+}
+
+// VotesConvertToDTO mocks base method.
+func (m *MockDTOConverterI) VotesConvertToDTO(data []*Vote) []*VoteDTO {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VotesConvertToDTO", data)
+	ret0, _ := ret[0].([]*VoteDTO)
+	return ret0
+}
+
+// VotesConvertToDTO indicates an expected call of VotesConvertToDTO.
+func (mr *MockDTOConverterIMockRecorder) VotesConvertToDTO(data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VotesConvertToDTO", reflect.TypeOf((*MockDTOConverterI)(nil).VotesConvertToDTO), data)
+}
+
+// MockTimeGetterI is a mock of TimeGetterI interface.
+type MockTimeGetterI struct {
+	ctrl     *gomock.Controller
+	recorder *MockTimeGetterIMockRecorder
+}
+
+// MockTimeGetterIMockRecorder is the mock recorder for MockTimeGetterI.
+type MockTimeGetterIMockRecorder struct {
+	mock *MockTimeGetterI
+}
+
+// NewMockTimeGetterI creates a new mock instance.
+func NewMockTimeGetterI(ctrl *gomock.Controller) *MockTimeGetterI {
+	mock := &MockTimeGetterI{ctrl: ctrl}
+	mock.recorder = &MockTimeGetterIMockRecorder{mock}
+	return mock
+}
 
 // EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockTimeGetterI) EXPECT() *MockTimeGetterIMockRecorder {
@@ -415,6 +1493,83 @@ func (mr *MockTimeGetterIMockRecorder) GetCreated() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreated", reflect.TypeOf((*MockTimeGetterI)(nil).GetCreated))
 }
 
+// Now mocks base method.
+func (m *MockTimeGetterI) Now() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Now")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// Now indicates an expected call of Now.
+func (mr *MockTimeGetterIMockRecorder) Now() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Now", reflect.TypeOf((*MockTimeGetterI)(nil).Now))
+}
+
+// MockEventHubI is a mock of EventHubI interface.
+type MockEventHubI struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventHubIMockRecorder
+}
+
+// MockEventHubIMockRecorder is the mock recorder for MockEventHubI.
+type MockEventHubIMockRecorder struct {
+	mock *MockEventHubI
+}
+
+// NewMockEventHubI creates a new mock instance.
+func NewMockEventHubI(ctrl *gomock.Controller) *MockEventHubI {
+	mock := &MockEventHubI{ctrl: ctrl}
+	mock.recorder = &MockEventHubIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventHubI) EXPECT() *MockEventHubIMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockEventHubI) Publish(event *Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", event)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockEventHubIMockRecorder) Publish(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockEventHubI)(nil).Publish), event)
+}
+
+// Subscribe mocks base method.
+func (m *MockEventHubI) Subscribe(postID, lastEventID string) (*EventClient, []*Event, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", postID, lastEventID)
+	ret0, _ := ret[0].(*EventClient)
+	ret1, _ := ret[1].([]*Event)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockEventHubIMockRecorder) Subscribe(postID, lastEventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockEventHubI)(nil).Subscribe), postID, lastEventID)
+}
+
+// Unsubscribe mocks base method.
+func (m *MockEventHubI) Unsubscribe(client *EventClient) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Unsubscribe", client)
+}
+
+// Unsubscribe indicates an expected call of Unsubscribe.
+func (mr *MockEventHubIMockRecorder) Unsubscribe(client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsubscribe", reflect.TypeOf((*MockEventHubI)(nil).Unsubscribe), client)
+}
+
 // MockUUIDGetterI is a mock of UUIDGetterI interface.
 type MockUUIDGetterI struct {
 	ctrl     *gomock.Controller
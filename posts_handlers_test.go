@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
@@ -92,7 +93,7 @@ var (
 
 	multipleExpectation           = `[{"id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"category":"fashion","comments":[],"created":"2022-11-09T19:51:42Z","score":1,"text":"test fashion","title":"test fashion","type":"text","upvotepercentage":0,"votes":[],"views":0}]`
 	singleExpectation             = `{"id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"category":"fashion","comments":[],"created":"2022-11-09T19:51:42Z","score":1,"text":"test fashion","title":"test fashion","type":"text","upvotepercentage":0,"votes":[],"views":0}`
-	singleExpectationWithComments = `{"id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"category":"fashion","comments":[{"author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"body":"test comment fashion","created":"2022-11-10T11:24:44Z","id":"dbed62a8-79c5-43bd-9594-92cddeb261ac"}],"created":"2022-11-09T19:51:42Z","score":1,"text":"test fashion","title":"test fashion","type":"text","upvotepercentage":0,"votes":[],"views":0}`
+	singleExpectationWithComments = `{"id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"category":"fashion","comments":[{"author":{"username":"mer","id":"522cd619-841f-43d5-866d-f880e5f48d18"},"body":"test comment fashion","created":"2022-11-10T11:24:44Z","id":"dbed62a8-79c5-43bd-9594-92cddeb261ac","score":0}],"created":"2022-11-09T19:51:42Z","score":1,"text":"test fashion","title":"test fashion","type":"text","upvotepercentage":0,"votes":[],"views":0}`
 
 	sess = &Session{
 		ID:     "123",
@@ -108,16 +109,19 @@ func TestList(t *testing.T) {
 	commentRepoMock := NewMockCommentRepoI(ctrl)
 	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:      postsRepoMock,
 		CommentRepo:    commentRepoMock,
 		DictionaryRepo: dictionaryRepoMock,
 		DTOConverter:   dtoConverterMock,
+		BanRepo:        banRepoMock,
 	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil).AnyTimes()
 
 	// success
 	postsRepoMock.EXPECT().GetAll().Return(multipleComplexData, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData).Return(postsDTO, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
 	req := httptest.NewRequest("GET", "/api/posts/", nil)
 	w := httptest.NewRecorder()
 	service.List(w, req)
@@ -142,7 +146,7 @@ func TestList(t *testing.T) {
 
 	//converter error
 	postsRepoMock.EXPECT().GetAll().Return(multipleComplexData, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/posts/", nil)
 	w = httptest.NewRecorder()
 	service.List(w, req)
@@ -153,6 +157,100 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestFeed(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	subscriptionRepoMock := NewMockSubscriptionRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:        postsRepoMock,
+		SubscriptionRepo: subscriptionRepoMock,
+		DTOConverter:     dtoConverterMock,
+		BanRepo:          banRepoMock,
+		TimeGetter:       timeGetterMock,
+	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil).AnyTimes()
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 16, 19, 51, 42, 0, time.UTC)).AnyTimes()
+	since := "2022-11-09T19:51:42Z"
+
+	// subscribed user
+	opts := &ListOptions{Sort: SortTop, Limit: 10, Offset: 20}
+	subscriptionRepoMock.EXPECT().HasSubscriptions(sess.UserID).Return(true, nil)
+	postsRepoMock.EXPECT().GetFeed(sess.UserID, since, opts).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
+	req := httptest.NewRequest("GET", "/api/feed?sort=top&limit=10&offset=20", nil)
+	ctx := context.WithValue(req.Context(), sessionKey, sess)
+	w := httptest.NewRecorder()
+	service.Feed(w, req.WithContext(ctx))
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != multipleExpectation {
+		t.Errorf("there aren't match; want: %#v; have: %#v", multipleExpectation, string(body))
+		return
+	}
+
+	// user without subscriptions gets the global listing
+	subscriptionRepoMock.EXPECT().HasSubscriptions(sess.UserID).Return(false, nil)
+	postsRepoMock.EXPECT().GetAllPaged(NewListOptions()).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
+	req = httptest.NewRequest("GET", "/api/feed", nil)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Feed(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected resp status 200, got %d", w.Result().StatusCode)
+		return
+	}
+
+	// anonymous, the limit is capped
+	postsRepoMock.EXPECT().GetAllPaged(&ListOptions{Sort: SortHot, Limit: ListMaxLimit}).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
+	req = httptest.NewRequest("GET", "/api/feed?sort=hot&limit=1000", nil)
+	w = httptest.NewRecorder()
+	service.Feed(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected resp status 200, got %d", w.Result().StatusCode)
+		return
+	}
+
+	// bad params
+	for _, query := range []string{"sort=random", "limit=0", "limit=x", "offset=-1"} {
+		req = httptest.NewRequest("GET", "/api/feed?"+query, nil)
+		w = httptest.NewRecorder()
+		service.Feed(w, req)
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected resp status 400 for %s, got %d", query, w.Result().StatusCode)
+			return
+		}
+	}
+
+	// subscriptions error
+	subscriptionRepoMock.EXPECT().HasSubscriptions(sess.UserID).Return(false, fmt.Errorf("db_error"))
+	req = httptest.NewRequest("GET", "/api/feed", nil)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Feed(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected resp status 500, got %d", w.Result().StatusCode)
+		return
+	}
+
+	// feed error
+	subscriptionRepoMock.EXPECT().HasSubscriptions(sess.UserID).Return(true, nil)
+	postsRepoMock.EXPECT().GetFeed(sess.UserID, since, NewListOptions()).Return(nil, fmt.Errorf("db_error"))
+	req = httptest.NewRequest("GET", "/api/feed", nil)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.Feed(w, req.WithContext(ctx))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected resp status 500, got %d", w.Result().StatusCode)
+		return
+	}
+}
+
 func TestGetById(t *testing.T) {
 	log.SetOutput(io.Discard)
 	ctrl := gomock.NewController(t)
@@ -161,12 +259,15 @@ func TestGetById(t *testing.T) {
 	commentRepoMock := NewMockCommentRepoI(ctrl)
 	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:      postsRepoMock,
 		DTOConverter:   dtoConverterMock,
 		DictionaryRepo: dictionaryRepoMock,
 		CommentRepo:    commentRepoMock,
+		BanRepo:        banRepoMock,
 	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil).AnyTimes()
 	var postId string = "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 	urlVars := map[string]string{
 		"POST_ID": "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
@@ -174,7 +275,7 @@ func TestGetById(t *testing.T) {
 
 	//success
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	req := httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
 	w := httptest.NewRecorder()
 	req = mux.SetURLVars(req, urlVars)
@@ -201,7 +302,7 @@ func TestGetById(t *testing.T) {
 
 	//converter error
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
 	w = httptest.NewRecorder()
 	req = mux.SetURLVars(req, urlVars)
@@ -211,6 +312,17 @@ func TestGetById(t *testing.T) {
 		t.Errorf("expected resp status code 500; got: %d", resp.StatusCode)
 		return
 	}
+
+	//unknown comment sort
+	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1?comment_sort=random", nil)
+	w = httptest.NewRecorder()
+	req = mux.SetURLVars(req, urlVars)
+	service.GetById(w, req)
+	resp = w.Result()
+	if resp.StatusCode != 400 {
+		t.Errorf("expected resp status code 400; got: %d", resp.StatusCode)
+		return
+	}
 }
 
 func TestGetByCategoryName(t *testing.T) {
@@ -221,12 +333,15 @@ func TestGetByCategoryName(t *testing.T) {
 	commentRepoMock := NewMockCommentRepoI(ctrl)
 	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:      postsRepoMock,
 		DTOConverter:   dtoConverterMock,
 		DictionaryRepo: dictionaryRepoMock,
 		CommentRepo:    commentRepoMock,
+		BanRepo:        banRepoMock,
 	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil).AnyTimes()
 	var categoryName string = "fashion"
 	urlVars := map[string]string{
 		"CATEGORY_NAME": "fashion",
@@ -234,7 +349,7 @@ func TestGetByCategoryName(t *testing.T) {
 
 	//successed
 	postsRepoMock.EXPECT().GetByCategoryName(categoryName).Return(multipleComplexData, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData).Return(postsDTO, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
 	req := httptest.NewRequest("GET", "/api/posts/fashion", nil)
 	w := httptest.NewRecorder()
 	req = mux.SetURLVars(req, urlVars)
@@ -261,7 +376,7 @@ func TestGetByCategoryName(t *testing.T) {
 
 	//converter error
 	postsRepoMock.EXPECT().GetByCategoryName(categoryName).Return(multipleComplexData, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/posts/fashion", nil)
 	w = httptest.NewRecorder()
 	req = mux.SetURLVars(req, urlVars)
@@ -284,6 +399,8 @@ func TestAdd(t *testing.T) {
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
 	timeGetterMock := NewMockTimeGetterI(ctrl)
 	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:      postsRepoMock,
 		DTOConverter:   dtoConverterMock,
@@ -291,7 +408,11 @@ func TestAdd(t *testing.T) {
 		CommentRepo:    commentRepoMock,
 		TimeGetter:     timeGetterMock,
 		UUIDGetter:     uuidGetterMock,
+		BanRepo:        banRepoMock,
+		Automod:        automodMock,
 	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	automodMock.EXPECT().Check(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	post := &Post{
 		ID:          "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
@@ -315,7 +436,7 @@ func TestAdd(t *testing.T) {
 	postsRepoMock.EXPECT().Add(post).Return(&lastID, nil)
 	postsRepoMock.EXPECT().GetById(lastID).Return(multipleComplexData[0], nil)
 	dictionaryRepoMock.EXPECT().GetCategoryByName(categoryName).Return(category, nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
 
@@ -346,6 +467,19 @@ func TestAdd(t *testing.T) {
 		return
 	}
 
+	//text is too long
+	longBody := `{"category":"fashion","type":"text","title":"test fashion","text":"` + strings.Repeat("a", PostTextMaxLen+1) + `"}`
+	req = httptest.NewRequest("POST", "/api/posts", strings.NewReader(longBody))
+	w = httptest.NewRecorder()
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	service.Add(w, req.WithContext(ctx))
+
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 status code, got : %d", resp.StatusCode)
+		return
+	}
+
 	//add error
 	dictionaryRepoMock.EXPECT().GetCategoryByName(categoryName).Return(category, nil)
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
@@ -381,7 +515,7 @@ func TestAdd(t *testing.T) {
 	postsRepoMock.EXPECT().Add(post).Return(&lastID, nil)
 	postsRepoMock.EXPECT().GetById(lastID).Return(multipleComplexData[0], nil)
 	dictionaryRepoMock.EXPECT().GetCategoryByName(categoryName).Return(category, nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	timeGetterMock.EXPECT().GetCreated().Return("2022-11-09T19:51:42Z")
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
 	req = httptest.NewRequest("POST", "/api/posts", strings.NewReader(reqBody))
@@ -395,6 +529,74 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestAddRequireVerified(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	userRepoMock := NewMockUserRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:      postsRepoMock,
+		DictionaryRepo: dictionaryRepoMock,
+		UserRepo:       userRepoMock,
+		BanRepo:        banRepoMock,
+		Automod:        automodMock,
+	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	automodMock.EXPECT().Check(gomock.Any()).Return(nil, nil).AnyTimes()
+	reqBody := `{"category":"fashion","type":"text","title":"test fashion","text":"test fashion"}`
+	category := &Category{
+		ID:              1,
+		Name:            "fashion",
+		RequireVerified: true,
+	}
+
+	//not verified author
+	dictionaryRepoMock.EXPECT().GetCategoryByName(category.Name).Return(category, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(&User{ID: sess.UserID, Verified: false}, nil)
+	req := httptest.NewRequest("POST", "/api/posts", strings.NewReader(reqBody))
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), sessionKey, sess)
+	service.Add(w, req.WithContext(ctx))
+	resp := w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code, got : %d", resp.StatusCode)
+		return
+	}
+
+	//get author error
+	dictionaryRepoMock.EXPECT().GetCategoryByName(category.Name).Return(category, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(nil, fmt.Errorf("db error"))
+	req = httptest.NewRequest("POST", "/api/posts", strings.NewReader(reqBody))
+	w = httptest.NewRecorder()
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	service.Add(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 status code, got : %d", resp.StatusCode)
+		return
+	}
+
+	//not verified commenter
+	postsRepoMock.EXPECT().GetById(multipleComplexData[0].Post.ID).Return(multipleComplexData[0], nil)
+	dictionaryRepoMock.EXPECT().GetCategoryByName(multipleComplexData[0].Category.Name).Return(category, nil)
+	userRepoMock.EXPECT().GetById(sess.UserID).Return(&User{ID: sess.UserID, Verified: false}, nil)
+	req = httptest.NewRequest("POST", "/api/post/"+multipleComplexData[0].Post.ID, strings.NewReader(`{"comment":"test comment"}`))
+	req = mux.SetURLVars(req, map[string]string{"POST_ID": multipleComplexData[0].Post.ID})
+	w = httptest.NewRecorder()
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	service.AddComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code, got : %d", resp.StatusCode)
+		return
+	}
+}
+
 func TestDelete(t *testing.T) {
 	log.SetOutput(io.Discard)
 	ctrl := gomock.NewController(t)
@@ -402,11 +604,14 @@ func TestDelete(t *testing.T) {
 	postsRepoMock := NewMockPostRepoI(ctrl)
 	commentRepoMock := NewMockCommentRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:    postsRepoMock,
 		DTOConverter: dtoConverterMock,
 		CommentRepo:  commentRepoMock,
+		TimeGetter:   timeGetterMock,
 	}
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC)).AnyTimes()
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 	urlVars := map[string]string{
 		"POST_ID": "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
@@ -414,11 +619,13 @@ func TestDelete(t *testing.T) {
 
 	//success
 	expect := `{"message": "success"}`
-	postsRepoMock.EXPECT().Delete(postId).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().Delete(postId, "2022-11-10T11:24:44Z", gomock.Any()).Return(true, nil)
 	req := httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
 	req = mux.SetURLVars(req, urlVars)
+	ctx := context.WithValue(req.Context(), sessionKey, sess)
 	w := httptest.NewRecorder()
-	service.Delete(w, req)
+	service.Delete(w, req.WithContext(ctx))
 	resp := w.Result()
 	body, _ := io.ReadAll(resp.Body)
 	bodyStr := string(body)
@@ -427,12 +634,44 @@ func TestDelete(t *testing.T) {
 		return
 	}
 
+	//not an author
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, &Session{ID: "456", UserID: "other"})
+	w = httptest.NewRecorder()
+	service.Delete(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
+	//moderator of the category
+	moderator := &Session{ID: "456", UserID: "other", Roles: []*Role{
+		{Name: RoleModerator, CategoryID: uint32(multipleComplexData[0].Post.CategoryID)},
+	}}
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().Delete(postId, "2022-11-10T11:24:44Z", gomock.Any()).Return(true, nil)
+	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, moderator)
+	w = httptest.NewRecorder()
+	service.Delete(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
 	//query error
-	postsRepoMock.EXPECT().Delete(postId).Return(false, fmt.Errorf("db_error"))
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	postsRepoMock.EXPECT().Delete(postId, "2022-11-10T11:24:44Z", gomock.Any()).Return(false, fmt.Errorf("db_error"))
 	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", nil)
 	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
 	w = httptest.NewRecorder()
-	service.Delete(w, req)
+	service.Delete(w, req.WithContext(ctx))
 	resp = w.Result()
 	if resp.StatusCode != http.StatusInternalServerError {
 		t.Errorf("expected 500 statuscode; got %d", resp.StatusCode)
@@ -447,23 +686,30 @@ func TestUpVote(t *testing.T) {
 
 	postsRepoMock := NewMockPostRepoI(ctrl)
 	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:    postsRepoMock,
 		DTOConverter: dtoConverterMock,
 		CommentRepo:  commentRepoMock,
+		VoteRepo:     voteRepoMock,
+		BanRepo:      banRepoMock,
 	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 	urlVars := map[string]string{
 		"POST_ID": "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
 	}
 
 	//success
-	postsRepoMock.EXPECT().UpVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteUp).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	req := httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/upvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w := httptest.NewRecorder()
 	service.UpVote(w, req)
 	resp := w.Result()
@@ -475,9 +721,11 @@ func TestUpVote(t *testing.T) {
 	}
 
 	//query error
-	postsRepoMock.EXPECT().UpVote(postId).Return(false, fmt.Errorf("upvote db_error"))
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteUp).Return(false, fmt.Errorf("upvote db_error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/upvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UpVote(w, req)
 	resp = w.Result()
@@ -487,10 +735,12 @@ func TestUpVote(t *testing.T) {
 	}
 
 	//get by id error
-	postsRepoMock.EXPECT().UpVote(postId).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteUp).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(nil, fmt.Errorf("get by id error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/upvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UpVote(w, req)
 	resp = w.Result()
@@ -500,11 +750,13 @@ func TestUpVote(t *testing.T) {
 	}
 
 	//converter error
-	postsRepoMock.EXPECT().UpVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("cconverter error"))
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteUp).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("cconverter error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/upvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UpVote(w, req)
 	resp = w.Result()
@@ -521,23 +773,30 @@ func TestDownVote(t *testing.T) {
 
 	postsRepoMock := NewMockPostRepoI(ctrl)
 	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:    postsRepoMock,
 		DTOConverter: dtoConverterMock,
 		CommentRepo:  commentRepoMock,
+		VoteRepo:     voteRepoMock,
+		BanRepo:      banRepoMock,
 	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 	urlVars := map[string]string{
 		"POST_ID": "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
 	}
 
 	//success
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteDown).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	req := httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/downvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w := httptest.NewRecorder()
 	service.DownVote(w, req)
 	resp := w.Result()
@@ -549,9 +808,11 @@ func TestDownVote(t *testing.T) {
 	}
 
 	//query errir
-	postsRepoMock.EXPECT().DownVote(postId).Return(false, fmt.Errorf("downvote query error"))
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteDown).Return(false, fmt.Errorf("downvote query error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/downvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.DownVote(w, req)
 	resp = w.Result()
@@ -561,10 +822,12 @@ func TestDownVote(t *testing.T) {
 	}
 
 	//query error
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteDown).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(nil, fmt.Errorf("get by id error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/downvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.DownVote(w, req)
 	resp = w.Result()
@@ -574,11 +837,13 @@ func TestDownVote(t *testing.T) {
 	}
 
 	//converter error
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteDown).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/downvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.DownVote(w, req)
 	resp = w.Result()
@@ -595,23 +860,30 @@ func TestUnVote(t *testing.T) {
 
 	postsRepoMock := NewMockPostRepoI(ctrl)
 	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &PostsHandler{
 		PostsRepo:    postsRepoMock,
 		DTOConverter: dtoConverterMock,
 		CommentRepo:  commentRepoMock,
+		VoteRepo:     voteRepoMock,
+		BanRepo:      banRepoMock,
 	}
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 	urlVars := map[string]string{
 		"POST_ID": "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
 	}
 
 	//success
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteNone).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	req := httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/unvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w := httptest.NewRecorder()
 	service.UnVote(w, req)
 	resp := w.Result()
@@ -623,9 +895,11 @@ func TestUnVote(t *testing.T) {
 	}
 
 	//query error
-	postsRepoMock.EXPECT().DownVote(postId).Return(false, fmt.Errorf("unvote query error"))
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteNone).Return(false, fmt.Errorf("unvote query error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/unvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UnVote(w, req)
 	resp = w.Result()
@@ -635,10 +909,12 @@ func TestUnVote(t *testing.T) {
 	}
 
 	//get by id error
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteNone).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(nil, fmt.Errorf("get by id error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/unvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UnVote(w, req)
 	resp = w.Result()
@@ -648,11 +924,13 @@ func TestUnVote(t *testing.T) {
 	}
 
 	//converter error
-	postsRepoMock.EXPECT().DownVote(postId).Return(true, nil)
 	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	voteRepoMock.EXPECT().Vote(postId, sess.UserID, VoteNone).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/unvote", nil)
 	req = mux.SetURLVars(req, urlVars)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
 	w = httptest.NewRecorder()
 	service.UnVote(w, req)
 	resp = w.Result()
@@ -672,13 +950,25 @@ func TestAddComment(t *testing.T) {
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
 	timeGetterMock := NewMockTimeGetterI(ctrl)
 	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	automodMock := NewMockAutomodI(ctrl)
+	blockRepoMock := NewMockBlockRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
 	service := &PostsHandler{
-		PostsRepo:    postsRepoMock,
-		DTOConverter: dtoConverterMock,
-		CommentRepo:  commentRepoMock,
-		TimeGetter:   timeGetterMock,
-		UUIDGetter:   uuidGetterMock,
+		PostsRepo:      postsRepoMock,
+		DTOConverter:   dtoConverterMock,
+		CommentRepo:    commentRepoMock,
+		TimeGetter:     timeGetterMock,
+		UUIDGetter:     uuidGetterMock,
+		BanRepo:        banRepoMock,
+		Automod:        automodMock,
+		BlockRepo:      blockRepoMock,
+		DictionaryRepo: dictionaryRepoMock,
 	}
+	dictionaryRepoMock.EXPECT().GetCategoryByName(gomock.Any()).Return(&Category{ID: 1, Name: "fashion"}, nil).AnyTimes()
+	banRepoMock.EXPECT().IsBanned(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	blockRepoMock.EXPECT().IsBlocked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	automodMock.EXPECT().Check(gomock.Any()).Return(nil, nil).AnyTimes()
 
 	lastID := "dbed62a8-79c5-43bd-9594-92cddeb261ac"
 	newComment := &Comment{
@@ -694,9 +984,9 @@ func TestAddComment(t *testing.T) {
 	}
 
 	//success
-	commentRepoMock.EXPECT().Add(newComment).Return(&lastID, nil)
 	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTOWithComments[0], nil)
+	commentRepoMock.EXPECT().Add(newComment).Return(&lastID, nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTOWithComments[0], nil)
 	timeGetterMock.EXPECT().GetCreated().Return(newComment.Created)
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
 
@@ -714,7 +1004,20 @@ func TestAddComment(t *testing.T) {
 		return
 	}
 
+	//comment is too long
+	req = httptest.NewRequest("POST", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", strings.NewReader(`{"comment":"`+strings.Repeat("a", CommentMaxLen+1)+`"}`))
+	w = httptest.NewRecorder()
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	service.AddComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
 	//query error
+	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(multipleComplexData[0], nil)
 	timeGetterMock.EXPECT().GetCreated().Return(newComment.Created)
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
 	commentRepoMock.EXPECT().Add(newComment).Return(nil, fmt.Errorf("add query error"))
@@ -730,9 +1033,6 @@ func TestAddComment(t *testing.T) {
 	}
 
 	//get by id error
-	timeGetterMock.EXPECT().GetCreated().Return(newComment.Created)
-	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
-	commentRepoMock.EXPECT().Add(newComment).Return(&lastID, nil)
 	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(nil, fmt.Errorf("get by id error"))
 	req = httptest.NewRequest("POST", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", strings.NewReader(reqBody))
 	w = httptest.NewRecorder()
@@ -746,11 +1046,11 @@ func TestAddComment(t *testing.T) {
 	}
 
 	//converter error
+	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(multipleComplexData[0], nil)
 	timeGetterMock.EXPECT().GetCreated().Return(newComment.Created)
 	uuidGetterMock.EXPECT().GetUUID().Return(lastID)
 	commentRepoMock.EXPECT().Add(newComment).Return(&lastID, nil)
-	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("POST", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", strings.NewReader(reqBody))
 	w = httptest.NewRecorder()
 	req = mux.SetURLVars(req, urlVars)
@@ -761,6 +1061,38 @@ func TestAddComment(t *testing.T) {
 		t.Errorf("expected 500 statuscode; got %d", resp.StatusCode)
 		return
 	}
+	//locked post
+	lockedPost := *multipleComplexData[0]
+	lockedPost.Post.Locked = true
+	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(&lockedPost, nil)
+	req = httptest.NewRequest("POST", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", strings.NewReader(reqBody))
+	w = httptest.NewRecorder()
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	service.AddComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
+	//the commenter and the post author blocked one another
+	blockingCtrl := gomock.NewController(t)
+	defer blockingCtrl.Finish()
+	blockingRepoMock := NewMockBlockRepoI(blockingCtrl)
+	service.BlockRepo = blockingRepoMock
+	postsRepoMock.EXPECT().GetById(newComment.PostId).Return(multipleComplexData[0], nil)
+	blockingRepoMock.EXPECT().IsBlocked("other", multipleComplexData[0].Post.UserID).Return(true, nil)
+	req = httptest.NewRequest("POST", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1", strings.NewReader(reqBody))
+	w = httptest.NewRecorder()
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, &Session{ID: "456", UserID: "other"})
+	service.AddComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", resp.StatusCode)
+		return
+	}
 }
 
 func TestDeleteComment(t *testing.T) {
@@ -779,6 +1111,7 @@ func TestDeleteComment(t *testing.T) {
 		TimeGetter:   timeGetterMock,
 		UUIDGetter:   uuidGetterMock,
 	}
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC)).AnyTimes()
 
 	commentID := "dbed62a8-79c5-43bd-9594-92cddeb261ac"
 	postID := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
@@ -786,11 +1119,17 @@ func TestDeleteComment(t *testing.T) {
 		"POST_ID":    postID,
 		"COMMENT_ID": commentID,
 	}
+	comment := &Comment{
+		ID:     commentID,
+		PostId: postID,
+		UserId: sess.UserID,
+	}
 
 	//success
-	commentRepoMock.EXPECT().Delete(commentID).Return(true, nil)
-	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(postsDTO[0], nil)
+	commentRepoMock.EXPECT().GetById(commentID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil).Times(2)
+	commentRepoMock.EXPECT().Delete(commentID, "2022-11-10T11:24:44Z", gomock.Any()).Return(true, nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
 	req := httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
 	req = mux.SetURLVars(req, urlVars)
 	ctx := context.WithValue(req.Context(), sessionKey, sess)
@@ -804,8 +1143,37 @@ func TestDeleteComment(t *testing.T) {
 		return
 	}
 
+	//not an author
+	commentRepoMock.EXPECT().GetById(commentID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, &Session{ID: "456", UserID: "other"})
+	w = httptest.NewRecorder()
+	service.DeleteComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
+	//comment of another post
+	commentRepoMock.EXPECT().GetById(commentID).Return(&Comment{ID: commentID, PostId: "other"}, nil)
+	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
+	req = mux.SetURLVars(req, urlVars)
+	ctx = context.WithValue(req.Context(), sessionKey, sess)
+	w = httptest.NewRecorder()
+	service.DeleteComment(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", resp.StatusCode)
+		return
+	}
+
 	//query error
-	commentRepoMock.EXPECT().Delete(commentID).Return(false, fmt.Errorf("delete query error"))
+	commentRepoMock.EXPECT().GetById(commentID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
+	commentRepoMock.EXPECT().Delete(commentID, "2022-11-10T11:24:44Z", gomock.Any()).Return(false, fmt.Errorf("delete query error"))
 	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
 	req = mux.SetURLVars(req, urlVars)
 	ctx = context.WithValue(req.Context(), sessionKey, sess)
@@ -818,7 +1186,7 @@ func TestDeleteComment(t *testing.T) {
 	}
 
 	//get by id error
-	commentRepoMock.EXPECT().Delete(commentID).Return(true, nil)
+	commentRepoMock.EXPECT().GetById(commentID).Return(comment, nil)
 	postsRepoMock.EXPECT().GetById(postID).Return(nil, fmt.Errorf("get by id error"))
 	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
 	req = mux.SetURLVars(req, urlVars)
@@ -832,9 +1200,10 @@ func TestDeleteComment(t *testing.T) {
 	}
 
 	//converter error
-	commentRepoMock.EXPECT().Delete(commentID).Return(true, nil)
-	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil)
-	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0]).Return(nil, fmt.Errorf("converter error"))
+	commentRepoMock.EXPECT().GetById(commentID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(multipleComplexData[0], nil).Times(2)
+	commentRepoMock.EXPECT().Delete(commentID, "2022-11-10T11:24:44Z", gomock.Any()).Return(true, nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("DELETE", "/api/post/dc1e2f25-76a5-4aac-9212-96e2121c16f1/dbed62a8-79c5-43bd-9594-92cddeb261ac", nil)
 	req = mux.SetURLVars(req, urlVars)
 	ctx = context.WithValue(req.Context(), sessionKey, sess)
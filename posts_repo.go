@@ -2,13 +2,82 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 )
 
 type PostsRepo struct {
 	DB *sql.DB
 }
 
+// postSelect and scanPost are shared by all the queries returning
+// PostComplexData, keep the columns and the scan order in sync.
+const postSelect = `
+	SELECT 
+	post.id AS post_id, title, type, description, 
+	score, user_id, category_id, post.created AS post_created,
+	post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+	user.id AS user_user_id, user.login,
+	category.name AS category_name,
+	COALESCE(link_preview.title, ''), COALESCE(link_preview.description, ''), 
+	COALESCE(link_preview.image, '')
+	FROM post 
+	LEFT JOIN user ON user.id = post.user_id
+	LEFT JOIN category ON category.id = post.category_id
+	LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'`
+
+// MaxPinnedPosts is how many posts a category may sticky at once
+const MaxPinnedPosts = 2
+
+// FeedMaxDepth is how many posts of the feed can be paged through, the
+// pages after it are empty
+const FeedMaxDepth = 1000
+
+var ErrPinLimit = errors.New("too many pinned posts")
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost shows the score below zero as zero, the column keeps the sum of
+// the votes so it never drifts from them
+func scanPost(row rowScanner) (*PostComplexData, error) {
+	data := &PostComplexData{}
+	var score int64
+	err := row.Scan(&data.Post.ID, &data.Post.Title,
+		&data.Post.Type, &data.Post.Description,
+		&score, &data.Post.UserID,
+		&data.Post.CategoryID, &data.Post.Created,
+		&data.Post.Removed, &data.Post.Locked, &data.Post.Pinned,
+		&data.Post.Flair, &data.Post.URL, &data.Post.Image,
+		&data.User.ID, &data.User.Login,
+		&data.Category.Name,
+		&data.LinkPreview.Title, &data.LinkPreview.Description,
+		&data.LinkPreview.Image)
+	if nil != err {
+		return nil, err
+	}
+	if score > 0 {
+		data.Post.Score = uint32(score)
+	}
+	return data, nil
+}
+
+func scanPosts(rows *sql.Rows) ([]*PostComplexData, error) {
+	defer rows.Close()
+	posts := make([]*PostComplexData, 0, 10)
+	for rows.Next() {
+		data, err := scanPost(rows)
+		if nil != err {
+			fmt.Println("scan: ", err)
+			return nil, err
+		}
+		posts = append(posts, data)
+	}
+	return posts, rows.Err()
+}
+
 func NewPostsRepo(db *sql.DB) *PostsRepo {
 	postsRepo := &PostsRepo{
 		DB: db,
@@ -20,154 +89,202 @@ func NewPostsRepo(db *sql.DB) *PostsRepo {
 func (repo *PostsRepo) GetAll() ([]*PostComplexData, error) {
 	fmt.Println("Repo post: get all posts")
 
-	rows, err := repo.DB.
-		Query(
-			`
-			SELECT 
-			post.id AS post_id, title, type, description, score, user_id, category_id, post.created AS post_created,
-			user.id AS user_id, user.login,
-			category.name AS category_name
-			FROM post
-			LEFT JOIN user ON user.id = post.user_id
-			LEFT JOIN category ON category.id = post.category_id
-			ORDER BY post.created DESC
-			`)
+	rows, err := repo.DB.Query(postSelect + `
+	WHERE post.removed = 0 AND post.deleted_at = ''
+	ORDER BY post.created DESC`)
 	if nil != err {
 		fmt.Println("get all: ", err)
 		return nil, err
 	}
-	defer rows.Close()
+	return scanPosts(rows)
+}
 
-	posts := make([]*PostComplexData, 0, 10)
-	for rows.Next() {
-		data := &PostComplexData{}
-		err := rows.Scan(&data.Post.ID, &data.Post.Title,
-			&data.Post.Type, &data.Post.Description,
-			&data.Post.Score, &data.Post.UserID,
-			&data.Post.CategoryID, &data.Post.Created,
-			&data.User.ID, &data.User.Login,
-			&data.Category.Name)
-		if nil != err {
-			fmt.Println("scan: ", err)
-			return nil, err
-		}
-		posts = append(posts, data)
+func (repo *PostsRepo) GetAllPaged(opts *ListOptions) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get all posts paged")
+
+	rows, err := repo.DB.Query(postSelect+`
+	WHERE post.removed = 0 AND post.deleted_at = ''
+	ORDER BY `+opts.OrderBy()+`
+	LIMIT ? OFFSET ?`,
+		opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("get all paged: ", err)
+		return nil, err
 	}
+	return scanPosts(rows)
+}
+
+// GetFeed merges the first posts of every subscribed category. Each
+// category gives the offset plus the limit of its posts at most, up to
+// FeedMaxDepth, read from the category_id_created or the category_id_score
+// index in the order of the page, so only those rows are sorted together
+// however many posts the categories have. The hot rank isn't indexed, so
+// the hot feed ranks the posts created since the given time only.
+func (repo *PostsRepo) GetFeed(userID string, since string, opts *ListOptions) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get feed")
 
-	return posts, nil
+	depth := opts.Offset + opts.Limit
+	if depth > FeedMaxDepth {
+		depth = FeedMaxDepth
+	}
+	window := ""
+	args := []interface{}{}
+	if opts.Sort == SortHot {
+		window = "AND post.created >= ?"
+		args = append(args, since)
+	}
+	args = append(args, depth, userID, opts.Limit, opts.Offset)
+	rows, err := repo.DB.Query(postSelect+`
+	JOIN (
+		SELECT feed_post.id
+		FROM subscription, LATERAL (
+			SELECT post.id FROM post
+			WHERE post.category_id = subscription.category_id
+			AND post.removed = 0 AND post.deleted_at = '' `+window+`
+			ORDER BY `+opts.OrderBy()+`
+			LIMIT ?
+		) AS feed_post
+		WHERE subscription.user_id = ?
+	) AS feed ON feed.id = post.id
+	ORDER BY `+opts.OrderBy()+`
+	LIMIT ? OFFSET ?`,
+		args...)
+	if nil != err {
+		fmt.Println("get feed: ", err)
+		return nil, err
+	}
+	return scanPosts(rows)
 }
 
 func (repo *PostsRepo) GetById(id string) (*PostComplexData, error) {
 	fmt.Println("Repo post: get by id post")
 
-	row := repo.DB.QueryRow(`
-	SELECT 
-	post.id AS post_id, title, type, description, 
-	score, user_id, category_id, post.created AS post_created,
-	user.id AS user_user_id, user.login,
-	category.name AS category_name
-	FROM post 
-	LEFT JOIN user ON user.id = post.user_id
-	LEFT JOIN category ON category.id = post.category_id
-	WHERE post.id = ?`, id)
+	row := repo.DB.QueryRow(postSelect+`
+	WHERE post.id = ? AND post.deleted_at = ''`, id)
 
-	data := &PostComplexData{}
-	err := row.Scan(&data.Post.ID, &data.Post.Title, &data.Post.Type,
-		&data.Post.Description, &data.Post.Score, &data.Post.UserID,
-		&data.Post.CategoryID, &data.Post.Created,
-		&data.User.ID, &data.User.Login,
-		&data.Category.Name)
+	return scanPost(row)
+}
+
+// GetByIds returns the visible posts of the ids in no particular order
+func (repo *PostsRepo) GetByIds(ids []string) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get by ids")
+	if len(ids) == 0 {
+		return []*PostComplexData{}, nil
+	}
+	placeHolders := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		placeHolders = append(placeHolders, "?")
+		args = append(args, id)
+	}
+	rows, err := repo.DB.Query(postSelect+`
+	WHERE post.id IN (`+strings.Join(placeHolders, ",")+`) AND post.removed = 0 AND post.deleted_at = ''`,
+		args...)
 	if nil != err {
+		fmt.Println("get by ids: ", err)
 		return nil, err
 	}
+	return scanPosts(rows)
+}
 
-	return data, nil
+// GetFollowedFeed lists the posts of the users the user follows
+func (repo *PostsRepo) GetFollowedFeed(userID string, opts *ListOptions) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get followed feed")
+
+	rows, err := repo.DB.Query(postSelect+`
+	JOIN user_follow ON user_follow.followed_id = post.user_id
+	WHERE user_follow.follower_id = ? AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY `+opts.OrderBy()+`
+	LIMIT ? OFFSET ?`,
+		userID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("get followed feed: ", err)
+		return nil, err
+	}
+	return scanPosts(rows)
 }
 
 func (repo *PostsRepo) GetByCategoryName(categoryName string) ([]*PostComplexData, error) {
 	fmt.Println("Repo post: get posts by categoryName")
-	rows, err := repo.DB.Query(
-		`
-		SELECT
-		post.id AS post_id, title, type, description, 
-		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
-		FROM post 
-		LEFT JOIN user ON user.id = post.user_id
-		LEFT JOIN category ON category.id = post.category_id
-		WHERE category.name = ?`,
+	rows, err := repo.DB.Query(postSelect+`
+	WHERE category.name = ? AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY post.pinned DESC, post.created DESC`,
 		categoryName)
 	if nil != err {
 		fmt.Println("get all: ", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	posts := make([]*PostComplexData, 0, 10)
-	for rows.Next() {
-		data := &PostComplexData{}
-		err := rows.Scan(&data.Post.ID, &data.Post.Title,
-			&data.Post.Type, &data.Post.Description,
-			&data.Post.Score, &data.Post.UserID,
-			&data.Post.CategoryID, &data.Post.Created,
-			&data.User.ID, &data.User.Login,
-			&data.Category.Name)
-		if nil != err {
-			fmt.Println("scan: ", err)
-			return nil, err
-		}
-		posts = append(posts, data)
-	}
-
-	return posts, nil
+	return scanPosts(rows)
 }
 
 func (repo *PostsRepo) GetByUserLogin(userLogin string) ([]*PostComplexData, error) {
 	fmt.Println("Repo post: get posts by user login")
 
-	rows, err := repo.DB.Query(`
-	SELECT 
-	post.id AS post_id, title, type, description, 
-	score, user_id, category_id, post.created AS post_created,
-	user.id AS user_id, user.login,
-	category.name AS category_name
-	FROM post 
-	LEFT JOIN user ON user.id = post.user_id
-	LEFT JOIN category ON category.id = post.category_id
-	WHERE user.login = ?`,
+	rows, err := repo.DB.Query(postSelect+`
+	WHERE user.login = ? AND post.removed = 0 AND post.deleted_at = ''`,
 		userLogin)
 	if nil != err {
 		fmt.Println("get all: ", err)
 		return nil, err
 	}
-	defer rows.Close()
+	return scanPosts(rows)
+}
 
-	posts := make([]*PostComplexData, 0, 10)
-	for rows.Next() {
-		data := &PostComplexData{}
-		err := rows.Scan(&data.Post.ID, &data.Post.Title,
-			&data.Post.Type, &data.Post.Description,
-			&data.Post.Score, &data.Post.UserID,
-			&data.Post.CategoryID, &data.Post.Created,
-			&data.User.ID, &data.User.Login,
-			&data.Category.Name)
-		if nil != err {
-			fmt.Println("scan: ", err)
-			return nil, err
-		}
-		posts = append(posts, data)
+func (repo *PostsRepo) GetByUserId(userID string, opts *ListOptions) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get posts by user id")
+
+	rows, err := repo.DB.Query(postSelect+`
+	WHERE post.user_id = ? AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY `+opts.OrderBy()+`
+	LIMIT ? OFFSET ?`,
+		userID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("get by user id: ", err)
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// GetVotedByUserId returns the visible posts the user gave the vote
+func (repo *PostsRepo) GetVotedByUserId(userID string, vote int32, opts *ListOptions) ([]*PostComplexData, error) {
+	fmt.Println("Repo post: get voted by user id")
+
+	rows, err := repo.DB.Query(postSelect+`
+	JOIN vote ON vote.post_id = post.id
+	WHERE vote.user_id = ? AND vote.vote = ? AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY `+opts.OrderBy()+`
+	LIMIT ? OFFSET ?`,
+		userID, vote, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("get voted by user id: ", err)
+		return nil, err
 	}
-	return posts, nil
+	return scanPosts(rows)
+}
+
+// GetRecentByURL returns the newest visible post of the category with
+// the same canonical url created after since
+func (repo *PostsRepo) GetRecentByURL(categoryID uint, url string, since string) (*PostComplexData, error) {
+	fmt.Println("Repo post: get recent by url")
+
+	row := repo.DB.QueryRow(postSelect+`
+	WHERE post.category_id = ? AND post.canonical_url = ? AND post.created > ? 
+	AND post.removed = 0 AND post.deleted_at = ''
+	ORDER BY post.created DESC
+	LIMIT 1`,
+		categoryID, url, since)
+
+	return scanPost(row)
 }
 
 func (repo *PostsRepo) Add(post *Post) (*string, error) {
 	fmt.Println("Repo post: add post")
 
 	result, err := repo.DB.Exec(`INSERT INTO post 
-	(id, title, type, description, score, user_id, category_id, created) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		post.ID, post.Title, post.Type, post.Description, post.Score, post.UserID, post.CategoryID, post.Created)
+	(id, title, type, url, canonical_url, image, description, score, user_id, category_id, created, removed, flair) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		post.ID, post.Title, post.Type, post.URL, post.CanonicalURL, post.Image, post.Description, post.Score, post.UserID, post.CategoryID, post.Created,
+		post.Removed, post.Flair)
 
 	if err != nil {
 		return nil, err
@@ -180,10 +297,12 @@ func (repo *PostsRepo) Add(post *Post) (*string, error) {
 	return &post.ID, nil
 }
 
-func (repo *PostsRepo) Delete(id string) (bool, error) {
+// Delete only marks the post, it stays restorable by the author until
+// PurgeDeleted removes it. A deleted post loses its pin.
+func (repo *PostsRepo) Delete(id string, deletedAt string, deletedBy string) (bool, error) {
 	fmt.Println("Repo post: delete post")
 
-	result, err := repo.DB.Exec(`DELETE FROM post WHERE id = ?`, id)
+	result, err := repo.DB.Exec(`UPDATE post SET deleted_at = ?, deleted_by = ?, pinned = 0 WHERE id = ? AND deleted_at = ''`, deletedAt, deletedBy, id)
 	if err != nil {
 		return false, err
 	}
@@ -197,10 +316,14 @@ func (repo *PostsRepo) Delete(id string) (bool, error) {
 	return true, nil
 }
 
-func (repo *PostsRepo) UpVote(id string) (bool, error) {
-	fmt.Println("Repo post: upvote")
-
-	result, err := repo.DB.Exec(`UPDATE post SET score = score + 1 WHERE id = ?`, id)
+// Restore brings back a post its author deleted after since, a post a
+// moderator deleted stays deleted. deleted_at is RFC3339 in UTC so it
+// compares as a string
+func (repo *PostsRepo) Restore(id string, userID string, since string) (bool, error) {
+	fmt.Println("Repo post: restore post")
+	result, err := repo.DB.Exec(`UPDATE post SET deleted_at = '', deleted_by = '' 
+	WHERE id = ? AND user_id = ? AND deleted_by = user_id AND deleted_at <> '' AND deleted_at > ?`,
+		id, userID, since)
 	if err != nil {
 		return false, err
 	}
@@ -208,21 +331,129 @@ func (repo *PostsRepo) UpVote(id string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if affected != 1 {
-		return false, fmt.Errorf("wrong update rows: %d for id post: %s", affected, id)
+	return affected == 1, nil
+}
+
+// PurgeDeleted removes the posts deleted before the given time together
+// with their votes, previews, comments, comment votes, reports, saves and
+// notifications, there are no cascading FKs
+// from them to post. It returns the image keys of the purged posts, their
+// blobs are left to the caller.
+func (repo *PostsRepo) PurgeDeleted(before string) (int64, []string, error) {
+	fmt.Println("Repo post: purge deleted")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return 0, nil, err
 	}
-	return true, nil
+	defer tx.Rollback()
+
+	const purged = `post.deleted_at <> '' AND post.deleted_at < ?`
+	rows, err := tx.Query(`SELECT image FROM post WHERE `+purged+` AND image <> '' FOR UPDATE`, before)
+	if nil != err {
+		return 0, nil, err
+	}
+	images := []string{}
+	for rows.Next() {
+		var image string
+		err = rows.Scan(&image)
+		if nil != err {
+			rows.Close()
+			return 0, nil, err
+		}
+		images = append(images, image)
+	}
+	rows.Close()
+	if err = rows.Err(); nil != err {
+		return 0, nil, err
+	}
+
+	for _, query := range []string{
+		`DELETE vote FROM vote JOIN post ON post.id = vote.post_id WHERE ` + purged,
+		`DELETE link_preview FROM link_preview JOIN post ON post.id = link_preview.post_id WHERE ` + purged,
+		`DELETE report FROM report JOIN post ON post.id = report.post_id WHERE ` + purged,
+		`DELETE saved_item FROM saved_item JOIN post ON post.id = saved_item.post_id WHERE ` + purged,
+		`DELETE notification FROM notification JOIN post ON post.id = notification.post_id WHERE ` + purged,
+		`DELETE comment_vote FROM comment_vote JOIN comment ON comment.id = comment_vote.comment_id 
+		JOIN post ON post.id = comment.post_id WHERE ` + purged,
+		`DELETE comment FROM comment JOIN post ON post.id = comment.post_id WHERE ` + purged,
+	} {
+		_, err = tx.Exec(query, before)
+		if nil != err {
+			return 0, nil, err
+		}
+	}
+	result, err := tx.Exec(`DELETE FROM post WHERE `+purged, before)
+	if nil != err {
+		return 0, nil, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return 0, nil, err
+	}
+	return affected, images, tx.Commit()
+}
+
+// SetRemoved unpins the removed post, so it doesn't come back pinned over
+// the limit when it's approved; the second assignment sees the new removed
+func (repo *PostsRepo) SetRemoved(id string, removed bool) (bool, error) {
+	fmt.Println("Repo post: set removed")
+	return repo.setFlag(`UPDATE post SET removed = ?, pinned = IF(removed = 1, 0, pinned) WHERE id = ?`, removed, id)
+}
+
+func (repo *PostsRepo) SetLocked(id string, locked bool) (bool, error) {
+	fmt.Println("Repo post: set locked")
+	return repo.setFlag(`UPDATE post SET locked = ? WHERE id = ?`, locked, id)
+}
+
+func (repo *PostsRepo) Unpin(id string) (bool, error) {
+	fmt.Println("Repo post: unpin")
+	return repo.setFlag(`UPDATE post SET pinned = ? WHERE id = ?`, false, id)
 }
 
-func (repo *PostsRepo) DownVote(id string) (bool, error) {
-	fmt.Println("Repo post: downvote")
-	result, err := repo.DB.Exec(`UPDATE post SET score = IF(score = 0, score, score - 1) WHERE id = ?`, id)
+// Pin locks the pinned posts of the category, so two moderators
+// can't sticky a third post at the same time. Only the visible posts
+// take the places.
+func (repo *PostsRepo) Pin(id string, categoryID uint) (bool, error) {
+	fmt.Println("Repo post: pin")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var pinned int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM post 
+	WHERE category_id = ? AND pinned = 1 AND removed = 0 AND deleted_at = '' FOR UPDATE`, categoryID).
+		Scan(&pinned)
+	if nil != err {
+		return false, err
+	}
+	if pinned >= MaxPinnedPosts {
+		return false, ErrPinLimit
+	}
+
+	result, err := tx.Exec(`UPDATE post SET pinned = 1 
+	WHERE id = ? AND pinned = 0 AND removed = 0 AND deleted_at = ''`, id)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, tx.Commit()
+}
+
+// setFlag reports whether the row has changed, so repeated moderation
+// actions are not logged twice
+func (repo *PostsRepo) setFlag(query string, value bool, id string) (bool, error) {
+	result, err := repo.DB.Exec(query, value, id)
 	if err != nil {
 		return false, err
 	}
-	_, err = result.RowsAffected()
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return false, err
 	}
-	return true, nil
+	return affected == 1, nil
 }
@@ -21,8 +21,10 @@ func TestPostsGetAllSuccessed(t *testing.T) {
 			"post_id", "title", "type",
 			"description", "score", "user_id",
 			"category_id", "post_created",
+			"removed", "locked", "pinned", "flair", "url", "image",
 			"user_user_id", "login",
 			"category_name",
+			"preview_title", "preview_description", "preview_image",
 		})
 
 	expect := []*PostComplexData{
@@ -49,19 +51,26 @@ func TestPostsGetAllSuccessed(t *testing.T) {
 	for _, post := range expect {
 		rows = rows.AddRow(post.Post.ID, post.Post.Title,
 			post.Post.Type, post.Post.Description, post.Post.Score,
-			post.Post.UserID, post.Post.CategoryID, post.Post.Created, post.User.ID, post.User.Login,
-			post.Category.Name)
+			post.Post.UserID, post.Post.CategoryID, post.Post.Created,
+			post.Post.Removed, post.Post.Locked, post.Post.Pinned, post.Post.Flair, post.Post.URL, post.Post.Image, post.User.ID, post.User.Login,
+			post.Category.Name,
+			post.LinkPreview.Title, post.LinkPreview.Description, post.LinkPreview.Image)
 	}
 
 	mock.
 		ExpectQuery(`
 		SELECT 
 		post.id AS post_id, title, type, description, score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
+		WHERE post.removed = 0 AND post.deleted_at = ''
 		ORDER BY post.created DESC`).
 		WillReturnRows(rows)
 
@@ -93,11 +102,16 @@ func TestPostsGetAllQueryError(t *testing.T) {
 	mock.ExpectQuery(
 		`SELECT
 		post.id AS post_id, title, type, description, score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
+		WHERE post.removed = 0 AND post.deleted_at = ''
 		ORDER BY post.created DESC`).
 		WillReturnError(fmt.Errorf("db_error"))
 
@@ -130,11 +144,16 @@ func TestPostsGetAllScanError(t *testing.T) {
 	mock.ExpectQuery(
 		`SELECT
 		post.id AS post_id, title, type, description, score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
+		WHERE post.removed = 0 AND post.deleted_at = ''
 		ORDER BY post.created DESC`).
 		WillReturnRows(rows)
 
@@ -168,11 +187,15 @@ func TestPostsGetByIdQueryError(t *testing.T) {
 		SELECT 
 	post.id AS post_id, title, type, description, 
 	score, user_id, category_id, post.created AS post_created,
+	post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
 	user.id AS user_user_id, user.login,
-	category.name AS category_name
+	category.name AS category_name,
+	COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+	COALESCE\(link_preview.image, ''\)
 	FROM post 
 	LEFT JOIN user ON user.id = post.user_id
-	LEFT JOIN category ON category.id = post.category_id WHERE post.id =`).
+	LEFT JOIN category ON category.id = post.category_id
+	LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok' WHERE post.id =`).
 		WithArgs(id).
 		WillReturnError(fmt.Errorf("db_error"))
 
@@ -203,8 +226,10 @@ func TestPostsGetByIdSuccessed(t *testing.T) {
 			"post_id", "title", "type",
 			"description", "score", "user_id",
 			"category_id", "post_created",
+			"removed", "locked", "pinned", "flair", "url", "image",
 			"user_user_id", "login",
-			"category_name"})
+			"category_name",
+			"preview_title", "preview_description", "preview_image"})
 
 	expect := []*PostComplexData{
 		{
@@ -230,8 +255,10 @@ func TestPostsGetByIdSuccessed(t *testing.T) {
 	for _, post := range expect {
 		rows = rows.AddRow(post.Post.ID, post.Post.Title,
 			post.Post.Type, post.Post.Description, post.Post.Score,
-			post.Post.UserID, post.Post.CategoryID, post.Post.Created, post.User.ID, post.User.Login,
-			post.Category.Name)
+			post.Post.UserID, post.Post.CategoryID, post.Post.Created,
+			post.Post.Removed, post.Post.Locked, post.Post.Pinned, post.Post.Flair, post.Post.URL, post.Post.Image, post.User.ID, post.User.Login,
+			post.Category.Name,
+			post.LinkPreview.Title, post.LinkPreview.Description, post.LinkPreview.Image)
 	}
 
 	mock.
@@ -239,11 +266,15 @@ func TestPostsGetByIdSuccessed(t *testing.T) {
 		SELECT 
 	post.id AS post_id, title, type, description, 
 	score, user_id, category_id, post.created AS post_created,
+	post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
 	user.id AS user_user_id, user.login,
-	category.name AS category_name
+	category.name AS category_name,
+	COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+	COALESCE\(link_preview.image, ''\)
 	FROM post 
 	LEFT JOIN user ON user.id = post.user_id
-	LEFT JOIN category ON category.id = post.category_id WHERE post.id =`).
+	LEFT JOIN category ON category.id = post.category_id
+	LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok' WHERE post.id =`).
 		WithArgs(id).
 		WillReturnRows(rows)
 
@@ -284,11 +315,15 @@ func TestPostsGetByIdScanError(t *testing.T) {
 		SELECT 
 	post.id AS post_id, title, type, description, 
 	score, user_id, category_id, post.created AS post_created,
+	post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
 	user.id AS user_user_id, user.login,
-	category.name AS category_name
+	category.name AS category_name,
+	COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+	COALESCE\(link_preview.image, ''\)
 	FROM post 
 	LEFT JOIN user ON user.id = post.user_id
-	LEFT JOIN category ON category.id = post.category_id WHERE post.id =`).
+	LEFT JOIN category ON category.id = post.category_id
+	LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok' WHERE post.id =`).
 		WithArgs(id).
 		WillReturnRows(rows)
 
@@ -326,7 +361,8 @@ func TestPostsAddSuccessed(t *testing.T) {
 
 	mock.
 		ExpectExec(`INSERT INTO post`).
-		WithArgs(post.ID, post.Title, post.Type, post.Description, post.Score, post.UserID, post.CategoryID, post.Created).
+		WithArgs(post.ID, post.Title, post.Type, post.URL, post.CanonicalURL, post.Image, post.Description, post.Score, post.UserID, post.CategoryID, post.Created,
+			post.Removed, post.Flair).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	postId, err := postsRepo.Add(post)
@@ -365,7 +401,8 @@ func TestPostsAddQueryError(t *testing.T) {
 
 	mock.
 		ExpectExec(`INSERT INTO post`).
-		WithArgs(post.ID, post.Title, post.Type, post.Description, post.Score, post.UserID, post.CategoryID, post.Created).
+		WithArgs(post.ID, post.Title, post.Type, post.URL, post.CanonicalURL, post.Image, post.Description, post.Score, post.UserID, post.CategoryID, post.Created,
+			post.Removed, post.Flair).
 		WillReturnError(fmt.Errorf("bad query"))
 
 	_, err = postsRepo.Add(post)
@@ -400,7 +437,8 @@ func TestPostsAddResultError(t *testing.T) {
 
 	mock.
 		ExpectExec(`INSERT INTO post`).
-		WithArgs(post.ID, post.Title, post.Type, post.Description, post.Score, post.UserID, post.CategoryID, post.Created).
+		WithArgs(post.ID, post.Title, post.Type, post.URL, post.CanonicalURL, post.Image, post.Description, post.Score, post.UserID, post.CategoryID, post.Created,
+			post.Removed, post.Flair).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
 
 	_, err = postsRepo.Add(post)
@@ -425,11 +463,11 @@ func TestPostsDeleteSuccessed(t *testing.T) {
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 
 	mock.
-		ExpectExec(`DELETE FROM post`).
-		WithArgs(postId).
+		ExpectExec(`UPDATE post SET deleted_at = \?, deleted_by = \?, pinned = 0 WHERE id = \? AND deleted_at = ''`).
+		WithArgs("2022-11-10T11:24:44Z", sess.UserID, postId).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	result, err := postsRepo.Delete(postId)
+	result, err := postsRepo.Delete(postId, "2022-11-10T11:24:44Z", sess.UserID)
 
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
@@ -456,11 +494,11 @@ func TestPostsDeleteQueryError(t *testing.T) {
 
 	//query error
 	mock.
-		ExpectExec(`DELETE FROM post`).
-		WithArgs(postId).
+		ExpectExec(`UPDATE post SET deleted_at = \?, deleted_by = \?, pinned = 0 WHERE id = \? AND deleted_at = ''`).
+		WithArgs("2022-11-10T11:24:44Z", sess.UserID, postId).
 		WillReturnError(fmt.Errorf("bad_query"))
 
-	_, err = postsRepo.Delete(postId)
+	_, err = postsRepo.Delete(postId, "2022-11-10T11:24:44Z", sess.UserID)
 
 	if err == nil {
 		t.Errorf("expected error, got nil")
@@ -482,11 +520,11 @@ func TestPostsDeleteResultError(t *testing.T) {
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 
 	mock.
-		ExpectExec(`DELETE FROM post`).
-		WithArgs(postId).
+		ExpectExec(`UPDATE post SET deleted_at = \?, deleted_by = \?, pinned = 0 WHERE id = \? AND deleted_at = ''`).
+		WithArgs("2022-11-10T11:24:44Z", sess.UserID, postId).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
 
-	_, err = postsRepo.Delete(postId)
+	_, err = postsRepo.Delete(postId, "2022-11-10T11:24:44Z", sess.UserID)
 
 	if err == nil {
 		t.Errorf("expected error, got nil")
@@ -508,214 +546,16 @@ func TestPostsDeleteRowsAffectedError(t *testing.T) {
 	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
 
 	mock.
-		ExpectExec(`DELETE FROM post`).
-		WithArgs(postId).
+		ExpectExec(`UPDATE post SET deleted_at = \?, deleted_by = \?, pinned = 0 WHERE id = \? AND deleted_at = ''`).
+		WithArgs("2022-11-10T11:24:44Z", sess.UserID, postId).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	_, err = postsRepo.Delete(postId)
-
-	if err == nil {
-		t.Errorf("expected error, got nil")
-		return
-	}
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsUpVoteSuccessed(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connetcion", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec("UPDATE post SET").
-		WithArgs(postId).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-
-	result, err := postsRepo.UpVote(postId)
-
-	if err != nil {
-		t.Errorf("unexpected error: %s", err)
-		return
-	}
-
-	if !result {
-		t.Errorf("expected true")
-		return
-	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsUpVoteQueryError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnError(fmt.Errorf("bad_query"))
-
-	_, err = postsRepo.UpVote(postId)
+	_, err = postsRepo.Delete(postId, "2022-11-10T11:24:44Z", sess.UserID)
 
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
 	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsUpVoteResultError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad result")))
-
-	_, err = postsRepo.UpVote(postId)
-
-	if err == nil {
-		t.Error("expected error, got nil")
-		return
-	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsUpVoteRowsAffectedError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnResult(sqlmock.NewResult(0, 0))
-
-	_, err = postsRepo.UpVote(postId)
-
-	if err == nil {
-		t.Error("expected error, got nil")
-		return
-	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsDownVoteSuccessed(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-
-	result, err := postsRepo.DownVote(postId)
-
-	if !result {
-		t.Errorf("expected true")
-		return
-	}
-
-	if err != nil {
-		t.Errorf("expected result, got error %s", err)
-	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsDownVoteQueryError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnError(fmt.Errorf("bad_query"))
-
-	_, err = postsRepo.DownVote(postId)
-
-	if err == nil {
-		t.Errorf("expected error, got nil")
-		return
-	}
-
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
-	}
-}
-
-func TestPostsDownVoteResultError(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	defer db.Close()
-
-	postsRepo := NewPostsRepo(db)
-	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
-
-	mock.
-		ExpectExec(`UPDATE post SET`).
-		WithArgs(postId).
-		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad result")))
-
-	_, err = postsRepo.DownVote(postId)
-
-	if err == nil {
-		t.Error("expected error, got nil")
-		return
-	}
-
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
@@ -736,8 +576,10 @@ func TestPostsGetByCategoryNameSuccessed(t *testing.T) {
 			"post_id", "title", "type",
 			"description", "score", "user_id",
 			"category_id", "post_created",
+			"removed", "locked", "pinned", "flair", "url", "image",
 			"user_user_id", "login",
-			"category_name"})
+			"category_name",
+			"preview_title", "preview_description", "preview_image"})
 
 	expect := []*PostComplexData{
 		{
@@ -763,19 +605,25 @@ func TestPostsGetByCategoryNameSuccessed(t *testing.T) {
 	for _, post := range expect {
 		rows = rows.AddRow(post.Post.ID, post.Post.Title,
 			post.Post.Type, post.Post.Description, post.Post.Score,
-			post.Post.UserID, post.Post.CategoryID, post.Post.Created, post.User.ID, post.User.Login,
-			post.Category.Name)
+			post.Post.UserID, post.Post.CategoryID, post.Post.Created,
+			post.Post.Removed, post.Post.Locked, post.Post.Pinned, post.Post.Flair, post.Post.URL, post.Post.Image, post.User.ID, post.User.Login,
+			post.Category.Name,
+			post.LinkPreview.Title, post.LinkPreview.Description, post.LinkPreview.Image)
 	}
 
 	mock.
 		ExpectQuery(`		SELECT
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE category.name = `).
 		WithArgs(categoryName).
 		WillReturnRows(rows)
@@ -816,11 +664,15 @@ func TestPostsGetByCategoryNameScanError(t *testing.T) {
 		ExpectQuery(`		SELECT
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE category.name = `).
 		WithArgs(categoryName).
 		WillReturnRows(rows)
@@ -851,11 +703,15 @@ func TestPostsGetByCategoryNameQueryError(t *testing.T) {
 		ExpectQuery(`		SELECT
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE category.name = `).
 		WithArgs(categoryName).
 		WillReturnError(fmt.Errorf("db_error"))
@@ -887,8 +743,10 @@ func TestPostsGetByLoginSuccessed(t *testing.T) {
 			"post_id", "title", "type",
 			"description", "score", "user_id",
 			"category_id", "post_created",
+			"removed", "locked", "pinned", "flair", "url", "image",
 			"user_user_id", "login",
-			"category_name"})
+			"category_name",
+			"preview_title", "preview_description", "preview_image"})
 
 	expect := []*PostComplexData{
 		{
@@ -914,19 +772,25 @@ func TestPostsGetByLoginSuccessed(t *testing.T) {
 	for _, post := range expect {
 		rows = rows.AddRow(post.Post.ID, post.Post.Title,
 			post.Post.Type, post.Post.Description, post.Post.Score,
-			post.Post.UserID, post.Post.CategoryID, post.Post.Created, post.User.ID, post.User.Login,
-			post.Category.Name)
+			post.Post.UserID, post.Post.CategoryID, post.Post.Created,
+			post.Post.Removed, post.Post.Locked, post.Post.Pinned, post.Post.Flair, post.Post.URL, post.Post.Image, post.User.ID, post.User.Login,
+			post.Category.Name,
+			post.LinkPreview.Title, post.LinkPreview.Description, post.LinkPreview.Image)
 	}
 
 	mock.
 		ExpectQuery(`	SELECT 
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE user.login = ?`).
 		WithArgs(login).
 		WillReturnRows(rows)
@@ -968,11 +832,15 @@ func TestPostsGetByLoginScanError(t *testing.T) {
 		ExpectQuery(`	SELECT 
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE user.login = ?`).
 		WithArgs(login).
 		WillReturnRows(rows)
@@ -1004,11 +872,15 @@ func TestPostsGetByLoginQueryError(t *testing.T) {
 		ExpectQuery(`	SELECT 
 		post.id AS post_id, title, type, description, 
 		score, user_id, category_id, post.created AS post_created,
-		user.id AS user_id, user.login,
-		category.name AS category_name
+		post.removed, post.locked, post.pinned, post.flair, post.url, post.image,
+		user.id AS user_user_id, user.login,
+		category.name AS category_name,
+		COALESCE\(link_preview.title, ''\), COALESCE\(link_preview.description, ''\),
+		COALESCE\(link_preview.image, ''\)
 		FROM post 
 		LEFT JOIN user ON user.id = post.user_id
 		LEFT JOIN category ON category.id = post.category_id
+		LEFT JOIN link_preview ON link_preview.post_id = post.id AND link_preview.status = 'ok'
 		WHERE user.login = ?`).
 		WithArgs(login).
 		WillReturnError(fmt.Errorf("db_error"))
@@ -1025,3 +897,232 @@ func TestPostsGetByLoginQueryError(t *testing.T) {
 		return
 	}
 }
+
+func TestPostsGetFeed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+	opts := &ListOptions{Sort: SortTop, Limit: 10, Offset: 20}
+	rows := sqlmock.
+		NewRows([]string{
+			"post_id", "title", "type",
+			"description", "score", "user_id",
+			"category_id", "post_created",
+			"removed", "locked", "pinned", "flair", "url", "image",
+			"user_user_id", "login",
+			"category_name",
+			"preview_title", "preview_description", "preview_image",
+		}).
+		AddRow("dc1e2f25-76a5-4aac-9212-96e2121c16f1", "test fashion", "text", "test fashion", 1,
+			userID, 1, "2022-11-09T19:51:42Z", false, false, false, "", "", "", userID, "mer", "fashion",
+			"", "", "")
+
+	// success, every category gives the 30 first posts at most
+	mock.
+		ExpectQuery(`JOIN \(
+		SELECT feed_post.id
+		FROM subscription, LATERAL \(
+			SELECT post.id FROM post
+			WHERE post.category_id = subscription.category_id
+			AND post.removed = 0 AND post.deleted_at = ''
+			ORDER BY post.score DESC, post.created DESC, post.id
+			LIMIT \?
+		\) AS feed_post
+		WHERE subscription.user_id = \?
+	\) AS feed ON feed.id = post.id
+	ORDER BY post.score DESC, post.created DESC, post.id
+	LIMIT \? OFFSET \?`).
+		WithArgs(30, userID, 10, 20).
+		WillReturnRows(rows)
+	posts, err := postsRepo.GetFeed(userID, "2022-11-02T19:51:42Z", opts)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(posts) != 1 || posts[0].Category.Name != "fashion" {
+		t.Errorf("results not match, got %#v", posts)
+		return
+	}
+
+	// the hot feed ranks the posts of the window, the depth is capped
+	mock.
+		ExpectQuery(`AND post.removed = 0 AND post.deleted_at = '' AND post.created >= \?`).
+		WithArgs("2022-11-02T19:51:42Z", FeedMaxDepth, userID, 10, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}))
+	_, err = postsRepo.GetFeed(userID, "2022-11-02T19:51:42Z", &ListOptions{Sort: SortHot, Limit: 10, Offset: 2000})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	// query error
+	mock.
+		ExpectQuery(`WHERE subscription.user_id = \?`).
+		WithArgs(30, userID, 10, 20).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = postsRepo.GetFeed(userID, "2022-11-02T19:51:42Z", opts)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestPostsGetAllPaged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+
+	// unknown sort falls back to new
+	mock.
+		ExpectQuery(`ORDER BY post.created DESC, post.id
+	LIMIT \? OFFSET \?`).
+		WithArgs(25, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}))
+	posts, err := postsRepo.GetAllPaged(&ListOptions{Sort: "drop table", Limit: 25})
+	if err != nil || len(posts) != 0 {
+		t.Errorf("unexpected result: %#v %s", posts, err)
+		return
+	}
+
+	// query error
+	mock.
+		ExpectQuery(`ORDER BY LOG10`).
+		WithArgs(25, 0).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = postsRepo.GetAllPaged(&ListOptions{Sort: SortHot, Limit: 25})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestPostsPin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	id := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
+
+	// success
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM post 
+	WHERE category_id = \? AND pinned = 1 AND removed = 0 AND deleted_at = '' FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(`UPDATE post SET pinned = 1 
+	WHERE id = \? AND pinned = 0 AND removed = 0 AND deleted_at = ''`).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isPinned, err := postsRepo.Pin(id, 1)
+	if err != nil || !isPinned {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	// limit
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM post`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(MaxPinnedPosts))
+	mock.ExpectRollback()
+	_, err = postsRepo.Pin(id, 1)
+	if err != ErrPinLimit {
+		t.Errorf("expected pin limit error, got %v", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestPostsSetRemoved(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	id := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
+
+	// the removed post is unpinned
+	mock.ExpectExec(`UPDATE post SET removed = \?, pinned = IF\(removed = 1, 0, pinned\) WHERE id = \?`).
+		WithArgs(true, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	isChanged, err := postsRepo.SetRemoved(id, true)
+	if err != nil || !isChanged {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestPostsSetLocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	id := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
+
+	// changed
+	mock.ExpectExec(`UPDATE post SET locked = \? WHERE id = \?`).
+		WithArgs(true, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	isChanged, err := postsRepo.SetLocked(id, true)
+	if err != nil || !isChanged {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	// already locked
+	mock.ExpectExec(`UPDATE post SET locked = \? WHERE id = \?`).
+		WithArgs(true, id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isChanged, err = postsRepo.SetLocked(id, true)
+	if err != nil || isChanged {
+		t.Errorf("expected no change, got %v %s", isChanged, err)
+		return
+	}
+
+	// query error
+	mock.ExpectExec(`UPDATE post SET locked`).
+		WithArgs(false, id).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = postsRepo.SetLocked(id, false)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
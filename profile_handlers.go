@@ -0,0 +1,194 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const ProfileBioMaxLen = 255
+
+func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	profile, err := h.UserRepo.GetProfileByLogin(mux.Vars(r)["USER_LOGIN"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return
+	} else if nil != err {
+		fmt.Println("can't get profile", err)
+		jsonError(w, http.StatusInternalServerError, "can't get profile")
+		return
+	}
+	followers, following, err := h.FollowRepo.Count(profile.ID)
+	if nil != err {
+		fmt.Println("can't count follows", err)
+		jsonError(w, http.StatusInternalServerError, "can't get profile")
+		return
+	}
+	jsonResponse(w, &ProfileDTO{
+		ID:           profile.ID,
+		UserName:     profile.Login,
+		Created:      profile.Created,
+		Bio:          profile.Bio,
+		Karma:        profile.PostKarma + profile.CommentKarma,
+		PostKarma:    profile.PostKarma,
+		CommentKarma: profile.CommentKarma,
+		Followers:    followers,
+		Following:    following,
+	})
+}
+
+func (h *UserHandler) SaveProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &ProfileRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	requestData.Bio = strings.TrimSpace(requestData.Bio)
+	if len(requestData.Bio) > ProfileBioMaxLen {
+		jsonError(w, http.StatusBadRequest, "bio is too long")
+		return
+	}
+
+	err = h.UserRepo.SetBio(sess.UserID, requestData.Bio)
+	if nil != err {
+		fmt.Println("can't save bio", err)
+		jsonError(w, http.StatusInternalServerError, "can't save profile")
+		return
+	}
+	jsonResponse(w, requestData)
+}
+
+// ProfilePosts is the posts tab, it takes the sort of the listings
+func (h *UserHandler) ProfilePosts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	user, opts, ok := h.readProfileTab(w, r, false)
+	if !ok {
+		return
+	}
+	data, err := h.PostsRepo.GetByUserId(user.ID, opts)
+	if nil != err {
+		fmt.Println("can't get posts by user id", err)
+		jsonError(w, http.StatusInternalServerError, "can't get posts")
+		return
+	}
+	h.writeProfilePosts(w, r, data)
+}
+
+func (h *UserHandler) ProfileComments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	user, opts, ok := h.readProfileTab(w, r, false)
+	if !ok {
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
+	isHidden, err := shadowbanFilter(h.BanRepo, sess)
+	if nil != err {
+		fmt.Println("can't get shadowbans", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+	commentsDTO := []*ProfileCommentDTO{}
+	if isHidden(user.ID) {
+		jsonResponse(w, commentsDTO)
+		return
+	}
+	data, err := h.CommentRepo.GetByUserId(user.ID, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get comments by user id", err)
+		jsonError(w, http.StatusInternalServerError, "can't get comments")
+		return
+	}
+	for i, comment := range h.DTOConverter.CommentsConvertToDTO(data) {
+		commentsDTO = append(commentsDTO, &ProfileCommentDTO{
+			PostID:  data[i].Comment.PostId,
+			Comment: comment,
+		})
+	}
+	jsonResponse(w, commentsDTO)
+}
+
+// ProfileUpvoted and ProfileDownvoted are for the user themselves only
+func (h *UserHandler) ProfileUpvoted(w http.ResponseWriter, r *http.Request) {
+	h.profileVoted(w, r, VoteUp)
+}
+
+func (h *UserHandler) ProfileDownvoted(w http.ResponseWriter, r *http.Request) {
+	h.profileVoted(w, r, VoteDown)
+}
+
+func (h *UserHandler) profileVoted(w http.ResponseWriter, r *http.Request, vote int32) {
+	w.Header().Add("Content-Type", "application/json")
+	user, opts, ok := h.readProfileTab(w, r, true)
+	if !ok {
+		return
+	}
+	data, err := h.PostsRepo.GetVotedByUserId(user.ID, vote, opts)
+	if nil != err {
+		fmt.Println("can't get voted posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get posts")
+		return
+	}
+	h.writeProfilePosts(w, r, data)
+}
+
+func (h *UserHandler) writeProfilePosts(w http.ResponseWriter, r *http.Request, data []*PostComplexData) {
+	sess, _ := SessionFromContext(r.Context())
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't convert posts")
+		return
+	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if nil != err {
+		fmt.Println("can't hide shadowbanned posts: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+	jsonResponse(w, postsDTO)
+}
+
+// readProfileTab resolves the user of the path and the page, the private
+// tabs answer 403 to anyone else; it writes the error itself.
+func (h *UserHandler) readProfileTab(w http.ResponseWriter, r *http.Request, private bool) (*User, *ListOptions, bool) {
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	user, err := h.UserRepo.GetByLogin(mux.Vars(r)["USER_LOGIN"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, nil, false
+	} else if nil != err {
+		fmt.Println("can't get user by login", err)
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, nil, false
+	}
+	if private {
+		sess, err := SessionFromContext(r.Context())
+		if err != nil || sess.UserID != user.ID {
+			jsonError(w, http.StatusForbidden, "the votes are private")
+			return nil, nil, false
+		}
+	}
+	return user, opts, true
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestProfile(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	followRepoMock := NewMockFollowRepoI(ctrl)
+	service := &UserHandler{
+		UserRepo:   userRepoMock,
+		FollowRepo: followRepoMock,
+	}
+
+	//success
+	userRepoMock.EXPECT().GetProfileByLogin("bob").Return(&UserProfile{
+		User:         User{ID: "u2", Login: "bob", Created: "2022-11-02T15:24:00Z"},
+		Bio:          "hi",
+		PostKarma:    10,
+		CommentKarma: -2,
+	}, nil)
+	followRepoMock.EXPECT().Count("u2").Return(int64(3), int64(1), nil)
+	req := httptest.NewRequest("GET", "/api/user/bob/profile", nil)
+	w := httptest.NewRecorder()
+	service.Profile(w, mux.SetURLVars(req, map[string]string{"USER_LOGIN": "bob"}))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `{"id":"u2","username":"bob","created":"2022-11-02T15:24:00Z","bio":"hi","karma":8,"post_karma":10,"comment_karma":-2,"followers":3,"following":1}`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//unknown user
+	userRepoMock.EXPECT().GetProfileByLogin("nobody").Return(nil, sql.ErrNoRows)
+	req = httptest.NewRequest("GET", "/api/user/nobody/profile", nil)
+	w = httptest.NewRecorder()
+	service.Profile(w, mux.SetURLVars(req, map[string]string{"USER_LOGIN": "nobody"}))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//bio
+	userRepoMock.EXPECT().SetBio(sess.UserID, "about me").Return(nil)
+	req = httptest.NewRequest("POST", "/api/user/me/profile", strings.NewReader(`{"bio":" about me "}`))
+	w = httptest.NewRecorder()
+	service.SaveProfile(w, req.WithContext(context.WithValue(req.Context(), sessionKey, sess)))
+	body, _ = io.ReadAll(w.Result().Body)
+	if string(body) != `{"bio":"about me"}` {
+		t.Errorf("bad response: %s", body)
+		return
+	}
+}
+
+func TestProfileTabs(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	service := &UserHandler{
+		UserRepo:     userRepoMock,
+		PostsRepo:    postsRepoMock,
+		CommentRepo:  commentRepoMock,
+		BanRepo:      banRepoMock,
+		DTOConverter: dtoConverterMock,
+	}
+	owner := &User{ID: sess.UserID, Login: "mer"}
+	newRequest := func(url string, withSession bool) *http.Request {
+		req := httptest.NewRequest("GET", url, nil)
+		if withSession {
+			req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+		}
+		return mux.SetURLVars(req, map[string]string{"USER_LOGIN": "mer"})
+	}
+
+	//posts with the sort and the page
+	userRepoMock.EXPECT().GetByLogin("mer").Return(owner, nil)
+	postsRepoMock.EXPECT().GetByUserId(sess.UserID, &ListOptions{Sort: SortTop, Limit: 5, Offset: 10}).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, gomock.Any()).Return(postsDTO, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	w := httptest.NewRecorder()
+	service.ProfilePosts(w, newRequest("/api/user/mer/posts?sort=top&limit=5&offset=10", false))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//comments
+	comments := []*CommentComplexData{{Comment: Comment{ID: "c1", PostId: "p1", Body: "nice"}, User: User{ID: sess.UserID, Login: "mer"}}}
+	userRepoMock.EXPECT().GetByLogin("mer").Return(owner, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	commentRepoMock.EXPECT().GetByUserId(sess.UserID, ListDefaultLimit, 0).Return(comments, nil)
+	dtoConverterMock.EXPECT().CommentsConvertToDTO(comments).Return((&DTOConverter{}).CommentsConvertToDTO(comments))
+	w = httptest.NewRecorder()
+	service.ProfileComments(w, newRequest("/api/user/mer/comments", false))
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `[{"post_id":"p1","comment":{"author":{"username":"mer","id":"` + sess.UserID + `"},"body":"nice","created":"","id":"c1","score":0}}]`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//the votes are private
+	userRepoMock.EXPECT().GetByLogin("mer").Return(&User{ID: "u2", Login: "mer"}, nil)
+	w = httptest.NewRecorder()
+	service.ProfileUpvoted(w, newRequest("/api/user/mer/upvoted", true))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//own downvotes
+	userRepoMock.EXPECT().GetByLogin("mer").Return(owner, nil)
+	postsRepoMock.EXPECT().GetVotedByUserId(sess.UserID, VoteDown, NewListOptions()).Return(multipleComplexData, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexData, sess).Return(postsDTO, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	w = httptest.NewRecorder()
+	service.ProfileDownvoted(w, newRequest("/api/user/mer/downvoted", true))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const DeletedPlaceholder = "[deleted]"
+
+var (
+	// RestoreWindow is how long an author may restore deleted content
+	RestoreWindow = 7 * 24 * time.Hour
+	// DeletedRetention must not be shorter than RestoreWindow
+	DeletedRetention = 30 * 24 * time.Hour
+	PurgeInterval    = time.Hour
+)
+
+// deletedNow is RFC3339 in UTC, so deleted_at compares as a string
+func deletedNow(timeGetter TimeGetterI) string {
+	return timeGetter.Now().UTC().Format(time.RFC3339)
+}
+
+// PurgeJob removes the soft deleted posts and comments once they are
+// past the retention period
+type PurgeJob struct {
+	PostsRepo   PostRepoI
+	CommentRepo CommentRepoI
+	BlobStore   BlobStore
+	TimeGetter  TimeGetterI
+	Retention   time.Duration
+	Interval    time.Duration
+}
+
+func NewPurgeJob(db *sql.DB) *PurgeJob {
+	return &PurgeJob{
+		PostsRepo:   NewPostsRepo(db),
+		CommentRepo: NewCommentRepo(db),
+		BlobStore:   NewBlobStore(),
+		TimeGetter:  &TimeGetter{},
+		Retention:   DeletedRetention,
+		Interval:    PurgeInterval,
+	}
+}
+
+// Run purges the posts first, their comments go with them. The images go
+// once the rows are gone, a blob left by a failed delete is only logged.
+func (job *PurgeJob) Run() error {
+	before := job.TimeGetter.Now().UTC().Add(-job.Retention).Format(time.RFC3339)
+	posts, images, err := job.PostsRepo.PurgeDeleted(before)
+	if nil != err {
+		return fmt.Errorf("can't purge posts: %s", err.Error())
+	}
+	for _, key := range images {
+		deleteImageBlobs(job.BlobStore, key)
+	}
+	comments, err := job.CommentRepo.PurgeDeleted(before)
+	if nil != err {
+		return fmt.Errorf("can't purge comments: %s", err.Error())
+	}
+	fmt.Println("purged deleted posts:", posts, "comments:", comments)
+	return nil
+}
+
+// Start runs the job right away and then every Interval until done is closed
+func (job *PurgeJob) Start(done <-chan struct{}) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		err := job.Run()
+		if nil != err {
+			fmt.Println("purge job:", err)
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestPurgeJobRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	store := NewLocalBlobStore(t.TempDir())
+	job := &PurgeJob{
+		PostsRepo:   postsRepoMock,
+		CommentRepo: commentRepoMock,
+		BlobStore:   store,
+		TimeGetter:  timeGetterMock,
+		Retention:   30 * 24 * time.Hour,
+	}
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 12, 10, 11, 24, 44, 0, time.UTC)).AnyTimes()
+	store.Put("images/p1.png", "image/png", []byte("png data"))
+	store.Put("images/p1_thumb.jpg", "image/jpeg", []byte("jpeg data"))
+	store.Put("images/p2.png", "image/png", []byte("png data"))
+
+	//success, the images of the purged posts go too
+	postsRepoMock.EXPECT().PurgeDeleted("2022-11-10T11:24:44Z").Return(int64(2), []string{"images/p1.png"}, nil)
+	commentRepoMock.EXPECT().PurgeDeleted("2022-11-10T11:24:44Z").Return(int64(5), nil)
+	if err := job.Run(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	for key, isKept := range map[string]bool{"images/p1.png": false, "images/p1_thumb.jpg": false, "images/p2.png": true} {
+		if _, err := store.Get(key); (err == nil) != isKept {
+			t.Errorf("%s: expected kept %v, got %v", key, isKept, err)
+		}
+	}
+
+	//posts error stops the run
+	postsRepoMock.EXPECT().PurgeDeleted("2022-11-10T11:24:44Z").Return(int64(0), nil, fmt.Errorf("db error"))
+	if err := job.Run(); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+}
+
+func TestPostsPurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	before := "2022-11-10T11:24:44Z"
+
+	//success, the dependent rows go first
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT image FROM post WHERE post.deleted_at <> '' AND post.deleted_at < \? AND image <> '' FOR UPDATE`).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"image"}).AddRow("images/p1.png"))
+	mock.ExpectExec(`DELETE vote FROM vote JOIN post ON post.id = vote.post_id WHERE post.deleted_at <> '' AND post.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE link_preview FROM link_preview JOIN post ON post.id = link_preview.post_id`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE report FROM report JOIN post ON post.id = report.post_id`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE saved_item FROM saved_item JOIN post ON post.id = saved_item.post_id WHERE post.deleted_at <> ''`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE notification FROM notification JOIN post ON post.id = notification.post_id WHERE post.deleted_at <> ''`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE comment_vote FROM comment_vote JOIN comment ON comment.id = comment_vote.comment_id JOIN post ON post.id = comment.post_id WHERE post.deleted_at <> ''`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE comment FROM comment JOIN post ON post.id = comment.post_id`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`DELETE FROM post WHERE post.deleted_at <> '' AND post.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	purged, images, err := postsRepo.PurgeDeleted(before)
+	if err != nil || purged != 2 || !reflect.DeepEqual(images, []string{"images/p1.png"}) {
+		t.Errorf("expected 2 purged posts, got %d %v %v", purged, images, err)
+		return
+	}
+
+	//error rolls back
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT image FROM post`).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"image"}))
+	mock.ExpectExec(`DELETE vote FROM vote`).
+		WithArgs(before).
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+	_, _, err = postsRepo.PurgeDeleted(before)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCommentsPurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	commentRepo := NewCommentRepo(db)
+	before := "2022-11-10T11:24:44Z"
+
+	//success, the reports, the votes, the saves and the notifications go first
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE report FROM report JOIN comment ON report.target_type = 'comment' AND report.target_id = comment.id WHERE comment.deleted_at <> '' AND comment.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE comment_vote FROM comment_vote JOIN comment ON comment.id = comment_vote.comment_id WHERE comment.deleted_at <> '' AND comment.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE saved_item FROM saved_item JOIN comment ON saved_item.type = 'comment' AND saved_item.item_id = comment.id WHERE comment.deleted_at <> ''`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE notification FROM notification JOIN comment ON notification.comment_id = comment.id WHERE comment.deleted_at <> ''`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE comment FROM comment LEFT JOIN comment AS reply ON reply.parent_id = comment.id WHERE reply.id IS NULL AND comment.deleted_at <> '' AND comment.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	//the ones with replies keep their place without the body
+	mock.ExpectExec(`UPDATE comment SET body = '' WHERE body <> '' AND comment.deleted_at <> '' AND comment.deleted_at < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	purged, err := commentRepo.PurgeDeleted(before)
+	if err != nil || purged != 2 {
+		t.Errorf("expected 2 purged comments, got %d %v", purged, err)
+		return
+	}
+
+	//error rolls back
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE report FROM report`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE comment_vote FROM comment_vote`).
+		WithArgs(before).
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+	_, err = commentRepo.PurgeDeleted(before)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostsRestore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	postsRepo := NewPostsRepo(db)
+	postId := "dc1e2f25-76a5-4aac-9212-96e2121c16f1"
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+	since := "2022-11-03T11:24:44Z"
+
+	//success
+	mock.ExpectExec(`UPDATE post SET deleted_at = '', deleted_by = '' WHERE id = \? AND user_id = \? AND deleted_by = user_id`).
+		WithArgs(postId, userID, since).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	isRestored, err := postsRepo.Restore(postId, userID, since)
+	if err != nil || !isRestored {
+		t.Errorf("expected restored post, got %v %v", isRestored, err)
+		return
+	}
+
+	//out of the window or deleted by a moderator
+	mock.ExpectExec(`UPDATE post SET deleted_at = ''`).
+		WithArgs(postId, userID, since).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isRestored, err = postsRepo.Restore(postId, userID, since)
+	if err != nil || isRestored {
+		t.Errorf("expected nothing restored, got %v %v", isRestored, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRestorePost(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:    postsRepoMock,
+		CommentRepo:  commentRepoMock,
+		DTOConverter: dtoConverterMock,
+		TimeGetter:   timeGetterMock,
+	}
+	postId := multipleComplexData[0].Post.ID
+	commentID := "dbed62a8-79c5-43bd-9594-92cddeb261ac"
+	since := "2022-11-03T11:24:44Z"
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC)).AnyTimes()
+	newRequest := func(vars map[string]string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+postId+"/restore", nil)
+		req = mux.SetURLVars(req, vars)
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+
+	//success
+	postsRepoMock.EXPECT().Restore(postId, sess.UserID, since).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
+	w := httptest.NewRecorder()
+	service.RestorePost(w, newRequest(map[string]string{"POST_ID": postId}))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//not the author or past the window
+	postsRepoMock.EXPECT().Restore(postId, sess.UserID, since).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.RestorePost(w, newRequest(map[string]string{"POST_ID": postId}))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//comment
+	commentRepoMock.EXPECT().Restore(commentID, postId, sess.UserID, since).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postId).Return(multipleComplexData[0], nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(multipleComplexData[0], gomock.Any()).Return(postsDTO[0], nil)
+	w = httptest.NewRecorder()
+	service.RestoreComment(w, newRequest(map[string]string{"POST_ID": postId, "COMMENT_ID": commentID}))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//comment of another post
+	commentRepoMock.EXPECT().Restore(commentID, "other", sess.UserID, since).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.RestoreComment(w, newRequest(map[string]string{"POST_ID": "other", "COMMENT_ID": commentID}))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	commentRepoMock.EXPECT().Restore(commentID, postId, sess.UserID, since).Return(false, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.RestoreComment(w, newRequest(map[string]string{"POST_ID": postId, "COMMENT_ID": commentID}))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestCommentsConvertToDTODeleted(t *testing.T) {
+	converter := &DTOConverter{}
+	commentsDTO := converter.CommentsConvertToDTO([]*CommentComplexData{
+		{
+			Comment: Comment{ID: "1", Body: "first", DeletedAt: "2022-11-10T11:24:44Z"},
+			User:    User{ID: sess.UserID, Login: "mer"},
+		},
+		{
+			Comment: Comment{ID: "2", Body: "second"},
+			User:    User{ID: sess.UserID, Login: "mer"},
+		},
+	})
+	if len(commentsDTO) != 2 {
+		t.Errorf("deleted comment lost its place: %d comments", len(commentsDTO))
+		return
+	}
+	deleted := commentsDTO[0]
+	if !deleted.Deleted || deleted.Body != DeletedPlaceholder || deleted.Author.UserName != DeletedPlaceholder || deleted.Author.ID != "" {
+		t.Errorf("deleted comment is not hidden: %#v %#v", deleted, deleted.Author)
+		return
+	}
+	if commentsDTO[1].Deleted || commentsDTO[1].Body != "second" {
+		t.Errorf("unexpected comment: %#v", commentsDTO[1])
+	}
+}
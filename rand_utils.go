@@ -1,6 +1,8 @@
 package main
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 )
@@ -27,3 +29,21 @@ func RandBytes(n int) []byte {
 	rand.Read(res)
 	return res
 }
+
+// RandSecureHex is for tokens sent to users, unlike the helpers above
+// it reads from crypto/rand.
+func RandSecureHex(n int) (string, error) {
+	res, err := RandSecureBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(res), nil
+}
+
+func RandSecureBytes(n int) ([]byte, error) {
+	res := make([]byte, n)
+	if _, err := crand.Read(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
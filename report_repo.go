@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// reportReasonSeparator joins the reasons in GROUP_CONCAT, reasons are
+// stored without line breaks
+const reportReasonSeparator = "\n"
+
+type ReportRepo struct {
+	DB *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) *ReportRepo {
+	return &ReportRepo{
+		DB: db,
+	}
+}
+
+// Add returns false if the user has an open report of the item already,
+// a report resolved by the moderators is opened again with the new reason
+func (repo *ReportRepo) Add(report *Report) (bool, error) {
+	fmt.Println("Report repo: add")
+	result, err := repo.DB.Exec(`INSERT INTO report 
+	(target_type, target_id, post_id, category_id, author_id, reporter_id, reason, created) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE
+	reason = IF(resolved = 1, VALUES(reason), reason),
+	created = IF(resolved = 1, VALUES(created), created),
+	resolved = 0`,
+		report.TargetType, report.TargetID, report.PostID, report.CategoryID,
+		report.AuthorID, report.ReporterID, report.Reason, report.Created)
+	if nil != err {
+		return false, err
+	}
+	// 1 for a new row, 2 for a reopened one and 0 for an open one
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (repo *ReportRepo) GetQueue(categoryID uint32, limit int, offset int) ([]*ReportQueueItem, error) {
+	fmt.Println("Report repo: get queue")
+	rows, err := repo.DB.Query(`
+	SELECT
+	target_type, target_id, post_id, author_id, 
+	COUNT(*) AS reports,
+	GROUP_CONCAT(DISTINCT reason ORDER BY reason SEPARATOR '\n') AS reasons,
+	MIN(created) AS first_reported
+	FROM report
+	WHERE category_id = ? AND resolved = 0
+	GROUP BY target_type, target_id, post_id, author_id
+	ORDER BY reports DESC, first_reported
+	LIMIT ? OFFSET ?`,
+		categoryID, limit, offset)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*ReportQueueItem, 0, 10)
+	for rows.Next() {
+		item := &ReportQueueItem{}
+		var reasons string
+		err := rows.Scan(&item.TargetType, &item.TargetID, &item.PostID, &item.AuthorID,
+			&item.Reports, &reasons, &item.FirstReported)
+		if nil != err {
+			return nil, err
+		}
+		item.Reasons = strings.Split(reasons, reportReasonSeparator)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (repo *ReportRepo) Resolve(targetType string, targetID string) (int64, error) {
+	fmt.Println("Report repo: resolve")
+	result, err := repo.DB.Exec(
+		"UPDATE report SET resolved = 1 WHERE target_type = ? AND target_id = ? AND resolved = 0",
+		targetType, targetID)
+	if nil != err {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ResolveByAuthor resolves the reports against the user in the category,
+// categoryID 0 resolves them everywhere
+func (repo *ReportRepo) ResolveByAuthor(authorID string, categoryID uint32) (int64, error) {
+	fmt.Println("Report repo: resolve by author")
+	result, err := repo.DB.Exec(
+		"UPDATE report SET resolved = 1 WHERE author_id = ? AND (? = 0 OR category_id = ?) AND resolved = 0",
+		authorID, categoryID, categoryID)
+	if nil != err {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
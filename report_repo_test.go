@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReportAdd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewReportRepo(db)
+	report := &Report{
+		TargetType: "post",
+		TargetID:   "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+		PostID:     "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+		CategoryID: 1,
+		AuthorID:   "522cd619-841f-43d5-866d-f880e5f48d18",
+		ReporterID: "7b9d3c3e-2a43-4d4e-9a55-3f1f1a0b7c11",
+		Reason:     "spam",
+		Created:    "2022-11-10T11:24:44Z",
+	}
+
+	// new report
+	mock.ExpectExec(`INSERT INTO report .* ON DUPLICATE KEY UPDATE`).
+		WithArgs(report.TargetType, report.TargetID, report.PostID, report.CategoryID,
+			report.AuthorID, report.ReporterID, report.Reason, report.Created).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	isAdded, err := repo.Add(report)
+	if err != nil || !isAdded {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// resolved one is reopened
+	mock.ExpectExec(`INSERT INTO report .* ON DUPLICATE KEY UPDATE`).
+		WithArgs(report.TargetType, report.TargetID, report.PostID, report.CategoryID,
+			report.AuthorID, report.ReporterID, report.Reason, report.Created).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	isAdded, err = repo.Add(report)
+	if err != nil || !isAdded {
+		t.Errorf("expected reopened report, got %v %s", isAdded, err)
+		return
+	}
+
+	// duplicate of an open one
+	mock.ExpectExec(`INSERT INTO report .* ON DUPLICATE KEY UPDATE`).
+		WithArgs(report.TargetType, report.TargetID, report.PostID, report.CategoryID,
+			report.AuthorID, report.ReporterID, report.Reason, report.Created).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	isAdded, err = repo.Add(report)
+	if err != nil || isAdded {
+		t.Errorf("expected duplicate, got %v %s", isAdded, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestReportGetQueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewReportRepo(db)
+	expected := []*ReportQueueItem{
+		{TargetType: "comment", TargetID: "dbed62a8-79c5-43bd-9594-92cddeb261ac", PostID: "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+			AuthorID: "522cd619-841f-43d5-866d-f880e5f48d18", Reports: 2, Reasons: []string{"rude", "spam"}, FirstReported: "2022-11-10T11:24:44Z"},
+	}
+	rows := sqlmock.NewRows([]string{
+		"target_type", "target_id", "post_id", "author_id", "reports", "reasons", "first_reported",
+	}).AddRow("comment", "dbed62a8-79c5-43bd-9594-92cddeb261ac", "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+		"522cd619-841f-43d5-866d-f880e5f48d18", 2, "rude\nspam", "2022-11-10T11:24:44Z")
+
+	// success
+	mock.ExpectQuery(`WHERE category_id = \? AND resolved = 0 GROUP BY target_type, target_id, post_id, author_id ORDER BY reports DESC`).
+		WithArgs(1, 25, 0).
+		WillReturnRows(rows)
+	items, err := repo.GetQueue(1, 25, 0)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("results are not matched; want: %#v, have: %#v", expected, items)
+		return
+	}
+
+	// query error
+	mock.ExpectQuery(`FROM report`).
+		WithArgs(1, 25, 0).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = repo.GetQueue(1, 25, 0)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestReportResolve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewReportRepo(db)
+
+	// success
+	mock.ExpectExec(`UPDATE report SET resolved = 1 WHERE target_type = \? AND target_id = \? AND resolved = 0`).
+		WithArgs("post", "dc1e2f25-76a5-4aac-9212-96e2121c16f1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	resolved, err := repo.Resolve("post", "dc1e2f25-76a5-4aac-9212-96e2121c16f1")
+	if err != nil || resolved != 3 {
+		t.Errorf("unexpected result %d %s", resolved, err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const ReportReasonMaxLen = 255
+
+func (h *PostsHandler) ReportPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	data, err := h.PostsRepo.GetById(mux.Vars(r)["POST_ID"])
+	if err == sql.ErrNoRows || (nil == err && data.Post.Removed) {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+
+	h.addReport(w, r, &Report{
+		TargetType: ModLogTargetPost,
+		TargetID:   data.Post.ID,
+		PostID:     data.Post.ID,
+		CategoryID: uint32(data.Post.CategoryID),
+		AuthorID:   data.Post.UserID,
+		ReporterID: sess.UserID,
+	})
+}
+
+func (h *PostsHandler) ReportComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	params := mux.Vars(r)
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	comment, err := h.CommentRepo.GetById(params["COMMENT_ID"])
+	if err == sql.ErrNoRows || (nil == err && (comment.PostId != params["POST_ID"] || comment.Removed)) {
+		jsonError(w, http.StatusNotFound, "comment not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get comment")
+		return
+	}
+	data, err := h.PostsRepo.GetById(comment.PostId)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post")
+		return
+	}
+
+	h.addReport(w, r, &Report{
+		TargetType: ModLogTargetComment,
+		TargetID:   comment.ID,
+		PostID:     comment.PostId,
+		CategoryID: uint32(data.Post.CategoryID),
+		AuthorID:   comment.UserId,
+		ReporterID: sess.UserID,
+	})
+}
+
+// addReport answers 409 while the report of the user is open, a user
+// can't raise the report count of an item twice
+func (h *PostsHandler) addReport(w http.ResponseWriter, r *http.Request, report *Report) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "read request err")
+		return
+	}
+	requestData := &ReportRequestDTO{}
+	err = json.Unmarshal(body, requestData)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	// line breaks separate the reasons in the queue
+	reason := strings.TrimSpace(strings.Join(strings.Fields(requestData.Reason), " "))
+	if reason == "" || len(reason) > ReportReasonMaxLen {
+		jsonError(w, http.StatusBadRequest, "reason is required and must be shorter than 256 characters")
+		return
+	}
+
+	report.Reason = reason
+	report.Created = h.TimeGetter.GetCreated()
+	isAdded, err := h.ReportRepo.Add(report)
+	if nil != err {
+		fmt.Println("can't add report", err)
+		jsonError(w, http.StatusInternalServerError, "can't add report")
+		return
+	}
+	if !isAdded {
+		jsonError(w, http.StatusConflict, "you have reported it already")
+		return
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// ReportQueue lists the reported items of the category, most reported first
+func (h *CategoriesHandler) ReportQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, err := h.DictionaryRepo.GetCategoryByName(mux.Vars(r)["CATEGORY_NAME"])
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "category not found")
+		return
+	} else if err != nil {
+		fmt.Println("can't get category", err)
+		jsonError(w, http.StatusInternalServerError, "can't get category")
+		return
+	}
+	if !Can(sess, ActionViewReports, &Resource{CategoryID: category.ID}) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	data, err := h.ReportRepo.GetQueue(category.ID, opts.Limit, opts.Offset)
+	if err != nil {
+		fmt.Println("can't get report queue", err)
+		jsonError(w, http.StatusInternalServerError, "can't get report queue")
+		return
+	}
+	jsonResponse(w, h.DTOConverter.ReportQueueConvertToDTO(data))
+}
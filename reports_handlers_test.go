@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestReportPost(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:  postsRepoMock,
+		ReportRepo: reportRepoMock,
+		TimeGetter: timeGetterMock,
+	}
+	post := multipleComplexData[0].Post
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+post.ID+"/report", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": post.ID})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, modSess))
+	}
+
+	//success, line breaks are folded
+	postsRepoMock.EXPECT().GetById(post.ID).Return(multipleComplexData[0], nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	reportRepoMock.EXPECT().Add(&Report{
+		TargetType: ModLogTargetPost,
+		TargetID:   post.ID,
+		PostID:     post.ID,
+		CategoryID: 1,
+		AuthorID:   post.UserID,
+		ReporterID: modSess.UserID,
+		Reason:     "spam link",
+		Created:    "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	w := httptest.NewRecorder()
+	service.ReportPost(w, newRequest(`{"reason":" spam\nlink "}`))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//repeated report of an open one
+	postsRepoMock.EXPECT().GetById(post.ID).Return(multipleComplexData[0], nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	reportRepoMock.EXPECT().Add(gomock.Any()).Return(false, nil)
+	w = httptest.NewRecorder()
+	service.ReportPost(w, newRequest(`{"reason":"spam"}`))
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//empty reason
+	postsRepoMock.EXPECT().GetById(post.ID).Return(multipleComplexData[0], nil)
+	w = httptest.NewRecorder()
+	service.ReportPost(w, newRequest(`{"reason":"  "}`))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unknown post
+	postsRepoMock.EXPECT().GetById(post.ID).Return(nil, sql.ErrNoRows)
+	w = httptest.NewRecorder()
+	service.ReportPost(w, newRequest(`{"reason":"spam"}`))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	postsRepoMock.EXPECT().GetById(post.ID).Return(multipleComplexData[0], nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	reportRepoMock.EXPECT().Add(gomock.Any()).Return(false, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.ReportPost(w, newRequest(`{"reason":"spam"}`))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestReportComment(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:   postsRepoMock,
+		CommentRepo: commentRepoMock,
+		ReportRepo:  reportRepoMock,
+		TimeGetter:  timeGetterMock,
+	}
+	comment := &Comment{
+		ID:     "dbed62a8-79c5-43bd-9594-92cddeb261ac",
+		PostId: multipleComplexData[0].Post.ID,
+		UserId: sess.UserID,
+	}
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("POST", "/api/post/"+comment.PostId+"/"+comment.ID+"/report", strings.NewReader(`{"reason":"rude"}`))
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": comment.PostId, "COMMENT_ID": comment.ID})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, modSess))
+	}
+
+	//success
+	commentRepoMock.EXPECT().GetById(comment.ID).Return(comment, nil)
+	postsRepoMock.EXPECT().GetById(comment.PostId).Return(multipleComplexData[0], nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T11:24:44Z")
+	reportRepoMock.EXPECT().Add(&Report{
+		TargetType: ModLogTargetComment,
+		TargetID:   comment.ID,
+		PostID:     comment.PostId,
+		CategoryID: 1,
+		AuthorID:   comment.UserId,
+		ReporterID: modSess.UserID,
+		Reason:     "rude",
+		Created:    "2022-11-10T11:24:44Z",
+	}).Return(true, nil)
+	w := httptest.NewRecorder()
+	service.ReportComment(w, newRequest())
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//removed comment
+	commentRepoMock.EXPECT().GetById(comment.ID).Return(&Comment{ID: comment.ID, PostId: comment.PostId, Removed: true}, nil)
+	w = httptest.NewRecorder()
+	service.ReportComment(w, newRequest())
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestReportQueue(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	reportRepoMock := NewMockReportRepoI(ctrl)
+	service := &CategoriesHandler{
+		DictionaryRepo: dictionaryRepoMock,
+		ReportRepo:     reportRepoMock,
+		DTOConverter:   &DTOConverter{},
+	}
+	newRequest := func(s *Session) *http.Request {
+		req := httptest.NewRequest("GET", "/api/categories/fashion/reports", nil)
+		req = mux.SetURLVars(req, map[string]string{"CATEGORY_NAME": "fashion"})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, s))
+	}
+	data := []*ReportQueueItem{
+		{TargetType: "post", TargetID: "dc1e2f25-76a5-4aac-9212-96e2121c16f1", PostID: "dc1e2f25-76a5-4aac-9212-96e2121c16f1",
+			AuthorID: sess.UserID, Reports: 3, Reasons: []string{"rude", "spam"}, FirstReported: "2022-11-10T11:24:44Z"},
+	}
+	expected := `[{"target_type":"post","target_id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1","post_id":"dc1e2f25-76a5-4aac-9212-96e2121c16f1",` +
+		`"author_id":"522cd619-841f-43d5-866d-f880e5f48d18","reports":3,"reasons":["rude","spam"],"first_reported":"2022-11-10T11:24:44Z"}]`
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil)
+	reportRepoMock.EXPECT().GetQueue(uint32(1), ListDefaultLimit, 0).Return(data, nil)
+	w := httptest.NewRecorder()
+	service.ReportQueue(w, newRequest(modSess))
+	body, _ := io.ReadAll(w.Result().Body)
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %#v; have: %#v", expected, string(body))
+		return
+	}
+
+	//not a moderator
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil)
+	w = httptest.NewRecorder()
+	service.ReportQueue(w, newRequest(sess))
+	if w.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+
+	//query error
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 1, Name: "fashion"}, nil)
+	reportRepoMock.EXPECT().GetQueue(uint32(1), ListDefaultLimit, 0).Return(nil, fmt.Errorf("db error"))
+	w = httptest.NewRecorder()
+	service.ReportQueue(w, newRequest(modSess))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 status code; got: %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RestorePost is for the author only, for what the author deleted and
+// only inside RestoreWindow
+func (h *PostsHandler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	postId := mux.Vars(r)["POST_ID"]
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+
+	isRestored, err := h.PostsRepo.Restore(postId, sess.UserID, h.restoreSince())
+	if nil != err {
+		fmt.Println("can't restore post", err)
+		jsonError(w, http.StatusInternalServerError, "can't restore post")
+		return
+	} else if !isRestored {
+		jsonError(w, http.StatusNotFound, "no deleted post to restore")
+		return
+	}
+	h.reindexPost(postId)
+	h.writePost(w, postId, sess)
+}
+
+func (h *PostsHandler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	params := mux.Vars(r)
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+
+	isRestored, err := h.CommentRepo.Restore(params["COMMENT_ID"], params["POST_ID"], sess.UserID, h.restoreSince())
+	if nil != err {
+		fmt.Println("can't restore comment", err)
+		jsonError(w, http.StatusInternalServerError, "can't restore comment")
+		return
+	} else if !isRestored {
+		jsonError(w, http.StatusNotFound, "no deleted comment to restore")
+		return
+	}
+	h.reindexComment(params["COMMENT_ID"])
+	h.writePost(w, params["POST_ID"], sess)
+}
+
+func (h *PostsHandler) restoreSince() string {
+	return h.TimeGetter.Now().UTC().Add(-RestoreWindow).Format(time.RFC3339)
+}
+
+func (h *PostsHandler) writePost(w http.ResponseWriter, postId string, sess *Session) {
+	data, err := h.PostsRepo.GetById(postId)
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get restored post")
+		return
+	}
+	postDTO, err := h.DTOConverter.PostConvertToDTO(data, sess)
+	if err != nil {
+		fmt.Println("can't convert post to dto", err)
+		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
+		return
+	}
+	jsonResponse(w, postDTO)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+type RoleRepo struct {
+	DB *sql.DB
+}
+
+func NewRoleRepo(db *sql.DB) *RoleRepo {
+	return &RoleRepo{
+		DB: db,
+	}
+}
+
+func (repo *RoleRepo) GetByUserId(userID string) ([]*Role, error) {
+	fmt.Println("Role repo: get by user id")
+	rows, err := repo.DB.Query("SELECT user_id, role, category_id FROM user_role WHERE user_id = ?", userID)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := []*Role{}
+	for rows.Next() {
+		role := &Role{}
+		err := rows.Scan(&role.UserID, &role.Name, &role.CategoryID)
+		if nil != err {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (repo *RoleRepo) Add(role *Role) error {
+	fmt.Println("Role repo: add")
+	_, err := repo.DB.Exec(
+		"INSERT IGNORE INTO user_role (user_id, role, category_id) VALUES (?, ?, ?)",
+		role.UserID, role.Name, role.CategoryID)
+	return err
+}
+
+func (repo *RoleRepo) Delete(role *Role) (bool, error) {
+	fmt.Println("Role repo: delete")
+	result, err := repo.DB.Exec(
+		"DELETE FROM user_role WHERE user_id = ? AND role = ? AND category_id = ?",
+		role.UserID, role.Name, role.CategoryID)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *RoleRepo) HasAdmin() (bool, error) {
+	fmt.Println("Role repo: has admin")
+	var exists bool
+	err := repo.DB.
+		QueryRow("SELECT EXISTS(SELECT 1 FROM user_role WHERE role = ?)", RoleAdmin).
+		Scan(&exists)
+	if nil != err {
+		return false, err
+	}
+	return exists, nil
+}
+
+// GrantAdminFromEnv makes the user of ADMIN_USER_ID a site admin on
+// startup, it's how the first admin of a deployment is made. The id comes
+// from the registration, so unlike a login nobody can take it in advance,
+// and nothing is granted once the deployment has an admin.
+func GrantAdminFromEnv(userRepo *UserRepo, roleRepo *RoleRepo) error {
+	userID := os.Getenv("ADMIN_USER_ID")
+	if userID == "" {
+		return nil
+	}
+	hasAdmin, err := roleRepo.HasAdmin()
+	if nil != err {
+		return err
+	}
+	if hasAdmin {
+		return nil
+	}
+	user, err := userRepo.GetById(userID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("admin user %s not found", userID)
+	} else if nil != err {
+		return err
+	}
+	return roleRepo.Add(&Role{
+		UserID: user.ID,
+		Name:   RoleAdmin,
+	})
+}
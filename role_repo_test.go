@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGrantAdminFromEnv(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+	userRepo, roleRepo := NewUserRepo(db), NewRoleRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+
+	// no admin without ADMIN_USER_ID
+	t.Setenv("ADMIN_USER_ID", "")
+	if err := GrantAdminFromEnv(userRepo, roleRepo); err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	t.Setenv("ADMIN_USER_ID", userID)
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM user_role WHERE role = \?\)`).
+		WithArgs(RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE id = `).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email", "verified", "created"}).
+			AddRow(userID, "mer", "", "", false, ""))
+	mock.ExpectExec(`INSERT IGNORE INTO user_role`).
+		WithArgs(userID, RoleAdmin, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := GrantAdminFromEnv(userRepo, roleRepo); err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// the deployment has an admin already
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM user_role WHERE role = \?\)`).
+		WithArgs(RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	if err := GrantAdminFromEnv(userRepo, roleRepo); err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// unknown user
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM user_role WHERE role = \?\)`).
+		WithArgs(RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`FROM user WHERE id = `).
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+	if err := GrantAdminFromEnv(userRepo, roleRepo); err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *PostsHandler) SavePost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	data, err := h.PostsRepo.GetById(mux.Vars(r)["POST_ID"])
+	if err == sql.ErrNoRows || (nil == err && data.Post.Removed) {
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+
+	h.addSaved(w, &SavedItem{
+		UserID: sess.UserID,
+		Type:   SavedTypePost,
+		ItemID: data.Post.ID,
+		PostID: data.Post.ID,
+	})
+}
+
+func (h *PostsHandler) SaveComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	params := mux.Vars(r)
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	comment, err := h.CommentRepo.GetById(params["COMMENT_ID"])
+	if err == sql.ErrNoRows || (nil == err && (comment.PostId != params["POST_ID"] || comment.Removed)) {
+		jsonError(w, http.StatusNotFound, "comment not found")
+		return
+	} else if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't get comment")
+		return
+	}
+
+	h.addSaved(w, &SavedItem{
+		UserID: sess.UserID,
+		Type:   SavedTypeComment,
+		ItemID: comment.ID,
+		PostID: comment.PostId,
+	})
+}
+
+// addSaved answers success for the items saved already too
+func (h *PostsHandler) addSaved(w http.ResponseWriter, item *SavedItem) {
+	item.Created = h.TimeGetter.GetCreated()
+	_, err := h.SavedRepo.Add(item)
+	if nil != err {
+		fmt.Println("can't save item", err)
+		jsonError(w, http.StatusInternalServerError, "can't save")
+		return
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *PostsHandler) UnsavePost(w http.ResponseWriter, r *http.Request) {
+	h.deleteSaved(w, r, SavedTypePost, mux.Vars(r)["POST_ID"])
+}
+
+func (h *PostsHandler) UnsaveComment(w http.ResponseWriter, r *http.Request) {
+	h.deleteSaved(w, r, SavedTypeComment, mux.Vars(r)["COMMENT_ID"])
+}
+
+// deleteSaved doesn't look the item up, the deleted and removed items
+// can be unsaved as well
+func (h *PostsHandler) deleteSaved(w http.ResponseWriter, r *http.Request, itemType string, itemID string) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	isDeleted, err := h.SavedRepo.Delete(sess.UserID, itemType, itemID)
+	if nil != err {
+		fmt.Println("can't unsave item", err)
+		jsonError(w, http.StatusInternalServerError, "can't unsave")
+		return
+	}
+	if !isDeleted {
+		jsonError(w, http.StatusNotFound, "not saved")
+		return
+	}
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// Saved lists the saved posts and comments of the current user, the last
+// saved first, with ?type=post or ?type=comment for one type only. The
+// items deleted or removed since are skipped.
+func (h *PostsHandler) Saved(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	opts, err := ListOptionsFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	itemType := r.URL.Query().Get("type")
+	if itemType != "" && itemType != SavedTypePost && itemType != SavedTypeComment {
+		jsonError(w, http.StatusBadRequest, "unknown type: "+itemType)
+		return
+	}
+
+	items, err := h.SavedRepo.GetByUserId(sess.UserID, itemType, opts.Limit, opts.Offset)
+	if nil != err {
+		fmt.Println("can't get saved items", err)
+		jsonError(w, http.StatusInternalServerError, "can't get saved items")
+		return
+	}
+	postIds := []string{}
+	commentIds := []string{}
+	for _, item := range items {
+		if item.Type == SavedTypePost {
+			postIds = append(postIds, item.ItemID)
+		} else {
+			commentIds = append(commentIds, item.ItemID)
+		}
+	}
+
+	posts, err := h.PostsRepo.GetByIds(postIds)
+	if nil != err {
+		fmt.Println("can't get saved posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get saved posts")
+		return
+	}
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(posts, sess)
+	if nil != err {
+		fmt.Println("can't convert posts to dto", err)
+		jsonError(w, http.StatusInternalServerError, "can't convert to dto")
+		return
+	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if nil != err {
+		fmt.Println("can't hide shadowbanned posts", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+	postsByID := map[string]*PostDTO{}
+	for _, post := range postsDTO {
+		postsByID[post.ID] = post
+	}
+
+	comments, err := h.CommentRepo.GetByIds(commentIds)
+	if nil != err {
+		fmt.Println("can't get saved comments", err)
+		jsonError(w, http.StatusInternalServerError, "can't get saved comments")
+		return
+	}
+	commentsByID := map[string]*CommentDTO{}
+	isSaved := true
+	for _, comment := range h.DTOConverter.CommentsConvertToDTO(comments) {
+		comment.Saved = &isSaved
+		commentsByID[comment.ID] = comment
+	}
+
+	itemsDTO := []*SavedItemDTO{}
+	for _, item := range items {
+		itemDTO := &SavedItemDTO{
+			Type:    item.Type,
+			PostID:  item.PostID,
+			SavedAt: item.Created,
+			Post:    postsByID[item.ItemID],
+			Comment: commentsByID[item.ItemID],
+		}
+		if itemDTO.Post == nil && itemDTO.Comment == nil {
+			continue
+		}
+		itemsDTO = append(itemsDTO, itemDTO)
+	}
+	jsonResponse(w, itemsDTO)
+}
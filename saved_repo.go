@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type SavedRepo struct {
+	DB *sql.DB
+}
+
+func NewSavedRepo(db *sql.DB) *SavedRepo {
+	return &SavedRepo{
+		DB: db,
+	}
+}
+
+// Add returns false when the item is saved already
+func (repo *SavedRepo) Add(item *SavedItem) (bool, error) {
+	fmt.Println("Saved repo: add")
+	result, err := repo.DB.Exec(`INSERT IGNORE INTO saved_item (user_id, type, item_id, post_id, created) VALUES (?, ?, ?, ?, ?)`,
+		item.UserID, item.Type, item.ItemID, item.PostID, item.Created)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *SavedRepo) Delete(userID string, itemType string, itemID string) (bool, error) {
+	fmt.Println("Saved repo: delete")
+	result, err := repo.DB.Exec(`DELETE FROM saved_item WHERE user_id = ? AND type = ? AND item_id = ?`,
+		userID, itemType, itemID)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetByUserId returns the saved items of the type, or of any type for an
+// empty one, the last saved first
+func (repo *SavedRepo) GetByUserId(userID string, itemType string, limit int, offset int) ([]*SavedItem, error) {
+	fmt.Println("Saved repo: get by user id")
+	query := `SELECT user_id, type, item_id, post_id, created FROM saved_item WHERE user_id = ?`
+	args := []interface{}{userID}
+	if itemType != "" {
+		query += ` AND type = ?`
+		args = append(args, itemType)
+	}
+	query += ` ORDER BY created DESC, item_id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+	rows, err := repo.DB.Query(query, args...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*SavedItem, 0, 10)
+	for rows.Next() {
+		item := &SavedItem{}
+		err := rows.Scan(&item.UserID, &item.Type, &item.ItemID, &item.PostID, &item.Created)
+		if nil != err {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetSavedIds tells which of the posts and comments the user saved
+func (repo *SavedRepo) GetSavedIds(userID string, ids []string) (map[string]bool, error) {
+	fmt.Println("Saved repo: get saved ids")
+	saved := map[string]bool{}
+	if len(ids) == 0 {
+		return saved, nil
+	}
+	placeHolders := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+	for _, id := range ids {
+		placeHolders = append(placeHolders, "?")
+		args = append(args, id)
+	}
+	rows, err := repo.DB.Query(`SELECT item_id FROM saved_item WHERE user_id = ? AND item_id IN (`+
+		strings.Join(placeHolders, ",")+`)`, args...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if nil != err {
+			return nil, err
+		}
+		saved[id] = true
+	}
+	return saved, rows.Err()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestSavedGetSavedIds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewSavedRepo(db)
+
+	// success
+	mock.ExpectQuery(`SELECT item_id FROM saved_item WHERE user_id = \? AND item_id IN \(\?,\?,\?\)`).
+		WithArgs("u1", "p1", "c1", "c2").
+		WillReturnRows(sqlmock.NewRows([]string{"item_id"}).AddRow("p1").AddRow("c2"))
+	saved, err := repo.GetSavedIds("u1", []string{"p1", "c1", "c2"})
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if !reflect.DeepEqual(saved, map[string]bool{"p1": true, "c2": true}) {
+		t.Errorf("results not match, have %v", saved)
+		return
+	}
+
+	// no ids, no query
+	saved, err = repo.GetSavedIds("u1", nil)
+	if err != nil || len(saved) != 0 {
+		t.Errorf("expected empty result, got %v %v", saved, err)
+		return
+	}
+
+	// type filter
+	mock.ExpectQuery(`FROM saved_item WHERE user_id = \? AND type = \? ORDER BY created DESC, item_id LIMIT \? OFFSET \?`).
+		WithArgs("u1", SavedTypeComment, 25, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "type", "item_id", "post_id", "created"}).
+			AddRow("u1", SavedTypeComment, "c2", "p1", "2022-11-10T12:00:00Z"))
+	items, err := repo.GetByUserId("u1", SavedTypeComment, 25, 0)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	expected := []*SavedItem{{UserID: "u1", Type: SavedTypeComment, ItemID: "c2", PostID: "p1", Created: "2022-11-10T12:00:00Z"}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, items)
+		return
+	}
+
+	// db error
+	mock.ExpectQuery(`FROM saved_item`).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = repo.GetByUserId("u1", "", 25, 0)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestPostsConvertToDTOSaved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
+	savedRepoMock := NewMockSavedRepoI(ctrl)
+	converter := &DTOConverter{
+		CommentRepo: commentRepoMock,
+		VoteRepo:    voteRepoMock,
+		SavedRepo:   savedRepoMock,
+	}
+	data := []*PostComplexData{{Post: Post{ID: "p1"}}, {Post: Post{ID: "p2"}}}
+	comments := map[string][]*CommentComplexData{
+		"p1": {{Comment: Comment{ID: "c1", PostId: "p1"}}},
+	}
+
+	//one query for the posts and the comments
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{"p1", "p2"}).Return(comments, nil)
+	voteRepoMock.EXPECT().GetVotesByPostIds([]string{"p1", "p2"}).Return(map[string][]*Vote{}, nil)
+	savedRepoMock.EXPECT().GetSavedIds(sess.UserID, []string{"p1", "c1", "p2"}).Return(map[string]bool{"c1": true}, nil)
+	postsDTO, err := converter.PostsConvertToDTO(data, sess)
+	if err != nil {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+	if *postsDTO[0].Saved || !*postsDTO[0].Comments[0].Saved || *postsDTO[1].Saved {
+		t.Errorf("bad saved state: %v %v %v", *postsDTO[0].Saved, *postsDTO[0].Comments[0].Saved, *postsDTO[1].Saved)
+		return
+	}
+
+	//anonymous
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{"p1", "p2"}).Return(comments, nil)
+	voteRepoMock.EXPECT().GetVotesByPostIds([]string{"p1", "p2"}).Return(map[string][]*Vote{}, nil)
+	postsDTO, err = converter.PostsConvertToDTO(data, nil)
+	if err != nil || postsDTO[0].Saved != nil || postsDTO[0].Comments[0].Saved != nil {
+		t.Errorf("expected no saved state for anonymous requests, got %v", err)
+		return
+	}
+}
+
+func TestSavedHandlers(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	voteRepoMock := NewMockVoteRepoI(ctrl)
+	savedRepoMock := NewMockSavedRepoI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	service := &PostsHandler{
+		PostsRepo:   postsRepoMock,
+		CommentRepo: commentRepoMock,
+		SavedRepo:   savedRepoMock,
+		BanRepo:     banRepoMock,
+		TimeGetter:  timeGetterMock,
+		DTOConverter: &DTOConverter{
+			CommentRepo: commentRepoMock,
+			VoteRepo:    voteRepoMock,
+			SavedRepo:   savedRepoMock,
+		},
+	}
+	newRequest := func(method string, url string, vars map[string]string) *http.Request {
+		req := httptest.NewRequest(method, url, nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+		return mux.SetURLVars(req, vars)
+	}
+
+	//save a comment of another post
+	commentRepoMock.EXPECT().GetById("c1").Return(&Comment{ID: "c1", PostId: "p2"}, nil)
+	w := httptest.NewRecorder()
+	service.SaveComment(w, newRequest("POST", "/api/post/p1/c1/save", map[string]string{"POST_ID": "p1", "COMMENT_ID": "c1"}))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//save a comment
+	commentRepoMock.EXPECT().GetById("c1").Return(&Comment{ID: "c1", PostId: "p1"}, nil)
+	timeGetterMock.EXPECT().GetCreated().Return("2022-11-10T12:00:00Z")
+	savedRepoMock.EXPECT().Add(&SavedItem{UserID: sess.UserID, Type: SavedTypeComment, ItemID: "c1", PostID: "p1", Created: "2022-11-10T12:00:00Z"}).
+		Return(true, nil)
+	w = httptest.NewRecorder()
+	service.SaveComment(w, newRequest("POST", "/api/post/p1/c1/save", map[string]string{"POST_ID": "p1", "COMMENT_ID": "c1"}))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//unsave what isn't saved
+	savedRepoMock.EXPECT().Delete(sess.UserID, SavedTypePost, "p1").Return(false, nil)
+	w = httptest.NewRecorder()
+	service.UnsavePost(w, newRequest("POST", "/api/post/p1/unsave", map[string]string{"POST_ID": "p1"}))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//the list keeps the saved order and skips the items gone since
+	savedRepoMock.EXPECT().GetByUserId(sess.UserID, "", 10, 0).Return([]*SavedItem{
+		{Type: SavedTypeComment, ItemID: "c1", PostID: "p1", Created: "2022-11-10T12:00:00Z"},
+		{Type: SavedTypePost, ItemID: "p3", PostID: "p3", Created: "2022-11-10T11:00:00Z"},
+		{Type: SavedTypePost, ItemID: "p1", PostID: "p1", Created: "2022-11-10T10:00:00Z"},
+	}, nil)
+	postsRepoMock.EXPECT().GetByIds([]string{"p3", "p1"}).Return([]*PostComplexData{{Post: Post{ID: "p1"}}}, nil)
+	commentRepoMock.EXPECT().GetByIds([]string{"c1"}).Return([]*CommentComplexData{
+		{Comment: Comment{ID: "c1", PostId: "p1", Body: "nice"}, User: User{ID: "u2", Login: "bob"}},
+	}, nil)
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{"p1"}).Return(map[string][]*CommentComplexData{}, nil)
+	voteRepoMock.EXPECT().GetVotesByPostIds([]string{"p1"}).Return(map[string][]*Vote{}, nil)
+	savedRepoMock.EXPECT().GetSavedIds(sess.UserID, []string{"p1"}).Return(map[string]bool{"p1": true}, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil)
+	w = httptest.NewRecorder()
+	service.Saved(w, newRequest("GET", "/api/user/me/saved?limit=10", nil))
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d %s", w.Result().StatusCode, body)
+		return
+	}
+	expected := `[{"type":"comment","post_id":"p1","saved_at":"2022-11-10T12:00:00Z","comment":{"author":{"username":"bob","id":"u2"},"body":"nice","created":"","id":"c1","score":0,"saved":true}},` +
+		`{"type":"post","post_id":"p1","saved_at":"2022-11-10T10:00:00Z","post":{"id":"p1","author":{"username":"","id":""},"category":"","comments":[],"created":"",` +
+		`"score":0,"text":"","title":"","type":"","upvotepercentage":0,"votes":[],"views":0,"saved":true}}]`
+	if string(body) != expected {
+		t.Errorf("it's not matched; want: %s; have: %s", expected, body)
+		return
+	}
+
+	//unknown type
+	w = httptest.NewRecorder()
+	service.Saved(w, newRequest("GET", "/api/user/me/saved?type=vote", nil))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
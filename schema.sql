@@ -11,29 +11,50 @@ DROP TABLE IF EXISTS `redditclone`.`category`;
 CREATE TABLE `category` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `name` varchar(255) NOT NULL,
-  PRIMARY KEY (`id`)
+  `description` text NOT NULL,
+  `rules` text NOT NULL,
+  `creator_id` varchar(36) NOT NULL DEFAULT '',
+  `created` varchar(255) DEFAULT NULL,
+  `subscribers` int(11) NOT NULL DEFAULT 0,
+  `require_verified` tinyint(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name` (`name`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
-INSERT INTO `redditclone`.`category` (`name`) VALUES 
-('music'),
-('funny'),
-('videos'),
-('programming'),
-('news'),
-('fashion');
+INSERT INTO `redditclone`.`category` (`name`, `description`, `rules`, `created`) VALUES 
+('music', '', '', '2022-11-02T15:24:00Z'),
+('funny', '', '', '2022-11-02T15:24:00Z'),
+('videos', '', '', '2022-11-02T15:24:00Z'),
+('programming', '', '', '2022-11-02T15:24:00Z'),
+('news', '', '', '2022-11-02T15:24:00Z'),
+('fashion', '', '', '2022-11-02T15:24:00Z');
 
 DROP TABLE IF EXISTS `redditclone`.`post`;
 CREATE TABLE `redditclone`.`post` (
   `id` varchar(36) NOT NULL,
   `title` varchar(255) NOT NULL,
-  `type` ENUM('text', 'link') DEFAULT NULL,
+  `type` ENUM('text', 'link', 'image') DEFAULT NULL,
+  `url` varchar(2048) NOT NULL DEFAULT '',
+  `canonical_url` varchar(2048) NOT NULL DEFAULT '',
+  `image` varchar(255) NOT NULL DEFAULT '',
   `description` text NOT NULL,
   `score` int(11) DEFAULT NULL,
   `user_id` varchar(36) NOT NULL,
   `category_id` int(11) NOT NULL, 
   `created` varchar(255) DEFAULT NULL,
+  `removed` tinyint(1) NOT NULL DEFAULT 0,
+  `locked` tinyint(1) NOT NULL DEFAULT 0,
+  `pinned` tinyint(1) NOT NULL DEFAULT 0,
+  `flair` varchar(64) NOT NULL DEFAULT '',
+  `deleted_at` varchar(255) NOT NULL DEFAULT '',
+  `deleted_by` varchar(36) NOT NULL DEFAULT '',
    UNIQUE KEY `id` (`id`),
    KEY `user_id` (`user_id`),
+   KEY `deleted_at` (`deleted_at`),
+   KEY `category_id_created` (`category_id`, `created`),
+   KEY `category_id_score` (`category_id`, `score`),
+   KEY `category_id_canonical_url` (`category_id`, `canonical_url`(255)),
+   FULLTEXT KEY `title_description` (`title`, `description`),
    CONSTRAINT `posts_user_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
@@ -44,8 +65,17 @@ CREATE TABLE `redditclone`.`comment` (
   `user_id` varchar(36) NOT NULL,
   `body` text NOT NULL,
   `created` varchar(255) DEFAULT NULL,
+  `removed` tinyint(1) NOT NULL DEFAULT 0,
+  `deleted_at` varchar(255) NOT NULL DEFAULT '',
+  `deleted_by` varchar(36) NOT NULL DEFAULT '',
+  `parent_id` varchar(36) NOT NULL DEFAULT '',
+  `ups` int(11) NOT NULL DEFAULT 0,
+  `downs` int(11) NOT NULL DEFAULT 0,
    UNIQUE KEY `id` (`id`),
    KEY `user_id` (`user_id`),
+   KEY `post_id` (`post_id`),
+   KEY `deleted_at` (`deleted_at`),
+   FULLTEXT KEY `body` (`body`),
    CONSTRAINT `user_comments_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
@@ -54,13 +84,19 @@ DROP TABLE IF EXISTS `redditclone`.`user`;
 CREATE TABLE `redditclone`.`user` (
   `id` varchar(36) NOT NULL,
   `login` varchar(255) NOT NULL,
-  `password` varchar(60) NOT NULL,
+  `password` varchar(255) NOT NULL,
+  `email` varchar(255) NOT NULL DEFAULT '',
+  `verified` tinyint(1) NOT NULL DEFAULT 0,
   `created` varchar(255) DEFAULT NULL,
+  `bio` varchar(255) NOT NULL DEFAULT '',
+  `post_karma` bigint(20) NOT NULL DEFAULT 0,
+  `comment_karma` bigint(20) NOT NULL DEFAULT 0,
    UNIQUE KEY `id` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 INSERT INTO `redditclone`.`user` (`id`, `login`, `password`, `created`) VALUES 
-('34420d9d-91c0-4c6f-96fa-e4346eb9361c', 'test', 'test', '2022-11-02 15:24:00');
+('34420d9d-91c0-4c6f-96fa-e4346eb9361c', 'test', '$2a$10$Fxv6omozmaVf/Z.gwi0U8e9gMOe5nntAU87.ZfYojGqFJ1AkMec3.', '2022-11-02 15:24:00'),
+('00000000-0000-0000-0000-00000000a170', 'AutoModerator', '', '2022-11-02 15:24:00');
 
 DROP TABLE IF EXISTS `redditclone`.`vote`;
 CREATE TABLE `redditclone`.`vote` (
@@ -74,6 +110,17 @@ CREATE TABLE `redditclone`.`vote` (
     CONSTRAINT `users_votes_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+DROP TABLE IF EXISTS `redditclone`.`comment_vote`;
+CREATE TABLE `redditclone`.`comment_vote` (
+    `comment_id` varchar(36) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `vote` int(11) NOT NULL,
+    PRIMARY KEY (`comment_id`, `user_id`),
+    KEY `user_id` (`user_id`),
+    CONSTRAINT `comments_votes_ibfk_1` FOREIGN KEY (`comment_id`) REFERENCES `comment`(`id`),
+    CONSTRAINT `users_comment_votes_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 
 DROP TABLE IF EXISTS `redditclone`.`sessions`;
 CREATE TABLE `redditclone`.`sessions` (
@@ -81,4 +128,247 @@ CREATE TABLE `redditclone`.`sessions` (
     `user_id` varchar(36) NOT NULL,
     UNIQUE KEY `id` (`id`),
     KEY `user_id` (`user_id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`email_verification`;
+CREATE TABLE `redditclone`.`email_verification` (
+    `token` varchar(64) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `email` varchar(255) NOT NULL,
+    `expires` varchar(255) NOT NULL,
+    UNIQUE KEY `token` (`token`),
+    KEY `user_id` (`user_id`),
+    CONSTRAINT `users_email_verification_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`user_totp`;
+CREATE TABLE `redditclone`.`user_totp` (
+    `user_id` varchar(36) NOT NULL,
+    `secret` varchar(64) NOT NULL,
+    `enabled` tinyint(1) NOT NULL DEFAULT 0,
+    `last_step` bigint(20) NOT NULL DEFAULT 0,
+    `created` varchar(255) DEFAULT NULL,
+    PRIMARY KEY (`user_id`),
+    CONSTRAINT `users_totp_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`recovery_code`;
+CREATE TABLE `redditclone`.`recovery_code` (
+    `user_id` varchar(36) NOT NULL,
+    `code_hash` varchar(64) NOT NULL,
+    `used` tinyint(1) NOT NULL DEFAULT 0,
+    UNIQUE KEY `user_id_code_hash` (`user_id`, `code_hash`),
+    CONSTRAINT `users_recovery_code_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`login_challenge`;
+CREATE TABLE `redditclone`.`login_challenge` (
+    `token` varchar(64) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `attempts` int(11) NOT NULL DEFAULT 0,
+    `expires` varchar(255) NOT NULL,
+    UNIQUE KEY `token` (`token`),
+    KEY `user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`login_attempt`;
+CREATE TABLE `redditclone`.`login_attempt` (
+    `key` varchar(255) NOT NULL,
+    `failures` int(11) NOT NULL DEFAULT 0,
+    `last_failure` bigint(20) NOT NULL DEFAULT 0,
+    `locked_until` bigint(20) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`audit_log`;
+CREATE TABLE `redditclone`.`audit_log` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `event` varchar(64) NOT NULL,
+    `user_id` varchar(36) NOT NULL DEFAULT '',
+    `login` varchar(255) NOT NULL DEFAULT '',
+    `ip` varchar(64) NOT NULL DEFAULT '',
+    `details` text NOT NULL,
+    `created` varchar(255) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `event` (`event`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`user_role`;
+CREATE TABLE `redditclone`.`user_role` (
+    `user_id` varchar(36) NOT NULL,
+    `role` ENUM('admin', 'moderator') NOT NULL,
+    `category_id` int(11) NOT NULL DEFAULT 0,
+    UNIQUE KEY `user_id_role_category_id` (`user_id`, `role`, `category_id`),
+    KEY `category_id` (`category_id`),
+    CONSTRAINT `users_role_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`subscription`;
+CREATE TABLE `redditclone`.`subscription` (
+    `user_id` varchar(36) NOT NULL,
+    `category_id` int(11) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`user_id`, `category_id`),
+    KEY `category_id` (`category_id`),
+    CONSTRAINT `users_subscription_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`),
+    CONSTRAINT `categories_subscription_ibfk_1` FOREIGN KEY (`category_id`) REFERENCES `category`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`mod_log`;
+CREATE TABLE `redditclone`.`mod_log` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+    `category_id` int(11) NOT NULL,
+    `moderator_id` varchar(36) NOT NULL,
+    `action` varchar(32) NOT NULL,
+    `target_type` ENUM('post', 'comment', 'user') NOT NULL,
+    `target_id` varchar(36) NOT NULL,
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `category_id_id` (`category_id`, `id`),
+    CONSTRAINT `users_mod_log_ibfk_1` FOREIGN KEY (`moderator_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`report`;
+CREATE TABLE `redditclone`.`report` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+    `target_type` ENUM('post', 'comment') NOT NULL,
+    `target_id` varchar(36) NOT NULL,
+    `post_id` varchar(36) NOT NULL,
+    `category_id` int(11) NOT NULL,
+    `author_id` varchar(36) NOT NULL,
+    `reporter_id` varchar(36) NOT NULL,
+    `reason` varchar(255) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    `resolved` tinyint(1) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `target_reporter` (`target_type`, `target_id`, `reporter_id`),
+    KEY `category_id_resolved` (`category_id`, `resolved`),
+    CONSTRAINT `users_report_ibfk_1` FOREIGN KEY (`reporter_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`ban`;
+CREATE TABLE `redditclone`.`ban` (
+    `user_id` varchar(36) NOT NULL,
+    `kind` ENUM('ban', 'shadowban') NOT NULL,
+    `category_id` int(11) NOT NULL DEFAULT 0,
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `moderator_id` varchar(36) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    `expires` varchar(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (`user_id`, `kind`, `category_id`),
+    KEY `kind_category_id` (`kind`, `category_id`),
+    CONSTRAINT `users_ban_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`automod_config`;
+CREATE TABLE `redditclone`.`automod_config` (
+    `category_id` int(11) NOT NULL,
+    `config` text NOT NULL,
+    `updated_by` varchar(36) NOT NULL,
+    `updated` varchar(255) NOT NULL,
+    PRIMARY KEY (`category_id`),
+    CONSTRAINT `categories_automod_config_ibfk_1` FOREIGN KEY (`category_id`) REFERENCES `category`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`link_preview`;
+CREATE TABLE `redditclone`.`link_preview` (
+    `post_id` varchar(36) NOT NULL,
+    `url` varchar(2048) NOT NULL,
+    `title` varchar(255) NOT NULL DEFAULT '',
+    `description` text NOT NULL,
+    `image` varchar(2048) NOT NULL DEFAULT '',
+    `status` ENUM('ok', 'failed') NOT NULL,
+    `error` varchar(255) NOT NULL DEFAULT '',
+    `fetched` varchar(255) NOT NULL,
+    PRIMARY KEY (`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`notification`;
+CREATE TABLE `redditclone`.`notification` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `type` varchar(32) NOT NULL,
+    `actor_id` varchar(36) NOT NULL DEFAULT '',
+    `post_id` varchar(36) NOT NULL DEFAULT '',
+    `comment_id` varchar(36) NOT NULL DEFAULT '',
+    `body` varchar(255) NOT NULL DEFAULT '',
+    `is_read` tinyint(1) NOT NULL DEFAULT 0,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `user_id_is_read` (`user_id`, `is_read`),
+    KEY `post_id` (`post_id`),
+    KEY `comment_id` (`comment_id`),
+    CONSTRAINT `users_notification_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`notification_mute`;
+CREATE TABLE `redditclone`.`notification_mute` (
+    `user_id` varchar(36) NOT NULL,
+    `type` varchar(32) NOT NULL,
+    PRIMARY KEY (`user_id`, `type`),
+    CONSTRAINT `users_notification_mute_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`message`;
+CREATE TABLE `redditclone`.`message` (
+    `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+    `sender_id` varchar(36) NOT NULL,
+    `recipient_id` varchar(36) NOT NULL,
+    `body` text NOT NULL,
+    `is_read` tinyint(1) NOT NULL DEFAULT 0,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `sender_id_created` (`sender_id`, `created`),
+    KEY `recipient_id_is_read` (`recipient_id`, `is_read`),
+    CONSTRAINT `users_message_sender_ibfk_1` FOREIGN KEY (`sender_id`) REFERENCES `user`(`id`),
+    CONSTRAINT `users_message_recipient_ibfk_1` FOREIGN KEY (`recipient_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`user_block`;
+CREATE TABLE `redditclone`.`user_block` (
+    `blocker_id` varchar(36) NOT NULL,
+    `blocked_id` varchar(36) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`blocker_id`, `blocked_id`),
+    KEY `blocked_id` (`blocked_id`),
+    CONSTRAINT `users_block_blocker_ibfk_1` FOREIGN KEY (`blocker_id`) REFERENCES `user`(`id`),
+    CONSTRAINT `users_block_blocked_ibfk_1` FOREIGN KEY (`blocked_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`saved_item`;
+CREATE TABLE `redditclone`.`saved_item` (
+    `user_id` varchar(36) NOT NULL,
+    `type` varchar(16) NOT NULL,
+    `item_id` varchar(36) NOT NULL,
+    `post_id` varchar(36) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`user_id`, `item_id`),
+    KEY `user_id_type_created` (`user_id`, `type`, `created`),
+    KEY `item_id` (`item_id`),
+    KEY `post_id` (`post_id`),
+    CONSTRAINT `users_saved_item_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `redditclone`.`user_follow`;
+CREATE TABLE `redditclone`.`user_follow` (
+    `follower_id` varchar(36) NOT NULL,
+    `followed_id` varchar(36) NOT NULL,
+    `created` varchar(255) NOT NULL,
+    PRIMARY KEY (`follower_id`, `followed_id`),
+    KEY `followed_id` (`followed_id`, `created`),
+    CONSTRAINT `users_follow_follower_ibfk_1` FOREIGN KEY (`follower_id`) REFERENCES `user`(`id`),
+    CONSTRAINT `users_follow_followed_ibfk_1` FOREIGN KEY (`followed_id`) REFERENCES `user`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- the migrations in migrations.go this file already covers, the app runs
+-- the missing ones on start
+DROP TABLE IF EXISTS `redditclone`.`schema_migration`;
+CREATE TABLE `redditclone`.`schema_migration` (
+    `name` varchar(64) NOT NULL,
+    `applied` varchar(255) NOT NULL,
+    PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO `redditclone`.`schema_migration` (`name`, `applied`) VALUES 
+('user_karma', '2022-11-02T15:24:00Z');
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	SearchTargetPost    = "post"
+	SearchTargetComment = "comment"
+
+	SearchSortRelevance = "relevance"
+	SearchSortNew       = "new"
+
+	SearchQueryMaxLen  = 256
+	SearchSnippetRunes = 200
+)
+
+// SearchDoc is a post or a comment as the index sees it. Comments carry
+// the title, the type and the category of their post.
+type SearchDoc struct {
+	Target      string
+	ID          string
+	PostID      string
+	PostType    string
+	Title       string
+	Body        string
+	AuthorID    string
+	AuthorLogin string
+	Category    string
+	Created     string
+}
+
+type SearchHit struct {
+	SearchDoc
+	Score float64
+}
+
+// SearchQuery filters are optional. Type is a post type or "comment",
+// From and To bound the creation time, To is exclusive.
+type SearchQuery struct {
+	Text     string
+	Category string
+	Author   string
+	Type     string
+	From     time.Time
+	To       time.Time
+	Sort     string
+	Limit    int
+	Offset   int
+}
+
+var searchTypes = map[string]struct{}{
+	"":                  {},
+	PostTypeText:        {},
+	PostTypeLink:        {},
+	PostTypeImage:       {},
+	SearchTargetComment: {},
+}
+
+func (query *SearchQuery) includes(target string) bool {
+	if query.Type == "" {
+		return true
+	}
+	return (query.Type == SearchTargetComment) == (target == SearchTargetComment)
+}
+
+// SearchQueryFromRequest reads q, category, author, type, from, to,
+// sort, limit and offset. The times are RFC3339 or plain dates.
+func SearchQueryFromRequest(r *http.Request) (*SearchQuery, error) {
+	params := r.URL.Query()
+	query := &SearchQuery{
+		Text:     strings.TrimSpace(params.Get("q")),
+		Category: params.Get("category"),
+		Author:   params.Get("author"),
+		Type:     params.Get("type"),
+		Sort:     SearchSortRelevance,
+		Limit:    ListDefaultLimit,
+	}
+	if len(searchTerms(query.Text)) == 0 {
+		return nil, fmt.Errorf("q is required")
+	}
+	if len(query.Text) > SearchQueryMaxLen {
+		return nil, fmt.Errorf("q is too long")
+	}
+	if _, ok := searchTypes[query.Type]; !ok {
+		return nil, fmt.Errorf("unknown type: %s", query.Type)
+	}
+	if sort := params.Get("sort"); sort != "" {
+		if sort != SearchSortRelevance && sort != SearchSortNew {
+			return nil, fmt.Errorf("unknown sort: %s", sort)
+		}
+		query.Sort = sort
+	}
+	var err error
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		raw := params.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		*bound.value, err = time.Parse(time.RFC3339, raw)
+		if nil != err {
+			*bound.value, err = time.ParseInLocation("2006-01-02", raw, time.Local)
+		}
+		if nil != err {
+			return nil, fmt.Errorf("bad %s: %s", bound.name, raw)
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if nil != err || value < 1 {
+			return nil, fmt.Errorf("bad limit: %s", limit)
+		}
+		if value > ListMaxLimit {
+			value = ListMaxLimit
+		}
+		query.Limit = value
+	}
+	if offset := params.Get("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if nil != err || value < 0 {
+			return nil, fmt.Errorf("bad offset: %s", offset)
+		}
+		query.Offset = value
+	}
+	return query, nil
+}
+
+// NewSearchIndex uses the MySQL FULLTEXT indexes unless SEARCH_INDEX=memory
+// is set, the embedded index is then loaded from the db on start.
+func NewSearchIndex(db *sql.DB) SearchIndex {
+	mysqlIndex := NewMySQLSearchIndex(db)
+	if os.Getenv("SEARCH_INDEX") != "memory" {
+		return mysqlIndex
+	}
+	index := NewMemorySearchIndex()
+	docs, err := mysqlIndex.Documents()
+	if nil != err {
+		fmt.Println("can't load the search index", err)
+		return index
+	}
+	for _, doc := range docs {
+		index.Index(doc)
+	}
+	fmt.Println("search index loaded, documents:", len(docs))
+	return index
+}
+
+func postSearchDoc(data *PostComplexData) *SearchDoc {
+	return &SearchDoc{
+		Target:      SearchTargetPost,
+		ID:          data.Post.ID,
+		PostID:      data.Post.ID,
+		PostType:    data.Post.Type,
+		Title:       data.Post.Title,
+		Body:        data.Post.Description,
+		AuthorID:    data.Post.UserID,
+		AuthorLogin: data.User.Login,
+		Category:    data.Category.Name,
+		Created:     data.Post.Created,
+	}
+}
+
+func commentSearchDoc(comment *Comment, author *User, post *PostComplexData) *SearchDoc {
+	return &SearchDoc{
+		Target:      SearchTargetComment,
+		ID:          comment.ID,
+		PostID:      post.Post.ID,
+		PostType:    post.Post.Type,
+		Title:       post.Post.Title,
+		Body:        comment.Body,
+		AuthorID:    author.ID,
+		AuthorLogin: author.Login,
+		Category:    post.Category.Name,
+		Created:     comment.Created,
+	}
+}
+
+// searchTerms lowercases the letters and digits runs of the text, the
+// same split is used for the documents, the queries and the snippets
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isNotSearchRune)
+}
+
+func isNotSearchRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// SearchSnippet cuts the text around the first matching term and wraps
+// every match in <mark>. The text is html escaped, so the snippet is
+// safe to render as html.
+func SearchSnippet(text string, terms []string) string {
+	wanted := map[string]struct{}{}
+	for _, term := range terms {
+		wanted[term] = struct{}{}
+	}
+	runes := []rune(text)
+
+	type span struct{ start, end int }
+	matches := []span{}
+	for start := 0; start < len(runes); {
+		if isNotSearchRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && !isNotSearchRune(runes[end]) {
+			end++
+		}
+		if _, ok := wanted[strings.ToLower(string(runes[start:end]))]; ok {
+			matches = append(matches, span{start, end})
+		}
+		start = end
+	}
+
+	from := 0
+	if len(matches) > 0 && matches[0].start > SearchSnippetRunes/4 {
+		from = matches[0].start - SearchSnippetRunes/4
+	}
+	to := from + SearchSnippetRunes
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	out := &strings.Builder{}
+	if from > 0 {
+		out.WriteString("…")
+	}
+	pos := from
+	for _, match := range matches {
+		if match.start < from || match.end > to {
+			continue
+		}
+		out.WriteString(html.EscapeString(string(runes[pos:match.start])))
+		out.WriteString("<mark>" + html.EscapeString(string(runes[match.start:match.end])) + "</mark>")
+		pos = match.end
+	}
+	out.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		out.WriteString("…")
+	}
+	return out.String()
+}
+
+// MySQLSearchIndex searches the tables through their FULLTEXT indexes,
+// InnoDB keeps them in sync so Index and Remove have nothing to do
+type MySQLSearchIndex struct {
+	DB *sql.DB
+}
+
+func NewMySQLSearchIndex(db *sql.DB) *MySQLSearchIndex {
+	return &MySQLSearchIndex{
+		DB: db,
+	}
+}
+
+func (index *MySQLSearchIndex) Index(doc *SearchDoc) error {
+	return nil
+}
+
+func (index *MySQLSearchIndex) Remove(target string, id string) error {
+	return nil
+}
+
+const (
+	searchPostsSelect = `SELECT 'post' AS target, post.id, post.id AS post_id, post.type, post.title,
+	post.description AS body, post.user_id, user.login, category.name, post.created AS created, %s AS relevance
+	FROM post
+	JOIN user ON user.id = post.user_id
+	JOIN category ON category.id = post.category_id
+	WHERE post.removed = 0 AND post.deleted_at = ''`
+	searchCommentsSelect = `SELECT 'comment', comment.id, comment.post_id, post.type, post.title,
+	comment.body, comment.user_id, user.login, category.name, comment.created, %s
+	FROM comment
+	JOIN post ON post.id = comment.post_id
+	JOIN user ON user.id = comment.user_id
+	JOIN category ON category.id = post.category_id
+	WHERE comment.removed = 0 AND comment.deleted_at = '' AND post.removed = 0 AND post.deleted_at = ''`
+	searchPostsMatch    = `MATCH(post.title, post.description) AGAINST (? IN NATURAL LANGUAGE MODE)`
+	searchCommentsMatch = `MATCH(comment.body) AGAINST (? IN NATURAL LANGUAGE MODE)`
+)
+
+// Search compares the created strings, they are RFC3339 in the server
+// time zone, so the bounds are formatted the same way
+func (index *MySQLSearchIndex) Search(query *SearchQuery) ([]*SearchHit, error) {
+	fmt.Println("Search index: search")
+	parts := []string{}
+	args := []interface{}{}
+	addPart := func(selectQuery, match, created, postType string) {
+		part := fmt.Sprintf(selectQuery, match) + ` AND ` + match
+		args = append(args, query.Text, query.Text)
+		if query.Category != "" {
+			part += ` AND category.name = ?`
+			args = append(args, query.Category)
+		}
+		if query.Author != "" {
+			part += ` AND user.login = ?`
+			args = append(args, query.Author)
+		}
+		if postType != "" {
+			part += ` AND post.type = ?`
+			args = append(args, postType)
+		}
+		if !query.From.IsZero() {
+			part += ` AND ` + created + ` >= ?`
+			args = append(args, query.From.In(time.Local).Format(time.RFC3339))
+		}
+		if !query.To.IsZero() {
+			part += ` AND ` + created + ` < ?`
+			args = append(args, query.To.In(time.Local).Format(time.RFC3339))
+		}
+		parts = append(parts, part)
+	}
+	if query.includes(SearchTargetPost) {
+		addPart(searchPostsSelect, searchPostsMatch, "post.created", query.Type)
+	}
+	if query.includes(SearchTargetComment) {
+		addPart(searchCommentsSelect, searchCommentsMatch, "comment.created", "")
+	}
+
+	orderBy := `relevance DESC, created DESC`
+	if query.Sort == SearchSortNew {
+		orderBy = `created DESC`
+	}
+	args = append(args, query.Limit, query.Offset)
+	rows, err := index.DB.Query(`SELECT * FROM (`+strings.Join(parts, ` UNION ALL `)+`) AS hits
+	ORDER BY `+orderBy+`
+	LIMIT ? OFFSET ?`, args...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	hits := []*SearchHit{}
+	for rows.Next() {
+		hit := &SearchHit{}
+		err = rows.Scan(&hit.Target, &hit.ID, &hit.PostID, &hit.PostType, &hit.Title, &hit.Body,
+			&hit.AuthorID, &hit.AuthorLogin, &hit.Category, &hit.Created, &hit.Score)
+		if nil != err {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// Documents returns everything visible, it loads the embedded index
+func (index *MySQLSearchIndex) Documents() ([]*SearchDoc, error) {
+	fmt.Println("Search index: documents")
+	rows, err := index.DB.Query(fmt.Sprintf(searchPostsSelect, "0") + ` UNION ALL ` + fmt.Sprintf(searchCommentsSelect, "0"))
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	docs := []*SearchDoc{}
+	for rows.Next() {
+		doc := &SearchDoc{}
+		var relevance float64
+		err = rows.Scan(&doc.Target, &doc.ID, &doc.PostID, &doc.PostType, &doc.Title, &doc.Body,
+			&doc.AuthorID, &doc.AuthorLogin, &doc.Category, &doc.Created, &relevance)
+		if nil != err {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// MemorySearchIndex is an inverted index kept in the process. Scoring
+// is BM25 with the title terms counted twice.
+type MemorySearchIndex struct {
+	mu          sync.RWMutex
+	docs        map[string]*searchEntry
+	postings    map[string]map[string]int
+	totalLength int
+}
+
+type searchEntry struct {
+	doc     *SearchDoc
+	created time.Time
+	terms   map[string]int
+	length  int
+}
+
+func NewMemorySearchIndex() *MemorySearchIndex {
+	return &MemorySearchIndex{
+		docs:     map[string]*searchEntry{},
+		postings: map[string]map[string]int{},
+	}
+}
+
+func searchDocKey(target string, id string) string {
+	return target + ":" + id
+}
+
+// Index replaces the document if it is indexed already
+func (index *MemorySearchIndex) Index(doc *SearchDoc) error {
+	entry := &searchEntry{doc: doc, terms: map[string]int{}}
+	entry.created, _ = time.Parse(time.RFC3339, doc.Created)
+	if doc.Target == SearchTargetPost {
+		for _, term := range searchTerms(doc.Title) {
+			entry.terms[term] += 2
+			entry.length += 2
+		}
+	}
+	for _, term := range searchTerms(doc.Body) {
+		entry.terms[term]++
+		entry.length++
+	}
+
+	key := searchDocKey(doc.Target, doc.ID)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(key)
+	index.docs[key] = entry
+	index.totalLength += entry.length
+	for term, count := range entry.terms {
+		if index.postings[term] == nil {
+			index.postings[term] = map[string]int{}
+		}
+		index.postings[term][key] = count
+	}
+	return nil
+}
+
+// Remove of a post takes its comments with it
+func (index *MemorySearchIndex) Remove(target string, id string) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(searchDocKey(target, id))
+	if target != SearchTargetPost {
+		return nil
+	}
+	for key, entry := range index.docs {
+		if entry.doc.Target == SearchTargetComment && entry.doc.PostID == id {
+			index.remove(key)
+		}
+	}
+	return nil
+}
+
+func (index *MemorySearchIndex) remove(key string) {
+	entry, ok := index.docs[key]
+	if !ok {
+		return
+	}
+	for term := range entry.terms {
+		delete(index.postings[term], key)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+	index.totalLength -= entry.length
+	delete(index.docs, key)
+}
+
+func (index *MemorySearchIndex) Search(query *SearchQuery) ([]*SearchHit, error) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	if len(index.docs) == 0 {
+		return []*SearchHit{}, nil
+	}
+
+	const k1, b = 1.2, 0.75
+	avgLength := float64(index.totalLength) / float64(len(index.docs))
+	scores := map[string]float64{}
+	seen := map[string]struct{}{}
+	for _, term := range searchTerms(query.Text) {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+		postings := index.postings[term]
+		idf := math.Log(1 + (float64(len(index.docs))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for key, count := range postings {
+			tf := float64(count)
+			norm := k1 * (1 - b + b*float64(index.docs[key].length)/avgLength)
+			scores[key] += idf * tf * (k1 + 1) / (tf + norm)
+		}
+	}
+
+	hits := []*SearchHit{}
+	created := map[*SearchHit]time.Time{}
+	for key, score := range scores {
+		entry := index.docs[key]
+		if !index.matches(entry, query) {
+			continue
+		}
+		hit := &SearchHit{SearchDoc: *entry.doc, Score: score}
+		created[hit] = entry.created
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if query.Sort != SearchSortNew && hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !created[hits[i]].Equal(created[hits[j]]) {
+			return created[hits[i]].After(created[hits[j]])
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if query.Offset >= len(hits) {
+		return []*SearchHit{}, nil
+	}
+	hits = hits[query.Offset:]
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+func (index *MemorySearchIndex) matches(entry *searchEntry, query *SearchQuery) bool {
+	doc := entry.doc
+	if !query.includes(doc.Target) {
+		return false
+	}
+	if query.Type != "" && query.Type != SearchTargetComment && query.Type != doc.PostType {
+		return false
+	}
+	if query.Category != "" && query.Category != doc.Category {
+		return false
+	}
+	if query.Author != "" && query.Author != doc.AuthorLogin {
+		return false
+	}
+	if !query.From.IsZero() && entry.created.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !entry.created.Before(query.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Search handles GET /api/search, the snippets are html escaped with the
+// matches wrapped in <mark>
+func (h *PostsHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	query, err := SearchQueryFromRequest(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hits, err := h.SearchIndex.Search(query)
+	if nil != err {
+		fmt.Println("can't search", err)
+		jsonError(w, http.StatusInternalServerError, "can't search")
+		return
+	}
+	sess, _ := SessionFromContext(r.Context())
+	isHidden, err := shadowbanFilter(h.BanRepo, sess)
+	if nil != err {
+		fmt.Println("can't hide shadowbanned results", err)
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
+
+	terms := searchTerms(query.Text)
+	results := make([]*SearchResultDTO, 0, len(hits))
+	for _, hit := range hits {
+		if isHidden(hit.AuthorID) {
+			continue
+		}
+		results = append(results, &SearchResultDTO{
+			Type:     hit.Target,
+			ID:       hit.ID,
+			PostID:   hit.PostID,
+			PostType: hit.PostType,
+			Title:    hit.Title,
+			Snippet:  SearchSnippet(hit.Body, terms),
+			Author: &AuthorDTO{
+				UserName: hit.AuthorLogin,
+				ID:       hit.AuthorID,
+			},
+			Category: hit.Category,
+			Created:  hit.Created,
+		})
+	}
+	jsonResponse(w, results)
+}
+
+// The search sync helpers don't fail the request, the embedded index is
+// loaded from the db again on restart. Handlers without an index skip
+// the sync.
+
+func (h *PostsHandler) indexSearch(doc *SearchDoc) {
+	if h.SearchIndex == nil {
+		return
+	}
+	err := h.SearchIndex.Index(doc)
+	if nil != err {
+		fmt.Println("can't index", doc.Target, doc.ID, err)
+	}
+}
+
+func (h *PostsHandler) unindexSearch(target string, id string) {
+	if h.SearchIndex == nil {
+		return
+	}
+	err := h.SearchIndex.Remove(target, id)
+	if nil != err {
+		fmt.Println("can't remove from the search index", target, id, err)
+	}
+}
+
+// reindexPost brings back a restored or approved post with its comments
+func (h *PostsHandler) reindexPost(postID string) {
+	if h.SearchIndex == nil {
+		return
+	}
+	data, err := h.PostsRepo.GetById(postID)
+	if nil != err {
+		fmt.Println("can't get post to index", err)
+		return
+	}
+	if data.Post.Removed {
+		return
+	}
+	h.indexSearch(postSearchDoc(data))
+	comments, err := h.CommentRepo.GetCommentsByPostIds([]string{postID})
+	if nil != err {
+		fmt.Println("can't get comments to index", err)
+		return
+	}
+	for _, comment := range comments[postID] {
+		if comment.Comment.DeletedAt != "" {
+			continue
+		}
+		comment.Comment.UserId = comment.User.ID
+		h.indexSearch(commentSearchDoc(&comment.Comment, &comment.User, data))
+	}
+}
+
+func (h *PostsHandler) reindexComment(commentID string) {
+	if h.SearchIndex == nil {
+		return
+	}
+	comment, err := h.CommentRepo.GetById(commentID)
+	if nil != err {
+		fmt.Println("can't get comment to index", err)
+		return
+	}
+	data, err := h.PostsRepo.GetById(comment.PostId)
+	if nil != err {
+		fmt.Println("can't get post to index", err)
+		return
+	}
+	if comment.Removed || data.Post.Removed {
+		return
+	}
+	author, err := h.UserRepo.GetById(comment.UserId)
+	if nil != err {
+		fmt.Println("can't get comment author to index", err)
+		return
+	}
+	h.indexSearch(commentSearchDoc(comment, author, data))
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestSearchQueryFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/search?q=Go+gophers&category=programming&author=mer&type=comment&from=2022-11-01&to=2022-11-10T12:00:00Z&sort=new&limit=1000&offset=5", nil)
+	query, err := SearchQueryFromRequest(req)
+	if nil != err {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	expected := &SearchQuery{
+		Text:     "Go gophers",
+		Category: "programming",
+		Author:   "mer",
+		Type:     SearchTargetComment,
+		From:     time.Date(2022, 11, 1, 0, 0, 0, 0, time.Local),
+		To:       time.Date(2022, 11, 10, 12, 0, 0, 0, time.UTC),
+		Sort:     SearchSortNew,
+		Limit:    ListMaxLimit,
+		Offset:   5,
+	}
+	if !query.From.Equal(expected.From) || !query.To.Equal(expected.To) {
+		t.Errorf("bad bounds: %v %v", query.From, query.To)
+		return
+	}
+	query.From, query.To = expected.From, expected.To
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, query)
+		return
+	}
+
+	//defaults
+	query, err = SearchQueryFromRequest(httptest.NewRequest("GET", "/api/search?q=go", nil))
+	if nil != err || query.Sort != SearchSortRelevance || query.Limit != ListDefaultLimit || query.Type != "" {
+		t.Errorf("bad defaults: %v %v", query, err)
+		return
+	}
+
+	for _, params := range []string{"", "q=+!?+", "q=go&type=video", "q=go&sort=top", "q=go&from=yesterday", "q=go&limit=0", "q=go&offset=-1"} {
+		_, err = SearchQueryFromRequest(httptest.NewRequest("GET", "/api/search?"+params, nil))
+		if nil == err {
+			t.Errorf("expected error for %q", params)
+		}
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	cases := []struct {
+		text     string
+		terms    []string
+		expected string
+	}{
+		{"Go is fun, go!", []string{"go"}, "<mark>Go</mark> is fun, <mark>go</mark>!"},
+		{"gopher <b>go</b>", []string{"go"}, "gopher &lt;b&gt;<mark>go</mark>&lt;/b&gt;"},
+		{"nothing here", []string{"go"}, "nothing here"},
+	}
+	for _, item := range cases {
+		snippet := SearchSnippet(item.text, item.terms)
+		if snippet != item.expected {
+			t.Errorf("bad snippet of %q: %q", item.text, snippet)
+		}
+	}
+
+	//long text is cut around the first match
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "word "
+	}
+	snippet := SearchSnippet(long+"needle "+long, []string{"needle"})
+	if !regexp.MustCompile(`^…word .*<mark>needle</mark> word.*…$`).MatchString(snippet) {
+		t.Errorf("bad long snippet: %q", snippet)
+	}
+}
+
+func TestMemorySearchIndex(t *testing.T) {
+	index := NewMemorySearchIndex()
+	docs := []*SearchDoc{
+		{Target: SearchTargetPost, ID: "p1", PostID: "p1", PostType: PostTypeText, Title: "Gophers", Body: "all about go",
+			AuthorID: "u1", AuthorLogin: "mer", Category: "programming", Created: "2022-11-01T10:00:00Z"},
+		{Target: SearchTargetPost, ID: "p2", PostID: "p2", PostType: PostTypeLink, Title: "Rust news", Body: "go and rust",
+			AuthorID: "u2", AuthorLogin: "bob", Category: "programming", Created: "2022-11-05T10:00:00Z"},
+		{Target: SearchTargetComment, ID: "c1", PostID: "p1", PostType: PostTypeText, Title: "Gophers", Body: "gophers are cute",
+			AuthorID: "u2", AuthorLogin: "bob", Category: "programming", Created: "2022-11-03T10:00:00Z"},
+		{Target: SearchTargetPost, ID: "p3", PostID: "p3", PostType: PostTypeText, Title: "Dresses", Body: "summer",
+			AuthorID: "u1", AuthorLogin: "mer", Category: "fashion", Created: "2022-11-06T10:00:00Z"},
+	}
+	for _, doc := range docs {
+		index.Index(doc)
+	}
+	ids := func(query *SearchQuery) []string {
+		if query.Limit == 0 {
+			query.Limit = 10
+		}
+		hits, err := index.Search(query)
+		if nil != err {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := []string{}
+		for _, hit := range hits {
+			result = append(result, hit.ID)
+		}
+		return result
+	}
+	cases := []struct {
+		query    *SearchQuery
+		expected []string
+	}{
+		//the title match counts more
+		{&SearchQuery{Text: "gophers"}, []string{"p1", "c1"}},
+		{&SearchQuery{Text: "GO"}, []string{"p1", "p2"}},
+		{&SearchQuery{Text: "go gophers", Sort: SearchSortNew}, []string{"p2", "c1", "p1"}},
+		{&SearchQuery{Text: "go gophers", Type: SearchTargetComment}, []string{"c1"}},
+		{&SearchQuery{Text: "go gophers", Type: PostTypeLink}, []string{"p2"}},
+		{&SearchQuery{Text: "go gophers", Author: "bob", Sort: SearchSortNew}, []string{"p2", "c1"}},
+		{&SearchQuery{Text: "summer go", Category: "fashion"}, []string{"p3"}},
+		{&SearchQuery{Text: "go gophers", Sort: SearchSortNew,
+			From: time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2022, 11, 5, 10, 0, 0, 0, time.UTC)}, []string{"c1"}},
+		{&SearchQuery{Text: "go gophers", Sort: SearchSortNew, Limit: 1, Offset: 1}, []string{"c1"}},
+		{&SearchQuery{Text: "go", Offset: 5}, []string{}},
+		{&SearchQuery{Text: "python"}, []string{}},
+	}
+	for _, item := range cases {
+		result := ids(item.query)
+		if !reflect.DeepEqual(result, item.expected) {
+			t.Errorf("bad result for %+v: %v", item.query, result)
+		}
+	}
+
+	//reindex replaces the old terms
+	index.Index(&SearchDoc{Target: SearchTargetPost, ID: "p3", PostID: "p3", PostType: PostTypeText, Title: "Dresses", Body: "winter",
+		AuthorID: "u1", AuthorLogin: "mer", Category: "fashion", Created: "2022-11-06T10:00:00Z"})
+	if result := ids(&SearchQuery{Text: "summer"}); len(result) != 0 {
+		t.Errorf("old terms are still indexed: %v", result)
+	}
+	if result := ids(&SearchQuery{Text: "winter"}); !reflect.DeepEqual(result, []string{"p3"}) {
+		t.Errorf("new terms aren't indexed: %v", result)
+	}
+
+	//remove of a post takes its comments
+	index.Remove(SearchTargetPost, "p1")
+	if result := ids(&SearchQuery{Text: "go gophers"}); !reflect.DeepEqual(result, []string{"p2"}) {
+		t.Errorf("removed post is still found: %v", result)
+	}
+}
+
+func TestMySQLSearchIndex(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+	index := NewMySQLSearchIndex(db)
+	columns := []string{"target", "id", "post_id", "type", "title", "body", "user_id", "login", "name", "created", "relevance"}
+
+	//comments only with filters
+	from := time.Date(2022, 11, 1, 0, 0, 0, 0, time.Local)
+	mock.
+		ExpectQuery(`SELECT \* FROM \(SELECT 'comment', .* FROM comment .* AND MATCH\(comment.body\) AGAINST \(\? IN NATURAL LANGUAGE MODE\) AND category.name = \? AND user.login = \? AND comment.created >= \?\) AS hits\s+ORDER BY relevance DESC, created DESC\s+LIMIT \? OFFSET \?`).
+		WithArgs("gophers", "gophers", "programming", "bob", from.Format(time.RFC3339), 10, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("comment", "c1", "p1", "text", "Gophers", "gophers are cute", "u2", "bob", "programming", "2022-11-03T10:00:00Z", 1.5))
+	hits, err := index.Search(&SearchQuery{Text: "gophers", Category: "programming", Author: "bob", Type: SearchTargetComment,
+		From: from, Sort: SearchSortRelevance, Limit: 10})
+	if nil != err {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	expected := []*SearchHit{{SearchDoc: SearchDoc{Target: "comment", ID: "c1", PostID: "p1", PostType: "text", Title: "Gophers",
+		Body: "gophers are cute", AuthorID: "u2", AuthorLogin: "bob", Category: "programming", Created: "2022-11-03T10:00:00Z"}, Score: 1.5}}
+	if !reflect.DeepEqual(hits, expected) {
+		t.Errorf("results not match, want %v, have %v", expected, hits)
+		return
+	}
+
+	//posts and comments, newest first
+	mock.
+		ExpectQuery(`FROM post .* AND MATCH\(post.title, post.description\) AGAINST \(\? IN NATURAL LANGUAGE MODE\) UNION ALL SELECT 'comment', .*\) AS hits\s+ORDER BY created DESC`).
+		WithArgs("go", "go", "go", "go", 5, 10).
+		WillReturnError(fmt.Errorf("db_error"))
+	_, err = index.Search(&SearchQuery{Text: "go", Sort: SearchSortNew, Limit: 5, Offset: 10})
+	if nil == err {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	searchIndexMock := NewMockSearchIndex(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
+	service := &PostsHandler{
+		SearchIndex: searchIndexMock,
+		BanRepo:     banRepoMock,
+	}
+	hits := []*SearchHit{
+		{SearchDoc: SearchDoc{Target: SearchTargetPost, ID: "p1", PostID: "p1", PostType: PostTypeText, Title: "Gophers",
+			Body: "all about <go>", AuthorID: "u1", AuthorLogin: "mer", Category: "programming", Created: "2022-11-01T10:00:00Z"}, Score: 2},
+		{SearchDoc: SearchDoc{Target: SearchTargetComment, ID: "c1", PostID: "p1", PostType: PostTypeText, Title: "Gophers",
+			Body: "go away", AuthorID: "u2", AuthorLogin: "spammer", Category: "programming", Created: "2022-11-03T10:00:00Z"}, Score: 1},
+	}
+
+	//shadowbanned authors are hidden
+	searchIndexMock.EXPECT().Search(gomock.Any()).Return(hits, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{"u2": {}}, nil)
+	req := httptest.NewRequest("GET", "/api/search?q=go", nil)
+	w := httptest.NewRecorder()
+	service.Search(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+	expected := `[{"type":"post","id":"p1","post_id":"p1","post_type":"text","title":"Gophers","snippet":"all about \u0026lt;\u003cmark\u003ego\u003c/mark\u003e\u0026gt;","author":{"username":"mer","id":"u1"},"category":"programming","created":"2022-11-01T10:00:00Z"}]`
+	if w.Result().StatusCode != http.StatusOK || string(body) != expected {
+		t.Errorf("bad response %d: %s", w.Result().StatusCode, body)
+		return
+	}
+
+	//the shadowbanned author sees their own comment
+	searchIndexMock.EXPECT().Search(gomock.Any()).Return(hits, nil)
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{"u2": {}}, nil)
+	req = httptest.NewRequest("GET", "/api/search?q=go", nil)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, &Session{ID: "1", UserID: "u2"}))
+	w = httptest.NewRecorder()
+	service.Search(w, req)
+	body, _ = io.ReadAll(w.Result().Body)
+	if w.Result().StatusCode != http.StatusOK || !regexp.MustCompile(`"id":"p1".*"id":"c1"`).Match(body) {
+		t.Errorf("bad response %d: %s", w.Result().StatusCode, body)
+		return
+	}
+
+	//bad query
+	w = httptest.NewRecorder()
+	service.Search(w, httptest.NewRequest("GET", "/api/search?q=go&sort=top", nil))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//index error
+	searchIndexMock.EXPECT().Search(gomock.Any()).Return(nil, fmt.Errorf("index error"))
+	w = httptest.NewRecorder()
+	service.Search(w, httptest.NewRequest("GET", "/api/search?q=go", nil))
+	if w.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
+
+func TestSearchIndexSync(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	searchIndexMock := NewMockSearchIndex(ctrl)
+	postsRepoMock := NewMockPostRepoI(ctrl)
+	commentRepoMock := NewMockCommentRepoI(ctrl)
+	timeGetterMock := NewMockTimeGetterI(ctrl)
+	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	service := &PostsHandler{
+		SearchIndex:  searchIndexMock,
+		PostsRepo:    postsRepoMock,
+		CommentRepo:  commentRepoMock,
+		TimeGetter:   timeGetterMock,
+		DTOConverter: dtoConverterMock,
+	}
+	data := multipleComplexData[0]
+	postID := data.Post.ID
+	newRequest := func(method string, path string) *http.Request {
+		req := httptest.NewRequest(method, path, nil)
+		req = mux.SetURLVars(req, map[string]string{"POST_ID": postID})
+		return req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	}
+
+	//deleted post leaves the index
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC))
+	postsRepoMock.EXPECT().Delete(postID, gomock.Any(), sess.UserID).Return(true, nil)
+	searchIndexMock.EXPECT().Remove(SearchTargetPost, postID).Return(nil)
+	w := httptest.NewRecorder()
+	service.Delete(w, newRequest("DELETE", "/api/post/"+postID))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//restored post is indexed again with its comments
+	comment := &CommentComplexData{
+		Comment: Comment{ID: "c1", PostId: postID, Body: "nice", Created: "2022-11-10T11:24:44Z"},
+		User:    User{ID: "u2", Login: "bob"},
+	}
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC))
+	postsRepoMock.EXPECT().Restore(postID, sess.UserID, gomock.Any()).Return(true, nil)
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil).Times(2)
+	commentRepoMock.EXPECT().GetCommentsByPostIds([]string{postID}).Return(map[string][]*CommentComplexData{postID: {comment}}, nil)
+	searchIndexMock.EXPECT().Index(postSearchDoc(data)).Return(nil)
+	searchIndexMock.EXPECT().Index(&SearchDoc{
+		Target:      SearchTargetComment,
+		ID:          "c1",
+		PostID:      postID,
+		PostType:    data.Post.Type,
+		Title:       data.Post.Title,
+		Body:        "nice",
+		AuthorID:    "u2",
+		AuthorLogin: "bob",
+		Category:    data.Category.Name,
+		Created:     "2022-11-10T11:24:44Z",
+	}).Return(nil)
+	dtoConverterMock.EXPECT().PostConvertToDTO(data, gomock.Any()).Return(postsDTO[0], nil)
+	w = httptest.NewRecorder()
+	service.RestorePost(w, newRequest("POST", "/api/post/"+postID+"/restore"))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+
+	//index errors don't fail the request
+	postsRepoMock.EXPECT().GetById(postID).Return(data, nil)
+	timeGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 10, 11, 24, 44, 0, time.UTC))
+	postsRepoMock.EXPECT().Delete(postID, gomock.Any(), sess.UserID).Return(true, nil)
+	searchIndexMock.EXPECT().Remove(SearchTargetPost, postID).Return(fmt.Errorf("index error"))
+	w = httptest.NewRecorder()
+	service.Delete(w, newRequest("DELETE", "/api/post/"+postID))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected 200 statuscode; got %d", w.Result().StatusCode)
+		return
+	}
+}
@@ -7,6 +7,7 @@ import (
 type Session struct {
 	ID     string
 	UserID string
+	Roles  []*Role
 }
 
 type ctxKey int
@@ -15,8 +16,26 @@ const sessionKey ctxKey = 1
 
 func SessionFromContext(ctx context.Context) (*Session, error) {
 	sess, ok := ctx.Value(sessionKey).(*Session)
-	if !ok {
+	if !ok || sess == nil {
 		return nil, ErrNoAuth
 	}
 	return sess, nil
 }
+
+func (sess *Session) IsAdmin() bool {
+	for _, role := range sess.Roles {
+		if role.Name == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func (sess *Session) IsModerator(categoryID uint32) bool {
+	for _, role := range sess.Roles {
+		if role.Name == RoleModerator && role.CategoryID == categoryID {
+			return true
+		}
+	}
+	return false
+}
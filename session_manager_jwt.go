@@ -69,7 +69,11 @@ func (sm *SessionsDBManagerJWT) Check(r *http.Request) (*Session, error) {
 
 	sess := &Session{}
 	fmt.Printf("check session %#v\n", payload)
-	row := sm.DB.QueryRow("SELECT id, user_id FROM sessions WHERE id = ?", payload.User.SessID)
+	// a site-wide ban rejects every session of the user until it expires
+	row := sm.DB.QueryRow(`SELECT id, user_id FROM sessions 
+	WHERE id = ? AND NOT EXISTS (SELECT 1 FROM ban 
+	WHERE ban.user_id = sessions.user_id AND kind = 'ban' AND category_id = 0 AND `+banActive+`)`,
+		payload.User.SessID, banNow())
 
 	err = row.Scan(&sess.ID, &sess.UserID)
 
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	StreamHeartbeatInterval = 15 * time.Second
+	StreamRetryMillis       = 3000
+)
+
+// StreamPost sends the comments and the votes of the post as
+// Server-Sent Events
+func (h *PostsHandler) StreamPost(w http.ResponseWriter, r *http.Request) {
+	postId := mux.Vars(r)["POST_ID"]
+	data, err := h.PostsRepo.GetById(postId)
+	if err == sql.ErrNoRows || (nil == err && data.Post.Removed) {
+		w.Header().Add("Content-Type", "application/json")
+		jsonError(w, http.StatusNotFound, "post not found")
+		return
+	} else if nil != err {
+		w.Header().Add("Content-Type", "application/json")
+		jsonError(w, http.StatusInternalServerError, "can't get post by id")
+		return
+	}
+	h.stream(w, r, postId)
+}
+
+// StreamPosts sends the events of every post, the new posts included
+func (h *PostsHandler) StreamPosts(w http.ResponseWriter, r *http.Request) {
+	h.stream(w, r, "")
+}
+
+// stream resumes from the Last-Event-ID header, or from the lastEventId
+// param for the clients which can't set it, and pings the client so the
+// proxies keep the connection open
+func (h *PostsHandler) stream(w http.ResponseWriter, r *http.Request, postId string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Add("Content-Type", "application/json")
+		jsonError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	client, missed, isComplete := h.Events.Subscribe(postId, lastEventID)
+	defer h.Events.Unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", StreamRetryMillis)
+	if !isComplete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", EventReset)
+	}
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	heartbeat := h.StreamHeartbeat
+	if heartbeat == 0 {
+		heartbeat = StreamHeartbeatInterval
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			if nil != err {
+				return
+			}
+		case event, ok := <-client.Events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); nil != err {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event *Event) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
+
+// publish doesn't fail the request, the clients that miss the event see
+// the change on the next fetch
+func (h *PostsHandler) publish(eventType string, postId string, payload interface{}) {
+	if h.Events == nil {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if nil != err {
+		fmt.Println("can't marshal event", eventType, err)
+		return
+	}
+	h.Events.Publish(&Event{
+		Type:   eventType,
+		PostID: postId,
+		Data:   data,
+	})
+}
+
+// isShadowbanned keeps the content of shadowbanned users out of the
+// streams, the streams are shared by all viewers
+func (h *PostsHandler) isShadowbanned(userID string) bool {
+	shadowbanned, err := h.BanRepo.GetShadowbannedUserIds()
+	if nil != err {
+		fmt.Println("can't get shadowbans", err)
+		return true
+	}
+	_, ok := shadowbanned[userID]
+	return ok
+}
+
+// commentAdded indexes the new comment and sends it to the streams,
+// comments held by the automoderator go to neither
+func (h *PostsHandler) commentAdded(comment *Comment, post *PostComplexData) {
+	if comment.Removed || (h.SearchIndex == nil && h.Events == nil) {
+		return
+	}
+	author, err := h.UserRepo.GetById(comment.UserId)
+	if nil != err {
+		fmt.Println("can't get comment author", err)
+		return
+	}
+	h.indexSearch(commentSearchDoc(comment, author, post))
+	if h.Events == nil || h.isShadowbanned(author.ID) {
+		return
+	}
+	// the same conversion as the post page, so the clients get the html
+	// and the score too
+	commentsDTO := h.DTOConverter.CommentsConvertToDTO([]*CommentComplexData{
+		{Comment: *comment, User: *author},
+	})
+	h.publish(EventComment, post.Post.ID, &CommentEventDTO{
+		PostID:  post.Post.ID,
+		Comment: commentsDTO[0],
+	})
+}
+
+func (h *PostsHandler) postAdded(post *PostDTO) {
+	if h.Events == nil || post.Author == nil || h.isShadowbanned(post.Author.ID) {
+		return
+	}
+	h.publish(EventPost, post.ID, post)
+}
+
+func (h *PostsHandler) publishVote(post *PostDTO) {
+	h.publish(EventVote, post.ID, &VoteEventDTO{
+		PostID:           post.ID,
+		Score:            post.Score,
+		UpVotePercentage: post.UpVotePercentage,
+	})
+}
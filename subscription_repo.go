@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type SubscriptionRepo struct {
+	DB *sql.DB
+}
+
+func NewSubscriptionRepo(db *sql.DB) *SubscriptionRepo {
+	return &SubscriptionRepo{
+		DB: db,
+	}
+}
+
+// Subscribe returns false if the user was already subscribed, the
+// category.subscribers counter changes only with a new row
+func (repo *SubscriptionRepo) Subscribe(userID string, categoryID uint32, created string) (bool, error) {
+	fmt.Println("Subscription repo: subscribe")
+	return repo.change(
+		"INSERT IGNORE INTO subscription (user_id, category_id, created) VALUES (?, ?, ?)",
+		"UPDATE category SET subscribers = subscribers + 1 WHERE id = ?",
+		categoryID, userID, categoryID, created)
+}
+
+func (repo *SubscriptionRepo) Unsubscribe(userID string, categoryID uint32) (bool, error) {
+	fmt.Println("Subscription repo: unsubscribe")
+	return repo.change(
+		"DELETE FROM subscription WHERE user_id = ? AND category_id = ?",
+		"UPDATE category SET subscribers = IF(subscribers = 0, 0, subscribers - 1) WHERE id = ?",
+		categoryID, userID, categoryID)
+}
+
+func (repo *SubscriptionRepo) change(query string, counterQuery string, categoryID uint32, args ...interface{}) (bool, error) {
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	if affected != 1 {
+		return false, tx.Commit()
+	}
+
+	_, err = tx.Exec(counterQuery, categoryID)
+	if nil != err {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (repo *SubscriptionRepo) HasSubscriptions(userID string) (bool, error) {
+	fmt.Println("Subscription repo: has subscriptions")
+	var exists bool
+	err := repo.DB.
+		QueryRow("SELECT EXISTS(SELECT 1 FROM subscription WHERE user_id = ?)", userID).
+		Scan(&exists)
+	if nil != err {
+		return false, err
+	}
+	return exists, nil
+}
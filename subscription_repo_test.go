@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSubscriptionSubscribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewSubscriptionRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+	created := "2022-11-09T19:51:42Z"
+
+	// new subscription increments the counter
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT IGNORE INTO subscription`).
+		WithArgs(userID, 7, created).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE category SET subscribers = subscribers \+ 1 WHERE id = \?`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isSubscribed, err := repo.Subscribe(userID, 7, created)
+	if err != nil || !isSubscribed {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// already subscribed, counter is untouched
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT IGNORE INTO subscription`).
+		WithArgs(userID, 7, created).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	isSubscribed, err = repo.Subscribe(userID, 7, created)
+	if err != nil || isSubscribed {
+		t.Errorf("expected no change, got %v %s", isSubscribed, err)
+		return
+	}
+
+	// counter error rolls back
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT IGNORE INTO subscription`).
+		WithArgs(userID, 7, created).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE category SET subscribers`).
+		WithArgs(7).
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+	_, err = repo.Subscribe(userID, 7, created)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestSubscriptionUnsubscribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewSubscriptionRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+
+	// success
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM subscription WHERE user_id = \? AND category_id = \?`).
+		WithArgs(userID, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE category SET subscribers = IF`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isUnsubscribed, err := repo.Unsubscribe(userID, 7)
+	if err != nil || !isUnsubscribed {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// query error
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM subscription`).
+		WithArgs(userID, 7).
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+	_, err = repo.Unsubscribe(userID, 7)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestSubscriptionHasSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewSubscriptionRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+
+	// success
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM subscription WHERE user_id = \?\)`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	has, err := repo.HasSubscriptions(userID)
+	if err != nil || !has {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// query error
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(userID).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = repo.HasSubscriptions(userID)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
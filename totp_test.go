@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 vectors truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).
+		EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range cases {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			return
+		}
+		if code != expected {
+			t.Errorf("code for %d is not matched; want: %s; have: %s", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	now := time.Unix(1668693946, 0)
+	code, _ := TOTPCode(secret, TOTPStep(now)-1)
+
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Errorf("expected previous step code to be valid")
+		return
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*TOTPPeriod*time.Second)); ok {
+		t.Errorf("expected outdated code to be rejected")
+		return
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Errorf("expected short code to be rejected")
+		return
+	}
+}
+
+func TestTOTPAuthURI(t *testing.T) {
+	uri := TOTPAuthURI("mer", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/redditclone:mer?") ||
+		!strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("unexpected uri: %s", uri)
+	}
+}
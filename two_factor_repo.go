@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type TwoFactorRepo struct {
+	DB *sql.DB
+}
+
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{
+		DB: db,
+	}
+}
+
+func (repo *TwoFactorRepo) GetByUserId(userID string) (*UserTOTP, error) {
+	fmt.Println("Two factor repo: get by user id")
+	totp := &UserTOTP{}
+	err := repo.DB.
+		QueryRow("SELECT user_id, secret, enabled, last_step, created FROM user_totp WHERE user_id = ?", userID).
+		Scan(&totp.UserID, &totp.Secret, &totp.Enabled, &totp.LastStep, &totp.Created)
+	if nil != err {
+		return nil, err
+	}
+	return totp, nil
+}
+
+// Save starts a new enrollment, it replaces a pending one if any.
+func (repo *TwoFactorRepo) Save(totp *UserTOTP) error {
+	fmt.Println("Two factor repo: save")
+	_, err := repo.DB.Exec(`INSERT INTO user_totp (user_id, secret, enabled, last_step, created)
+	VALUES (?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled = VALUES(enabled),
+	last_step = VALUES(last_step), created = VALUES(created)`,
+		totp.UserID, totp.Secret, totp.Enabled, totp.LastStep, totp.Created)
+	return err
+}
+
+func (repo *TwoFactorRepo) Enable(userID string) error {
+	fmt.Println("Two factor repo: enable")
+	result, err := repo.DB.Exec("UPDATE user_totp SET enabled = 1 WHERE user_id = ?", userID)
+	if nil != err {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return err
+	}
+	if affected != 1 {
+		return fmt.Errorf("wrong affected rows: %d for user id: %s", affected, userID)
+	}
+	return nil
+}
+
+// UseStep moves last_step forward; it fails for an already used or older
+// step, so a code can't be replayed.
+func (repo *TwoFactorRepo) UseStep(userID string, step int64) (bool, error) {
+	fmt.Println("Two factor repo: use step")
+	result, err := repo.DB.Exec(
+		"UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?",
+		step, userID, step)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *TwoFactorRepo) Delete(userID string) error {
+	fmt.Println("Two factor repo: delete")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("DELETE FROM recovery_code WHERE user_id = ?", userID)
+	if nil != err {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM user_totp WHERE user_id = ?", userID)
+	if nil != err {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (repo *TwoFactorRepo) SetRecoveryCodes(userID string, hashes []string) error {
+	fmt.Println("Two factor repo: set recovery codes")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("DELETE FROM recovery_code WHERE user_id = ?", userID)
+	if nil != err {
+		return err
+	}
+	for _, hash := range hashes {
+		_, err = tx.Exec("INSERT INTO recovery_code (user_id, code_hash, used) VALUES (?, ?, 0)", userID, hash)
+		if nil != err {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *TwoFactorRepo) UseRecoveryCode(userID string, hash string) (bool, error) {
+	fmt.Println("Two factor repo: use recovery code")
+	result, err := repo.DB.Exec(
+		"UPDATE recovery_code SET used = 1 WHERE user_id = ? AND code_hash = ? AND used = 0",
+		userID, hash)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *TwoFactorRepo) CreateChallenge(challenge *LoginChallenge) error {
+	fmt.Println("Two factor repo: create challenge")
+	_, err := repo.DB.Exec(
+		"INSERT INTO login_challenge (token, user_id, attempts, expires) VALUES (?, ?, ?, ?)",
+		challenge.Token, challenge.UserID, challenge.Attempts, challenge.Expires)
+	return err
+}
+
+func (repo *TwoFactorRepo) GetChallenge(token string) (*LoginChallenge, error) {
+	fmt.Println("Two factor repo: get challenge")
+	challenge := &LoginChallenge{}
+	err := repo.DB.
+		QueryRow("SELECT token, user_id, attempts, expires FROM login_challenge WHERE token = ?", token).
+		Scan(&challenge.Token, &challenge.UserID, &challenge.Attempts, &challenge.Expires)
+	if nil != err {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// UseChallengeAttempt counts an attempt while the challenge has some left,
+// the concurrent requests can't take more than maxAttempts together
+func (repo *TwoFactorRepo) UseChallengeAttempt(token string, maxAttempts int) (bool, error) {
+	fmt.Println("Two factor repo: use challenge attempt")
+	result, err := repo.DB.Exec(
+		"UPDATE login_challenge SET attempts = attempts + 1 WHERE token = ? AND attempts < ?",
+		token, maxAttempts)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (repo *TwoFactorRepo) DeleteChallenge(token string) error {
+	fmt.Println("Two factor repo: delete challenge")
+	_, err := repo.DB.Exec("DELETE FROM login_challenge WHERE token = ?", token)
+	return err
+}
@@ -6,7 +6,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -22,37 +28,163 @@ type UserRepoI interface {
 	GetById(id string) (*User, error)
 	GetByLogin(login string) (*User, error)
 	Create(user *User) (*string, error)
+	SetVerified(id string, email string) (bool, error)
+	UpdatePassword(id string, password string) (bool, error)
+	GetKarma(id string) (int64, error)
+	GetProfileByLogin(login string) (*UserProfile, error)
+	SetBio(id string, bio string) error
+}
+
+type EmailVerificationRepoI interface {
+	Create(verification *EmailVerification) error
+	GetByToken(token string) (*EmailVerification, error)
+	GetByUserId(userID string) (*EmailVerification, error)
+	DeleteByUserId(userID string) error
+}
+
+type TwoFactorRepoI interface {
+	GetByUserId(userID string) (*UserTOTP, error)
+	Save(totp *UserTOTP) error
+	Enable(userID string) error
+	UseStep(userID string, step int64) (bool, error)
+	Delete(userID string) error
+	SetRecoveryCodes(userID string, hashes []string) error
+	UseRecoveryCode(userID string, hash string) (bool, error)
+	CreateChallenge(challenge *LoginChallenge) error
+	GetChallenge(token string) (*LoginChallenge, error)
+	UseChallengeAttempt(token string, maxAttempts int) (bool, error)
+	DeleteChallenge(token string) error
+}
+
+type RoleRepoI interface {
+	GetByUserId(userID string) ([]*Role, error)
+	Add(role *Role) error
+	Delete(role *Role) (bool, error)
+}
+
+type BanRepoI interface {
+	Add(ban *Ban) error
+	Delete(userID string, kind string, categoryID uint32) (bool, error)
+	IsBanned(userID string, categoryID uint32) (bool, error)
+	GetShadowbannedUserIds() (map[string]struct{}, error)
+}
+
+type NotificationRepoI interface {
+	Add(notification *Notification) (bool, error)
+	GetByUserId(userID string, unreadOnly bool, limit int, offset int) ([]*NotificationComplexData, error)
+	CountUnread(userID string) (int64, error)
+	MarkRead(userID string, ids []uint64) (int64, error)
+	GetMuted(userID string) ([]string, error)
+	SetMuted(userID string, types []string) error
+}
+
+type MessageRepoI interface {
+	Add(message *Message) (uint64, error)
+	GetConversations(userID string, limit int, offset int) ([]*Conversation, error)
+	GetThread(userID string, otherID string, limit int, offset int) ([]*Message, error)
+	MarkThreadRead(userID string, otherID string) (int64, error)
+	CountUnread(userID string) (int64, error)
+	CountSentSince(senderID string, since string) (int64, error)
+}
+
+type BlockRepoI interface {
+	Add(blockerID string, blockedID string, created string) (bool, error)
+	Delete(blockerID string, blockedID string) (bool, error)
+	IsBlocked(userID string, otherID string) (bool, error)
+	GetBlocked(blockerID string, limit int, offset int) ([]*BlockComplexData, error)
+	GetBlockedIds(blockerID string) (map[string]struct{}, error)
+}
+
+type FollowRepoI interface {
+	Add(follow *Follow) (bool, error)
+	Delete(followerID string, followedID string) (bool, error)
+	GetFollowers(userID string, limit int, offset int) ([]*FollowComplexData, error)
+	GetFollowing(userID string, limit int, offset int) ([]*FollowComplexData, error)
+	Count(userID string) (int64, int64, error)
+}
+
+type LoginThrottleI interface {
+	Check(login string, ip string, now time.Time) (time.Duration, error)
+	Failed(login string, ip string, now time.Time) error
+	Succeeded(login string) error
+}
+
+type AuditLogRepoI interface {
+	Add(entry *AuditLogEntry) error
+}
+
+type MailSenderI interface {
+	Send(mail *Mail) error
 }
 
 type UserUtilsI interface {
 	GenerateJWT(user *User, sessID string) (string, error)
 	GeneratePasswordHash(password string) (string, error)
 	CheckPasswordHash(passwordReceived string, hash string) bool
+	NeedsRehash(hash string) bool
 }
 
 type UserHandler struct {
-	SessionManager SessionManagerI
-	UserRepo       UserRepoI
-	PostsRepo      PostRepoI
-	DTOConverter   DTOConverterI
-	UUIDGetter     UUIDGetterI
-	TimeGetter     TimeGetterI
-	UserUtils      UserUtilsI
-	Logger         *log.Logger
+	SessionManager        SessionManagerI
+	UserRepo              UserRepoI
+	PostsRepo             PostRepoI
+	CommentRepo           CommentRepoI
+	EmailVerificationRepo EmailVerificationRepoI
+	TwoFactorRepo         TwoFactorRepoI
+	MailSender            MailSenderI
+	LoginThrottle         LoginThrottleI
+	RoleRepo              RoleRepoI
+	DictionaryRepo        DictionaryRepoI
+	BanRepo               BanRepoI
+	NotificationRepo      NotificationRepoI
+	MessageRepo           MessageRepoI
+	BlockRepo             BlockRepoI
+	FollowRepo            FollowRepoI
+	DTOConverter          DTOConverterI
+	UUIDGetter            UUIDGetterI
+	TimeGetter            TimeGetterI
+	UserUtils             UserUtilsI
+	Logger                *log.Logger
+}
+
+var EmailVerificationTTL = 24 * time.Hour
+
+// a user gets one verification email per EmailVerificationResendInterval
+// at most, whatever the addresses
+var EmailVerificationResendInterval = 10 * time.Minute
+
+// reservedLogins are path segments of the own user routes, /api/user/me/...
+var reservedLogins = map[string]struct{}{
+	"me": {},
 }
 
 func NewUserHandler(db *sql.DB, sm SessionManagerI) *UserHandler {
 	return &UserHandler{
-		SessionManager: sm,
-		UserRepo:       NewUserRepo(db),
-		PostsRepo:      NewPostsRepo(db),
+		SessionManager:        sm,
+		UserRepo:              NewUserRepo(db),
+		PostsRepo:             NewPostsRepo(db),
+		CommentRepo:           NewCommentRepo(db),
+		EmailVerificationRepo: NewEmailVerificationRepo(db),
+		TwoFactorRepo:         NewTwoFactorRepo(db),
+		MailSender:            NewMailSender(),
+		LoginThrottle:         NewLoginThrottle(db),
+		RoleRepo:              NewRoleRepo(db),
+		DictionaryRepo:        NewDictionaryRepo(db),
+		BanRepo:               NewBanRepo(db),
+		NotificationRepo:      NewNotificationRepo(db),
+		MessageRepo:           NewMessageRepo(db),
+		BlockRepo:             NewBlockRepo(db),
+		FollowRepo:            NewFollowRepo(db),
 		DTOConverter: &DTOConverter{
 			CommentRepo: NewCommentRepo(db),
 			VoteRepo:    NewVoteRepo(db),
+			SavedRepo:   NewSavedRepo(db),
+			BlockRepo:   NewBlockRepo(db),
+			Markdown:    NewMarkdownCache(),
 		},
 		UUIDGetter: &UUIDGetter{},
 		TimeGetter: &TimeGetter{},
-		UserUtils:  &UserUtils{},
+		UserUtils:  NewUserUtils(),
 		Logger:     nil,
 	}
 }
@@ -65,13 +197,21 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusInternalServerError, "can't read request")
 		return
 	}
-	registerReuqest := &LoginDTO{}
+	registerReuqest := &RegisterDTO{}
 	err = json.Unmarshal(body, registerReuqest)
 	if nil != err {
 		fmt.Println("can't unpack payload: ", err.Error())
 		jsonError(w, http.StatusInternalServerError, "can't unpack payload")
 		return
 	}
+	if _, ok := reservedLogins[strings.ToLower(registerReuqest.UserName)]; ok {
+		jsonError(w, http.StatusBadRequest, "this username is reserved")
+		return
+	}
+	if registerReuqest.Email != "" && !isValidEmail(registerReuqest.Email) {
+		jsonError(w, http.StatusBadRequest, "invalid email")
+		return
+	}
 	passwordHash, err := h.UserUtils.GeneratePasswordHash(registerReuqest.Password)
 	if nil != err {
 		fmt.Println("can't generate a hash for the password: ", err.Error())
@@ -82,6 +222,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		ID:       h.UUIDGetter.GetUUID(),
 		Login:    registerReuqest.UserName,
 		Password: passwordHash,
+		Email:    registerReuqest.Email,
 		Created:  h.TimeGetter.GetCreated(),
 	}
 	lastID, err := h.UserRepo.Create(user)
@@ -98,6 +239,13 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if userAdded.Email != "" {
+		err = h.sendVerification(userAdded, userAdded.Email)
+		if nil != err {
+			fmt.Println("can't send verification email: ", err.Error())
+		}
+	}
+
 	sess, err := h.SessionManager.Create(w, userAdded)
 
 	if err != nil {
@@ -135,15 +283,37 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusInternalServerError, "can't unpack payload")
 		return
 	}
+	ip := clientIP(r)
+	if h.loginThrottled(w, loginRequest.UserName, ip, h.TimeGetter.Now()) {
+		return
+	}
 	userStored, err := h.UserRepo.GetByLogin(loginRequest.UserName)
-	if nil != err {
+	if err == sql.ErrNoRows {
+		h.UserUtils.CheckPasswordHash(loginRequest.Password, "")
+		h.loginFailed(w, loginRequest.UserName, ip)
+		return
+	} else if nil != err {
 		fmt.Println("can't get user by login: ", err.Error())
-		jsonError(w, http.StatusInternalServerError, "user not found")
+		jsonError(w, http.StatusInternalServerError, "can't get user")
 		return
 	}
 	if !h.UserUtils.CheckPasswordHash(loginRequest.Password, userStored.Password) {
 		fmt.Println("invalid password")
-		jsonError(w, http.StatusUnauthorized, "invalid password")
+		h.loginFailed(w, loginRequest.UserName, ip)
+		return
+	}
+	if h.UserUtils.NeedsRehash(userStored.Password) {
+		h.rehashPassword(userStored, loginRequest.Password)
+	}
+
+	twoFactor, err := h.TwoFactorRepo.GetByUserId(userStored.ID)
+	if nil != err && err != sql.ErrNoRows {
+		fmt.Println("can't get two factor settings: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get two factor settings")
+		return
+	}
+	if nil == err && twoFactor.Enabled {
+		h.startTwoFactorLogin(w, userStored)
 		return
 	}
 
@@ -154,6 +324,9 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, http.StatusInternalServerError, "can't create session")
 		return
 	}
+	// the failures are forgotten once a session is issued, a correct
+	// password alone doesn't reset them while the second factor is due
+	h.loginSucceeded(loginRequest.UserName)
 
 	validToken, err := h.UserUtils.GenerateJWT(userStored, sess.ID)
 	if nil != err {
@@ -178,12 +351,308 @@ func (h *UserHandler) GetPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data)
+	sess, _ := SessionFromContext(r.Context())
+	postsDTO, err := h.DTOConverter.PostsConvertToDTO(data, sess)
 	if nil != err {
 		jsonError(w, http.StatusInternalServerError, "can't convert posts by user login")
 		return
 	}
+	postsDTO, err = hideShadowbanned(h.BanRepo, sess, postsDTO)
+	if nil != err {
+		fmt.Println("can't hide shadowbanned posts: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get shadowbans")
+		return
+	}
 
 	w.Header().Add("Content-Type", "application/json")
 	jsonResponse(w, postsDTO)
 }
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	token := params["TOKEN"]
+
+	verification, err := h.EmailVerificationRepo.GetByToken(token)
+	if nil != err {
+		fmt.Println("can't get verification token: ", err.Error())
+		jsonError(w, http.StatusBadRequest, "invalid verification token")
+		return
+	}
+	expires, err := time.Parse(time.RFC3339, verification.Expires)
+	if nil != err || h.TimeGetter.Now().After(expires) {
+		jsonError(w, http.StatusBadRequest, "verification token expired")
+		return
+	}
+	_, err = h.UserRepo.SetVerified(verification.UserID, verification.Email)
+	if nil != err {
+		fmt.Println("can't set user verified: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't verify email")
+		return
+	}
+	err = h.EmailVerificationRepo.DeleteByUserId(verification.UserID)
+	if nil != err {
+		fmt.Println("can't delete verification tokens: ", err.Error())
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't read request")
+		return
+	}
+	verifyRequest := &RegisterDTO{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, verifyRequest)
+		if nil != err {
+			jsonError(w, http.StatusBadRequest, "can't unpack payload")
+			return
+		}
+	}
+	user, err := h.UserRepo.GetById(sess.UserID)
+	if nil != err {
+		fmt.Println("can't get user: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return
+	}
+	email := verifyRequest.Email
+	if email == "" {
+		email = user.Email
+	}
+	if email == "" || !isValidEmail(email) {
+		jsonError(w, http.StatusBadRequest, "invalid email")
+		return
+	}
+	if user.Verified && email == user.Email {
+		jsonError(w, http.StatusBadRequest, "email is already verified")
+		return
+	}
+	limited, err := h.verificationRateLimited(user.ID)
+	if nil != err {
+		fmt.Println("can't check verification rate limit: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't send verification email")
+		return
+	}
+	if limited {
+		jsonError(w, http.StatusTooManyRequests, "verification email was sent recently, try again later")
+		return
+	}
+	err = h.sendVerification(user, email)
+	if nil != err {
+		fmt.Println("can't send verification email: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't send verification email")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// verificationRateLimited tells if the token of the user was sent less than
+// EmailVerificationResendInterval ago, it was sent EmailVerificationTTL
+// before it expires
+func (h *UserHandler) verificationRateLimited(userID string) (bool, error) {
+	verification, err := h.EmailVerificationRepo.GetByUserId(userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if nil != err {
+		return false, err
+	}
+	expires, err := time.Parse(time.RFC3339, verification.Expires)
+	if nil != err {
+		return false, nil
+	}
+	sent := expires.Add(-EmailVerificationTTL)
+	return h.TimeGetter.Now().Before(sent.Add(EmailVerificationResendInterval)), nil
+}
+
+// sendVerification replaces the tokens of the user with a new one, so an
+// older token can't set the email the user has changed since
+func (h *UserHandler) sendVerification(user *User, email string) error {
+	token, err := RandSecureHex(32)
+	if nil != err {
+		return err
+	}
+	err = h.EmailVerificationRepo.DeleteByUserId(user.ID)
+	if nil != err {
+		return err
+	}
+	verification := &EmailVerification{
+		Token:   token,
+		UserID:  user.ID,
+		Email:   email,
+		Expires: h.TimeGetter.Now().Add(EmailVerificationTTL).Format(time.RFC3339),
+	}
+	err = h.EmailVerificationRepo.Create(verification)
+	if nil != err {
+		return err
+	}
+	return h.MailSender.Send(&Mail{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nconfirm your email by opening the link below:\n%s/api/verify/%s\n",
+			user.Login, baseURL(), token),
+	})
+}
+
+func (h *UserHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := h.readRoleRequest(w, r)
+	if !ok {
+		return
+	}
+	err := h.RoleRepo.Add(role)
+	if nil != err {
+		fmt.Println("can't add role: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't add role")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *UserHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := h.readRoleRequest(w, r)
+	if !ok {
+		return
+	}
+	isDeleted, err := h.RoleRepo.Delete(role)
+	if nil != err {
+		fmt.Println("can't delete role: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't delete role")
+		return
+	}
+	if !isDeleted {
+		jsonError(w, http.StatusNotFound, "role not found")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+// readRoleRequest checks that the caller may manage roles and resolves
+// the user and the category of the request; it writes the error itself.
+func (h *UserHandler) readRoleRequest(w http.ResponseWriter, r *http.Request) (*Role, bool) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return nil, false
+	}
+	if !Can(sess, ActionManageRoles, nil) {
+		jsonError(w, http.StatusForbidden, "forbidden")
+		return nil, false
+	}
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't read request")
+		return nil, false
+	}
+	roleRequest := &RoleRequestDTO{}
+	err = json.Unmarshal(body, roleRequest)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return nil, false
+	}
+	role := &Role{
+		Name: roleRequest.Role,
+	}
+	switch roleRequest.Role {
+	case RoleAdmin:
+	case RoleModerator:
+		category, err := h.DictionaryRepo.GetCategoryByName(roleRequest.Category)
+		if err == sql.ErrNoRows {
+			jsonError(w, http.StatusNotFound, "category not found")
+			return nil, false
+		} else if nil != err {
+			fmt.Println("can't get category: ", err.Error())
+			jsonError(w, http.StatusInternalServerError, "can't get category")
+			return nil, false
+		}
+		role.CategoryID = category.ID
+	default:
+		jsonError(w, http.StatusBadRequest, "unknown role")
+		return nil, false
+	}
+	user, err := h.UserRepo.GetByLogin(roleRequest.UserName)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusNotFound, "user not found")
+		return nil, false
+	} else if nil != err {
+		fmt.Println("can't get user: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return nil, false
+	}
+	role.UserID = user.ID
+	return role, true
+}
+
+// rehashPassword upgrades an outdated hash while the plain password is
+// at hand; a failure here must not break the login.
+func (h *UserHandler) rehashPassword(user *User, password string) {
+	passwordHash, err := h.UserUtils.GeneratePasswordHash(password)
+	if nil != err {
+		fmt.Println("can't rehash password: ", err.Error())
+		return
+	}
+	_, err = h.UserRepo.UpdatePassword(user.ID, passwordHash)
+	if nil != err {
+		fmt.Println("can't update password hash: ", err.Error())
+		return
+	}
+	user.Password = passwordHash
+}
+
+// loginFailed gives the same answer for an unknown login and a wrong
+// password, so the response doesn't tell which logins exist.
+// loginThrottled answers 429 while the account or the address is locked;
+// it writes the error itself.
+func (h *UserHandler) loginThrottled(w http.ResponseWriter, login string, ip string, now time.Time) bool {
+	wait, err := h.LoginThrottle.Check(login, ip, now)
+	if nil != err {
+		fmt.Println("can't check login attempts: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't check login attempts")
+		return true
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		jsonError(w, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+		return true
+	}
+	return false
+}
+
+func (h *UserHandler) loginSucceeded(login string) {
+	err := h.LoginThrottle.Succeeded(login)
+	if nil != err {
+		fmt.Println("can't reset login attempts: ", err.Error())
+	}
+}
+
+func (h *UserHandler) loginFailed(w http.ResponseWriter, login string, ip string) {
+	err := h.LoginThrottle.Failed(login, ip, h.TimeGetter.Now())
+	if nil != err {
+		fmt.Println("can't save failed login attempt: ", err.Error())
+	}
+	jsonError(w, http.StatusUnauthorized, "invalid login or password")
+}
+
+func isValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return nil == err && address.Address == email
+}
+
+func baseURL() string {
+	if url := os.Getenv("BASE_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8080"
+}
@@ -7,6 +7,7 @@ package main
 import (
 	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -160,6 +161,1054 @@ func (mr *MockUserRepoIMockRecorder) GetByLogin(login interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByLogin", reflect.TypeOf((*MockUserRepoI)(nil).GetByLogin), login)
 }
 
+// GetKarma mocks base method.
+func (m *MockUserRepoI) GetKarma(id string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKarma", id)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKarma indicates an expected call of GetKarma.
+func (mr *MockUserRepoIMockRecorder) GetKarma(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKarma", reflect.TypeOf((*MockUserRepoI)(nil).GetKarma), id)
+}
+
+// GetProfileByLogin mocks base method.
+func (m *MockUserRepoI) GetProfileByLogin(login string) (*UserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileByLogin", login)
+	ret0, _ := ret[0].(*UserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileByLogin indicates an expected call of GetProfileByLogin.
+func (mr *MockUserRepoIMockRecorder) GetProfileByLogin(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileByLogin", reflect.TypeOf((*MockUserRepoI)(nil).GetProfileByLogin), login)
+}
+
+// SetBio mocks base method.
+func (m *MockUserRepoI) SetBio(id, bio string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBio", id, bio)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetBio indicates an expected call of SetBio.
+func (mr *MockUserRepoIMockRecorder) SetBio(id, bio interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBio", reflect.TypeOf((*MockUserRepoI)(nil).SetBio), id, bio)
+}
+
+// SetVerified mocks base method.
+func (m *MockUserRepoI) SetVerified(id, email string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVerified", id, email)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetVerified indicates an expected call of SetVerified.
+func (mr *MockUserRepoIMockRecorder) SetVerified(id, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVerified", reflect.TypeOf((*MockUserRepoI)(nil).SetVerified), id, email)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepoI) UpdatePassword(id, password string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", id, password)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepoIMockRecorder) UpdatePassword(id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepoI)(nil).UpdatePassword), id, password)
+}
+
+// MockEmailVerificationRepoI is a mock of EmailVerificationRepoI interface.
+type MockEmailVerificationRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailVerificationRepoIMockRecorder
+}
+
+// MockEmailVerificationRepoIMockRecorder is the mock recorder for MockEmailVerificationRepoI.
+type MockEmailVerificationRepoIMockRecorder struct {
+	mock *MockEmailVerificationRepoI
+}
+
+// NewMockEmailVerificationRepoI creates a new mock instance.
+func NewMockEmailVerificationRepoI(ctrl *gomock.Controller) *MockEmailVerificationRepoI {
+	mock := &MockEmailVerificationRepoI{ctrl: ctrl}
+	mock.recorder = &MockEmailVerificationRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmailVerificationRepoI) EXPECT() *MockEmailVerificationRepoIMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockEmailVerificationRepoI) Create(verification *EmailVerification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", verification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockEmailVerificationRepoIMockRecorder) Create(verification interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockEmailVerificationRepoI)(nil).Create), verification)
+}
+
+// DeleteByUserId mocks base method.
+func (m *MockEmailVerificationRepoI) DeleteByUserId(userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUserId", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUserId indicates an expected call of DeleteByUserId.
+func (mr *MockEmailVerificationRepoIMockRecorder) DeleteByUserId(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUserId", reflect.TypeOf((*MockEmailVerificationRepoI)(nil).DeleteByUserId), userID)
+}
+
+// GetByToken mocks base method.
+func (m *MockEmailVerificationRepoI) GetByToken(token string) (*EmailVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByToken", token)
+	ret0, _ := ret[0].(*EmailVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByToken indicates an expected call of GetByToken.
+func (mr *MockEmailVerificationRepoIMockRecorder) GetByToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByToken", reflect.TypeOf((*MockEmailVerificationRepoI)(nil).GetByToken), token)
+}
+
+// GetByUserId mocks base method.
+func (m *MockEmailVerificationRepoI) GetByUserId(userID string) (*EmailVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID)
+	ret0, _ := ret[0].(*EmailVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockEmailVerificationRepoIMockRecorder) GetByUserId(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockEmailVerificationRepoI)(nil).GetByUserId), userID)
+}
+
+// MockTwoFactorRepoI is a mock of TwoFactorRepoI interface.
+type MockTwoFactorRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorRepoIMockRecorder
+}
+
+// MockTwoFactorRepoIMockRecorder is the mock recorder for MockTwoFactorRepoI.
+type MockTwoFactorRepoIMockRecorder struct {
+	mock *MockTwoFactorRepoI
+}
+
+// NewMockTwoFactorRepoI creates a new mock instance.
+func NewMockTwoFactorRepoI(ctrl *gomock.Controller) *MockTwoFactorRepoI {
+	mock := &MockTwoFactorRepoI{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorRepoI) EXPECT() *MockTwoFactorRepoIMockRecorder {
+	return m.recorder
+}
+
+// CreateChallenge mocks base method.
+func (m *MockTwoFactorRepoI) CreateChallenge(challenge *LoginChallenge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChallenge", challenge)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateChallenge indicates an expected call of CreateChallenge.
+func (mr *MockTwoFactorRepoIMockRecorder) CreateChallenge(challenge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChallenge", reflect.TypeOf((*MockTwoFactorRepoI)(nil).CreateChallenge), challenge)
+}
+
+// Delete mocks base method.
+func (m *MockTwoFactorRepoI) Delete(userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTwoFactorRepoIMockRecorder) Delete(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTwoFactorRepoI)(nil).Delete), userID)
+}
+
+// DeleteChallenge mocks base method.
+func (m *MockTwoFactorRepoI) DeleteChallenge(token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteChallenge", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteChallenge indicates an expected call of DeleteChallenge.
+func (mr *MockTwoFactorRepoIMockRecorder) DeleteChallenge(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChallenge", reflect.TypeOf((*MockTwoFactorRepoI)(nil).DeleteChallenge), token)
+}
+
+// Enable mocks base method.
+func (m *MockTwoFactorRepoI) Enable(userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockTwoFactorRepoIMockRecorder) Enable(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockTwoFactorRepoI)(nil).Enable), userID)
+}
+
+// GetByUserId mocks base method.
+func (m *MockTwoFactorRepoI) GetByUserId(userID string) (*UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID)
+	ret0, _ := ret[0].(*UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockTwoFactorRepoIMockRecorder) GetByUserId(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockTwoFactorRepoI)(nil).GetByUserId), userID)
+}
+
+// GetChallenge mocks base method.
+func (m *MockTwoFactorRepoI) GetChallenge(token string) (*LoginChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChallenge", token)
+	ret0, _ := ret[0].(*LoginChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChallenge indicates an expected call of GetChallenge.
+func (mr *MockTwoFactorRepoIMockRecorder) GetChallenge(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChallenge", reflect.TypeOf((*MockTwoFactorRepoI)(nil).GetChallenge), token)
+}
+
+// Save mocks base method.
+func (m *MockTwoFactorRepoI) Save(totp *UserTOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", totp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockTwoFactorRepoIMockRecorder) Save(totp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockTwoFactorRepoI)(nil).Save), totp)
+}
+
+// SetRecoveryCodes mocks base method.
+func (m *MockTwoFactorRepoI) SetRecoveryCodes(userID string, hashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRecoveryCodes", userID, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRecoveryCodes indicates an expected call of SetRecoveryCodes.
+func (mr *MockTwoFactorRepoIMockRecorder) SetRecoveryCodes(userID, hashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRecoveryCodes", reflect.TypeOf((*MockTwoFactorRepoI)(nil).SetRecoveryCodes), userID, hashes)
+}
+
+// UseChallengeAttempt mocks base method.
+func (m *MockTwoFactorRepoI) UseChallengeAttempt(token string, maxAttempts int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseChallengeAttempt", token, maxAttempts)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseChallengeAttempt indicates an expected call of UseChallengeAttempt.
+func (mr *MockTwoFactorRepoIMockRecorder) UseChallengeAttempt(token, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseChallengeAttempt", reflect.TypeOf((*MockTwoFactorRepoI)(nil).UseChallengeAttempt), token, maxAttempts)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTwoFactorRepoI) UseRecoveryCode(userID, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", userID, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTwoFactorRepoIMockRecorder) UseRecoveryCode(userID, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTwoFactorRepoI)(nil).UseRecoveryCode), userID, hash)
+}
+
+// UseStep mocks base method.
+func (m *MockTwoFactorRepoI) UseStep(userID string, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseStep", userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseStep indicates an expected call of UseStep.
+func (mr *MockTwoFactorRepoIMockRecorder) UseStep(userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseStep", reflect.TypeOf((*MockTwoFactorRepoI)(nil).UseStep), userID, step)
+}
+
+// MockRoleRepoI is a mock of RoleRepoI interface.
+type MockRoleRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleRepoIMockRecorder
+}
+
+// MockRoleRepoIMockRecorder is the mock recorder for MockRoleRepoI.
+type MockRoleRepoIMockRecorder struct {
+	mock *MockRoleRepoI
+}
+
+// NewMockRoleRepoI creates a new mock instance.
+func NewMockRoleRepoI(ctrl *gomock.Controller) *MockRoleRepoI {
+	mock := &MockRoleRepoI{ctrl: ctrl}
+	mock.recorder = &MockRoleRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleRepoI) EXPECT() *MockRoleRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockRoleRepoI) Add(role *Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockRoleRepoIMockRecorder) Add(role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockRoleRepoI)(nil).Add), role)
+}
+
+// Delete mocks base method.
+func (m *MockRoleRepoI) Delete(role *Role) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", role)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRoleRepoIMockRecorder) Delete(role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRoleRepoI)(nil).Delete), role)
+}
+
+// GetByUserId mocks base method.
+func (m *MockRoleRepoI) GetByUserId(userID string) ([]*Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID)
+	ret0, _ := ret[0].([]*Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockRoleRepoIMockRecorder) GetByUserId(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockRoleRepoI)(nil).GetByUserId), userID)
+}
+
+// MockBanRepoI is a mock of BanRepoI interface.
+type MockBanRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockBanRepoIMockRecorder
+}
+
+// MockBanRepoIMockRecorder is the mock recorder for MockBanRepoI.
+type MockBanRepoIMockRecorder struct {
+	mock *MockBanRepoI
+}
+
+// NewMockBanRepoI creates a new mock instance.
+func NewMockBanRepoI(ctrl *gomock.Controller) *MockBanRepoI {
+	mock := &MockBanRepoI{ctrl: ctrl}
+	mock.recorder = &MockBanRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBanRepoI) EXPECT() *MockBanRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockBanRepoI) Add(ban *Ban) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ban)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockBanRepoIMockRecorder) Add(ban interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockBanRepoI)(nil).Add), ban)
+}
+
+// Delete mocks base method.
+func (m *MockBanRepoI) Delete(userID, kind string, categoryID uint32) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", userID, kind, categoryID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBanRepoIMockRecorder) Delete(userID, kind, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBanRepoI)(nil).Delete), userID, kind, categoryID)
+}
+
+// GetShadowbannedUserIds mocks base method.
+func (m *MockBanRepoI) GetShadowbannedUserIds() (map[string]struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShadowbannedUserIds")
+	ret0, _ := ret[0].(map[string]struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShadowbannedUserIds indicates an expected call of GetShadowbannedUserIds.
+func (mr *MockBanRepoIMockRecorder) GetShadowbannedUserIds() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShadowbannedUserIds", reflect.TypeOf((*MockBanRepoI)(nil).GetShadowbannedUserIds))
+}
+
+// IsBanned mocks base method.
+func (m *MockBanRepoI) IsBanned(userID string, categoryID uint32) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBanned", userID, categoryID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBanned indicates an expected call of IsBanned.
+func (mr *MockBanRepoIMockRecorder) IsBanned(userID, categoryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBanned", reflect.TypeOf((*MockBanRepoI)(nil).IsBanned), userID, categoryID)
+}
+
+// MockNotificationRepoI is a mock of NotificationRepoI interface.
+type MockNotificationRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRepoIMockRecorder
+}
+
+// MockNotificationRepoIMockRecorder is the mock recorder for MockNotificationRepoI.
+type MockNotificationRepoIMockRecorder struct {
+	mock *MockNotificationRepoI
+}
+
+// NewMockNotificationRepoI creates a new mock instance.
+func NewMockNotificationRepoI(ctrl *gomock.Controller) *MockNotificationRepoI {
+	mock := &MockNotificationRepoI{ctrl: ctrl}
+	mock.recorder = &MockNotificationRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationRepoI) EXPECT() *MockNotificationRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockNotificationRepoI) Add(notification *Notification) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", notification)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockNotificationRepoIMockRecorder) Add(notification interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockNotificationRepoI)(nil).Add), notification)
+}
+
+// CountUnread mocks base method.
+func (m *MockNotificationRepoI) CountUnread(userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnread", userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnread indicates an expected call of CountUnread.
+func (mr *MockNotificationRepoIMockRecorder) CountUnread(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnread", reflect.TypeOf((*MockNotificationRepoI)(nil).CountUnread), userID)
+}
+
+// GetByUserId mocks base method.
+func (m *MockNotificationRepoI) GetByUserId(userID string, unreadOnly bool, limit, offset int) ([]*NotificationComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", userID, unreadOnly, limit, offset)
+	ret0, _ := ret[0].([]*NotificationComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockNotificationRepoIMockRecorder) GetByUserId(userID, unreadOnly, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockNotificationRepoI)(nil).GetByUserId), userID, unreadOnly, limit, offset)
+}
+
+// GetMuted mocks base method.
+func (m *MockNotificationRepoI) GetMuted(userID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMuted", userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMuted indicates an expected call of GetMuted.
+func (mr *MockNotificationRepoIMockRecorder) GetMuted(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMuted", reflect.TypeOf((*MockNotificationRepoI)(nil).GetMuted), userID)
+}
+
+// MarkRead mocks base method.
+func (m *MockNotificationRepoI) MarkRead(userID string, ids []uint64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRead", userID, ids)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRead indicates an expected call of MarkRead.
+func (mr *MockNotificationRepoIMockRecorder) MarkRead(userID, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRead", reflect.TypeOf((*MockNotificationRepoI)(nil).MarkRead), userID, ids)
+}
+
+// SetMuted mocks base method.
+func (m *MockNotificationRepoI) SetMuted(userID string, types []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMuted", userID, types)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMuted indicates an expected call of SetMuted.
+func (mr *MockNotificationRepoIMockRecorder) SetMuted(userID, types interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMuted", reflect.TypeOf((*MockNotificationRepoI)(nil).SetMuted), userID, types)
+}
+
+// MockMessageRepoI is a mock of MessageRepoI interface.
+type MockMessageRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockMessageRepoIMockRecorder
+}
+
+// MockMessageRepoIMockRecorder is the mock recorder for MockMessageRepoI.
+type MockMessageRepoIMockRecorder struct {
+	mock *MockMessageRepoI
+}
+
+// NewMockMessageRepoI creates a new mock instance.
+func NewMockMessageRepoI(ctrl *gomock.Controller) *MockMessageRepoI {
+	mock := &MockMessageRepoI{ctrl: ctrl}
+	mock.recorder = &MockMessageRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMessageRepoI) EXPECT() *MockMessageRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockMessageRepoI) Add(message *Message) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", message)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockMessageRepoIMockRecorder) Add(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockMessageRepoI)(nil).Add), message)
+}
+
+// CountSentSince mocks base method.
+func (m *MockMessageRepoI) CountSentSince(senderID, since string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSentSince", senderID, since)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSentSince indicates an expected call of CountSentSince.
+func (mr *MockMessageRepoIMockRecorder) CountSentSince(senderID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSentSince", reflect.TypeOf((*MockMessageRepoI)(nil).CountSentSince), senderID, since)
+}
+
+// CountUnread mocks base method.
+func (m *MockMessageRepoI) CountUnread(userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnread", userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnread indicates an expected call of CountUnread.
+func (mr *MockMessageRepoIMockRecorder) CountUnread(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnread", reflect.TypeOf((*MockMessageRepoI)(nil).CountUnread), userID)
+}
+
+// GetConversations mocks base method.
+func (m *MockMessageRepoI) GetConversations(userID string, limit, offset int) ([]*Conversation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConversations", userID, limit, offset)
+	ret0, _ := ret[0].([]*Conversation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConversations indicates an expected call of GetConversations.
+func (mr *MockMessageRepoIMockRecorder) GetConversations(userID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConversations", reflect.TypeOf((*MockMessageRepoI)(nil).GetConversations), userID, limit, offset)
+}
+
+// GetThread mocks base method.
+func (m *MockMessageRepoI) GetThread(userID, otherID string, limit, offset int) ([]*Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetThread", userID, otherID, limit, offset)
+	ret0, _ := ret[0].([]*Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetThread indicates an expected call of GetThread.
+func (mr *MockMessageRepoIMockRecorder) GetThread(userID, otherID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetThread", reflect.TypeOf((*MockMessageRepoI)(nil).GetThread), userID, otherID, limit, offset)
+}
+
+// MarkThreadRead mocks base method.
+func (m *MockMessageRepoI) MarkThreadRead(userID, otherID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkThreadRead", userID, otherID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkThreadRead indicates an expected call of MarkThreadRead.
+func (mr *MockMessageRepoIMockRecorder) MarkThreadRead(userID, otherID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkThreadRead", reflect.TypeOf((*MockMessageRepoI)(nil).MarkThreadRead), userID, otherID)
+}
+
+// MockBlockRepoI is a mock of BlockRepoI interface.
+type MockBlockRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlockRepoIMockRecorder
+}
+
+// MockBlockRepoIMockRecorder is the mock recorder for MockBlockRepoI.
+type MockBlockRepoIMockRecorder struct {
+	mock *MockBlockRepoI
+}
+
+// NewMockBlockRepoI creates a new mock instance.
+func NewMockBlockRepoI(ctrl *gomock.Controller) *MockBlockRepoI {
+	mock := &MockBlockRepoI{ctrl: ctrl}
+	mock.recorder = &MockBlockRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlockRepoI) EXPECT() *MockBlockRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockBlockRepoI) Add(blockerID, blockedID, created string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", blockerID, blockedID, created)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockBlockRepoIMockRecorder) Add(blockerID, blockedID, created interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockBlockRepoI)(nil).Add), blockerID, blockedID, created)
+}
+
+// Delete mocks base method.
+func (m *MockBlockRepoI) Delete(blockerID, blockedID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", blockerID, blockedID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBlockRepoIMockRecorder) Delete(blockerID, blockedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBlockRepoI)(nil).Delete), blockerID, blockedID)
+}
+
+// GetBlocked mocks base method.
+func (m *MockBlockRepoI) GetBlocked(blockerID string, limit, offset int) ([]*BlockComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlocked", blockerID, limit, offset)
+	ret0, _ := ret[0].([]*BlockComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlocked indicates an expected call of GetBlocked.
+func (mr *MockBlockRepoIMockRecorder) GetBlocked(blockerID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocked", reflect.TypeOf((*MockBlockRepoI)(nil).GetBlocked), blockerID, limit, offset)
+}
+
+// GetBlockedIds mocks base method.
+func (m *MockBlockRepoI) GetBlockedIds(blockerID string) (map[string]struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockedIds", blockerID)
+	ret0, _ := ret[0].(map[string]struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockedIds indicates an expected call of GetBlockedIds.
+func (mr *MockBlockRepoIMockRecorder) GetBlockedIds(blockerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockedIds", reflect.TypeOf((*MockBlockRepoI)(nil).GetBlockedIds), blockerID)
+}
+
+// IsBlocked mocks base method.
+func (m *MockBlockRepoI) IsBlocked(userID, otherID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBlocked", userID, otherID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBlocked indicates an expected call of IsBlocked.
+func (mr *MockBlockRepoIMockRecorder) IsBlocked(userID, otherID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBlocked", reflect.TypeOf((*MockBlockRepoI)(nil).IsBlocked), userID, otherID)
+}
+
+// MockFollowRepoI is a mock of FollowRepoI interface.
+type MockFollowRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockFollowRepoIMockRecorder
+}
+
+// MockFollowRepoIMockRecorder is the mock recorder for MockFollowRepoI.
+type MockFollowRepoIMockRecorder struct {
+	mock *MockFollowRepoI
+}
+
+// NewMockFollowRepoI creates a new mock instance.
+func NewMockFollowRepoI(ctrl *gomock.Controller) *MockFollowRepoI {
+	mock := &MockFollowRepoI{ctrl: ctrl}
+	mock.recorder = &MockFollowRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFollowRepoI) EXPECT() *MockFollowRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockFollowRepoI) Add(follow *Follow) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", follow)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockFollowRepoIMockRecorder) Add(follow interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockFollowRepoI)(nil).Add), follow)
+}
+
+// Count mocks base method.
+func (m *MockFollowRepoI) Count(userID string) (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockFollowRepoIMockRecorder) Count(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockFollowRepoI)(nil).Count), userID)
+}
+
+// Delete mocks base method.
+func (m *MockFollowRepoI) Delete(followerID, followedID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", followerID, followedID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFollowRepoIMockRecorder) Delete(followerID, followedID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFollowRepoI)(nil).Delete), followerID, followedID)
+}
+
+// GetFollowers mocks base method.
+func (m *MockFollowRepoI) GetFollowers(userID string, limit, offset int) ([]*FollowComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", userID, limit, offset)
+	ret0, _ := ret[0].([]*FollowComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockFollowRepoIMockRecorder) GetFollowers(userID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockFollowRepoI)(nil).GetFollowers), userID, limit, offset)
+}
+
+// GetFollowing mocks base method.
+func (m *MockFollowRepoI) GetFollowing(userID string, limit, offset int) ([]*FollowComplexData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", userID, limit, offset)
+	ret0, _ := ret[0].([]*FollowComplexData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockFollowRepoIMockRecorder) GetFollowing(userID, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockFollowRepoI)(nil).GetFollowing), userID, limit, offset)
+}
+
+// MockLoginThrottleI is a mock of LoginThrottleI interface.
+type MockLoginThrottleI struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginThrottleIMockRecorder
+}
+
+// MockLoginThrottleIMockRecorder is the mock recorder for MockLoginThrottleI.
+type MockLoginThrottleIMockRecorder struct {
+	mock *MockLoginThrottleI
+}
+
+// NewMockLoginThrottleI creates a new mock instance.
+func NewMockLoginThrottleI(ctrl *gomock.Controller) *MockLoginThrottleI {
+	mock := &MockLoginThrottleI{ctrl: ctrl}
+	mock.recorder = &MockLoginThrottleIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginThrottleI) EXPECT() *MockLoginThrottleIMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLoginThrottleI) Check(login, ip string, now time.Time) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", login, ip, now)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLoginThrottleIMockRecorder) Check(login, ip, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLoginThrottleI)(nil).Check), login, ip, now)
+}
+
+// Failed mocks base method.
+func (m *MockLoginThrottleI) Failed(login, ip string, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Failed", login, ip, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Failed indicates an expected call of Failed.
+func (mr *MockLoginThrottleIMockRecorder) Failed(login, ip, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Failed", reflect.TypeOf((*MockLoginThrottleI)(nil).Failed), login, ip, now)
+}
+
+// Succeeded mocks base method.
+func (m *MockLoginThrottleI) Succeeded(login string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Succeeded", login)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Succeeded indicates an expected call of Succeeded.
+func (mr *MockLoginThrottleIMockRecorder) Succeeded(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeeded", reflect.TypeOf((*MockLoginThrottleI)(nil).Succeeded), login)
+}
+
+// MockAuditLogRepoI is a mock of AuditLogRepoI interface.
+type MockAuditLogRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogRepoIMockRecorder
+}
+
+// MockAuditLogRepoIMockRecorder is the mock recorder for MockAuditLogRepoI.
+type MockAuditLogRepoIMockRecorder struct {
+	mock *MockAuditLogRepoI
+}
+
+// NewMockAuditLogRepoI creates a new mock instance.
+func NewMockAuditLogRepoI(ctrl *gomock.Controller) *MockAuditLogRepoI {
+	mock := &MockAuditLogRepoI{ctrl: ctrl}
+	mock.recorder = &MockAuditLogRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLogRepoI) EXPECT() *MockAuditLogRepoIMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockAuditLogRepoI) Add(entry *AuditLogEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockAuditLogRepoIMockRecorder) Add(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockAuditLogRepoI)(nil).Add), entry)
+}
+
+// MockMailSenderI is a mock of MailSenderI interface.
+type MockMailSenderI struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailSenderIMockRecorder
+}
+
+// MockMailSenderIMockRecorder is the mock recorder for MockMailSenderI.
+type MockMailSenderIMockRecorder struct {
+	mock *MockMailSenderI
+}
+
+// NewMockMailSenderI creates a new mock instance.
+func NewMockMailSenderI(ctrl *gomock.Controller) *MockMailSenderI {
+	mock := &MockMailSenderI{ctrl: ctrl}
+	mock.recorder = &MockMailSenderIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailSenderI) EXPECT() *MockMailSenderIMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockMailSenderI) Send(mail *Mail) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", mail)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockMailSenderIMockRecorder) Send(mail interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockMailSenderI)(nil).Send), mail)
+}
+
 // MockUserUtilsI is a mock of UserUtilsI interface.
 type MockUserUtilsI struct {
 	ctrl     *gomock.Controller
@@ -226,3 +1275,17 @@ func (mr *MockUserUtilsIMockRecorder) GeneratePasswordHash(password interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePasswordHash", reflect.TypeOf((*MockUserUtilsI)(nil).GeneratePasswordHash), password)
 }
+
+// NeedsRehash mocks base method.
+func (m *MockUserUtilsI) NeedsRehash(hash string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NeedsRehash", hash)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NeedsRehash indicates an expected call of NeedsRehash.
+func (mr *MockUserUtilsIMockRecorder) NeedsRehash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NeedsRehash", reflect.TypeOf((*MockUserUtilsI)(nil).NeedsRehash), hash)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"log"
@@ -9,6 +11,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
@@ -86,11 +89,14 @@ func TestGetPosts(t *testing.T) {
 	postsRepoMock := NewMockPostRepoI(ctrl)
 	sessionManagerMock := NewMockSessionManagerI(ctrl)
 	dtoConverterMock := NewMockDTOConverterI(ctrl)
+	banRepoMock := NewMockBanRepoI(ctrl)
 	service := &UserHandler{
 		PostsRepo:      postsRepoMock,
 		SessionManager: sessionManagerMock,
 		DTOConverter:   dtoConverterMock,
+		BanRepo:        banRepoMock,
 	}
+	banRepoMock.EXPECT().GetShadowbannedUserIds().Return(map[string]struct{}{}, nil).AnyTimes()
 	login := "test"
 	urlVars := map[string]string{
 		"USER_LOGIN": login,
@@ -98,7 +104,7 @@ func TestGetPosts(t *testing.T) {
 
 	//success
 	postsRepoMock.EXPECT().GetByUserLogin(login).Return(multipleComplexDataByUserLogin, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexDataByUserLogin).Return(postsDTOByUserLogin, nil)
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexDataByUserLogin, gomock.Any()).Return(postsDTOByUserLogin, nil)
 	req := httptest.NewRequest("GET", "/api/user/test", nil)
 	req = mux.SetURLVars(req, urlVars)
 	w := httptest.NewRecorder()
@@ -126,7 +132,7 @@ func TestGetPosts(t *testing.T) {
 
 	//converter error
 	postsRepoMock.EXPECT().GetByUserLogin(login).Return(multipleComplexDataByUserLogin, nil)
-	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexDataByUserLogin).Return(nil, fmt.Errorf("converter error"))
+	dtoConverterMock.EXPECT().PostsConvertToDTO(multipleComplexDataByUserLogin, gomock.Any()).Return(nil, fmt.Errorf("converter error"))
 	req = httptest.NewRequest("GET", "/api/user/test", nil)
 	req = mux.SetURLVars(req, urlVars)
 	w = httptest.NewRecorder()
@@ -150,6 +156,8 @@ func TestLogin(t *testing.T) {
 	timerGetterMock := NewMockTimeGetterI(ctrl)
 	uuidGetterMock := NewMockUUIDGetterI(ctrl)
 	userUtilsMock := NewMockUserUtilsI(ctrl)
+	twoFactorRepoMock := NewMockTwoFactorRepoI(ctrl)
+	loginThrottleMock := NewMockLoginThrottleI(ctrl)
 	service := &UserHandler{
 		UserRepo:       userRepoMock,
 		SessionManager: sessionManagerMock,
@@ -157,15 +165,25 @@ func TestLogin(t *testing.T) {
 		UUIDGetter:     uuidGetterMock,
 		TimeGetter:     timerGetterMock,
 		UserUtils:      userUtilsMock,
+		TwoFactorRepo:  twoFactorRepoMock,
+		LoginThrottle:  loginThrottleMock,
 	}
+	now := time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)
+	ip := "192.0.2.1"
+	timerGetterMock.EXPECT().Now().Return(now).AnyTimes()
+	loginThrottleMock.EXPECT().Check(loginDTO.UserName, ip, now).Return(time.Duration(0), nil).AnyTimes()
+	userUtilsMock.EXPECT().NeedsRehash(user.Password).Return(false).AnyTimes()
 
 	//sucess
 	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(user, nil)
 	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(true)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(nil, sql.ErrNoRows)
 	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
 	w := httptest.NewRecorder()
 	sessionManagerMock.EXPECT().Create(w, user).Return(sessUser, nil)
 	userUtilsMock.EXPECT().GenerateJWT(user, sessUser.ID).Return(token, nil)
+	loginThrottleMock.EXPECT().Succeeded(loginDTO.UserName).Return(nil)
 	service.Login(w, req)
 	resp := w.Result()
 	body, _ := io.ReadAll(resp.Body)
@@ -178,6 +196,7 @@ func TestLogin(t *testing.T) {
 	//query error
 	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(nil, fmt.Errorf("db error"))
 	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
 	w = httptest.NewRecorder()
 	service.Login(w, req)
 	resp = w.Result()
@@ -189,19 +208,40 @@ func TestLogin(t *testing.T) {
 	//check password error
 	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(user, nil)
 	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(false)
+	loginThrottleMock.EXPECT().Failed(loginDTO.UserName, ip, now).Return(nil)
 	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
 	w = httptest.NewRecorder()
 	service.Login(w, req)
 	resp = w.Result()
+	body, _ = io.ReadAll(resp.Body)
+	wrongPasswordBody := string(body)
 	if resp.StatusCode != http.StatusUnauthorized {
 		t.Errorf("expected 401 status code; got: %d", resp.StatusCode)
 		return
 	}
 
+	//unknown login, the answer is the same as for a wrong password
+	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(nil, sql.ErrNoRows)
+	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, "").Return(false)
+	loginThrottleMock.EXPECT().Failed(loginDTO.UserName, ip, now).Return(nil)
+	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
+	w = httptest.NewRecorder()
+	service.Login(w, req)
+	resp = w.Result()
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusUnauthorized || string(body) != wrongPasswordBody {
+		t.Errorf("expected generic 401 error; got: %d %s", resp.StatusCode, body)
+		return
+	}
+
 	//sess create error
 	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(user, nil)
 	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(true)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(nil, sql.ErrNoRows)
 	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
 	w = httptest.NewRecorder()
 	sessionManagerMock.EXPECT().Create(w, user).Return(nil, fmt.Errorf("sess create errror"))
 	service.Login(w, req)
@@ -214,16 +254,106 @@ func TestLogin(t *testing.T) {
 	//jwt generate error
 	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(user, nil)
 	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(true)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(nil, sql.ErrNoRows)
 	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
 	w = httptest.NewRecorder()
 	sessionManagerMock.EXPECT().Create(w, user).Return(sessUser, nil)
 	userUtilsMock.EXPECT().GenerateJWT(user, sessUser.ID).Return("", fmt.Errorf("jwt generate error"))
+	loginThrottleMock.EXPECT().Succeeded(loginDTO.UserName).Return(nil)
 	service.Login(w, req)
 	resp = w.Result()
 	if resp.StatusCode != http.StatusInternalServerError {
 		t.Errorf("expected 500 status code; got: %d", resp.StatusCode)
 		return
 	}
+
+	//two factor enabled, the failures stay until the code is checked
+	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(user, nil)
+	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(true)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(&UserTOTP{UserID: user.ID, Enabled: true}, nil)
+	twoFactorRepoMock.EXPECT().CreateChallenge(gomock.Any()).DoAndReturn(func(c *LoginChallenge) error {
+		if c.UserID != user.ID || c.Expires != "2022-11-09T19:56:42Z" {
+			t.Errorf("unexpected challenge: %#v", c)
+		}
+		return nil
+	})
+	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
+	w = httptest.NewRecorder()
+	service.Login(w, req)
+	resp = w.Result()
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"two_factor_required":true`) {
+		t.Errorf("expected two factor challenge; got: %d %s", resp.StatusCode, body)
+		return
+	}
+
+	//locked out
+	lockedThrottleMock := NewMockLoginThrottleI(ctrl)
+	service.LoginThrottle = lockedThrottleMock
+	lockedThrottleMock.EXPECT().Check(loginDTO.UserName, ip, now).Return(90*time.Second, nil)
+	req = httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	req.RemoteAddr = ip + ":1234"
+	w = httptest.NewRecorder()
+	service.Login(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "90" {
+		t.Errorf("expected 429 status code with retry after; got: %d %s", resp.StatusCode, resp.Header.Get("Retry-After"))
+		return
+	}
+}
+
+func TestLoginRehash(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	sessionManagerMock := NewMockSessionManagerI(ctrl)
+	timerGetterMock := NewMockTimeGetterI(ctrl)
+	userUtilsMock := NewMockUserUtilsI(ctrl)
+	twoFactorRepoMock := NewMockTwoFactorRepoI(ctrl)
+	loginThrottleMock := NewMockLoginThrottleI(ctrl)
+	service := &UserHandler{
+		UserRepo:       userRepoMock,
+		SessionManager: sessionManagerMock,
+		TimeGetter:     timerGetterMock,
+		UserUtils:      userUtilsMock,
+		TwoFactorRepo:  twoFactorRepoMock,
+		LoginThrottle:  loginThrottleMock,
+	}
+	storedUser := &User{
+		ID:       user.ID,
+		Login:    user.Login,
+		Password: user.Password,
+	}
+	newHash := "$2a$10$JW9COT4Lbor8tt.hUABkrueH8bSlEju3FL/g1RruLD5CvjXoFKx1a"
+	rehashedUser := &User{
+		ID:       user.ID,
+		Login:    user.Login,
+		Password: newHash,
+	}
+
+	timerGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)).AnyTimes()
+	loginThrottleMock.EXPECT().Check(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+	loginThrottleMock.EXPECT().Succeeded(loginDTO.UserName).Return(nil)
+	userRepoMock.EXPECT().GetByLogin(loginDTO.UserName).Return(storedUser, nil)
+	userUtilsMock.EXPECT().CheckPasswordHash(loginDTO.Password, user.Password).Return(true)
+	userUtilsMock.EXPECT().NeedsRehash(user.Password).Return(true)
+	userUtilsMock.EXPECT().GeneratePasswordHash(loginDTO.Password).Return(newHash, nil)
+	userRepoMock.EXPECT().UpdatePassword(user.ID, newHash).Return(true, nil)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(nil, sql.ErrNoRows)
+	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(reqLogin))
+	w := httptest.NewRecorder()
+	sessionManagerMock.EXPECT().Create(w, rehashedUser).Return(sessUser, nil)
+	userUtilsMock.EXPECT().GenerateJWT(rehashedUser, sessUser.ID).Return(token, nil)
+	service.Login(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", resp.StatusCode)
+		return
+	}
 }
 
 func TestRegister(t *testing.T) {
@@ -337,4 +467,262 @@ func TestRegister(t *testing.T) {
 		t.Errorf("expected 500 statuscode; got: %d", resp.StatusCode)
 		return
 	}
+
+	//reserved login
+	req = httptest.NewRequest("POST", "/api/register", strings.NewReader(`{"username":"Me","password":"testtest"}`))
+	w = httptest.NewRecorder()
+	service.Register(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 statuscode; got: %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestRegisterWithEmail(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	sessionManagerMock := NewMockSessionManagerI(ctrl)
+	verificationRepoMock := NewMockEmailVerificationRepoI(ctrl)
+	timerGetterMock := NewMockTimeGetterI(ctrl)
+	uuidGetterMock := NewMockUUIDGetterI(ctrl)
+	userUtilsMock := NewMockUserUtilsI(ctrl)
+	mailSender := &LocalMailSender{}
+	service := &UserHandler{
+		UserRepo:              userRepoMock,
+		SessionManager:        sessionManagerMock,
+		EmailVerificationRepo: verificationRepoMock,
+		MailSender:            mailSender,
+		UUIDGetter:            uuidGetterMock,
+		TimeGetter:            timerGetterMock,
+		UserUtils:             userUtilsMock,
+	}
+	userWithEmail := &User{
+		ID:       user.ID,
+		Login:    user.Login,
+		Password: user.Password,
+		Email:    "mer@example.com",
+		Created:  user.Created,
+	}
+	reqRegister := `{"username":"mer","password":"testtest","email":"mer@example.com"}`
+
+	//success
+	userUtilsMock.EXPECT().GeneratePasswordHash(loginDTO.Password).Return(user.Password, nil)
+	uuidGetterMock.EXPECT().GetUUID().Return(user.ID)
+	timerGetterMock.EXPECT().GetCreated().Return(user.Created)
+	timerGetterMock.EXPECT().Now().Return(time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC))
+	userRepoMock.EXPECT().Create(userWithEmail).Return(&user.ID, nil)
+	userRepoMock.EXPECT().GetById(user.ID).Return(userWithEmail, nil)
+	verificationRepoMock.EXPECT().DeleteByUserId(user.ID).Return(nil)
+	verificationRepoMock.EXPECT().Create(gomock.Any()).DoAndReturn(func(v *EmailVerification) error {
+		if v.UserID != user.ID || v.Email != userWithEmail.Email || v.Expires != "2022-11-10T19:51:42Z" {
+			t.Errorf("unexpected verification: %#v", v)
+		}
+		return nil
+	})
+	req := httptest.NewRequest("POST", "/api/register", strings.NewReader(reqRegister))
+	w := httptest.NewRecorder()
+	sessionManagerMock.EXPECT().Create(w, userWithEmail).Return(sessUser, nil)
+	userUtilsMock.EXPECT().GenerateJWT(userWithEmail, sessUser.ID).Return(token, nil)
+	service.Register(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201 status code; got: %d", resp.StatusCode)
+		return
+	}
+	sent := mailSender.Last()
+	if sent == nil || sent.To != userWithEmail.Email {
+		t.Errorf("expected verification mail to %s; got: %#v", userWithEmail.Email, sent)
+		return
+	}
+
+	//invalid email
+	req = httptest.NewRequest("POST", "/api/register", strings.NewReader(`{"username":"mer","password":"testtest","email":"mer"}`))
+	w = httptest.NewRecorder()
+	service.Register(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 status code; got: %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	verificationRepoMock := NewMockEmailVerificationRepoI(ctrl)
+	timerGetterMock := NewMockTimeGetterI(ctrl)
+	service := &UserHandler{
+		UserRepo:              userRepoMock,
+		EmailVerificationRepo: verificationRepoMock,
+		TimeGetter:            timerGetterMock,
+	}
+	verification := &EmailVerification{
+		Token:   "abc",
+		UserID:  user.ID,
+		Email:   "mer@example.com",
+		Expires: "2022-11-10T19:51:42Z",
+	}
+	urlVars := map[string]string{
+		"TOKEN": verification.Token,
+	}
+	now := time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)
+
+	//success
+	verificationRepoMock.EXPECT().GetByToken(verification.Token).Return(verification, nil)
+	timerGetterMock.EXPECT().Now().Return(now)
+	userRepoMock.EXPECT().SetVerified(user.ID, verification.Email).Return(true, nil)
+	verificationRepoMock.EXPECT().DeleteByUserId(user.ID).Return(nil)
+	req := httptest.NewRequest("GET", "/api/verify/abc", nil)
+	req = mux.SetURLVars(req, urlVars)
+	w := httptest.NewRecorder()
+	service.VerifyEmail(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//unknown token
+	verificationRepoMock.EXPECT().GetByToken(verification.Token).Return(nil, fmt.Errorf("no rows"))
+	req = httptest.NewRequest("GET", "/api/verify/abc", nil)
+	req = mux.SetURLVars(req, urlVars)
+	w = httptest.NewRecorder()
+	service.VerifyEmail(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//expired token
+	verificationRepoMock.EXPECT().GetByToken(verification.Token).Return(verification, nil)
+	timerGetterMock.EXPECT().Now().Return(now.Add(48 * time.Hour))
+	req = httptest.NewRequest("GET", "/api/verify/abc", nil)
+	req = mux.SetURLVars(req, urlVars)
+	w = httptest.NewRecorder()
+	service.VerifyEmail(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 status code; got: %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	verificationRepoMock := NewMockEmailVerificationRepoI(ctrl)
+	timerGetterMock := NewMockTimeGetterI(ctrl)
+	mailSender := &LocalMailSender{}
+	service := &UserHandler{
+		UserRepo:              userRepoMock,
+		EmailVerificationRepo: verificationRepoMock,
+		MailSender:            mailSender,
+		TimeGetter:            timerGetterMock,
+	}
+	now := time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)
+	reqBody := `{"email":"new@example.com"}`
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil).AnyTimes()
+
+	//success, the older tokens are dropped
+	verificationRepoMock.EXPECT().GetByUserId(user.ID).Return(nil, sql.ErrNoRows)
+	timerGetterMock.EXPECT().Now().Return(now)
+	gomock.InOrder(
+		verificationRepoMock.EXPECT().DeleteByUserId(user.ID).Return(nil),
+		verificationRepoMock.EXPECT().Create(gomock.Any()).Return(nil),
+	)
+	req := httptest.NewRequest("POST", "/api/verify", strings.NewReader(reqBody))
+	ctx := context.WithValue(req.Context(), sessionKey, sessUser)
+	w := httptest.NewRecorder()
+	service.ResendVerification(w, req.WithContext(ctx))
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", resp.StatusCode)
+		return
+	}
+	if sent := mailSender.Last(); sent == nil || sent.To != "new@example.com" {
+		t.Errorf("expected verification mail to new@example.com; got: %#v", sent)
+		return
+	}
+
+	//sent recently
+	verificationRepoMock.EXPECT().GetByUserId(user.ID).Return(&EmailVerification{
+		Token:   "abc",
+		UserID:  user.ID,
+		Email:   "new@example.com",
+		Expires: now.Add(EmailVerificationTTL).Format(time.RFC3339),
+	}, nil)
+	timerGetterMock.EXPECT().Now().Return(now.Add(time.Minute))
+	req = httptest.NewRequest("POST", "/api/verify", strings.NewReader(`{"email":"other@example.com"}`))
+	ctx = context.WithValue(req.Context(), sessionKey, sessUser)
+	w = httptest.NewRecorder()
+	service.ResendVerification(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 status code; got: %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestGrantRole(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	roleRepoMock := NewMockRoleRepoI(ctrl)
+	dictionaryRepoMock := NewMockDictionaryRepoI(ctrl)
+	service := &UserHandler{
+		UserRepo:       userRepoMock,
+		RoleRepo:       roleRepoMock,
+		DictionaryRepo: dictionaryRepoMock,
+	}
+	admin := &Session{ID: "1", UserID: "admin", Roles: []*Role{{Name: RoleAdmin}}}
+	reqBody := `{"username":"mer","role":"moderator","category":"fashion"}`
+
+	//success
+	dictionaryRepoMock.EXPECT().GetCategoryByName("fashion").Return(&Category{ID: 6, Name: "fashion"}, nil)
+	userRepoMock.EXPECT().GetByLogin("mer").Return(user, nil)
+	roleRepoMock.EXPECT().Add(&Role{UserID: user.ID, Name: RoleModerator, CategoryID: 6}).Return(nil)
+	req := httptest.NewRequest("POST", "/api/roles", strings.NewReader(reqBody))
+	ctx := context.WithValue(req.Context(), sessionKey, admin)
+	w := httptest.NewRecorder()
+	service.GrantRole(w, req.WithContext(ctx))
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//not an admin
+	req = httptest.NewRequest("POST", "/api/roles", strings.NewReader(reqBody))
+	ctx = context.WithValue(req.Context(), sessionKey, sessUser)
+	w = httptest.NewRecorder()
+	service.GrantRole(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//unknown role
+	req = httptest.NewRequest("POST", "/api/roles", strings.NewReader(`{"username":"mer","role":"owner"}`))
+	ctx = context.WithValue(req.Context(), sessionKey, admin)
+	w = httptest.NewRecorder()
+	service.GrantRole(w, req.WithContext(ctx))
+	resp = w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 status code; got: %d", resp.StatusCode)
+		return
+	}
 }
@@ -19,8 +19,8 @@ func (repo *UserRepo) GetById(id string) (*User, error) {
 	fmt.Println("Get user by id")
 	user := &User{}
 	err := repo.DB.
-		QueryRow("SELECT id, login, password FROM user WHERE id = ?", id).
-		Scan(&user.ID, &user.Login, &user.Password)
+		QueryRow("SELECT id, login, password, email, verified, COALESCE(created, '') FROM user WHERE id = ?", id).
+		Scan(&user.ID, &user.Login, &user.Password, &user.Email, &user.Verified, &user.Created)
 	if nil != err {
 		return nil, err
 	}
@@ -31,8 +31,8 @@ func (repo *UserRepo) GetByLogin(login string) (*User, error) {
 	fmt.Println("Get user by login")
 	user := &User{}
 	err := repo.DB.
-		QueryRow("SELECT id, login, password FROM user WHERE login = ?", login).
-		Scan(&user.ID, &user.Login, &user.Password)
+		QueryRow("SELECT id, login, password, email, verified, COALESCE(created, '') FROM user WHERE login = ?", login).
+		Scan(&user.ID, &user.Login, &user.Password, &user.Email, &user.Verified, &user.Created)
 	if nil != err {
 		return nil, err
 	}
@@ -42,10 +42,12 @@ func (repo *UserRepo) GetByLogin(login string) (*User, error) {
 func (repo *UserRepo) Create(user *User) (*string, error) {
 	fmt.Println("Create new user")
 	_, err := repo.DB.Exec(
-		"INSERT INTO user (id, login, password, created) VALUES(?, ?, ?, ?)",
+		"INSERT INTO user (id, login, password, email, verified, created) VALUES(?, ?, ?, ?, ?, ?)",
 		user.ID,
 		user.Login,
 		user.Password,
+		user.Email,
+		user.Verified,
 		user.Created,
 	)
 
@@ -56,3 +58,90 @@ func (repo *UserRepo) Create(user *User) (*string, error) {
 	fmt.Println("new id", user.ID)
 	return &user.ID, nil
 }
+
+func (repo *UserRepo) SetVerified(id string, email string) (bool, error) {
+	fmt.Println("Set user verified")
+	result, err := repo.DB.Exec(
+		"UPDATE user SET email = ?, verified = 1 WHERE id = ?",
+		email,
+		id,
+	)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	if affected != 1 {
+		return false, fmt.Errorf("wrong affected rows: %d for user id: %s", affected, id)
+	}
+	return true, nil
+}
+
+func (repo *UserRepo) UpdatePassword(id string, password string) (bool, error) {
+	fmt.Println("Update user password")
+	result, err := repo.DB.Exec("UPDATE user SET password = ? WHERE id = ?", password, id)
+	if nil != err {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return false, err
+	}
+	if affected != 1 {
+		return false, fmt.Errorf("wrong affected rows: %d for user id: %s", affected, id)
+	}
+	return true, nil
+}
+
+// GetKarma is the post and the comment karma the votes have left
+func (repo *UserRepo) GetKarma(id string) (int64, error) {
+	fmt.Println("Get user karma")
+	var karma int64
+	err := repo.DB.
+		QueryRow("SELECT post_karma + comment_karma FROM user WHERE id = ?", id).
+		Scan(&karma)
+	if nil != err {
+		return 0, err
+	}
+	return karma, nil
+}
+
+func (repo *UserRepo) GetProfileByLogin(login string) (*UserProfile, error) {
+	fmt.Println("Get user profile by login")
+	profile := &UserProfile{}
+	err := repo.DB.
+		QueryRow("SELECT id, login, COALESCE(created, ''), bio, post_karma, comment_karma FROM user WHERE login = ?", login).
+		Scan(&profile.ID, &profile.Login, &profile.Created, &profile.Bio, &profile.PostKarma, &profile.CommentKarma)
+	if nil != err {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (repo *UserRepo) SetBio(id string, bio string) error {
+	fmt.Println("Set user bio")
+	_, err := repo.DB.Exec("UPDATE user SET bio = ? WHERE id = ?", bio, id)
+	return err
+}
+
+func (repo *UserRepo) GetPasswordHashes() (map[string]string, error) {
+	fmt.Println("Get user password hashes")
+	// system accounts like the automoderator have no password
+	rows, err := repo.DB.Query("SELECT login, password FROM user WHERE password <> ''")
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	hashes := map[string]string{}
+	for rows.Next() {
+		var login, password string
+		err := rows.Scan(&login, &password)
+		if nil != err {
+			return nil, err
+		}
+		hashes[login] = password
+	}
+	return hashes, rows.Err()
+}
@@ -21,14 +21,17 @@ func TestUserGetByID(t *testing.T) {
 		ID:       "522cd619-841f-43d5-866d-f880e5f48d18",
 		Login:    "mer",
 		Password: "test",
+		Email:    "mer@example.com",
+		Verified: true,
+		Created:  "2022-11-02T15:24:00Z",
 	}
 
 	// success
 	rows := sqlmock.NewRows([]string{
-		"id", "login", "password",
-	}).AddRow(userExpected.ID, userExpected.Login, userExpected.Password)
+		"id", "login", "password", "email", "verified", "created",
+	}).AddRow(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Email, userExpected.Verified, userExpected.Created)
 
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE id = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE id = `).
 		WithArgs(userExpected.ID).
 		WillReturnRows(rows)
 	user, err := userRepo.GetById(userExpected.ID)
@@ -46,7 +49,7 @@ func TestUserGetByID(t *testing.T) {
 	}
 
 	//query error
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE id = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE id = `).
 		WithArgs(userExpected.ID).
 		WillReturnError(fmt.Errorf("db error"))
 	_, err = userRepo.GetById(userExpected.ID)
@@ -63,7 +66,7 @@ func TestUserGetByID(t *testing.T) {
 	rows = sqlmock.NewRows([]string{
 		"id", "login",
 	}).AddRow(userExpected.ID, userExpected.Login)
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE id = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE id = `).
 		WithArgs(userExpected.ID).
 		WillReturnRows(rows)
 
@@ -91,14 +94,17 @@ func TestUserGetByLogin(t *testing.T) {
 		ID:       "522cd619-841f-43d5-866d-f880e5f48d18",
 		Login:    "mer",
 		Password: "test",
+		Email:    "mer@example.com",
+		Verified: true,
+		Created:  "2022-11-02T15:24:00Z",
 	}
 
 	// success
 	rows := sqlmock.NewRows([]string{
-		"id", "login", "password",
-	}).AddRow(userExpected.ID, userExpected.Login, userExpected.Password)
+		"id", "login", "password", "email", "verified", "created",
+	}).AddRow(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Email, userExpected.Verified, userExpected.Created)
 
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE login = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE login = `).
 		WithArgs(userExpected.Login).
 		WillReturnRows(rows)
 	user, err := userRepo.GetByLogin(userExpected.Login)
@@ -116,7 +122,7 @@ func TestUserGetByLogin(t *testing.T) {
 	}
 
 	//query error
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE login = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE login = `).
 		WithArgs(userExpected.Login).
 		WillReturnError(fmt.Errorf("db error"))
 	_, err = userRepo.GetByLogin(userExpected.Login)
@@ -133,7 +139,7 @@ func TestUserGetByLogin(t *testing.T) {
 	rows = sqlmock.NewRows([]string{
 		"id", "login",
 	}).AddRow(userExpected.ID, userExpected.Login)
-	mock.ExpectQuery(`SELECT id, login, password FROM user WHERE login = `).
+	mock.ExpectQuery(`SELECT id, login, password, email, verified, COALESCE\(created, ''\) FROM user WHERE login = `).
 		WithArgs(userExpected.Login).
 		WillReturnRows(rows)
 
@@ -161,12 +167,13 @@ func TestUserCreate(t *testing.T) {
 		ID:       "522cd619-841f-43d5-866d-f880e5f48d18",
 		Login:    "mer",
 		Password: "test",
+		Email:    "mer@example.com",
 		Created:  "2022-11-09T19:51:42Z",
 	}
 
 	// success
 	mock.ExpectExec(`INSERT INTO user`).
-		WithArgs(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Created).
+		WithArgs(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Email, userExpected.Verified, userExpected.Created).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	lastID, err := userRepo.Create(userExpected)
 	if err != nil {
@@ -184,7 +191,7 @@ func TestUserCreate(t *testing.T) {
 
 	// query error
 	mock.ExpectExec(`INSERT INTO user`).
-		WithArgs(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Created).
+		WithArgs(userExpected.ID, userExpected.Login, userExpected.Password, userExpected.Email, userExpected.Verified, userExpected.Created).
 		WillReturnError(fmt.Errorf("db error"))
 	_, err = userRepo.Create(userExpected)
 	if err == nil {
@@ -196,3 +203,85 @@ func TestUserCreate(t *testing.T) {
 		return
 	}
 }
+
+func TestUserSetVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	userRepo := NewUserRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+	email := "mer@example.com"
+
+	// success
+	mock.ExpectExec(`UPDATE user SET email = \?, verified = 1 WHERE id = \?`).
+		WithArgs(email, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	isVerified, err := userRepo.SetVerified(userID, email)
+	if err != nil || !isVerified {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// no rows affected
+	mock.ExpectExec(`UPDATE user SET email`).
+		WithArgs(email, userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	_, err = userRepo.SetVerified(userID, email)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	// query error
+	mock.ExpectExec(`UPDATE user SET email`).
+		WithArgs(email, userID).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = userRepo.SetVerified(userID, email)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestUserUpdatePassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	userRepo := NewUserRepo(db)
+	userID := "522cd619-841f-43d5-866d-f880e5f48d18"
+	hash := "$2a$10$JW9COT4Lbor8tt.hUABkrueH8bSlEju3FL/g1RruLD5CvjXoFKx1a"
+
+	// success
+	mock.ExpectExec(`UPDATE user SET password = \?`).
+		WithArgs(hash, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	isUpdated, err := userRepo.UpdatePassword(userID, hash)
+	if err != nil || !isUpdated {
+		t.Errorf("not expected error %s", err)
+		return
+	}
+
+	// query error
+	mock.ExpectExec(`UPDATE user SET password = \?`).
+		WithArgs(hash, userID).
+		WillReturnError(fmt.Errorf("db error"))
+	_, err = userRepo.UpdatePassword(userID, hash)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	LoginChallengeTTL         = 5 * time.Minute
+	LoginChallengeMaxAttempts = 5
+)
+
+func (h *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	user, err := h.UserRepo.GetById(sess.UserID)
+	if nil != err {
+		fmt.Println("can't get user: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return
+	}
+	current, err := h.TwoFactorRepo.GetByUserId(user.ID)
+	if nil != err && err != sql.ErrNoRows {
+		fmt.Println("can't get two factor settings: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get two factor settings")
+		return
+	}
+	if nil == err && current.Enabled {
+		jsonError(w, http.StatusBadRequest, "two factor authentication is already enabled")
+		return
+	}
+	secret, err := GenerateTOTPSecret()
+	if nil != err {
+		fmt.Println("can't generate totp secret: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't generate totp secret")
+		return
+	}
+	err = h.TwoFactorRepo.Save(&UserTOTP{
+		UserID:  user.ID,
+		Secret:  secret,
+		Enabled: false,
+		Created: h.TimeGetter.GetCreated(),
+	})
+	if nil != err {
+		fmt.Println("can't save totp secret: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't save totp secret")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, &TwoFactorEnrollDTO{
+		Secret:     secret,
+		OTPAuthURI: TOTPAuthURI(user.Login, secret),
+	})
+}
+
+func (h *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	codeRequest, err := readTwoFactorCode(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	twoFactor, err := h.TwoFactorRepo.GetByUserId(sess.UserID)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusBadRequest, "two factor enrollment is not started")
+		return
+	} else if nil != err {
+		fmt.Println("can't get two factor settings: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get two factor settings")
+		return
+	}
+	if twoFactor.Enabled {
+		jsonError(w, http.StatusBadRequest, "two factor authentication is already enabled")
+		return
+	}
+	step, ok := ValidateTOTP(twoFactor.Secret, codeRequest.Code, h.TimeGetter.Now())
+	if !ok {
+		jsonError(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if nil != err {
+		fmt.Println("can't generate recovery codes: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't generate recovery codes")
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	err = h.TwoFactorRepo.SetRecoveryCodes(sess.UserID, hashes)
+	if nil != err {
+		fmt.Println("can't save recovery codes: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't save recovery codes")
+		return
+	}
+	err = h.TwoFactorRepo.Enable(sess.UserID)
+	if nil != err {
+		fmt.Println("can't enable two factor: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't enable two factor authentication")
+		return
+	}
+	_, err = h.TwoFactorRepo.UseStep(sess.UserID, step)
+	if nil != err {
+		fmt.Println("can't save used step: ", err.Error())
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, map[string][]string{
+		"recovery_codes": codes,
+	})
+}
+
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	sess, err := SessionFromContext(r.Context())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "can't receive session")
+		return
+	}
+	codeRequest, err := readTwoFactorCode(r)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	twoFactor, err := h.TwoFactorRepo.GetByUserId(sess.UserID)
+	if err == sql.ErrNoRows {
+		jsonError(w, http.StatusBadRequest, "two factor authentication is not enabled")
+		return
+	} else if nil != err {
+		fmt.Println("can't get two factor settings: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get two factor settings")
+		return
+	}
+	if twoFactor.Enabled {
+		ok, err := h.checkSecondFactor(twoFactor, codeRequest.Code)
+		if nil != err {
+			fmt.Println("can't check code: ", err.Error())
+			jsonError(w, http.StatusInternalServerError, "can't check code")
+			return
+		}
+		if !ok {
+			jsonError(w, http.StatusBadRequest, "invalid code")
+			return
+		}
+	}
+	err = h.TwoFactorRepo.Delete(sess.UserID)
+	if nil != err {
+		fmt.Println("can't disable two factor: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't disable two factor authentication")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "success"}`))
+}
+
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		jsonError(w, http.StatusInternalServerError, "can't read request body")
+		return
+	}
+	loginRequest := &TwoFactorLoginDTO{}
+	err = json.Unmarshal(body, loginRequest)
+	if nil != err {
+		jsonError(w, http.StatusBadRequest, "can't unpack payload")
+		return
+	}
+	challenge, err := h.TwoFactorRepo.GetChallenge(loginRequest.Challenge)
+	if nil != err {
+		fmt.Println("can't get login challenge: ", err.Error())
+		jsonError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	now := h.TimeGetter.Now()
+	expires, err := time.Parse(time.RFC3339, challenge.Expires)
+	if nil != err || now.After(expires) {
+		h.TwoFactorRepo.DeleteChallenge(challenge.Token)
+		jsonError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	userStored, err := h.UserRepo.GetById(challenge.UserID)
+	if nil != err {
+		fmt.Println("can't get user: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get user")
+		return
+	}
+	// the codes share the failures and the locks of the passwords, so a
+	// new challenge doesn't bring new guesses
+	ip := clientIP(r)
+	if h.loginThrottled(w, userStored.Login, ip, now) {
+		return
+	}
+	isUsed, err := h.TwoFactorRepo.UseChallengeAttempt(challenge.Token, LoginChallengeMaxAttempts)
+	if nil != err {
+		fmt.Println("can't update challenge: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't update challenge")
+		return
+	}
+	if !isUsed {
+		h.TwoFactorRepo.DeleteChallenge(challenge.Token)
+		jsonError(w, http.StatusUnauthorized, "invalid or expired challenge")
+		return
+	}
+	twoFactor, err := h.TwoFactorRepo.GetByUserId(challenge.UserID)
+	if nil != err {
+		fmt.Println("can't get two factor settings: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't get two factor settings")
+		return
+	}
+	ok, err := h.checkSecondFactor(twoFactor, loginRequest.Code)
+	if nil != err {
+		fmt.Println("can't check code: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't check code")
+		return
+	}
+	if !ok {
+		err = h.LoginThrottle.Failed(userStored.Login, ip, now)
+		if nil != err {
+			fmt.Println("can't save failed login attempt: ", err.Error())
+		}
+		jsonError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	err = h.TwoFactorRepo.DeleteChallenge(challenge.Token)
+	if nil != err {
+		fmt.Println("can't delete challenge: ", err.Error())
+	}
+
+	sess, err := h.SessionManager.Create(w, userStored)
+	if err != nil {
+		fmt.Println("can't create session: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't create session")
+		return
+	}
+	h.loginSucceeded(userStored.Login)
+	validToken, err := h.UserUtils.GenerateJWT(userStored, sess.ID)
+	if nil != err {
+		fmt.Println("can't generate jwt token: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't generate jwt token")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, map[string]string{
+		"token": validToken,
+	})
+}
+
+// startTwoFactorLogin answers a correct password of an enrolled user with
+// a challenge instead of a session.
+func (h *UserHandler) startTwoFactorLogin(w http.ResponseWriter, user *User) {
+	token, err := RandSecureHex(32)
+	if nil != err {
+		fmt.Println("can't generate challenge: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't generate challenge")
+		return
+	}
+	err = h.TwoFactorRepo.CreateChallenge(&LoginChallenge{
+		Token:   token,
+		UserID:  user.ID,
+		Expires: h.TimeGetter.Now().Add(LoginChallengeTTL).Format(time.RFC3339),
+	})
+	if nil != err {
+		fmt.Println("can't create challenge: ", err.Error())
+		jsonError(w, http.StatusInternalServerError, "can't create challenge")
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	jsonResponse(w, map[string]interface{}{
+		"two_factor_required": true,
+		"challenge":           token,
+	})
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused
+// recovery code.
+func (h *UserHandler) checkSecondFactor(twoFactor *UserTOTP, code string) (bool, error) {
+	if step, ok := ValidateTOTP(twoFactor.Secret, code, h.TimeGetter.Now()); ok {
+		return h.TwoFactorRepo.UseStep(twoFactor.UserID, step)
+	}
+	if code == "" {
+		return false, nil
+	}
+	return h.TwoFactorRepo.UseRecoveryCode(twoFactor.UserID, HashRecoveryCode(code))
+}
+
+func readTwoFactorCode(r *http.Request) (*TwoFactorCodeDTO, error) {
+	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if nil != err {
+		return nil, err
+	}
+	codeRequest := &TwoFactorCodeDTO{}
+	err = json.Unmarshal(body, codeRequest)
+	if nil != err {
+		return nil, err
+	}
+	return codeRequest, nil
+}
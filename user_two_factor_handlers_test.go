@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestLoginTwoFactor(t *testing.T) {
+	log.SetOutput(io.Discard)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := NewMockUserRepoI(ctrl)
+	sessionManagerMock := NewMockSessionManagerI(ctrl)
+	twoFactorRepoMock := NewMockTwoFactorRepoI(ctrl)
+	timerGetterMock := NewMockTimeGetterI(ctrl)
+	userUtilsMock := NewMockUserUtilsI(ctrl)
+	loginThrottleMock := NewMockLoginThrottleI(ctrl)
+	service := &UserHandler{
+		UserRepo:       userRepoMock,
+		SessionManager: sessionManagerMock,
+		TwoFactorRepo:  twoFactorRepoMock,
+		TimeGetter:     timerGetterMock,
+		UserUtils:      userUtilsMock,
+		LoginThrottle:  loginThrottleMock,
+	}
+
+	now := time.Date(2022, 11, 9, 19, 51, 42, 0, time.UTC)
+	secret := "JBSWY3DPEHPK3PXP"
+	code, _ := TOTPCode(secret, TOTPStep(now))
+	twoFactor := &UserTOTP{
+		UserID:  user.ID,
+		Secret:  secret,
+		Enabled: true,
+	}
+	challenge := &LoginChallenge{
+		Token:   "challenge",
+		UserID:  user.ID,
+		Expires: "2022-11-09T19:56:42Z",
+	}
+
+	//success
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now).Times(2)
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil)
+	loginThrottleMock.EXPECT().Check(user.Login, gomock.Any(), now).Return(time.Duration(0), nil)
+	twoFactorRepoMock.EXPECT().UseChallengeAttempt(challenge.Token, LoginChallengeMaxAttempts).Return(true, nil)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(twoFactor, nil)
+	twoFactorRepoMock.EXPECT().UseStep(user.ID, TOTPStep(now)).Return(true, nil)
+	twoFactorRepoMock.EXPECT().DeleteChallenge(challenge.Token).Return(nil)
+	loginThrottleMock.EXPECT().Succeeded(user.Login).Return(nil)
+	req := httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"challenge":"challenge","code":"%s"}`, code)))
+	w := httptest.NewRecorder()
+	sessionManagerMock.EXPECT().Create(w, user).Return(sessUser, nil)
+	userUtilsMock.EXPECT().GenerateJWT(user, sessUser.ID).Return(token, nil)
+	service.LoginTwoFactor(w, req)
+	resp := w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != respLogin {
+		t.Errorf("it's not matched; want: %#v; have: %#v", respLogin, string(body))
+		return
+	}
+
+	//replayed code, recovery code doesn't match either
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now).Times(2)
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil)
+	loginThrottleMock.EXPECT().Check(user.Login, gomock.Any(), now).Return(time.Duration(0), nil)
+	twoFactorRepoMock.EXPECT().UseChallengeAttempt(challenge.Token, LoginChallengeMaxAttempts).Return(true, nil)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(twoFactor, nil)
+	twoFactorRepoMock.EXPECT().UseStep(user.ID, TOTPStep(now)).Return(false, nil)
+	loginThrottleMock.EXPECT().Failed(user.Login, gomock.Any(), now).Return(nil)
+	req = httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"challenge":"challenge","code":"%s"}`, code)))
+	w = httptest.NewRecorder()
+	service.LoginTwoFactor(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//recovery code
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now).Times(2)
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil)
+	loginThrottleMock.EXPECT().Check(user.Login, gomock.Any(), now).Return(time.Duration(0), nil)
+	twoFactorRepoMock.EXPECT().UseChallengeAttempt(challenge.Token, LoginChallengeMaxAttempts).Return(true, nil)
+	twoFactorRepoMock.EXPECT().GetByUserId(user.ID).Return(twoFactor, nil)
+	twoFactorRepoMock.EXPECT().UseRecoveryCode(user.ID, HashRecoveryCode("abcde-12345")).Return(true, nil)
+	twoFactorRepoMock.EXPECT().DeleteChallenge(challenge.Token).Return(nil)
+	loginThrottleMock.EXPECT().Succeeded(user.Login).Return(nil)
+	req = httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(`{"challenge":"challenge","code":"abcde-12345"}`))
+	w = httptest.NewRecorder()
+	sessionManagerMock.EXPECT().Create(w, user).Return(sessUser, nil)
+	userUtilsMock.EXPECT().GenerateJWT(user, sessUser.ID).Return(token, nil)
+	service.LoginTwoFactor(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//no attempts left
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now)
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil)
+	loginThrottleMock.EXPECT().Check(user.Login, gomock.Any(), now).Return(time.Duration(0), nil)
+	twoFactorRepoMock.EXPECT().UseChallengeAttempt(challenge.Token, LoginChallengeMaxAttempts).Return(false, nil)
+	twoFactorRepoMock.EXPECT().DeleteChallenge(challenge.Token).Return(nil)
+	req = httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"challenge":"challenge","code":"%s"}`, code)))
+	w = httptest.NewRecorder()
+	service.LoginTwoFactor(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//the account is locked by the failed passwords and codes
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now)
+	userRepoMock.EXPECT().GetById(user.ID).Return(user, nil)
+	loginThrottleMock.EXPECT().Check(user.Login, gomock.Any(), now).Return(time.Minute, nil)
+	req = httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"challenge":"challenge","code":"%s"}`, code)))
+	w = httptest.NewRecorder()
+	service.LoginTwoFactor(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("expected 429 status code; got: %d", resp.StatusCode)
+		return
+	}
+
+	//expired challenge
+	twoFactorRepoMock.EXPECT().GetChallenge(challenge.Token).Return(challenge, nil)
+	timerGetterMock.EXPECT().Now().Return(now.Add(time.Hour))
+	twoFactorRepoMock.EXPECT().DeleteChallenge(challenge.Token).Return(nil)
+	req = httptest.NewRequest("POST", "/api/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"challenge":"challenge","code":"%s"}`, code)))
+	w = httptest.NewRecorder()
+	service.LoginTwoFactor(w, req)
+	resp = w.Result()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 status code; got: %d", resp.StatusCode)
+		return
+	}
+}
@@ -1,15 +1,105 @@
 package main
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type UserUtils struct{}
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// PasswordHashConfig is the target for new and rehashed passwords.
+type PasswordHashConfig struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+var DefaultPasswordHashConfig = PasswordHashConfig{
+	Algorithm:     HashBcrypt,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Time:    2,
+	Argon2Memory:  19 * 1024,
+	Argon2Threads: 1,
+}
+
+// PasswordHashConfigFromEnv reads PASSWORD_HASH (bcrypt or argon2id),
+// BCRYPT_COST, ARGON2_TIME, ARGON2_MEMORY (KiB) and ARGON2_THREADS.
+func PasswordHashConfigFromEnv() (PasswordHashConfig, error) {
+	config := DefaultPasswordHashConfig
+	if algorithm := os.Getenv("PASSWORD_HASH"); algorithm != "" {
+		config.Algorithm = algorithm
+	}
+	if cost, err := strconv.Atoi(os.Getenv("BCRYPT_COST")); err == nil {
+		config.BcryptCost = cost
+	}
+	if t, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil {
+		config.Argon2Time = uint32(t)
+	}
+	if m, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil {
+		config.Argon2Memory = uint32(m)
+	}
+	if p, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil {
+		config.Argon2Threads = uint8(p)
+	}
+	return config, config.Validate()
+}
+
+// Validate rejects the configs no password could be hashed with
+func (config PasswordHashConfig) Validate() error {
+	switch config.Algorithm {
+	case HashBcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be from %d to %d: %d", bcrypt.MinCost, bcrypt.MaxCost, config.BcryptCost)
+		}
+	case HashArgon2id:
+		if config.Argon2Time < 1 || config.Argon2Threads < 1 {
+			return fmt.Errorf("argon2id time and threads must be positive")
+		}
+		if config.Argon2Memory < 8*uint32(config.Argon2Threads) {
+			return fmt.Errorf("argon2id memory must be at least 8 KiB per thread: %d", config.Argon2Memory)
+		}
+	default:
+		return fmt.Errorf("unknown password hash algorithm: %s", config.Algorithm)
+	}
+	return nil
+}
+
+type UserUtils struct {
+	HashConfig PasswordHashConfig
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewUserUtils panics on a broken config, main checks it before the
+// handlers are made
+func NewUserUtils() *UserUtils {
+	config, err := PasswordHashConfigFromEnv()
+	if nil != err {
+		panic("bad password hash config: " + err.Error())
+	}
+	fmt.Printf("password hash: %s\n", config.Algorithm)
+	utils := &UserUtils{
+		HashConfig: config,
+	}
+	utils.dummyPasswordHash()
+	return utils
+}
 
 func (u *UserUtils) GenerateJWT(user *User, sessID string) (string, error) {
 	var signingKey = []byte(os.Getenv("SECRET_KEY"))
@@ -33,17 +123,145 @@ func (u *UserUtils) GenerateJWT(user *User, sessID string) (string, error) {
 	return tokenString, nil
 }
 
+func (u *UserUtils) config() PasswordHashConfig {
+	if u.HashConfig.Algorithm == "" {
+		return DefaultPasswordHashConfig
+	}
+	return u.HashConfig
+}
+
 func (u *UserUtils) GeneratePasswordHash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	config := u.config()
+	switch config.Algorithm {
+	case HashArgon2id:
+		salt, err := RandSecureBytes(16)
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt,
+			config.Argon2Time, config.Argon2Memory, config.Argon2Threads, 32)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, config.Argon2Memory, config.Argon2Time, config.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	case HashBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), config.BcryptCost)
+		return string(bytes), err
+	}
+	return "", fmt.Errorf("unknown password hash algorithm: %s", config.Algorithm)
 }
 
+// CheckPasswordHash compares against a dummy hash when the stored one
+// is missing or broken, so the check always costs about the same.
 func (u *UserUtils) CheckPasswordHash(passwordReceived string, hash string) bool {
+	if !u.IsValidPasswordHash(hash) {
+		fmt.Printf("check password hash: not a valid hash\n")
+		comparePasswordHash(passwordReceived, u.dummyPasswordHash())
+		return false
+	}
+	return comparePasswordHash(passwordReceived, hash)
+}
+
+// comparePasswordHash is false for the hashes which are not valid
+func comparePasswordHash(passwordReceived string, hash string) bool {
+	if params, ok := parseArgon2Hash(hash); ok {
+		key := argon2.IDKey([]byte(passwordReceived), params.salt,
+			params.time, params.memory, params.threads, uint32(len(params.key)))
+		return subtle.ConstantTimeCompare(key, params.key) == 1
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(passwordReceived))
-	isEqual := nil == err
-	if !isEqual {
+	if nil != err {
 		fmt.Printf("check password hash: %s", err.Error())
 		return false
 	}
-	return isEqual
+	return true
+}
+
+// NeedsRehash reports a valid hash made by another algorithm or with
+// other parameters than configured now.
+func (u *UserUtils) NeedsRehash(hash string) bool {
+	config := u.config()
+	if params, ok := parseArgon2Hash(hash); ok {
+		return config.Algorithm != HashArgon2id ||
+			params.time != config.Argon2Time ||
+			params.memory != config.Argon2Memory ||
+			params.threads != config.Argon2Threads
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return config.Algorithm != HashBcrypt || cost != config.BcryptCost
+}
+
+func (u *UserUtils) IsValidPasswordHash(hash string) bool {
+	if _, ok := parseArgon2Hash(hash); ok {
+		return true
+	}
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// dummyPasswordHash falls back to the default config, so it's a valid
+// hash even when the configured one can't be made
+func (u *UserUtils) dummyPasswordHash() string {
+	u.dummyOnce.Do(func() {
+		var err error
+		u.dummyHash, err = u.GeneratePasswordHash(RandStringRunes(16))
+		if nil != err {
+			fmt.Println("can't make dummy password hash: ", err.Error())
+			u.dummyHash, _ = (&UserUtils{}).GeneratePasswordHash(RandStringRunes(16))
+		}
+	})
+	return u.dummyHash
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2Hash(hash string) (*argon2Params, bool) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return nil, false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, false
+	}
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, false
+	}
+	var err error
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, false
+	}
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(params.key) == 0 {
+		return nil, false
+	}
+	return params, true
+}
+
+// CheckStoredPasswordHashes is run on startup and reports accounts whose
+// password column doesn't hold a hash; such accounts can't log in.
+func CheckStoredPasswordHashes(repo *UserRepo, utils *UserUtils) ([]string, error) {
+	hashes, err := repo.GetPasswordHashes()
+	if err != nil {
+		return nil, err
+	}
+	invalid := []string{}
+	for login, hash := range hashes {
+		if !utils.IsValidPasswordHash(hash) {
+			fmt.Printf("WARNING: user %s has a password that is not a valid hash\n", login)
+			invalid = append(invalid, login)
+		}
+	}
+	return invalid, nil
 }
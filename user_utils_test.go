@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestPasswordHash(t *testing.T) {
+	log.SetOutput(io.Discard)
+	bcryptUtils := &UserUtils{HashConfig: PasswordHashConfig{
+		Algorithm:  HashBcrypt,
+		BcryptCost: 4,
+	}}
+	argonUtils := &UserUtils{HashConfig: PasswordHashConfig{
+		Algorithm:     HashArgon2id,
+		BcryptCost:    4,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	}}
+
+	for _, utils := range []*UserUtils{bcryptUtils, argonUtils} {
+		hash, err := utils.GeneratePasswordHash("testtest")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			return
+		}
+		if !utils.CheckPasswordHash("testtest", hash) {
+			t.Errorf("%s: expected password to match", utils.HashConfig.Algorithm)
+			return
+		}
+		if utils.CheckPasswordHash("wrong", hash) {
+			t.Errorf("%s: expected wrong password to fail", utils.HashConfig.Algorithm)
+			return
+		}
+		if utils.NeedsRehash(hash) {
+			t.Errorf("%s: fresh hash shouldn't need rehash", utils.HashConfig.Algorithm)
+			return
+		}
+	}
+
+	// algorithm change
+	bcryptHash, _ := bcryptUtils.GeneratePasswordHash("testtest")
+	if !argonUtils.NeedsRehash(bcryptHash) {
+		t.Errorf("expected bcrypt hash to need rehash for argon2id config")
+		return
+	}
+	// argon2id hash checked with bcrypt config still works
+	argonHash, _ := argonUtils.GeneratePasswordHash("testtest")
+	if !bcryptUtils.CheckPasswordHash("testtest", argonHash) || !bcryptUtils.NeedsRehash(argonHash) {
+		t.Errorf("expected argon2id hash to be valid and need rehash for bcrypt config")
+		return
+	}
+	// cost change
+	costlyUtils := &UserUtils{HashConfig: PasswordHashConfig{Algorithm: HashBcrypt, BcryptCost: 5}}
+	if !costlyUtils.NeedsRehash(bcryptHash) {
+		t.Errorf("expected bcrypt hash with other cost to need rehash")
+		return
+	}
+
+	// plain text password from the old seed
+	if bcryptUtils.IsValidPasswordHash("test") || bcryptUtils.CheckPasswordHash("test", "test") {
+		t.Errorf("expected plain text password to be rejected")
+		return
+	}
+	if bcryptUtils.NeedsRehash("test") {
+		t.Errorf("invalid hash can't be rehashed")
+		return
+	}
+}
+
+func TestPasswordHashConfig(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Setenv("PASSWORD_HASH", "")
+	if _, err := PasswordHashConfigFromEnv(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	bad := []map[string]string{
+		{"PASSWORD_HASH": "md5"},
+		{"BCRYPT_COST": "32"},
+		{"BCRYPT_COST": "3"},
+		{"PASSWORD_HASH": HashArgon2id, "ARGON2_THREADS": "0"},
+		{"PASSWORD_HASH": HashArgon2id, "ARGON2_TIME": "0"},
+		{"PASSWORD_HASH": HashArgon2id, "ARGON2_MEMORY": "4"},
+	}
+	for _, env := range bad {
+		for _, key := range []string{"PASSWORD_HASH", "BCRYPT_COST", "ARGON2_TIME", "ARGON2_MEMORY", "ARGON2_THREADS"} {
+			t.Setenv(key, env[key])
+		}
+		if _, err := PasswordHashConfigFromEnv(); err == nil {
+			t.Errorf("expected error for %v", env)
+		}
+	}
+
+	// a broken config still checks against a valid dummy hash and fails
+	brokenUtils := &UserUtils{HashConfig: PasswordHashConfig{Algorithm: HashBcrypt, BcryptCost: 32}}
+	if brokenUtils.CheckPasswordHash("test", "") {
+		t.Errorf("expected empty hash to be rejected")
+		return
+	}
+	if !brokenUtils.IsValidPasswordHash(brokenUtils.dummyPasswordHash()) {
+		t.Errorf("expected valid dummy hash")
+	}
+}
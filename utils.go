@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 )
 
@@ -23,6 +24,14 @@ func jsonError(w http.ResponseWriter, status int, msg string) {
 	w.Write(resp)
 }
 
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if nil != err {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func PostToDTO(post *Post) *PostDTO {
 	author := &AuthorDTO{
 		UserName: "test author",
@@ -50,3 +50,110 @@ func (repo *VoteRepo) GetVotesByPostIds(postIds []string) (map[string][]*Vote, e
 	}
 	return votes, err
 }
+
+// Vote sets the vote of the user on the post, VoteNone takes it back. The
+// score of the post and the post karma of the author move by the change in
+// the same transaction, so neither is recomputed from the votes. Neither is
+// clamped either, a clamped score would drift from the votes. It returns
+// false when the vote is the same already.
+func (repo *VoteRepo) Vote(postID string, userID string, vote int32) (bool, error) {
+	fmt.Println("Vote repo: vote")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var authorID string
+	err = tx.QueryRow(`SELECT user_id FROM post WHERE id = ? FOR UPDATE`, postID).Scan(&authorID)
+	if nil != err {
+		return false, err
+	}
+	var previous int32
+	err = tx.QueryRow(`SELECT vote FROM vote WHERE post_id = ? AND user_id = ? FOR UPDATE`, postID, userID).
+		Scan(&previous)
+	if nil != err && err != sql.ErrNoRows {
+		return false, err
+	}
+	if previous == vote {
+		return false, nil
+	}
+
+	if vote == VoteNone {
+		_, err = tx.Exec(`DELETE FROM vote WHERE post_id = ? AND user_id = ?`, postID, userID)
+	} else {
+		_, err = tx.Exec(`INSERT INTO vote (post_id, user_id, vote) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE vote = VALUES(vote)`, postID, userID, vote)
+	}
+	if nil != err {
+		return false, err
+	}
+	delta := vote - previous
+	_, err = tx.Exec(`UPDATE post SET score = score + ? WHERE id = ?`, delta, postID)
+	if nil != err {
+		return false, err
+	}
+	// the own votes don't count
+	if authorID != userID {
+		_, err = tx.Exec(`UPDATE user SET post_karma = post_karma + ? WHERE id = ?`, delta, authorID)
+		if nil != err {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// VoteComment is Vote for the comments, they keep the ups and the downs
+// for the best and the controversial sorts, the score is their difference
+func (repo *VoteRepo) VoteComment(commentID string, userID string, vote int32) (bool, error) {
+	fmt.Println("Vote repo: vote comment")
+	tx, err := repo.DB.Begin()
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var authorID string
+	err = tx.QueryRow(`SELECT user_id FROM comment WHERE id = ? FOR UPDATE`, commentID).Scan(&authorID)
+	if nil != err {
+		return false, err
+	}
+	var previous int32
+	err = tx.QueryRow(`SELECT vote FROM comment_vote WHERE comment_id = ? AND user_id = ? FOR UPDATE`, commentID, userID).
+		Scan(&previous)
+	if nil != err && err != sql.ErrNoRows {
+		return false, err
+	}
+	if previous == vote {
+		return false, nil
+	}
+
+	if vote == VoteNone {
+		_, err = tx.Exec(`DELETE FROM comment_vote WHERE comment_id = ? AND user_id = ?`, commentID, userID)
+	} else {
+		_, err = tx.Exec(`INSERT INTO comment_vote (comment_id, user_id, vote) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE vote = VALUES(vote)`, commentID, userID, vote)
+	}
+	if nil != err {
+		return false, err
+	}
+	ups, downs := voteCount(vote, VoteUp)-voteCount(previous, VoteUp), voteCount(vote, VoteDown)-voteCount(previous, VoteDown)
+	_, err = tx.Exec(`UPDATE comment SET ups = ups + ?, downs = downs + ? WHERE id = ?`, ups, downs, commentID)
+	if nil != err {
+		return false, err
+	}
+	if authorID != userID {
+		_, err = tx.Exec(`UPDATE user SET comment_karma = comment_karma + ? WHERE id = ?`, vote-previous, authorID)
+		if nil != err {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func voteCount(vote int32, kind int32) int32 {
+	if vote == kind {
+		return 1
+	}
+	return 0
+}
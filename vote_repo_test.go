@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewVoteRepo(db)
+
+	// a downvote turns into an upvote: the score and the karma move by 2
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM post WHERE id = \? FOR UPDATE`).
+		WithArgs("p1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("author"))
+	mock.ExpectQuery(`SELECT vote FROM vote WHERE post_id = \? AND user_id = \? FOR UPDATE`).
+		WithArgs("p1", "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"vote"}).AddRow(-1))
+	mock.ExpectExec(`INSERT INTO vote .* ON DUPLICATE KEY UPDATE vote = VALUES\(vote\)`).
+		WithArgs("p1", "u1", VoteUp).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE post SET score = score \+ \? WHERE id = \?`).
+		WithArgs(int32(2), "p1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user SET post_karma = post_karma \+ \? WHERE id = \?`).
+		WithArgs(int32(2), "author").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isChanged, err := repo.Vote("p1", "u1", VoteUp)
+	if err != nil || !isChanged {
+		t.Errorf("expected changed vote, got %v %v", isChanged, err)
+		return
+	}
+
+	// unvote of the own post leaves the karma
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM post`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u1"))
+	mock.ExpectQuery(`SELECT vote FROM vote`).
+		WillReturnRows(sqlmock.NewRows([]string{"vote"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM vote WHERE post_id = \? AND user_id = \?`).
+		WithArgs("p1", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE post SET score`).
+		WithArgs(int32(-1), "p1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isChanged, err = repo.Vote("p1", "u1", VoteNone)
+	if err != nil || !isChanged {
+		t.Errorf("expected changed vote, got %v %v", isChanged, err)
+		return
+	}
+
+	// the same vote again changes nothing
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM post`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("author"))
+	mock.ExpectQuery(`SELECT vote FROM vote`).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	isChanged, err = repo.Vote("p1", "u1", VoteNone)
+	if err != nil || isChanged {
+		t.Errorf("expected unchanged vote, got %v %v", isChanged, err)
+		return
+	}
+
+	// db error rolls back
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM post`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("author"))
+	mock.ExpectQuery(`SELECT vote FROM vote`).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`INSERT INTO vote`).
+		WillReturnError(fmt.Errorf("db_error"))
+	mock.ExpectRollback()
+	_, err = repo.Vote("p1", "u1", VoteDown)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestVoteComment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	repo := NewVoteRepo(db)
+
+	// an upvote turns into a downvote
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM comment WHERE id = \? FOR UPDATE`).
+		WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("author"))
+	mock.ExpectQuery(`SELECT vote FROM comment_vote WHERE comment_id = \? AND user_id = \? FOR UPDATE`).
+		WithArgs("c1", "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"vote"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO comment_vote .* ON DUPLICATE KEY UPDATE vote = VALUES\(vote\)`).
+		WithArgs("c1", "u1", VoteDown).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE comment SET ups = ups \+ \?, downs = downs \+ \? WHERE id = \?`).
+		WithArgs(int32(-1), int32(1), "c1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user SET comment_karma = comment_karma \+ \? WHERE id = \?`).
+		WithArgs(int32(-2), "author").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isChanged, err := repo.VoteComment("c1", "u1", VoteDown)
+	if err != nil || !isChanged {
+		t.Errorf("expected changed vote, got %v %v", isChanged, err)
+		return
+	}
+
+	// unvote
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM comment`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("author"))
+	mock.ExpectQuery(`SELECT vote FROM comment_vote`).
+		WillReturnRows(sqlmock.NewRows([]string{"vote"}).AddRow(-1))
+	mock.ExpectExec(`DELETE FROM comment_vote WHERE comment_id = \? AND user_id = \?`).
+		WithArgs("c1", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE comment SET ups`).
+		WithArgs(int32(0), int32(-1), "c1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user SET comment_karma`).
+		WithArgs(int32(1), "author").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	isChanged, err = repo.VoteComment("c1", "u1", VoteNone)
+	if err != nil || !isChanged {
+		t.Errorf("expected changed vote, got %v %v", isChanged, err)
+		return
+	}
+
+	// unknown comment
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM comment`).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	_, err = repo.VoteComment("c9", "u1", VoteUp)
+	if err != sql.ErrNoRows {
+		t.Errorf("expected no rows error, got %v", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there are unfulfilled expectations: %s", err)
+		return
+	}
+}
+
+func TestVoteScoreBelowZero(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error %s was not expected when open stub connetcion", err)
+	}
+	defer db.Close()
+
+	// the score column keeps the sum of the votes, it shows as zero
+	rows := sqlmock.NewRows([]string{
+		"post_id", "title", "type", "description", "score", "user_id", "category_id", "post_created",
+		"removed", "locked", "pinned", "flair", "url", "image",
+		"user_user_id", "login", "category_name",
+		"preview_title", "preview_description", "preview_image",
+	}).AddRow("p1", "title", "text", "text", -2, "author", 1, "2022-11-09T19:51:42Z",
+		false, false, false, "", "", "", "author", "mer", "fashion", "", "", "")
+	mock.ExpectQuery(`WHERE post.id = \? AND post.deleted_at = ''`).
+		WithArgs("p1").
+		WillReturnRows(rows)
+	data, err := NewPostsRepo(db).GetById("p1")
+	if err != nil || data.Post.Score != 0 {
+		t.Errorf("expected zero score, got %v %v", data, err)
+	}
+}